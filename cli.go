@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	scalarmWorker "github.com/scalarm/scalarm_simulation_manager_go/scalarmWorker"
+)
+
+// Command is a single subcommand of the SiM command line interface;
+// Setup defines flags of the command and returns a function executing it with positional arguments
+type Command struct {
+	Name        string
+	Args        string
+	Description string
+	Setup       func(flags *flag.FlagSet) func(args []string) error
+}
+
+const defaultCommand = "run"
+
+var validateConfigLog = scalarmWorker.NewLogger("validate-config")
+
+func addConfigFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", "config.json", "path to the config file")
+}
+
+func addLogLevelFlag(flags *flag.FlagSet) *string {
+	return flags.String("log-level", "", "minimum level of printed messages: debug, info, warn or error, overrides 'log_level' from the config file")
+}
+
+func applyLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+
+	return scalarmWorker.SetLogLevel(level)
+}
+
+func commands() []Command {
+	return []Command{
+		{
+			Name:        "run",
+			Description: "fetch and execute simulation runs from Scalarm (default command)",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+				simulationsLimit := flags.Int("simulations_limit", -1, "max number of simulation run to execute, overrides 'simulations_limit' from the config file")
+				tail := flags.Bool("tail", false, "print output of the executor to the console while it runs")
+				pauseOnFailure := flags.Bool("pause-on-failure", false, "keep the simulation directory and wait for inspection when an adapter fails")
+				showDashboard := flags.Bool("dashboard", false, "show a terminal dashboard with the current simulation run instead of the output")
+				logLevel := addLogLevelFlag(flags)
+
+				return func(args []string) error {
+					if err := applyLogLevel(*logLevel); err != nil {
+						return err
+					}
+
+					sim, err := newSimulationManager(*configPath)
+					if err != nil {
+						return err
+					}
+
+					// the flag overrides log_level from the config file
+					if err = applyLogLevel(*logLevel); err != nil {
+						return err
+					}
+					if *logLevel != "" {
+						sim.Config.LogLevel = *logLevel
+					}
+
+					if *simulationsLimit != -1 {
+						sim.Config.SimulationsLimit = *simulationsLimit
+					}
+					if *tail {
+						sim.Config.Tail = true
+					}
+					if *pauseOnFailure {
+						sim.Config.PauseOnFailure = true
+					}
+					if *showDashboard {
+						sim.Config.Dashboard = true
+					}
+
+					sim.Run()
+					return nil
+				}
+			},
+		},
+		{
+			Name:        "agent",
+			Description: "launch and supervise multiple workers on this node",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+				workers := flags.Int("workers", runtime.NumCPU(), "number of workers")
+				logPath := flags.String("log", "", "file to which output of all workers is also appended")
+				logLevel := addLogLevelFlag(flags)
+				coordinate := flags.Bool("coordinator", false, "fetch simulation runs once for all workers and dispatch them over a unix socket")
+				fleetPath := flags.String("fleet", "", "fleet config file with the number of workers and CPUs each of them is pinned to, overrides -workers")
+
+				return func(args []string) error {
+					if err := applyLogLevel(*logLevel); err != nil {
+						return err
+					}
+
+					var slots []scalarmWorker.FleetSlot
+					if *fleetPath != "" {
+						fleet, err := scalarmWorker.LoadFleetConfig(*fleetPath)
+						if err != nil {
+							return err
+						}
+
+						if slots, err = fleet.Plan(scalarmWorker.DetectCPUs()); err != nil {
+							return err
+						}
+						*workers = len(slots)
+
+						// only the coordinator matches simulation runs to kinds of slots
+						for _, slot := range slots {
+							if slot.Kind != "" && !*coordinate {
+								return errors.New("CPU and GPU slots require -coordinator")
+							}
+						}
+					}
+
+					var coordinator *scalarmWorker.Coordinator
+					var config *scalarmWorker.SimulationManagerConfig
+					var rootDirPath string
+
+					if *coordinate {
+						sim, err := newSimulationManager(*configPath)
+						if err != nil {
+							return err
+						}
+						config, rootDirPath = sim.Config, sim.RootDirPath
+
+						batchSize := *workers
+						if sim.Config.SimulationBatchSize > 1 {
+							batchSize = sim.Config.SimulationBatchSize
+						}
+						socketPath := filepath.Join(rootDirPath, "coordinator.sock")
+						coordinator = scalarmWorker.NewCoordinator(socketPath, sim.Config, sim.HttpClient, batchSize)
+					} else {
+						rootDirPath = printBanner()
+
+						var err error
+						if config, err = scalarmWorker.CreateSimulationManagerConfig(*configPath); err != nil {
+							return err
+						}
+					}
+
+					executable, err := os.Executable()
+					if err != nil {
+						return err
+					}
+
+					absoluteConfigPath, err := filepath.Abs(*configPath)
+					if err != nil {
+						return err
+					}
+
+					workerCommand := []string{executable, "run", "-config", absoluteConfigPath}
+					if *logLevel != "" {
+						workerCommand = append(workerCommand, "-log-level", *logLevel)
+					}
+
+					agent := scalarmWorker.NewAgent(workerCommand, *workers, rootDirPath)
+					agent.Coordinator = coordinator
+					agent.Slots = slots
+
+					if config.AdminAddress != "" {
+						token, err := scalarmWorker.AdminToken(config, rootDirPath)
+						if err != nil {
+							return err
+						}
+						agent.Admin = scalarmWorker.NewAdminServer(config.AdminAddress, token, agent.AdminController(absoluteConfigPath, config))
+					}
+
+					if *logPath != "" {
+						logFile, err := os.OpenFile(*logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+						if err != nil {
+							return err
+						}
+						defer logFile.Close()
+						agent.Output = io.MultiWriter(os.Stdout, scalarmWorker.RedactingWriter{Writer: logFile})
+					}
+
+					return agent.Run()
+				}
+			},
+		},
+		{
+			Name:        "validate-config",
+			Description: "check the config file without contacting Scalarm",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+
+				return func(args []string) error {
+					config, err := scalarmWorker.CreateSimulationManagerConfig(*configPath)
+					if err != nil {
+						return err
+					}
+
+					errs := config.Validate()
+					for _, err := range errs {
+						validateConfigLog.Errorf("%v", err)
+					}
+
+					if len(errs) > 0 {
+						return fmt.Errorf("%s is not valid", *configPath)
+					}
+
+					validateConfigLog.Infof("%s is valid", *configPath)
+					return nil
+				}
+			},
+		},
+		{
+			Name:        "doctor",
+			Description: "check connectivity with Scalarm and the local environment",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+
+				return func(args []string) error {
+					sim, err := newSimulationManager(*configPath)
+					if err != nil {
+						return err
+					}
+
+					return sim.Doctor()
+				}
+			},
+		},
+		{
+			Name:        "verify-codebase",
+			Description: "download the experiment code base and check its adapters",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+
+				return func(args []string) error {
+					sim, err := newSimulationManager(*configPath)
+					if err != nil {
+						return err
+					}
+
+					return sim.VerifyCodeBase()
+				}
+			},
+		},
+		{
+			Name:        "replay",
+			Args:        "<simulation directory | report.json>",
+			Description: "re-execute a saved simulation run locally without contacting Scalarm",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					if len(args) != 1 {
+						return errors.New("replay requires exactly one argument: a simulation directory or a report.json file")
+					}
+
+					return scalarmWorker.Replay(args[0], printBanner())
+				}
+			},
+		},
+		{
+			Name:        "history",
+			Description: "list simulation runs executed on this node",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				configPath := addConfigFlag(flags)
+				experimentID := flags.String("experiment", "", "show only runs of the experiment with this id")
+				status := flags.String("status", "", "show only finished runs with this status, e.g. 'ok' or 'error'")
+				since := flags.Duration("since", 0, "show only entries recorded within this period, e.g. '24h'")
+				limit := flags.Int("limit", 50, "max number of the most recent entries, 0 shows all of them")
+				asJSON := flags.Bool("json", false, "print entries as JSON lines")
+
+				return func(args []string) error {
+					config, err := scalarmWorker.CreateSimulationManagerConfig(*configPath)
+					if err != nil {
+						return err
+					}
+
+					rootDirPath, err := os.Getwd()
+					if err != nil {
+						return err
+					}
+
+					filter := scalarmWorker.HistoryFilter{ExperimentID: *experimentID, Status: *status, Limit: *limit}
+					if *since > 0 {
+						filter.Since = time.Now().Add(-*since)
+					}
+
+					historyPath := scalarmWorker.HistoryPath(config, rootDirPath)
+					if historyPath == "" {
+						return errors.New("history_file is not set in the config, the history is not recorded")
+					}
+
+					entries, err := scalarmWorker.ReadHistory(historyPath, filter)
+					if err != nil {
+						return err
+					}
+
+					for _, entry := range entries {
+						if *asJSON {
+							line, _ := json.Marshal(entry)
+							fmt.Println(string(line))
+							continue
+						}
+
+						duration := ""
+						if entry.FinishedAt > 0 {
+							duration = (time.Duration(entry.FinishedAt-entry.StartedAt) * time.Second).String()
+						}
+						fmt.Printf("%s  worker %-3s %-8s %s/%-6d %-6s %-22s %-8s %d artifacts\n",
+							time.Unix(entry.Time, 0).Format(time.RFC3339), entry.Worker, entry.Event, entry.ExperimentID,
+							entry.SimulationIndex, entry.Status, entry.ReasonCode, duration, len(entry.Artifacts))
+					}
+
+					return nil
+				}
+			},
+		},
+		{
+			Name:        "version",
+			Description: "print the version",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					fmt.Println(VERSION)
+					return nil
+				}
+			},
+		},
+		{
+			Name:        "completion",
+			Args:        "<bash | zsh>",
+			Description: "print a shell completion script",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					if len(args) != 1 {
+						return errors.New("completion requires a shell name: bash or zsh")
+					}
+
+					script, err := completionScript(args[0])
+					if err != nil {
+						return err
+					}
+
+					fmt.Print(script)
+					return nil
+				}
+			},
+		},
+		{
+			Name:        "help",
+			Args:        "[command]",
+			Description: "print help about SiM or one of its commands",
+			Setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					if len(args) == 0 {
+						printUsage()
+						return nil
+					}
+
+					command, ok := findCommand(args[0])
+					if !ok {
+						return fmt.Errorf("unknown command '%s'", args[0])
+					}
+
+					commandFlags, _ := newFlagSet(command)
+					commandFlags.SetOutput(os.Stdout)
+					commandFlags.Usage()
+					return nil
+				}
+			},
+		},
+	}
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+func findCommand(name string) (Command, bool) {
+	for _, command := range commands() {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return Command{}, false
+}
+
+// newFlagSet creates a flag set with all flags of the command defined and generated usage,
+// the returned function executes the command
+func newFlagSet(command Command) (*flag.FlagSet, func(args []string) error) {
+	flags := flag.NewFlagSet(command.Name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [options] %s\n\n%s\n\nOptions:\n",
+			programName(), command.Name, command.Args, command.Description)
+		flags.PrintDefaults()
+	}
+
+	return flags, command.Setup(flags)
+}
+
+func printUsage() {
+	fmt.Printf("Usage: %s [command] [options]\n\nCommands:\n", programName())
+	for _, command := range commands() {
+		fmt.Printf("  %-16s %s\n", command.Name, command.Description)
+	}
+	fmt.Printf("\nRun '%s help <command>' for options of a command.\n", programName())
+}
+
+// runCommand executes the command named by the first argument, 'run' is used when
+// the first argument is missing or is an option for backward compatibility
+func runCommand(args []string) error {
+	name := defaultCommand
+
+	if len(args) > 0 {
+		switch {
+		case args[0] == "-h" || args[0] == "-help" || args[0] == "--help":
+			printUsage()
+			return nil
+		case !strings.HasPrefix(args[0], "-"):
+			name = args[0]
+			args = args[1:]
+		}
+	}
+
+	command, ok := findCommand(name)
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command '%s'", name)
+	}
+
+	flags, execute := newFlagSet(command)
+	flags.Parse(args)
+
+	return execute(flags.Args())
+}
+
+// completionScript generates a completion script for the given shell based on defined commands and their flags
+func completionScript(shell string) (string, error) {
+	names := []string{}
+	flagsByCommand := map[string][]string{}
+
+	for _, command := range commands() {
+		names = append(names, command.Name)
+
+		flagNames := []string{}
+		commandFlags, _ := newFlagSet(command)
+		commandFlags.VisitAll(func(f *flag.Flag) {
+			flagNames = append(flagNames, "-"+f.Name)
+		})
+		flagsByCommand[command.Name] = flagNames
+	}
+
+	program := programName()
+	functionName := "_" + strings.Replace(program, "-", "_", -1)
+
+	switch shell {
+	case "bash":
+		script := fmt.Sprintf("%s() {\n", functionName)
+		script += "  local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n"
+		script += "  if [ \"$COMP_CWORD\" -eq 1 ]; then\n"
+		script += fmt.Sprintf("    COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(names, " "))
+		script += "    return\n  fi\n"
+		script += "  case \"${COMP_WORDS[1]}\" in\n"
+		for _, name := range names {
+			script += fmt.Sprintf("    %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", name, strings.Join(flagsByCommand[name], " "))
+		}
+		script += "  esac\n}\n"
+		script += fmt.Sprintf("complete -o default -F %s %s\n", functionName, program)
+		return script, nil
+
+	case "zsh":
+		script := fmt.Sprintf("#compdef %s\n\n%s() {\n", program, functionName)
+		script += "  if (( CURRENT == 2 )); then\n"
+		script += fmt.Sprintf("    compadd -- %s\n", strings.Join(names, " "))
+		script += "    return\n  fi\n"
+		script += "  case \"${words[2]}\" in\n"
+		for _, name := range names {
+			script += fmt.Sprintf("    %s) compadd -- %s; _files ;;\n", name, strings.Join(flagsByCommand[name], " "))
+		}
+		script += "  esac\n}\n\n"
+		script += fmt.Sprintf("compdef %s %s\n", functionName, program)
+		return script, nil
+	}
+
+	return "", fmt.Errorf("unsupported shell '%s', use bash or zsh", shell)
+}
@@ -1,65 +1,116 @@
 package main
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
-
-	"math/rand"
+	"path/filepath"
 	"time"
 
 	scalarmWorker "github.com/scalarm/scalarm_simulation_manager_go/scalarmWorker"
 )
 
+var simLog = scalarmWorker.NewLogger("")
+
 // VERSION current version of the app
 const VERSION string = "17.04"
 
 // Fatal utility function to log a fatal error
 func Fatal(err error) {
-	fmt.Printf("[Fatal error] %v\n", err)
-	os.Exit(1)
+	scalarmWorker.Fatal(err)
 }
 
-func main() {
-	fmt.Printf("[SiM] Scalarm Simulation Manager, version: %s\n", VERSION)
-	rand.Seed(time.Now().UTC().UnixNano())
+// printBanner prints the version and remembers the current location as the root dir of SiM
+func printBanner() string {
+	simLog.Infof("Scalarm Simulation Manager, version: %s", VERSION)
 
-	// 0. remember current location
 	rootDirPath, _ := os.Getwd()
-	fmt.Printf("[SiM] working directory: %s\n", rootDirPath)
+	simLog.Infof("working directory: %s", rootDirPath)
+
+	return rootDirPath
+}
+
+// newHttpClient prepares an HTTP client trusting the Scalarm certificate or pinned fingerprints if configured,
+// which follows redirects only between Scalarm services and optionally caches their addresses
+func newHttpClient(config *scalarmWorker.SimulationManagerConfig) (*http.Client, error) {
+	tlsConfig, err := scalarmWorker.NewTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	if config.DNSCacheTTL > 0 {
+		transport.DialContext = scalarmWorker.NewDNSCache(time.Duration(config.DNSCacheTTL) * time.Second).DialContext
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: scalarmWorker.RedirectPolicy(config),
+	}, nil
+}
+
+// newSimulationManager loads the config file and creates a simulation manager instance
+func newSimulationManager(configPath string) (*scalarmWorker.SimulationManager, error) {
+	// 0. remember current location
+	rootDirPath := printBanner()
 
 	// 1. load config file
-	config, err := scalarmWorker.CreateSimulationManagerConfig("config.json")
+	config, err := scalarmWorker.CreateSimulationManagerConfig(configPath)
 	if err != nil {
-		Fatal(err)
+		return nil, err
 	}
 
-	// 2. prepare HTTP client
-	var client *http.Client
-	tlsConfig := tls.Config{InsecureSkipVerify: config.InsecureSSL}
-
-	if config.ScalarmCertificatePath != "" {
-		CAPool := x509.NewCertPool()
-		severCert, err := ioutil.ReadFile(config.ScalarmCertificatePath)
-		if err != nil {
-			Fatal(fmt.Errorf("Could not load Scalarm certificate"))
+	if config.LogLevel != "" {
+		if err = scalarmWorker.SetLogLevel(config.LogLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	// credentials from a helper are never stored in the config file
+	if config.CredentialHelper != "" {
+		if err = config.LoadCredentialsFromHelper(); err != nil {
+			return nil, err
+		}
+	}
+
+	// adapters executed as another account must not be able to read credentials from the config file
+	if config.AdapterUser != "" {
+		if err = os.Chmod(configPath, 0600); err != nil {
+			return nil, err
 		}
-		CAPool.AppendCertsFromPEM(severCert)
+	}
+
+	// 2. prepare HTTP client
+	client, err := newHttpClient(config)
+	if err != nil {
+		return nil, err
+	}
 
-		tlsConfig.RootCAs = CAPool
+	if config.AuditLogPath != "" {
+		if err = scalarmWorker.EnableAuditLog(config, client); err != nil {
+			return nil, err
+		}
 	}
 
-	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tlsConfig}}
+	absoluteConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
 
-	// 3. create simulation manager instance and run it
-	sim := scalarmWorker.SimulationManager{
+	// 3. create simulation manager instance
+	return &scalarmWorker.SimulationManager{
 		Config:      config,
 		HttpClient:  client,
 		RootDirPath: rootDirPath,
+		ConfigPath:  absoluteConfigPath,
+	}, nil
+}
+
+func main() {
+	scalarmWorker.WorkerVersion = VERSION
+
+	if err := runCommand(os.Args[1:]); err != nil {
+		Fatal(err)
 	}
 
-	sim.Run()
+	scalarmWorker.Exit(0)
 }
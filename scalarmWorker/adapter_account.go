@@ -0,0 +1,82 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AdapterAccount is a low-privilege account which executes adapters of experiments, so simulation code
+// cannot read credentials of the worker nor data of other experiments
+type AdapterAccount struct {
+	Name string
+	Uid  uint32
+	Gid  uint32
+	Home string
+}
+
+// LookupAdapterAccount finds the system account with the given name; adapters get home as their HOME,
+// a directory in the system temporary directory is used when home is empty
+func LookupAdapterAccount(name string, home string) (*AdapterAccount, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Account %s does not have a numeric uid: %v", name, err)
+	}
+
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Account %s does not have a numeric gid: %v", name, err)
+	}
+
+	if home == "" {
+		home = filepath.Join(os.TempDir(), "scalarm_adapter_home_"+name)
+	}
+
+	return &AdapterAccount{Name: name, Uid: uint32(uid), Gid: uint32(gid), Home: home}, nil
+}
+
+// Prepare creates the restricted home directory accessible only by the account
+func (account *AdapterAccount) Prepare() error {
+	if err := os.MkdirAll(account.Home, 0700); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(account.Home, 0700); err != nil {
+		return err
+	}
+
+	return account.Own(account.Home)
+}
+
+// Own hands the file or directory over to the account, e.g. a simulation directory adapters write to
+func (account *AdapterAccount) Own(path string) error {
+	return os.Lchown(path, int(account.Uid), int(account.Gid))
+}
+
+// environment returns the environment of the worker with the identity variables of the account
+func (account *AdapterAccount) environment() []string {
+	env := []string{"HOME=" + account.Home, "USER=" + account.Name, "LOGNAME=" + account.Name}
+
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, "HOME=") && !strings.HasPrefix(variable, "USER=") && !strings.HasPrefix(variable, "LOGNAME=") {
+			env = append(env, variable)
+		}
+	}
+
+	return env
+}
+
+// Apply makes the command run as the account
+func (account *AdapterAccount) Apply(cmd *exec.Cmd) {
+	cmd.Env = account.environment()
+	setCommandCredential(cmd, account.Uid, account.Gid)
+}
@@ -0,0 +1,72 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLookupAdapterAccountShouldFailForUnknownUser(t *testing.T) {
+	if _, err := LookupAdapterAccount("scalarm_user_which_does_not_exist", ""); err == nil {
+		t.Errorf("Lookup of an unknown user should fail")
+	}
+}
+
+func TestAdapterCommandShouldRunAsAdapterAccount(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users requires root")
+	}
+
+	// === GIVEN ===
+	homeDir, _ := ioutil.TempDir("", "adapter_home_")
+	defer os.RemoveAll(homeDir)
+	simulationDir, _ := ioutil.TempDir("", "adapter_simulation_")
+	defer os.RemoveAll(simulationDir)
+
+	account, err := LookupAdapterAccount("nobody", homeDir+"/home")
+	if err != nil {
+		t.Skip("there is no 'nobody' account")
+	}
+	account.Prepare()
+	account.Own(simulationDir)
+
+	sim := SimulationManager{Config: getSimConfig(), adapterAccount: account}
+
+	// === WHEN ===
+	output, err := sim.adapterCommand("id -u; echo $HOME; touch result", simulationDir).CombinedOutput()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v' %s", err, output)
+		return
+	}
+
+	expected := "65534\n" + homeDir + "/home\n"
+	if account.Uid == 65534 && string(output) != expected {
+		t.Errorf("Got: '%s' - Expected '%v'", output, expected)
+	}
+
+	if info, err := os.Stat(homeDir + "/home"); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Home directory should be accessible only by the account: %v", err)
+	}
+
+	if _, err := os.Stat(simulationDir + "/result"); err != nil {
+		t.Errorf("Adapter should be able to write to the simulation directory: %v", err)
+	}
+}
+
+func TestAdapterAccountEnvironmentShouldReplaceHome(t *testing.T) {
+	account := &AdapterAccount{Name: "nobody", Home: "/tmp/restricted_home"}
+
+	homes := []string{}
+	for _, variable := range account.environment() {
+		if strings.HasPrefix(variable, "HOME=") {
+			homes = append(homes, variable)
+		}
+	}
+
+	if len(homes) != 1 || homes[0] != "HOME=/tmp/restricted_home" {
+		t.Errorf("Got: '%v' - Expected '%v'", homes, "HOME=/tmp/restricted_home")
+	}
+}
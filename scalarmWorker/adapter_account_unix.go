@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setCommandCredential(cmd *exec.Cmd, uid uint32, gid uint32) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}}
+}
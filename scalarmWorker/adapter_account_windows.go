@@ -0,0 +1,8 @@
+package scalarmWorker
+
+import "os/exec"
+
+// processes cannot be started as another user without its password on Windows, adapters run as the worker
+func setCommandCredential(cmd *exec.Cmd, uid uint32, gid uint32) {
+	simLog.Infof("Running adapters as another account is not supported on Windows")
+}
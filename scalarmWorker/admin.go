@@ -0,0 +1,608 @@
+package scalarmWorker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var adminLog = NewLogger("admin")
+
+// set when the worker should not start new simulation runs until resumed
+var paused int32
+
+// simulation runs being executed, reported by the status of the admin API and the dashboard
+var currentRuns struct {
+	mutex sync.Mutex
+	runs  map[runKey]*currentRun
+	// phase of the worker outside of simulation runs, e.g. code_base or next_simulation, reported in error.json
+	phase string
+}
+
+// currentRun is a simulation run being executed and its phase, e.g. executor
+type currentRun struct {
+	experimentID    string
+	simulationIndex int
+	startedAt       time.Time
+	phase           string
+}
+
+// pool executing simulation runs of the worker loop and the concurrency set through the admin API, if any
+var workerPool struct {
+	mutex       sync.Mutex
+	pool        *RunPool
+	concurrency int
+}
+
+// config read by the reload-config command, applied by the worker loop between simulation runs
+var reloadedConfig struct {
+	mutex  sync.Mutex
+	config *SimulationManagerConfig
+}
+
+// AdminController executes commands of the admin API, it is implemented by a worker and by an agent
+type AdminController interface {
+	Status() map[string]interface{}
+	Pause() error
+	Resume() error
+	Drain() error
+	SetConcurrency(concurrency int) error
+	ReloadConfig() error
+}
+
+// AdminServer serves a JSON API for node orchestrators on a loopback address or a unix socket
+// ('unix://<path>'); every request has to carry the token in the 'Authorization: Bearer <token>' header
+type AdminServer struct {
+	Address    string
+	Token      string
+	Controller AdminController
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewAdminServer creates an admin API executing commands with controller
+func NewAdminServer(address string, token string, controller AdminController) *AdminServer {
+	return &AdminServer{Address: address, Token: token, Controller: controller}
+}
+
+// AdminToken returns the token of the admin API from the config, without one a random token is generated
+// once and stored in admin.token in rootDirPath, readable only by the account running SiM
+func AdminToken(config *SimulationManagerConfig, rootDirPath string) (string, error) {
+	if config.AdminToken != "" {
+		RegisterSecret(config.AdminToken)
+		return config.AdminToken, nil
+	}
+
+	tokenPath := filepath.Join(rootDirPath, "admin.token")
+
+	unlock, err := lockFile(tokenPath + ".lock")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if content, err := ioutil.ReadFile(tokenPath); err == nil && len(strings.TrimSpace(string(content))) > 0 {
+		token := strings.TrimSpace(string(content))
+		RegisterSecret(token)
+		return token, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(random)
+
+	if err := ioutil.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	RegisterSecret(token)
+
+	return token, nil
+}
+
+// Start starts serving the API, a unix socket is accessible only to the account running SiM
+func (admin *AdminServer) Start() error {
+	if admin.Token == "" {
+		return errors.New("Admin API requires a token")
+	}
+
+	var err error
+	if strings.HasPrefix(admin.Address, "unix://") {
+		socketPath := strings.TrimPrefix(admin.Address, "unix://")
+		os.Remove(socketPath)
+
+		if admin.listener, err = net.Listen("unix", socketPath); err != nil {
+			return err
+		}
+
+		if err = os.Chmod(socketPath, 0600); err != nil {
+			admin.listener.Close()
+			return err
+		}
+	} else {
+		if err = checkLoopbackAddress(admin.Address); err != nil {
+			return err
+		}
+
+		if admin.listener, err = net.Listen("tcp", admin.Address); err != nil {
+			return err
+		}
+	}
+
+	admin.server = &http.Server{Handler: admin}
+	go admin.server.Serve(admin.listener)
+
+	adminLog.Infof("Serving the admin API on %v", admin.listener.Addr())
+	return nil
+}
+
+// Addr returns the address the API listens on
+func (admin *AdminServer) Addr() net.Addr {
+	return admin.listener.Addr()
+}
+
+// Close stops serving the API
+func (admin *AdminServer) Close() {
+	if admin.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	admin.server.Shutdown(ctx)
+	cancel()
+
+	if strings.HasPrefix(admin.Address, "unix://") {
+		os.Remove(strings.TrimPrefix(admin.Address, "unix://"))
+	}
+}
+
+// ServeHTTP handles GET /status and POST /pause, /resume, /drain, /concurrency and /reload-config
+func (admin *AdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := []byte(r.Header.Get("Authorization"))
+	if subtle.ConstantTimeCompare(authorization, []byte("Bearer "+admin.Token)) != 1 {
+		writeAdminResponse(w, http.StatusUnauthorized, map[string]interface{}{"status": "error", "reason": "Unauthorized"})
+		return
+	}
+
+	command := strings.Trim(r.URL.Path, "/")
+	if (command == "status") != (r.Method == "GET") {
+		writeAdminResponse(w, http.StatusMethodNotAllowed, map[string]interface{}{"status": "error", "reason": "Method not allowed"})
+		return
+	}
+
+	var err error
+	switch command {
+	case "status":
+		status := admin.Controller.Status()
+		status["status"] = "ok"
+		writeAdminResponse(w, http.StatusOK, status)
+		return
+	case "pause":
+		err = admin.Controller.Pause()
+	case "resume":
+		err = admin.Controller.Resume()
+	case "drain":
+		err = admin.Controller.Drain()
+	case "concurrency":
+		request := struct {
+			Concurrency *int `json:"concurrency"`
+		}{}
+		if decodeErr := json.NewDecoder(r.Body).Decode(&request); decodeErr != nil || request.Concurrency == nil || *request.Concurrency < 0 {
+			writeAdminResponse(w, http.StatusBadRequest, map[string]interface{}{"status": "error",
+				"reason": "Expected a body like {\"concurrency\": <non-negative number>}"})
+			return
+		}
+		err = admin.Controller.SetConcurrency(*request.Concurrency)
+	case "reload-config":
+		err = admin.Controller.ReloadConfig()
+	default:
+		writeAdminResponse(w, http.StatusNotFound, map[string]interface{}{"status": "error", "reason": "Unknown command"})
+		return
+	}
+
+	if err != nil {
+		adminLog.Warnf("%s failed: %v", command, err)
+		writeAdminResponse(w, http.StatusConflict, map[string]interface{}{"status": "error", "reason": err.Error()})
+		return
+	}
+
+	adminLog.Infof("%s", command)
+	writeAdminResponse(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+func writeAdminResponse(w http.ResponseWriter, statusCode int, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// PauseWorker stops the worker from starting new simulation runs, the current one is finished
+func PauseWorker() {
+	atomic.StoreInt32(&paused, 1)
+}
+
+// ResumeWorker lets a paused worker start simulation runs again
+func ResumeWorker() {
+	atomic.StoreInt32(&paused, 0)
+}
+
+// pauseRequested tells whether the worker is paused by the admin API or with the pause file,
+// which is shared by all workers of an agent
+func (sim SimulationManager) pauseRequested() bool {
+	if atomic.LoadInt32(&paused) == 1 {
+		return true
+	}
+
+	if sim.Config.PauseFile != "" {
+		if _, err := os.Stat(sim.Config.PauseFile); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitWhilePaused blocks between simulation runs until the worker is resumed or drained
+func (sim SimulationManager) waitWhilePaused() {
+	if !sim.pauseRequested() {
+		return
+	}
+
+	simLog.Infof("Paused, waiting to be resumed ...")
+	for sim.pauseRequested() && !sim.drainRequested() {
+		time.Sleep(time.Second)
+	}
+	simLog.Infof("Resumed")
+}
+
+// addCurrentRun records a simulation run which has just been started
+func addCurrentRun(experimentID string, simulationIndex int) {
+	currentRuns.mutex.Lock()
+	defer currentRuns.mutex.Unlock()
+
+	if currentRuns.runs == nil {
+		currentRuns.runs = map[runKey]*currentRun{}
+	}
+	currentRuns.runs[runKey{experimentID, simulationIndex}] = &currentRun{
+		experimentID:    experimentID,
+		simulationIndex: simulationIndex,
+		startedAt:       time.Now(),
+	}
+}
+
+// removeCurrentRun forgets a simulation run which is not executed anymore
+func removeCurrentRun(experimentID string, simulationIndex int) {
+	currentRuns.mutex.Lock()
+	defer currentRuns.mutex.Unlock()
+
+	delete(currentRuns.runs, runKey{experimentID, simulationIndex})
+}
+
+// setRunPhase records the phase of a simulation run being executed, e.g. executor
+func setRunPhase(experimentID string, simulationIndex int, phase string) {
+	currentRuns.mutex.Lock()
+	defer currentRuns.mutex.Unlock()
+
+	if run, ok := currentRuns.runs[runKey{experimentID, simulationIndex}]; ok {
+		run.phase = phase
+	}
+}
+
+// listCurrentRuns returns copies of simulation runs being executed, ordered by the time they were started
+func listCurrentRuns() []currentRun {
+	currentRuns.mutex.Lock()
+	defer currentRuns.mutex.Unlock()
+
+	runs := []currentRun{}
+	for _, run := range currentRuns.runs {
+		runs = append(runs, *run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].startedAt.Equal(runs[j].startedAt) {
+			return runs[i].startedAt.Before(runs[j].startedAt)
+		}
+		return runs[i].simulationIndex < runs[j].simulationIndex
+	})
+
+	return runs
+}
+
+// setWorkerPool makes the admin API resize the pool of the worker loop, concurrency set before, e.g. by a loop
+// which was restarted, is applied to it
+func setWorkerPool(pool *RunPool) {
+	workerPool.mutex.Lock()
+	defer workerPool.mutex.Unlock()
+
+	workerPool.pool = pool
+	if pool != nil && workerPool.concurrency > 0 {
+		pool.Resize(workerPool.concurrency)
+	}
+}
+
+// loadReloadableConfig reads and validates the config file
+func loadReloadableConfig(configPath string) (*SimulationManagerConfig, error) {
+	if configPath == "" {
+		return nil, errors.New("Path of the config file is not known")
+	}
+
+	config, err := CreateSimulationManagerConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := config.Validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	return config, nil
+}
+
+// reloadPending tells whether there is a reloaded config which was not applied yet
+func reloadPending() bool {
+	reloadedConfig.mutex.Lock()
+	defer reloadedConfig.mutex.Unlock()
+
+	return reloadedConfig.config != nil
+}
+
+// applyReloadedConfig applies settings of a config reloaded since the last simulation run:
+// cooldown_interval, simulations_limit, tail and pause_on_failure; it returns whether there was one
+func (sim SimulationManager) applyReloadedConfig() bool {
+	reloadedConfig.mutex.Lock()
+	config := reloadedConfig.config
+	reloadedConfig.config = nil
+	reloadedConfig.mutex.Unlock()
+
+	if config == nil {
+		return false
+	}
+
+	if config.CooldownInterval > 0 {
+		sim.Config.CooldownInterval = config.CooldownInterval
+	}
+	sim.Config.SimulationsLimit = remainingSimulationsLimit(config.SimulationsLimit)
+	if config.SimulationsLimit > 0 && sim.Config.SimulationsLimit <= 0 {
+		simLog.Infof("Exiting due to simulation runs limit (%v)", config.SimulationsLimit)
+		Exit(0)
+	}
+	sim.Config.Tail = config.Tail
+	sim.Config.PauseOnFailure = config.PauseOnFailure
+	if config.LogLevel != "" && SetLogLevel(config.LogLevel) == nil {
+		sim.Config.LogLevel = config.LogLevel
+	}
+
+	simLog.Infof("Applied the reloaded config")
+	return true
+}
+
+// startAdmin serves the admin API of a worker for its lifetime, failures are only logged
+func (sim SimulationManager) startAdmin() {
+	token, err := AdminToken(sim.Config, sim.RootDirPath)
+	if err != nil {
+		adminLog.Warnf("Could not prepare the token: %v", err)
+		return
+	}
+
+	admin := NewAdminServer(sim.Config.AdminAddress, token, workerAdmin{sim: sim, startedAt: time.Now()})
+	if err = admin.Start(); err != nil {
+		adminLog.Warnf("Could not start the admin API: %v", err)
+		return
+	}
+	OnExit(admin.Close)
+}
+
+// workerAdmin controls a single worker
+type workerAdmin struct {
+	sim       SimulationManager
+	startedAt time.Time
+}
+
+func (admin workerAdmin) Status() map[string]interface{} {
+	state := "running"
+	if Draining() {
+		state = "draining"
+	} else if admin.sim.pauseRequested() {
+		state = "paused"
+	}
+
+	status := map[string]interface{}{
+		"state":    state,
+		"pid":      os.Getpid(),
+		"root_dir": admin.sim.RootDirPath,
+		"uptime":   int64(time.Since(admin.startedAt).Seconds()),
+	}
+
+	runs := []map[string]interface{}{}
+	for _, run := range listCurrentRuns() {
+		runs = append(runs, map[string]interface{}{
+			"experiment_id":           run.experimentID,
+			"simulation_id":           run.simulationIndex,
+			"phase":                   run.phase,
+			"simulation_run_duration": int64(time.Since(run.startedAt).Seconds()),
+		})
+	}
+	status["simulation_runs"] = runs
+
+	workerPool.mutex.Lock()
+	if workerPool.pool != nil {
+		status["concurrency"] = workerPool.pool.Size()
+	}
+	workerPool.mutex.Unlock()
+
+	// a worker executing runs one by one reports its run the way it did before concurrency was supported
+	if len(runs) == 1 {
+		for _, key := range []string{"experiment_id", "simulation_id", "simulation_run_duration"} {
+			status[key] = runs[0][key]
+		}
+	}
+
+	return status
+}
+
+func (admin workerAdmin) Pause() error {
+	PauseWorker()
+	return nil
+}
+
+func (admin workerAdmin) Resume() error {
+	ResumeWorker()
+	return nil
+}
+
+func (admin workerAdmin) Drain() error {
+	RequestDrain("the admin API")
+	return nil
+}
+
+// SetConcurrency resizes the pool of the worker loop, 0 restores concurrency of the config
+func (admin workerAdmin) SetConcurrency(concurrency int) error {
+	workerPool.mutex.Lock()
+	defer workerPool.mutex.Unlock()
+
+	workerPool.concurrency = concurrency
+	if concurrency == 0 {
+		concurrency = admin.sim.Config.Concurrency
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if workerPool.pool != nil {
+		workerPool.pool.Resize(concurrency)
+	}
+
+	adminLog.Infof("Executing up to %d simulation runs at the same time", concurrency)
+	return nil
+}
+
+func (admin workerAdmin) ReloadConfig() error {
+	config, err := loadReloadableConfig(admin.sim.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	reloadedConfig.mutex.Lock()
+	reloadedConfig.config = config
+	reloadedConfig.mutex.Unlock()
+
+	return nil
+}
+
+// agentAdmin controls workers of an agent through the pause and drain files they share
+// and through its coordinator
+type agentAdmin struct {
+	agent      *Agent
+	configPath string
+	config     *SimulationManagerConfig
+	startedAt  time.Time
+}
+
+// AdminController returns a controller of the agent and its workers, which read configPath
+func (agent *Agent) AdminController(configPath string, config *SimulationManagerConfig) AdminController {
+	return agentAdmin{agent: agent, configPath: configPath, config: config, startedAt: time.Now()}
+}
+
+func (admin agentAdmin) pauseFile() string {
+	if admin.config.PauseFile != "" {
+		return admin.config.PauseFile
+	}
+
+	return filepath.Join(admin.agent.Dir, "scalarm.pause")
+}
+
+func (admin agentAdmin) drainFile() string {
+	if admin.config.DrainFile != "" {
+		return admin.config.DrainFile
+	}
+
+	return filepath.Join(admin.agent.Dir, "scalarm.drain")
+}
+
+func (admin agentAdmin) Status() map[string]interface{} {
+	state := "running"
+	if _, err := os.Stat(admin.drainFile()); err == nil {
+		state = "draining"
+	} else if _, err := os.Stat(admin.pauseFile()); err == nil {
+		state = "paused"
+	}
+
+	admin.agent.processMutex.Lock()
+	pids := map[string]int{}
+	for worker, process := range admin.agent.processes {
+		pids[fmt.Sprint(worker)] = process.Pid
+	}
+	admin.agent.processMutex.Unlock()
+
+	status := map[string]interface{}{
+		"state":    state,
+		"pid":      os.Getpid(),
+		"root_dir": admin.agent.Dir,
+		"uptime":   int64(time.Since(admin.startedAt).Seconds()),
+		"workers":  admin.agent.Workers,
+		"running":  pids,
+	}
+
+	if admin.agent.Coordinator != nil {
+		admin.agent.Coordinator.mutex.Lock()
+		status["concurrency"] = admin.agent.Coordinator.concurrency
+		admin.agent.Coordinator.mutex.Unlock()
+	}
+
+	return status
+}
+
+func (admin agentAdmin) Pause() error {
+	return ioutil.WriteFile(admin.pauseFile(), []byte("paused by the admin API\n"), 0600)
+}
+
+func (admin agentAdmin) Resume() error {
+	if err := os.Remove(admin.pauseFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (admin agentAdmin) Drain() error {
+	return ioutil.WriteFile(admin.drainFile(), []byte("drained by the admin API\n"), 0600)
+}
+
+func (admin agentAdmin) SetConcurrency(concurrency int) error {
+	if admin.agent.Coordinator == nil {
+		return errors.New("Concurrency can be set only on an agent started with -coordinator")
+	}
+
+	admin.agent.Coordinator.SetConcurrency(concurrency)
+	return nil
+}
+
+func (admin agentAdmin) ReloadConfig() error {
+	config, err := loadReloadableConfig(admin.configPath)
+	if err != nil {
+		return err
+	}
+
+	// workers read the config file when they start, the coordinator takes the new cooldown_interval
+	if admin.agent.Coordinator != nil {
+		admin.agent.Coordinator.mutex.Lock()
+		admin.agent.Coordinator.Config.CooldownInterval = config.CooldownInterval
+		admin.agent.Coordinator.mutex.Unlock()
+	}
+
+	return nil
+}
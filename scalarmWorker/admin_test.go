@@ -0,0 +1,144 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func adminRequest(admin *AdminServer, method string, path string, token string, body string) (int, map[string]interface{}) {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	recorder := httptest.NewRecorder()
+	admin.ServeHTTP(recorder, request)
+
+	response := map[string]interface{}{}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestAdminServerShouldRejectRequestsWithoutToken(t *testing.T) {
+	// === GIVEN ===
+	admin := NewAdminServer("localhost:0", "secret-token", workerAdmin{sim: SimulationManager{Config: getSimConfig()}})
+
+	// === WHEN ===
+	code, _ := adminRequest(admin, "POST", "/pause", "wrong-token", "")
+
+	// === THEN ===
+	if code != http.StatusUnauthorized {
+		t.Errorf("Got: '%v' - Expected '%v'", code, http.StatusUnauthorized)
+	}
+
+	if admin.Controller.(workerAdmin).sim.pauseRequested() {
+		t.Errorf("Worker should not be paused by an unauthorized request")
+	}
+}
+
+func TestAdminServerShouldPauseAndResumeWorker(t *testing.T) {
+	// === GIVEN ===
+	defer ResumeWorker()
+	admin := NewAdminServer("localhost:0", "secret-token",
+		workerAdmin{sim: SimulationManager{Config: getSimConfig()}, startedAt: time.Now()})
+
+	// === WHEN ===
+	pauseCode, _ := adminRequest(admin, "POST", "/pause", "secret-token", "")
+	_, pausedStatus := adminRequest(admin, "GET", "/status", "secret-token", "")
+	adminRequest(admin, "POST", "/resume", "secret-token", "")
+	_, resumedStatus := adminRequest(admin, "GET", "/status", "secret-token", "")
+
+	// === THEN ===
+	if pauseCode != http.StatusOK {
+		t.Errorf("Got: '%v' - Expected '%v'", pauseCode, http.StatusOK)
+	}
+
+	if pausedStatus["state"] != "paused" || resumedStatus["state"] != "running" {
+		t.Errorf("Got: '%v', '%v' - Expected paused and running states", pausedStatus["state"], resumedStatus["state"])
+	}
+}
+
+func TestAdminServerShouldResizePoolOfWorker(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+	config.Concurrency = 3
+	admin := NewAdminServer("localhost:0", "secret-token",
+		workerAdmin{sim: SimulationManager{Config: config}, startedAt: time.Now()})
+
+	pool := NewRunPool(config.Concurrency)
+	setWorkerPool(pool)
+	defer setWorkerPool(nil)
+
+	// === WHEN ===
+	code, _ := adminRequest(admin, "POST", "/concurrency", "secret-token", `{"concurrency":2}`)
+	_, status := adminRequest(admin, "GET", "/status", "secret-token", "")
+
+	// === THEN ===
+	if code != http.StatusOK || pool.Size() != 2 {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", code, pool.Size(), http.StatusOK, 2)
+	}
+
+	if status["concurrency"] != float64(2) {
+		t.Errorf("Got: '%v' - Expected '%v'", status["concurrency"], 2)
+	}
+
+	// === WHEN ===
+	adminRequest(admin, "POST", "/concurrency", "secret-token", `{"concurrency":0}`)
+	restarted := NewRunPool(config.Concurrency)
+	setWorkerPool(restarted)
+
+	// === THEN ===
+	if pool.Size() != 3 || restarted.Size() != 3 {
+		t.Errorf("Got: '%v, %v' - Expected concurrency of the config", pool.Size(), restarted.Size())
+	}
+}
+
+func TestAdminServerShouldReportAllSimulationRunsBeingExecuted(t *testing.T) {
+	// === GIVEN ===
+	admin := NewAdminServer("localhost:0", "secret-token",
+		workerAdmin{sim: SimulationManager{Config: getSimConfig()}, startedAt: time.Now()})
+
+	addCurrentRun("e1", 1)
+	addCurrentRun("e1", 2)
+	defer removeCurrentRun("e1", 1)
+	defer removeCurrentRun("e1", 2)
+	setRunPhase("e1", 2, "executor")
+
+	// === WHEN ===
+	_, status := adminRequest(admin, "GET", "/status", "secret-token", "")
+
+	// === THEN ===
+	runs, _ := status["simulation_runs"].([]interface{})
+	if len(runs) != 2 {
+		t.Fatalf("Got: '%v' - Expected '%v'", status["simulation_runs"], "2 simulation runs")
+	}
+
+	phases := map[float64]interface{}{}
+	for _, run := range runs {
+		run := run.(map[string]interface{})
+		phases[run["simulation_id"].(float64)] = run["phase"]
+	}
+	if phases[1] != "" || phases[2] != "executor" {
+		t.Errorf("Got: '%v' - Expected '%v'", phases, "runs 1 and 2 with their phases")
+	}
+
+	if _, ok := status["simulation_id"]; ok {
+		t.Errorf("Got: '%v' - Expected no single simulation run", status["simulation_id"])
+	}
+}
+
+func TestCoordinatorShouldIgnoreConcurrencyHintsAfterSetConcurrency(t *testing.T) {
+	// === GIVEN ===
+	coordinator := NewCoordinator("coordinator.sock", getSimConfig(), nil, 1)
+
+	// === WHEN ===
+	coordinator.SetConcurrency(2)
+	coordinator.updateConcurrency(map[string]interface{}{"concurrency": float64(1)})
+
+	// === THEN ===
+	if !coordinator.admits(2) || coordinator.admits(3) {
+		t.Errorf("Got: '%v' - Expected concurrency '%v'", coordinator.concurrency, 2)
+	}
+}
@@ -0,0 +1,287 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Agent launches and supervises multiple SiM workers on a single node; workers share the directory
+// of the agent, so code bases and cached input files are downloaded once for all of them
+type Agent struct {
+	// command line starting a single worker, e.g. [<path of SiM>, "run", "-config", "config.json"]
+	Command []string
+	Workers int
+	Dir     string
+	// output of all workers, every line is prefixed with the number of its worker
+	Output io.Writer
+	// delay before restarting a crashed worker, doubled after every crash up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// time given to workers to finish after SIGTERM before they are killed
+	ShutdownTimeout time.Duration
+	// when set, workers get simulation runs from this coordinator instead of Experiment Managers
+	Coordinator *Coordinator
+	// CPUs, NUMA node and scratch directory of every worker, workers without a slot are not pinned
+	Slots []FleetSlot
+	// when set, node orchestrators control the agent and its workers through this API
+	Admin *AdminServer
+
+	outputMutex  sync.Mutex
+	processMutex sync.Mutex
+	processes    map[int]*os.Process
+	stopping     chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewAgent creates an agent starting workers with the given command in dir
+func NewAgent(command []string, workers int, dir string) *Agent {
+	return &Agent{
+		Command:         command,
+		Workers:         workers,
+		Dir:             dir,
+		Output:          os.Stdout,
+		MinBackoff:      time.Second,
+		MaxBackoff:      time.Minute,
+		ShutdownTimeout: 30 * time.Second,
+		processes:       map[int]*os.Process{},
+		stopping:        make(chan struct{}),
+	}
+}
+
+// Run starts all workers and returns when every one of them finished its work or after Stop;
+// SIGINT and SIGTERM stop all workers
+func (agent *Agent) Run() error {
+	if len(agent.Command) == 0 || agent.Workers < 1 {
+		return errors.New("Agent requires a worker command and at least one worker")
+	}
+
+	if len(agent.Slots) > 0 {
+		if _, err := pinCommand(agent.Command, agent.Slots[0]); err != nil {
+			return err
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			agent.log(0, fmt.Sprintf("Received %v, stopping all workers ...", sig))
+			agent.Stop()
+		case <-agent.stopping:
+		}
+	}()
+
+	if agent.Coordinator != nil {
+		if err := agent.Coordinator.Start(); err != nil {
+			return err
+		}
+		// workers have finished, so runs which were not handed out are returned to Experiment Managers
+		defer agent.Coordinator.Close()
+	}
+
+	if agent.Admin != nil {
+		if err := agent.Admin.Start(); err != nil {
+			return err
+		}
+		defer agent.Admin.Close()
+	}
+
+	agent.log(0, fmt.Sprintf("Starting %d workers in %s", agent.Workers, agent.Dir))
+	for worker, slot := range agent.Slots {
+		if slot.GPUs != "" {
+			agent.log(0, fmt.Sprintf("Worker %d gets GPUs %s", worker+1, slot.GPUs))
+		}
+
+		if slot.Node != nil {
+			agent.log(0, fmt.Sprintf("Worker %d pinned to CPUs %s and memory of NUMA node %d", worker+1, slot.CPUs, *slot.Node))
+		} else if slot.CPUs != "" {
+			agent.log(0, fmt.Sprintf("Worker %d pinned to CPUs %s", worker+1, slot.CPUs))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for worker := 1; worker <= agent.Workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			agent.supervise(worker)
+		}(worker)
+	}
+	wg.Wait()
+
+	agent.log(0, "All workers finished")
+	return nil
+}
+
+// Stop terminates all workers, those which do not exit within ShutdownTimeout are killed
+func (agent *Agent) Stop() {
+	agent.stopOnce.Do(func() {
+		close(agent.stopping)
+
+		agent.processMutex.Lock()
+		processes := []*os.Process{}
+		for _, process := range agent.processes {
+			processes = append(processes, process)
+		}
+		agent.processMutex.Unlock()
+
+		for _, process := range processes {
+			if err := process.Signal(syscall.SIGTERM); err != nil {
+				process.Kill()
+			}
+		}
+
+		go func() {
+			time.Sleep(agent.ShutdownTimeout)
+			for _, process := range processes {
+				process.Kill()
+			}
+		}()
+	})
+}
+
+// supervise runs a worker until it exits successfully or the agent is stopped, a crashed worker
+// is restarted with exponential backoff which is reset when the worker ran longer than MaxBackoff
+func (agent *Agent) supervise(worker int) {
+	backoff := agent.MinBackoff
+
+	for {
+		startedAt := time.Now()
+		err := agent.runWorker(worker)
+
+		select {
+		case <-agent.stopping:
+			return
+		default:
+		}
+
+		if err == nil {
+			agent.log(worker, "Finished")
+			return
+		}
+
+		if time.Since(startedAt) > agent.MaxBackoff {
+			backoff = agent.MinBackoff
+		}
+
+		agent.log(worker, fmt.Sprintf("Exited: %v, restarting in %v", err, backoff))
+
+		select {
+		case <-time.After(backoff):
+		case <-agent.stopping:
+			return
+		}
+
+		backoff *= 2
+		if backoff > agent.MaxBackoff {
+			backoff = agent.MaxBackoff
+		}
+	}
+}
+
+func (agent *Agent) runWorker(worker int) error {
+	command := agent.Command
+	env := append(os.Environ(), "SCALARM_WORKER_ID="+strconv.Itoa(worker))
+
+	if worker <= len(agent.Slots) {
+		slot := agent.Slots[worker-1]
+
+		if slot.CPUs != "" {
+			var err error
+			if command, err = pinCommand(command, slot); err != nil {
+				return err
+			}
+			env = append(env, "SCALARM_WORKER_CPUS="+slot.CPUs)
+		}
+
+		if slot.Kind != "" {
+			env = append(env, workerSlotEnv+"="+slot.Kind)
+		}
+		if slot.GPUs != "" {
+			env = append(env, "CUDA_VISIBLE_DEVICES="+slot.GPUs)
+		}
+
+		// files of runs written to a tmpfs scratch directory are allocated on the NUMA node of the worker
+		if slot.ScratchDir != "" {
+			if err := os.MkdirAll(slot.ScratchDir, 0700); err != nil {
+				return err
+			}
+			env = append(env, "SCALARM_SCRATCH_DIR="+slot.ScratchDir, "TMPDIR="+slot.ScratchDir)
+		}
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = agent.Dir
+	cmd.Env = env
+	if agent.Coordinator != nil {
+		cmd.Env = append(cmd.Env, coordinatorSocketEnv+"="+agent.Coordinator.SocketPath)
+	}
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			agent.log(worker, scanner.Text())
+		}
+		io.Copy(io.Discard, reader)
+	}()
+
+	agent.processMutex.Lock()
+	select {
+	case <-agent.stopping:
+		agent.processMutex.Unlock()
+		writer.Close()
+		<-outputDone
+		return nil
+	default:
+	}
+
+	err := cmd.Start()
+	if err == nil {
+		agent.processes[worker] = cmd.Process
+	}
+	agent.processMutex.Unlock()
+
+	if err == nil {
+		err = cmd.Wait()
+
+		agent.processMutex.Lock()
+		delete(agent.processes, worker)
+		agent.processMutex.Unlock()
+	}
+
+	writer.Close()
+	<-outputDone
+
+	return err
+}
+
+// log writes a line to the output of the agent, worker 0 is the agent itself
+func (agent *Agent) log(worker int, line string) {
+	agent.outputMutex.Lock()
+	defer agent.outputMutex.Unlock()
+
+	if worker == 0 {
+		fmt.Fprintf(agent.Output, "[SiM][agent] %s\n", line)
+	} else {
+		fmt.Fprintf(agent.Output, "[worker %d] %s\n", worker, line)
+	}
+}
@@ -0,0 +1,69 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentShouldRestartCrashedWorkersAndPrefixTheirOutput(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "agent")
+	defer os.RemoveAll(dir)
+
+	// every worker crashes on its first start
+	script := `echo "started"; if [ -f crashed_$SCALARM_WORKER_ID ]; then exit 0; fi; touch crashed_$SCALARM_WORKER_ID; exit 3`
+
+	output := &bytes.Buffer{}
+	agent := NewAgent([]string{"sh", "-c", script}, 2, dir)
+	agent.Output = output
+	agent.MinBackoff = 10 * time.Millisecond
+
+	// === WHEN ===
+	err := agent.Run()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	for _, prefix := range []string{"[worker 1] ", "[worker 2] "} {
+		if count := strings.Count(output.String(), prefix+"started"); count != 2 {
+			t.Errorf("Got: '%v' - Expected 2 starts of '%v' in: %s", count, prefix, output)
+		}
+		if !strings.Contains(output.String(), prefix+"Finished") {
+			t.Errorf("Worker '%v' should finish, output: %s", prefix, output)
+		}
+	}
+}
+
+func TestAgentShouldStopAllWorkers(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "agent")
+	defer os.RemoveAll(dir)
+
+	agent := NewAgent([]string{"sh", "-c", "exec sleep 60"}, 3, dir)
+	agent.Output = &bytes.Buffer{}
+
+	finished := make(chan error)
+	go func() {
+		finished <- agent.Run()
+	}()
+
+	// === WHEN ===
+	time.Sleep(200 * time.Millisecond)
+	agent.Stop()
+
+	// === THEN ===
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Agent should finish after all workers are stopped")
+	}
+}
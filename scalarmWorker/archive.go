@@ -0,0 +1,211 @@
+package scalarmWorker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// types of code base archives
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+	ArchiveTarXz = "tar.xz"
+)
+
+// magic numbers at the beginning of archives, zip files start with a local file header
+// or, when empty, with the end of central directory record
+var archiveMagics = []struct {
+	magic       []byte
+	archiveType string
+}{
+	{[]byte("PK\x03\x04"), ArchiveZip},
+	{[]byte("PK\x05\x06"), ArchiveZip},
+	{[]byte{0x1f, 0x8b}, ArchiveTarGz},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, ArchiveTarXz},
+}
+
+// DetectArchiveType tells the type of an archive from its content, regardless of its name
+func DetectArchiveType(archivePath string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 6)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	for _, candidate := range archiveMagics {
+		if bytes.HasPrefix(header[:n], candidate.magic) {
+			return candidate.archiveType, nil
+		}
+	}
+
+	return "", errors.New("Unsupported archive format of " + filepath.Base(archivePath) + ", expected zip, tar.gz or tar.xz")
+}
+
+// SanitizeArchivePath returns the path an archive entry is extracted to in dest; entries with absolute paths
+// or escaping dest with '..' are rejected, so a malicious archive cannot write outside dest
+func SanitizeArchivePath(dest string, name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("Archive entry '%s' has an absolute path", name)
+	}
+
+	entryPath := filepath.Join(dest, filepath.FromSlash(name))
+	if !withinDir(dest, entryPath) {
+		return "", fmt.Errorf("Archive entry '%s' is outside the destination directory", name)
+	}
+
+	return entryPath, nil
+}
+
+// withinDir tells whether target is dir or any path below it
+func withinDir(dir string, target string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(target))
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Extract unpacks a zip, tar.gz or tar.xz archive into dest, the type is detected from the content
+// so a code base is extracted the same way whatever name it was saved under; paths of entries are
+// sanitized with SanitizeArchivePath and an unsafe entry fails the extraction
+func Extract(archivePath, dest string) error {
+	archiveType, err := DetectArchiveType(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if archiveType == ArchiveZip {
+		return extractZip(archivePath, dest)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var decompressed io.Reader
+	if archiveType == ArchiveTarGz {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		decompressed = gzipReader
+	} else {
+		if decompressed, err = xz.NewReader(file); err != nil {
+			return err
+		}
+	}
+
+	return extractTar(tar.NewReader(decompressed), dest)
+}
+
+// extractTar unpacks directories, regular files and symbolic links of a tar stream into dest,
+// permissions of files are kept
+func extractTar(tarReader *tar.Reader, dest string) error {
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entryPath, err := SanitizeArchivePath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if err = checkNoSymlinkParents(dest, entryPath); err != nil {
+			return fmt.Errorf("Archive entry '%s' is extracted through a symbolic link: %v", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(entryPath, os.ModeDir|os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(entryPath), os.ModeDir|os.ModePerm); err != nil {
+				return err
+			}
+			// a file replaces a link of an earlier entry instead of being written through it
+			if info, err := os.Lstat(entryPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				os.Remove(entryPath)
+			}
+			if err = extractTarFile(tarReader, entryPath, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err = os.MkdirAll(filepath.Dir(entryPath), os.ModeDir|os.ModePerm); err != nil {
+				return err
+			}
+			// links may point only within dest, otherwise files could be written through them
+			target := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(target) || !withinDir(dest, filepath.Join(filepath.Dir(entryPath), target)) {
+				return fmt.Errorf("Archive entry '%s' links outside the destination directory", header.Name)
+			}
+			os.Remove(entryPath)
+			if err = os.Symlink(header.Linkname, entryPath); err != nil {
+				return err
+			}
+		default:
+			simLog.Debugf("Skipping %s of type %c in the archive", header.Name, header.Typeflag)
+		}
+	}
+}
+
+// checkNoSymlinkParents fails when a directory between dest and entryPath is a symbolic link, links are checked
+// only lexically so a chain of them, e.g. x -> . followed by x/l -> .., could otherwise lead outside dest
+func checkNoSymlinkParents(dest, entryPath string) error {
+	rel, err := filepath.Rel(dest, filepath.Dir(entryPath))
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := dest
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("'%s' is a symbolic link", current)
+		}
+	}
+
+	return nil
+}
+
+func extractTarFile(reader io.Reader, filePath string, mode os.FileMode) error {
+	fileCopy, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = copyWithPooledBuffer(fileCopy, reader)
+	if closeErr := fileCopy.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
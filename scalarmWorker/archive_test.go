@@ -0,0 +1,190 @@
+package scalarmWorker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+func writeTestTar(t *testing.T, w io.Writer) {
+	tarWriter := tar.NewWriter(w)
+	tarWriter.WriteHeader(&tar.Header{Name: "adapters/", Typeflag: tar.TypeDir, Mode: 0755})
+	content := []byte("#!/bin/sh\necho ok\n")
+	tarWriter.WriteHeader(&tar.Header{Name: "adapters/executor", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(content))})
+	tarWriter.Write(content)
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractShouldDetectArchiveTypeFromContent(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "test_archive")
+	defer os.RemoveAll(dir)
+
+	archives := map[string]string{}
+
+	// code bases are saved as code_base.zip whatever their format
+	tarGzPath := path.Join(dir, "tar_gz.zip")
+	tarGzFile, _ := os.Create(tarGzPath)
+	gzipWriter := gzip.NewWriter(tarGzFile)
+	writeTestTar(t, gzipWriter)
+	gzipWriter.Close()
+	tarGzFile.Close()
+	archives[tarGzPath] = ArchiveTarGz
+
+	tarXzPath := path.Join(dir, "tar_xz.zip")
+	tarXzFile, _ := os.Create(tarXzPath)
+	xzWriter, err := xz.NewWriter(tarXzFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestTar(t, xzWriter)
+	xzWriter.Close()
+	tarXzFile.Close()
+	archives[tarXzPath] = ArchiveTarXz
+
+	zipPath := path.Join(dir, "code_base.zip")
+	zipFile, _ := os.Create(zipPath)
+	zipWriter := zip.NewWriter(zipFile)
+	entry, _ := zipWriter.Create("adapters/executor")
+	entry.Write([]byte("#!/bin/sh\necho ok\n"))
+	zipWriter.Close()
+	zipFile.Close()
+	archives[zipPath] = ArchiveZip
+
+	for archivePath, expectedType := range archives {
+		// === WHEN ===
+		archiveType, err := DetectArchiveType(archivePath)
+		dest := path.Join(dir, expectedType)
+		extractErr := Extract(archivePath, dest)
+
+		// === THEN ===
+		if err != nil || archiveType != expectedType {
+			t.Errorf("Got: '%v, %v' - Expected '%v'", archiveType, err, expectedType)
+		}
+		if extractErr != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", extractErr)
+		}
+		content, _ := ioutil.ReadFile(path.Join(dest, "adapters", "executor"))
+		if string(content) != "#!/bin/sh\necho ok\n" {
+			t.Errorf("Got: '%v' - Expected '%v'", string(content), "#!/bin/sh\necho ok\n")
+		}
+	}
+}
+
+func TestDetectArchiveTypeShouldRejectUnknownFormats(t *testing.T) {
+	// === GIVEN ===
+	file, _ := ioutil.TempFile("", "test_archive")
+	defer os.Remove(file.Name())
+	file.WriteString("not an archive")
+	file.Close()
+
+	// === WHEN ===
+	_, err := DetectArchiveType(file.Name())
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "an error")
+	}
+}
+
+func TestSanitizeArchivePathShouldRejectEntriesOutsideDestination(t *testing.T) {
+	dest := path.Join(os.TempDir(), "code_base")
+
+	cases := []struct {
+		name string
+		safe bool
+	}{
+		{"adapters/executor", true},
+		{"adapters/../executor", true},
+		{"../executor", false},
+		{"adapters/../../executor", false},
+		{"/etc/passwd", false},
+		{`..\executor`, false},
+	}
+
+	for _, c := range cases {
+		entryPath, err := SanitizeArchivePath(dest, c.name)
+		if (err == nil) != c.safe {
+			t.Errorf("Got: '%v, %v' - Expected safe: '%v' for %v", entryPath, err, c.safe, c.name)
+		}
+	}
+}
+
+func TestExtractShouldNotWriteOutsideDestination(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "test_archive")
+	defer os.RemoveAll(dir)
+
+	zipPath := path.Join(dir, "code_base.zip")
+	zipFile, _ := os.Create(zipPath)
+	zipWriter := zip.NewWriter(zipFile)
+	entry, _ := zipWriter.Create("executor")
+	entry.Write([]byte("ok"))
+	entry, _ = zipWriter.Create("../evil")
+	entry.Write([]byte("evil"))
+	zipWriter.Close()
+	zipFile.Close()
+
+	tarPath := path.Join(dir, "code_base.tar.gz")
+	tarFile, _ := os.Create(tarPath)
+	gzipWriter := gzip.NewWriter(tarFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../.."})
+	tarWriter.Close()
+	gzipWriter.Close()
+	tarFile.Close()
+
+	for _, archivePath := range []string{zipPath, tarPath} {
+		// === WHEN ===
+		err := Extract(archivePath, path.Join(dir, "dest", "code_base"))
+
+		// === THEN ===
+		if err == nil {
+			t.Errorf("Got: '%v' - Expected '%v' for %v", err, "an error", archivePath)
+		}
+	}
+	if _, err := os.Stat(path.Join(dir, "dest", "evil")); err == nil {
+		t.Errorf("A file outside the destination directory should not be written")
+	}
+	if _, err := os.Stat(path.Join(dir, "dest", "code_base", "executor")); err == nil {
+		t.Errorf("An archive with unsafe entries should not be extracted")
+	}
+}
+
+func TestExtractShouldNotWriteThroughChainedSymlinks(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "test_archive")
+	defer os.RemoveAll(dir)
+
+	tarPath := path.Join(dir, "code_base.tar.gz")
+	tarFile, _ := os.Create(tarPath)
+	gzipWriter := gzip.NewWriter(tarFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "."})
+	tarWriter.WriteHeader(&tar.Header{Name: "x/l", Typeflag: tar.TypeSymlink, Linkname: ".."})
+	tarWriter.WriteHeader(&tar.Header{Name: "l/evil", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	tarWriter.Write([]byte("evil"))
+	tarWriter.Close()
+	gzipWriter.Close()
+	tarFile.Close()
+
+	// === WHEN ===
+	err := Extract(tarPath, path.Join(dir, "dest", "code_base"))
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "an error")
+	}
+	if _, err := os.Stat(path.Join(dir, "dest", "evil")); err == nil {
+		t.Errorf("A file outside the destination directory should not be written")
+	}
+}
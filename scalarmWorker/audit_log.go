@@ -0,0 +1,191 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var auditLogger = NewLogger("audit")
+
+// AuditEntry describes a single HTTP interaction of SiM with Scalarm services
+type AuditEntry struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	URL           string    `json:"url"`
+	Status        int       `json:"status,omitempty"`
+	RequestBytes  int64     `json:"request_bytes"`
+	ResponseBytes int64     `json:"response_bytes"`
+	DurationMs    int64     `json:"duration_ms"`
+	Outcome       string    `json:"outcome"`
+}
+
+// AuditLog is an append-only file with one JSON entry per line, separate from the console output
+type AuditLog struct {
+	Path  string
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{Path: path, file: file}, nil
+}
+
+// Record appends an entry to the log
+func (auditLog *AuditLog) Record(entry AuditEntry) {
+	line, err := marshalJSON(entry)
+	if err != nil {
+		return
+	}
+
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	if _, err = auditLog.file.Write(append(line, '\n')); err != nil {
+		auditLogger.Warnf("Could not write to %s: %v", auditLog.Path, err)
+	}
+}
+
+func (auditLog *AuditLog) Close() error {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	return auditLog.file.Close()
+}
+
+// Transport wraps next so that every request sent through it is recorded, an entry is written
+// when the response body is closed or the request fails
+func (auditLog *AuditLog) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &auditTransport{auditLog: auditLog, next: next}
+}
+
+type auditTransport struct {
+	auditLog *AuditLog
+	next     http.RoundTripper
+}
+
+func (transport *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := AuditEntry{Time: time.Now(), Method: req.Method, URL: Redact(req.URL.String())}
+
+	// the request is copied as a RoundTripper must not modify the original one
+	requestBody := &countingReader{}
+	if req.Body != nil {
+		requestBody.ReadCloser = req.Body
+		req = req.Clone(req.Context())
+		req.Body = requestBody
+	}
+
+	resp, err := transport.next.RoundTrip(req)
+	entry.RequestBytes = requestBody.count
+
+	if err != nil {
+		entry.DurationMs = time.Since(entry.Time).Nanoseconds() / int64(time.Millisecond)
+		entry.Outcome = "error: " + Redact(err.Error())
+		transport.auditLog.Record(entry)
+		return nil, err
+	}
+
+	entry.Status = resp.StatusCode
+	resp.Body = &auditedBody{countingReader: countingReader{ReadCloser: resp.Body}, entry: entry, auditLog: transport.auditLog}
+	return resp, nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	reader.count += int64(n)
+	return n, err
+}
+
+type auditedBody struct {
+	countingReader
+	entry     AuditEntry
+	auditLog  *AuditLog
+	closeOnce sync.Once
+}
+
+func (body *auditedBody) Close() error {
+	err := body.countingReader.Close()
+
+	body.closeOnce.Do(func() {
+		body.entry.ResponseBytes = body.count
+		body.entry.DurationMs = time.Since(body.entry.Time).Nanoseconds() / int64(time.Millisecond)
+		body.entry.Outcome = "ok"
+		if body.entry.Status >= 400 {
+			body.entry.Outcome = "http_error"
+		}
+		body.auditLog.Record(body.entry)
+	})
+
+	return err
+}
+
+// EnableAuditLog records all requests of the client in audit_log_path; the log is uploaded
+// to audit_log_upload_url when SiM exits, if configured
+func EnableAuditLog(config *SimulationManagerConfig, client *http.Client) error {
+	auditLog, err := OpenAuditLog(config.AuditLogPath)
+	if err != nil {
+		return err
+	}
+
+	client.Transport = auditLog.Transport(client.Transport)
+
+	OnExit(func() {
+		if config.AuditLogUploadUrl != "" {
+			if err := auditLog.Upload(config.AuditLogUploadUrl, config, client); err != nil {
+				auditLogger.Warnf("Could not upload the audit log: %v", Redact(err.Error()))
+			}
+		}
+		auditLog.Close()
+	})
+
+	return nil
+}
+
+// Upload sends the audit log as multipart form data to uploadUrl
+func (auditLog *AuditLog) Upload(uploadUrl string, config *SimulationManagerConfig, client *http.Client) error {
+	upload := NewFileUpload(auditLog.Path)
+	body, err := upload.Open()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", uploadUrl, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", upload.ContentType())
+	req.SetBasicAuth(config.Credentials())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("upload response code: %d", resp.StatusCode)
+	}
+
+	auditLogger.Infof("Audit log uploaded to %s", Redact(uploadUrl))
+	return nil
+}
@@ -0,0 +1,79 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogShouldRecordRequests(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(404)
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		fmt.Fprint(w, "0123456789")
+	}))
+	defer server.Close()
+
+	logPath := "./test_audit.log"
+	defer os.Remove(logPath)
+	auditLog, err := OpenAuditLog(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: auditLog.Transport(nil)}
+
+	// === WHEN ===
+	resp, _ := client.Post(server.URL+"/upload?password=secret_value", "text/plain", strings.NewReader("request"))
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	resp, _ = client.Get(server.URL + "/missing")
+	resp.Body.Close()
+
+	client.Get("http://127.0.0.1:1/unreachable")
+	auditLog.Close()
+
+	// === THEN ===
+	file, _ := os.Open(logPath)
+	defer file.Close()
+
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := AuditEntry{}
+		json.Unmarshal(scanner.Bytes(), &entry)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Got: '%v' - Expected 3 entries", entries)
+	}
+
+	upload := entries[0]
+	if upload.Method != "POST" || upload.Status != 200 || upload.RequestBytes != 7 || upload.ResponseBytes != 10 || upload.Outcome != "ok" {
+		t.Errorf("Got: '%+v' - Expected a successful POST with 7 bytes sent and 10 received", upload)
+	}
+
+	if strings.Contains(upload.URL, "secret_value") {
+		t.Errorf("Got: '%v' - Expected credentials to be redacted", upload.URL)
+	}
+
+	if entries[1].Status != 404 || entries[1].Outcome != "http_error" {
+		t.Errorf("Got: '%+v' - Expected an HTTP error", entries[1])
+	}
+
+	if !strings.HasPrefix(entries[2].Outcome, "error: ") {
+		t.Errorf("Got: '%+v' - Expected a connection error", entries[2])
+	}
+}
@@ -0,0 +1,105 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+// throughputs of the reference node in MB/s, a node as fast as the reference one gets the score of 100
+const (
+	referenceCPUThroughput    = 500.0
+	referenceMemoryThroughput = 5000.0
+	referenceIOThroughput     = 200.0
+)
+
+// size of buffers hashed, copied and written by the benchmark
+const benchmarkBlockSize = 4 * 1024 * 1024
+
+// BenchmarkResult holds throughputs measured by the startup benchmark in MB/s and their combined score
+type BenchmarkResult struct {
+	CPU      float64 `json:"cpu"`
+	Memory   float64 `json:"memory"`
+	IO       float64 `json:"io"`
+	Score    float64 `json:"score"`
+	Duration float64 `json:"duration"` // in seconds
+}
+
+// RunBenchmark measures SHA-256 hashing, memory copying and rewriting a block with fsync in a file in dir,
+// each for a third of duration; the score is 100 times the geometric mean of throughputs relative
+// to the reference node, so durations of simulation runs can be compared across heterogeneous nodes
+func RunBenchmark(dir string, duration time.Duration) (*BenchmarkResult, error) {
+	start := time.Now()
+	phase := duration / 3
+
+	block := make([]byte, benchmarkBlockSize)
+	for i := range block {
+		block[i] = byte(i * 31)
+	}
+
+	result := &BenchmarkResult{}
+
+	result.CPU = measureThroughput(phase, func() error {
+		digest := sha256.Sum256(block)
+		block[0] = digest[0]
+		return nil
+	})
+
+	copyBlock := make([]byte, benchmarkBlockSize)
+	result.Memory = measureThroughput(phase, func() error {
+		copy(copyBlock, block)
+		copy(block, copyBlock)
+		return nil
+	})
+	// every iteration copies the block twice
+	result.Memory *= 2
+
+	ioFile, err := ioutil.TempFile(dir, "scalarm_benchmark")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(ioFile.Name())
+	defer ioFile.Close()
+
+	var ioErr error
+	result.IO = measureThroughput(phase, func() error {
+		if _, ioErr = ioFile.WriteAt(block, 0); ioErr != nil {
+			return ioErr
+		}
+		return ioFile.Sync()
+	})
+	if ioErr != nil {
+		return nil, ioErr
+	}
+
+	result.Score = 100 * math.Cbrt(result.CPU/referenceCPUThroughput*
+		result.Memory/referenceMemoryThroughput*
+		result.IO/referenceIOThroughput)
+	result.Duration = time.Since(start).Seconds()
+
+	return result, nil
+}
+
+// measureThroughput repeats operation on a block for at least duration and returns its throughput in MB/s
+func measureThroughput(duration time.Duration, operation func() error) float64 {
+	start := time.Now()
+	blocks := 0
+
+	for blocks == 0 || time.Since(start) < duration {
+		if err := operation(); err != nil {
+			return 0
+		}
+		blocks++
+	}
+
+	return float64(blocks) * benchmarkBlockSize / 1024 / 1024 / time.Since(start).Seconds()
+}
+
+// String returns a one line summary of the benchmark
+func (result *BenchmarkResult) String() string {
+	return fmt.Sprintf("score: %.1f (CPU: %.0f MB/s, memory: %.0f MB/s, IO: %.0f MB/s)",
+		result.Score, result.CPU, result.Memory, result.IO)
+}
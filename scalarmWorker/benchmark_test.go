@@ -0,0 +1,32 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRunBenchmarkShouldMeasureAllThroughputs(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "benchmark")
+	defer os.RemoveAll(dir)
+
+	// === WHEN ===
+	result, err := RunBenchmark(dir, 30*time.Millisecond)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if result.CPU <= 0 || result.Memory <= 0 || result.IO <= 0 || result.Score <= 0 {
+		t.Errorf("Got: '%+v' - Expected positive throughputs and score", result)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Got: '%v' files - Expected the benchmark file to be removed", len(files))
+	}
+}
@@ -0,0 +1,55 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+const copyBufferSize = 32 * 1024
+
+// buffers bigger than this are dropped instead of being kept in the pool for the lifetime of the worker
+const maxPooledBufferSize = 1024 * 1024
+
+// copyBufferPool keeps buffers used to copy files and response bodies between simulation runs
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, copyBufferSize)
+		return &buffer
+	},
+}
+
+// jsonBufferPool keeps buffers used to encode JSON sent to Scalarm
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// copyWithPooledBuffer works as io.Copy but takes its buffer from copyBufferPool
+func copyWithPooledBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	buffer := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buffer)
+
+	return io.CopyBuffer(dst, src, *buffer)
+}
+
+// marshalJSON returns the same encoding as json.Marshal, the encoding is done in a pooled buffer
+func marshalJSON(v interface{}) ([]byte, error) {
+	buffer := jsonBufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	defer func() {
+		if buffer.Cap() <= maxPooledBufferSize {
+			jsonBufferPool.Put(buffer)
+		}
+	}()
+
+	if err := json.NewEncoder(buffer).Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Encode terminates the value with a newline which json.Marshal does not add
+	encoded := bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+	return append([]byte(nil), encoded...), nil
+}
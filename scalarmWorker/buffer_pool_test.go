@@ -0,0 +1,53 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSONShouldEncodeLikeJsonMarshal(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{"parameter1": 0.5, "name": "<a & b>"},
+		[]int{1, 2, 3},
+		"text",
+		nil,
+	}
+
+	for _, value := range values {
+		// === WHEN ===
+		encoded, err := marshalJSON(value)
+
+		// === THEN ===
+		expected, _ := json.Marshal(value)
+		if err != nil || !bytes.Equal(encoded, expected) {
+			t.Errorf("Got: '%s' - Expected '%s'", encoded, expected)
+		}
+	}
+}
+
+func TestMarshalJSONShouldNotShareBuffersBetweenResults(t *testing.T) {
+	// === WHEN ===
+	first, _ := marshalJSON("first")
+	marshalJSON("second")
+
+	// === THEN ===
+	if string(first) != `"first"` {
+		t.Errorf("Got: '%s' - Expected '%v'", first, `"first"`)
+	}
+}
+
+func TestCopyWithPooledBufferShouldCopyWholeContent(t *testing.T) {
+	// === GIVEN ===
+	content := strings.Repeat("scalarm", 20000)
+	out := &bytes.Buffer{}
+
+	// === WHEN ===
+	copied, err := copyWithPooledBuffer(out, strings.NewReader(content))
+
+	// === THEN ===
+	if err != nil || copied != int64(len(content)) || out.String() != content {
+		t.Errorf("Got: %d bytes, '%v' - Expected %d bytes", copied, err, len(content))
+	}
+}
@@ -0,0 +1,130 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// CodeBaseCapabilities describes adapters provided by an experiment code base, it is created
+// once per code base so simulation runs do not need to check the code base again
+type CodeBaseCapabilities struct {
+	Dir             string
+	InputWriter     bool
+	Executor        bool
+	OutputReader    bool
+	ProgressMonitor bool
+	// input files are rendered from templates in the 'input_templates' directory
+	InputTemplates bool
+	// values from HDF5 and NetCDF output files are added to results as listed in 'result_summary.json'
+	ResultSummary bool
+	// datasets listed in 'shared_data.json' are downloaded once per experiment
+	SharedData bool
+	// adapter name -> interpreter from its shebang line, binaries and scripts without shebang are omitted
+	Interpreters map[string]string
+}
+
+// ScanCodeBase checks which adapters are available in codeBaseDir and what interpreters they require
+func ScanCodeBase(codeBaseDir string) *CodeBaseCapabilities {
+	capabilities := &CodeBaseCapabilities{
+		Dir:          codeBaseDir,
+		Interpreters: map[string]string{},
+	}
+
+	adapters := map[string]*bool{
+		"input_writer":     &capabilities.InputWriter,
+		"executor":         &capabilities.Executor,
+		"output_reader":    &capabilities.OutputReader,
+		"progress_monitor": &capabilities.ProgressMonitor,
+	}
+
+	for name, available := range adapters {
+		adapterPath := adapterFile(codeBaseDir, name)
+		if adapterPath == "" {
+			continue
+		}
+		*available = true
+
+		if interpreter, err := shebangInterpreter(adapterPath); err == nil && interpreter != "" {
+			capabilities.Interpreters[name] = interpreter
+		}
+	}
+
+	if info, err := os.Stat(path.Join(codeBaseDir, inputTemplatesDir)); err == nil && info.IsDir() {
+		capabilities.InputTemplates = true
+	}
+
+	if info, err := os.Stat(path.Join(codeBaseDir, resultSummaryFile)); err == nil && !info.IsDir() {
+		capabilities.ResultSummary = true
+	}
+
+	if info, err := os.Stat(path.Join(codeBaseDir, sharedDataManifest)); err == nil && !info.IsDir() {
+		capabilities.SharedData = true
+	}
+
+	return capabilities
+}
+
+// Print logs available adapters and warns about interpreters which cannot be found
+func (capabilities *CodeBaseCapabilities) Print() {
+	simLog.Infof("Code base adapters - input_writer: %v, executor: %v, output_reader: %v, progress_monitor: %v, input_templates: %v, result_summary: %v, shared_data: %v",
+		capabilities.InputWriter, capabilities.Executor, capabilities.OutputReader, capabilities.ProgressMonitor,
+		capabilities.InputTemplates, capabilities.ResultSummary, capabilities.SharedData)
+
+	for adapter, interpreter := range capabilities.Interpreters {
+		if err := interpreterAvailable(interpreter); err != nil {
+			simLog.Warnf("'%s' requires %v", adapter, err)
+		}
+	}
+}
+
+// shebangInterpreter returns an interpreter from the shebang line of a script ('/usr/bin/env' is skipped)
+// or an empty string when the file does not start with a shebang
+func shebangInterpreter(adapterPath string) (string, error) {
+	file, err := os.Open(adapterPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	firstLine, _ := bufio.NewReader(file).ReadString('\n')
+
+	if !strings.HasPrefix(firstLine, "#!") {
+		return "", nil
+	}
+
+	if strings.HasSuffix(firstLine, "\r\n") {
+		return "", errors.New("shebang line ends with CRLF, convert the file to Unix line endings")
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+	if len(fields) == 0 {
+		return "", errors.New("empty shebang line")
+	}
+
+	if path.Base(fields[0]) == "env" && len(fields) > 1 {
+		return fields[1], nil
+	}
+
+	return fields[0], nil
+}
+
+// interpreterAvailable checks if an interpreter given by a path or by a name from PATH exists
+func interpreterAvailable(interpreter string) error {
+	if strings.Contains(interpreter, "/") {
+		if _, err := os.Stat(interpreter); err != nil {
+			return fmt.Errorf("interpreter '%s' not found", interpreter)
+		}
+		return nil
+	}
+
+	if _, err := exec.LookPath(interpreter); err != nil {
+		return fmt.Errorf("interpreter '%s' not found in PATH", interpreter)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestScanCodeBaseShouldFindAdaptersAndInterpreters(t *testing.T) {
+	// === GIVEN ===
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/usr/bin/env python2\n"), 0777)
+	ioutil.WriteFile(path.Join(codeBaseDir, "output_reader"), []byte("#!/bin/sh\n"), 0777)
+	ioutil.WriteFile(path.Join(codeBaseDir, "progress_monitor"), []byte("echo 'no shebang'\n"), 0777)
+
+	// === WHEN ===
+	capabilities := ScanCodeBase(codeBaseDir)
+
+	// === THEN ===
+	if capabilities.InputWriter || !capabilities.Executor || !capabilities.OutputReader || !capabilities.ProgressMonitor {
+		t.Errorf("Got: '%+v' - Expected executor, output_reader and progress_monitor only", capabilities)
+	}
+
+	if capabilities.Interpreters["executor"] != "python2" {
+		t.Errorf("Got: '%v' - Expected '%v'", capabilities.Interpreters["executor"], "python2")
+	}
+
+	if capabilities.Interpreters["output_reader"] != "/bin/sh" {
+		t.Errorf("Got: '%v' - Expected '%v'", capabilities.Interpreters["output_reader"], "/bin/sh")
+	}
+
+	if _, ok := capabilities.Interpreters["progress_monitor"]; ok {
+		t.Errorf("Scripts without shebang should not have an interpreter")
+	}
+}
@@ -0,0 +1,81 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// number of recent samples the clock offset is averaged over
+const clockOffsetSamples = 20
+
+// samples of requests which took longer tell little about when the server produced the response
+const maxClockSampleRoundTrip = 2 * time.Second
+
+// offset between the local clock and the clock of Experiment Manager, estimated from Date headers of its responses
+var clockOffset struct {
+	samples []time.Duration
+	mutex   sync.Mutex
+}
+
+// recordClockOffset takes a sample of the clock offset from the Date header of a response to a request sent
+// at sentAt and received at receivedAt
+func recordClockOffset(resp *http.Response, sentAt time.Time, receivedAt time.Time) {
+	if resp == nil || receivedAt.Sub(sentAt) > maxClockSampleRoundTrip {
+		return
+	}
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	// the Date header is truncated to seconds and the response was produced half way through the request
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	localTime := sentAt.Add(receivedAt.Sub(sentAt) / 2)
+
+	clockOffset.mutex.Lock()
+	defer clockOffset.mutex.Unlock()
+
+	clockOffset.samples = appendRecentDuration(clockOffset.samples, serverTime.Sub(localTime), clockOffsetSamples)
+}
+
+func appendRecentDuration(samples []time.Duration, sample time.Duration, limit int) []time.Duration {
+	samples = append(samples, sample)
+	if len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+
+	return samples
+}
+
+// ClockOffset returns how far the clock of Experiment Manager is ahead of the local clock,
+// false when no response with a Date header was received yet
+func ClockOffset() (time.Duration, bool) {
+	clockOffset.mutex.Lock()
+	defer clockOffset.mutex.Unlock()
+
+	if len(clockOffset.samples) == 0 {
+		return 0, false
+	}
+
+	var sum time.Duration
+	for _, sample := range clockOffset.samples {
+		sum += sample
+	}
+
+	return sum / time.Duration(len(clockOffset.samples)), true
+}
+
+// ServerTime converts a local time to the time of Experiment Manager, so timings reported by workers on nodes
+// with drifting clocks can be compared; the local time is returned until the offset is known
+func ServerTime(t time.Time) time.Time {
+	offset, _ := ClockOffset()
+
+	return t.Add(offset)
+}
+
+// serverNow returns the current time of Experiment Manager
+func serverNow() time.Time {
+	return ServerTime(time.Now())
+}
@@ -0,0 +1,61 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func resetClockOffset() {
+	clockOffset.mutex.Lock()
+	defer clockOffset.mutex.Unlock()
+
+	clockOffset.samples = nil
+}
+
+func TestExperimentManagerResponsesShouldCorrectReportedTimesForClockOffset(t *testing.T) {
+	// === GIVEN ===
+	resetClockOffset()
+	defer resetClockOffset()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		fmt.Fprintln(w, `{"status": "all_sent"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	if _, err := em.GetNextSimulationRunConfig(); err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	report := NewRunReport("568e5bece138232e76000002", 1, []byte("{}"))
+	offset, ok := ClockOffset()
+
+	// === THEN ===
+	if !ok || offset < time.Hour-2*time.Second || offset > time.Hour+2*time.Second {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", offset, ok, time.Hour, true)
+	}
+
+	if delay := report.StartedAt - time.Now().Unix(); delay < 3598 || delay > 3602 {
+		t.Errorf("Got: '%v' - Expected '%v'", delay, 3600)
+	}
+}
+
+func TestClockOffsetShouldBeUnknownWithoutDateHeaders(t *testing.T) {
+	// === GIVEN ===
+	resetClockOffset()
+	now := time.Now()
+
+	// === WHEN ===
+	recordClockOffset(&http.Response{Header: http.Header{}}, now, now)
+	_, ok := ClockOffset()
+
+	// === THEN ===
+	if ok || !ServerTime(now).Equal(now) {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", ok, ServerTime(now), false, now)
+	}
+}
@@ -0,0 +1,140 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// downloadResumable fetches serviceMethod from one of Experiment Managers into filePath through filePath.part;
+// an interrupted download is kept and resumed with a Range request by the next attempt, and the file is moved
+// into place only when its whole content was received. The ETag of the content is kept in filePath.part.etag
+// and sent in If-Range, so a code base changed in the meantime is downloaded again instead of being spliced
+func (em *ExperimentManager) downloadResumable(serviceMethod string, filePath string) error {
+	partPath := filePath + ".part"
+	etagPath := partPath + ".etag"
+
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	etag := ""
+	if content, err := ioutil.ReadFile(etagPath); err == nil {
+		etag = strings.TrimSpace(string(content))
+	}
+
+	resp, err := em.getRange(serviceMethod, offset, etag)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		var start int64
+		if start, total, err = parseContentRange(resp.Header.Get("Content-Range")); err != nil || start != offset {
+			file.Truncate(0)
+			return fmt.Errorf("Unexpected Content-Range '%s' when resuming from byte %d", resp.Header.Get("Content-Range"), offset)
+		}
+		simLog.Infof("Resuming download of %s from byte %d", serviceMethod, offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the previous attempt received the whole content, unless it changed in the meantime
+		if _, total, err = parseContentRange(resp.Header.Get("Content-Range")); err != nil || total != offset {
+			file.Truncate(0)
+			return errors.New("Could not resume download of " + serviceMethod + ", it is downloaded again")
+		}
+	case http.StatusOK:
+		if err = file.Truncate(0); err != nil {
+			return err
+		}
+		offset = 0
+		total = resp.ContentLength
+	default:
+		return errors.New("Could not download " + serviceMethod + ", response code: " + resp.Status)
+	}
+
+	// without an ETag a later attempt resumes without If-Range, as before
+	if newEtag := resp.Header.Get("ETag"); newEtag != "" && newEtag != etag {
+		ioutil.WriteFile(etagPath, []byte(newEtag), 0666)
+	}
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	written, err := copyWithPooledBuffer(file, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// without a known length the content is complete when the response ends without an error
+	if total >= 0 && offset+written != total {
+		return fmt.Errorf("Download of %s interrupted after %d of %d bytes", serviceMethod, offset+written, total)
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+	os.Remove(etagPath)
+	return os.Rename(partPath, filePath)
+}
+
+// getRange requests content from the offset, if it still has the given ETag, from one of Experiment Managers
+func (em *ExperimentManager) getRange(serviceMethod string, offset int64, etag string) (*http.Response, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if etag != "" {
+			header.Set("If-Range", etag)
+		}
+	}
+
+	ranged := *em
+	ranged.Context = WithRequestHeaders(em.context(), header)
+
+	return ranged.execute(RequestInfo{"GET", nil, "", serviceMethod})
+}
+
+// parseContentRange returns the first byte and the total length from 'bytes <start>-<end>/<total>'
+// or 'bytes */<total>', the total is -1 when it is unknown
+func parseContentRange(contentRange string) (int64, int64, error) {
+	invalid := errors.New("Invalid Content-Range: " + contentRange)
+
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, 0, invalid
+	}
+	rangeAndTotal := strings.SplitN(strings.TrimPrefix(contentRange, "bytes "), "/", 2)
+	if len(rangeAndTotal) != 2 {
+		return 0, 0, invalid
+	}
+
+	total := int64(-1)
+	if rangeAndTotal[1] != "*" {
+		var err error
+		if total, err = strconv.ParseInt(rangeAndTotal[1], 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+	}
+
+	if rangeAndTotal[0] == "*" {
+		return 0, total, nil
+	}
+	start, err := strconv.ParseInt(strings.SplitN(rangeAndTotal[0], "-", 2)[0], 10, 64)
+	if err != nil {
+		return 0, 0, invalid
+	}
+
+	return start, total, nil
+}
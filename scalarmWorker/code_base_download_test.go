@@ -0,0 +1,87 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadExperimentCodeBaseShouldResumeInterruptedDownload(t *testing.T) {
+	// === GIVEN ===
+	content := strings.Repeat("code base content ", 1000)
+	ranges := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		var start int
+		if r.Header.Get("Range") != "" {
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)-start))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, content[start:])
+			return
+		}
+		// the first response is cut off in the middle
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		fmt.Fprint(w, content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+
+	config := getSimConfig()
+	config.MaxAttempts = 1
+	em := &ExperimentManager{HttpClient: getHttpClientMock(server.URL), BaseUrls: []string{"system.scalarm.com"},
+		CommunicationTimeout: 5 * time.Second, Config: config, ExperimentId: "1"}
+
+	// === WHEN ===
+	firstErr := em.DownloadExperimentCodeBase(codeBaseDir)
+	_, statErr := os.Stat(path.Join(codeBaseDir, "code_base.zip"))
+	secondErr := em.DownloadExperimentCodeBase(codeBaseDir)
+
+	// === THEN ===
+	if firstErr == nil || statErr == nil {
+		t.Errorf("An interrupted download should not produce code_base.zip")
+	}
+	if secondErr != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", secondErr)
+	}
+	if downloaded, _ := ioutil.ReadFile(path.Join(codeBaseDir, "code_base.zip")); string(downloaded) != content {
+		t.Errorf("Got: '%v' bytes - Expected '%v' bytes", len(downloaded), len(content))
+	}
+	if len(ranges) != 2 || ranges[1] != fmt.Sprintf("bytes=%d-", len(content)/2) {
+		t.Errorf("Got: '%v' - Expected '%v'", ranges, "a Range request resuming from the middle")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct {
+		contentRange string
+		start, total int64
+		valid        bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", 0, 200, true},
+		{"items 0-1/2", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		start, total, err := parseContentRange(c.contentRange)
+		if (err == nil) != c.valid || start != c.start || total != c.total {
+			t.Errorf("Got: '%v, %v, %v' - Expected '%v, %v' for %v", start, total, err, c.start, c.total, c.contentRange)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+)
+
+// value of code_base_store which shares code bases between all workers of a node through its temporary directory
+const CodeBaseStoreNode = "node"
+
+// codeBaseStoreDir returns the directory of the code base store, relative paths are resolved in the root directory
+func (sim SimulationManager) codeBaseStoreDir() string {
+	if sim.Config.CodeBaseStore == CodeBaseStoreNode {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("scalarm_code_base_store_%d", os.Getuid()))
+	}
+	if !path.IsAbs(sim.Config.CodeBaseStore) {
+		return path.Join(sim.RootDirPath, sim.Config.CodeBaseStore)
+	}
+
+	return sim.Config.CodeBaseStore
+}
+
+// ensurePrivateDir creates a directory accessible only to the current user, an existing one has to belong
+// to the user and must not be accessible to others, so nobody else on the node can plant a code base in it
+func ensurePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || !ownedByCurrentUser(info) {
+		return fmt.Errorf("%s is not a directory of the current user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 && runtime.GOOS != "windows" {
+		return fmt.Errorf("%s is accessible to other users (mode %v)", dir, info.Mode().Perm())
+	}
+
+	return nil
+}
+
+// prepareCodeBaseOnce downloads and extracts the code base into a '.part' directory renamed to codeBaseDir
+// when it is complete, so workers waiting for the lock never see a half-extracted code base, even when
+// the worker preparing it crashed or failed
+func (sim SimulationManager) prepareCodeBaseOnce(em *ExperimentManager, codeBaseDir string) error {
+	partialDir := codeBaseDir + ".part"
+	os.RemoveAll(partialDir)
+
+	if err := sim.PrepareCodeBase(em, partialDir); err != nil {
+		os.RemoveAll(partialDir)
+		return err
+	}
+
+	return os.Rename(partialDir, codeBaseDir)
+}
+
+// prepareExperimentCodeBase gets the code base of the experiment unless it is in codeBaseDir already,
+// workers sharing the root directory download it only once
+func (sim SimulationManager) prepareExperimentCodeBase(em *ExperimentManager, experimentID string, experimentDir string,
+	codeBaseDir string) error {
+
+	unlock, err := lockFile(path.Join(experimentDir, "code_base.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(codeBaseDir); !os.IsNotExist(err) {
+		return nil
+	}
+	if sim.Config.CodeBaseStore != "" {
+		return sim.prepareStoredCodeBase(em, experimentID, codeBaseDir)
+	}
+
+	return sim.prepareCodeBaseOnce(em, codeBaseDir)
+}
+
+// prepareLockedCodeBase prepares the code base in codeBaseDir once, holding the lock in lockDir
+func (sim SimulationManager) prepareLockedCodeBase(em *ExperimentManager, lockDir string, codeBaseDir string) error {
+	unlock, err := lockFile(path.Join(lockDir, "code_base.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(codeBaseDir); !os.IsNotExist(err) {
+		return nil
+	}
+
+	return sim.prepareCodeBaseOnce(em, codeBaseDir)
+}
+
+// prepareStoredCodeBase extracts the code base of the experiment once into the code base store shared
+// by workers with different root directories; when the experiment directory is on the same filesystem
+// as the store, codeBaseDir becomes a symlink to the single extracted copy, otherwise the code base
+// is copied, so adapters are not read over the network from another filesystem
+func (sim SimulationManager) prepareStoredCodeBase(em *ExperimentManager, experimentID string, codeBaseDir string) error {
+	if sim.Config.CodeBaseStore == CodeBaseStoreNode {
+		if err := ensurePrivateDir(sim.codeBaseStoreDir()); err != nil {
+			return err
+		}
+	}
+
+	storeDir := path.Join(sim.codeBaseStoreDir(), experimentID)
+	if err := os.MkdirAll(storeDir, 0777); err != nil {
+		return err
+	}
+
+	storedCodeBaseDir := path.Join(storeDir, "code_base")
+
+	err := sim.prepareLockedCodeBase(em, storeDir, storedCodeBaseDir)
+	if err != nil {
+		return err
+	}
+
+	if sameFilesystem(storedCodeBaseDir, path.Dir(codeBaseDir)) {
+		simLog.Infof("Linking the code base from %s", storedCodeBaseDir)
+		return os.Symlink(storedCodeBaseDir, codeBaseDir)
+	}
+
+	simLog.Infof("Copying the code base from %s, which is on another filesystem", storedCodeBaseDir)
+	partialDir := codeBaseDir + ".part"
+	os.RemoveAll(partialDir)
+
+	if err = copyTree(storedCodeBaseDir, partialDir, nil); err != nil {
+		os.RemoveAll(partialDir)
+		return err
+	}
+
+	return os.Rename(partialDir, codeBaseDir)
+}
@@ -0,0 +1,147 @@
+package scalarmWorker
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoredCodeBaseShouldBeLinkedOnTheSameFilesystem(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "code_base_store")
+	defer os.RemoveAll(dir)
+
+	config := getSimConfig()
+	config.CodeBaseStore = filepath.Join(dir, "store")
+	sim := SimulationManager{Config: config, RootDirPath: filepath.Join(dir, "worker_1")}
+
+	// the code base was already extracted by another worker
+	storedCodeBaseDir := filepath.Join(config.CodeBaseStore, "568e5bece138232e76000002", "code_base")
+	os.MkdirAll(storedCodeBaseDir, 0755)
+	ioutil.WriteFile(filepath.Join(storedCodeBaseDir, "executor"), []byte("#!/bin/sh\n"), 0755)
+
+	experimentDir := filepath.Join(sim.RootDirPath, "experiment_568e5bece138232e76000002")
+	os.MkdirAll(experimentDir, 0755)
+	codeBaseDir := filepath.Join(experimentDir, "code_base")
+
+	// === WHEN ===
+	err := sim.prepareStoredCodeBase(nil, "568e5bece138232e76000002", codeBaseDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	target, err := os.Readlink(codeBaseDir)
+	if err != nil || target != storedCodeBaseDir {
+		t.Errorf("Got: '%v' - Expected a symlink to '%v'", target, storedCodeBaseDir)
+	}
+}
+
+func codeBaseZip(t *testing.T) []byte {
+	binaries := &bytes.Buffer{}
+	zip.NewWriter(binaries).Close()
+
+	archive := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(archive)
+	entry, _ := zipWriter.Create("executor")
+	entry.Write([]byte("#!/bin/sh\n"))
+	entry, _ = zipWriter.Create("simulation_binaries.zip")
+	entry.Write(binaries.Bytes())
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archive.Bytes()
+}
+
+func TestStoredCodeBaseShouldBeDownloadedOnceByConcurrentWorkers(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "code_base_store")
+	defer os.RemoveAll(dir)
+
+	archive := codeBaseZip(t)
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.CodeBaseStore = filepath.Join(dir, "store")
+	em := &ExperimentManager{HttpClient: getHttpClientMock(server.URL), BaseUrls: []string{"system.scalarm.com"},
+		CommunicationTimeout: 5 * time.Second, Config: config, ExperimentId: "1"}
+
+	// === WHEN ===
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sim := SimulationManager{Config: config, RootDirPath: filepath.Join(dir, fmt.Sprintf("worker_%d", i))}
+			os.MkdirAll(sim.RootDirPath, 0755)
+			errs[i] = sim.prepareStoredCodeBase(em, "1", filepath.Join(sim.RootDirPath, "code_base"))
+		}(i)
+	}
+	wg.Wait()
+
+	// === THEN ===
+	if downloads != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", downloads, 1)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+		if _, err = os.Stat(filepath.Join(dir, fmt.Sprintf("worker_%d", i), "code_base", "executor")); err != nil {
+			t.Errorf("Got: '%v' - Expected '%v'", err, "the executor in the code base of every worker")
+		}
+	}
+	if _, err := os.Stat(filepath.Join(config.CodeBaseStore, "1", "code_base.part")); !os.IsNotExist(err) {
+		t.Errorf("The partial code base directory should be renamed once complete")
+	}
+}
+
+func TestNodeCodeBaseStoreShouldBeInTemporaryDirectory(t *testing.T) {
+	config := getSimConfig()
+	config.CodeBaseStore = CodeBaseStoreNode
+	sim := SimulationManager{Config: config, RootDirPath: "/root_dir"}
+
+	if storeDir := sim.codeBaseStoreDir(); filepath.Dir(storeDir) != os.TempDir() {
+		t.Errorf("Got: '%v' - Expected a directory in '%v'", storeDir, os.TempDir())
+	}
+}
+
+func TestEnsurePrivateDirShouldRejectDirectoriesAccessibleToOthers(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "code_base_store")
+	defer os.RemoveAll(dir)
+
+	privateDir := filepath.Join(dir, "private")
+	sharedDir := filepath.Join(dir, "shared")
+	os.Mkdir(sharedDir, 0777)
+	os.Chmod(sharedDir, 0777)
+
+	// === WHEN ===
+	privateErr := ensurePrivateDir(privateDir)
+	sharedErr := ensurePrivateDir(sharedDir)
+
+	// === THEN ===
+	if info, err := os.Stat(privateDir); privateErr != nil || err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Got: '%v' - Expected '%v'", privateErr, "a directory with mode 0700")
+	}
+	if sharedErr == nil {
+		t.Errorf("Got: '%v' - Expected '%v'", sharedErr, "an error")
+	}
+}
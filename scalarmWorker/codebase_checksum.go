@@ -0,0 +1,109 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// archives of a code base whose checksums can be verified
+const (
+	CodeBaseArchive           = "code_base.zip"
+	SimulationBinariesArchive = "simulation_binaries.zip"
+)
+
+// value of code_base_sha256 which makes SiM request checksums of archives from the Experiment Manager
+const ChecksumRequest = "request"
+
+// ChecksumError - a downloaded archive does not match its SHA-256 checksum, e.g. because it was truncated
+type ChecksumError struct {
+	Archive  string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("SHA-256 checksum of %s is %s, expected %s", e.Archive, e.Actual, e.Expected)
+}
+
+// VerifyChecksum compares the SHA-256 checksum of a file with the expected hex digest, an empty digest
+// means there is nothing to verify
+func VerifyChecksum(filePath string, archive string, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	actual, err := fileSHA256(filePath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumError{Archive: archive, Expected: strings.ToLower(expected), Actual: actual}
+	}
+
+	return nil
+}
+
+// isSHA256Digest tells whether the value is a hex encoded SHA-256 digest
+func isSHA256Digest(value string) bool {
+	decoded, err := hex.DecodeString(value)
+
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// codeBaseChecksumsEnabled tells whether archives of code bases are verified
+func (config *SimulationManagerConfig) codeBaseChecksumsEnabled() bool {
+	return config.CodeBaseSHA256 != "" || config.SimulationBinariesSHA256 != ""
+}
+
+// codeBaseChecksums returns expected checksums of archives of a code base, configured with code_base_sha256
+// and simulation_binaries_sha256 or, with code_base_sha256 set to 'request', obtained from the Experiment Manager
+func (sim SimulationManager) codeBaseChecksums(em *ExperimentManager) (map[string]string, error) {
+	checksums := map[string]string{
+		CodeBaseArchive:           sim.Config.CodeBaseSHA256,
+		SimulationBinariesArchive: sim.Config.SimulationBinariesSHA256,
+	}
+	if sim.Config.CodeBaseSHA256 != ChecksumRequest {
+		return checksums, nil
+	}
+
+	requested, err := em.GetCodeBaseChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	checksums[CodeBaseArchive] = requested[CodeBaseArchive]
+	if checksums[CodeBaseArchive] == "" {
+		return nil, fmt.Errorf("The Experiment Manager did not send a checksum of %s", CodeBaseArchive)
+	}
+	if checksums[SimulationBinariesArchive] == "" {
+		checksums[SimulationBinariesArchive] = requested[SimulationBinariesArchive]
+	}
+
+	return checksums, nil
+}
+
+// GetCodeBaseChecksums fetches SHA-256 checksums of code base archives, a JSON object mapping names of archives
+// to hex digests, from experiments/<id>/code_base_checksum
+func (em *ExperimentManager) GetCodeBaseChecksums() (map[string]string, error) {
+	reqInfo := RequestInfo{"GET", nil, "", "experiments/" + em.ExperimentId + "/code_base_checksum"}
+
+	resp, err := em.execute(reqInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Code base checksum is not available, Experiment manager response code: %d", resp.StatusCode)
+	}
+
+	checksums := map[string]string{}
+	if err = json.NewDecoder(resp.Body).Decode(&checksums); err != nil {
+		return nil, err
+	}
+
+	return checksums, nil
+}
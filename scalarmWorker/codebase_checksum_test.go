@@ -0,0 +1,65 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerifyChecksumShouldRejectTruncatedArchive(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "checksum_test_")
+	defer os.RemoveAll(dir)
+
+	archive := []byte("code base content")
+	digest := sha256.Sum256(archive)
+	ioutil.WriteFile(dir+"/code_base.zip", archive[:5], 0666)
+
+	// === WHEN ===
+	err := VerifyChecksum(dir+"/code_base.zip", CodeBaseArchive, hex.EncodeToString(digest[:]))
+
+	// === THEN ===
+	if _, ok := err.(*ChecksumError); !ok {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "a ChecksumError")
+	}
+
+	ioutil.WriteFile(dir+"/code_base.zip", archive, 0666)
+	if err = VerifyChecksum(dir+"/code_base.zip", CodeBaseArchive, hex.EncodeToString(digest[:])); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+}
+
+func TestCodeBaseChecksumsShouldBeRequestedFromExperimentManager(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/1/code_base_checksum" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintln(w, `{"code_base.zip":"abc","simulation_binaries.zip":"def"}`)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.CodeBaseSHA256 = ChecksumRequest
+	sim := SimulationManager{Config: config}
+	em := &ExperimentManager{HttpClient: getHttpClientMock(server.URL), BaseUrls: []string{"system.scalarm.com"},
+		CommunicationTimeout: 5 * time.Second, Config: config, ExperimentId: "1"}
+
+	// === WHEN ===
+	checksums, err := sim.codeBaseChecksums(em)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	if checksums[CodeBaseArchive] != "abc" || checksums[SimulationBinariesArchive] != "def" {
+		t.Errorf("Got: '%v' - Expected '%v'", checksums, "checksums of both archives")
+	}
+}
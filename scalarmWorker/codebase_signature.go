@@ -0,0 +1,114 @@
+package scalarmWorker
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+const (
+	SignatureGPG     = "gpg"
+	SignatureEd25519 = "ed25519"
+)
+
+// DownloadCodeBaseSignature fetches the detached signature of code_base.zip into code_base.zip.sig
+func (em *ExperimentManager) DownloadCodeBaseSignature(codeBaseDir string) error {
+	reqInfo := RequestInfo{"GET", nil, "", "experiments/" + em.ExperimentId + "/code_base_signature"}
+
+	resp, err := ExecuteScalarmRequest(em.context(), reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Code base signature is not available, Experiment manager response code: %d", resp.StatusCode)
+	}
+
+	signature, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path.Join(codeBaseDir, "code_base.zip.sig"), signature, 0666)
+}
+
+// VerifyCodeBaseSignature checks the detached signature of a code base archive against trusted keys;
+// for 'gpg' trusted keys are paths of exported public keys, for 'ed25519' base64 encoded public keys
+func VerifyCodeBaseSignature(method string, trustedKeys []string, archivePath string, signaturePath string) error {
+	if len(trustedKeys) == 0 {
+		return errors.New("No trusted keys configured for code base signature verification")
+	}
+
+	switch method {
+	case SignatureGPG:
+		return verifyGPGSignature(trustedKeys, archivePath, signaturePath)
+	case SignatureEd25519:
+		return verifyEd25519Signature(trustedKeys, archivePath, signaturePath)
+	}
+
+	return fmt.Errorf("Unsupported code base signature method '%s', use %s or %s", method, SignatureGPG, SignatureEd25519)
+}
+
+// verifyGPGSignature imports trusted keys into a temporary keyring so only they can make the signature valid
+func verifyGPGSignature(trustedKeys []string, archivePath string, signaturePath string) error {
+	gpgHome, err := ioutil.TempDir("", "scalarm_gpg_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(gpgHome)
+
+	for _, keyPath := range trustedKeys {
+		if output, err := exec.Command("gpg", "--batch", "--homedir", gpgHome, "--import", keyPath).CombinedOutput(); err != nil {
+			return fmt.Errorf("Could not import trusted key %s: %v %s", keyPath, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	output, err := exec.Command("gpg", "--batch", "--homedir", gpgHome, "--verify", signaturePath, archivePath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Invalid code base signature: %v %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// verifyEd25519Signature accepts the signature of the whole archive made by any of the trusted keys,
+// the signature file can contain raw or base64 encoded bytes
+func verifyEd25519Signature(trustedKeys []string, archivePath string, signaturePath string) error {
+	signature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return err
+	}
+
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return errors.New("Code base signature is not a valid ed25519 signature")
+		}
+		signature = decoded
+	}
+
+	archive, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		return err
+	}
+
+	for _, trustedKey := range trustedKeys {
+		publicKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(trustedKey))
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("Trusted key '%s' is not a base64 encoded ed25519 public key", trustedKey)
+		}
+
+		if ed25519.Verify(ed25519.PublicKey(publicKey), archive, signature) {
+			return nil
+		}
+	}
+
+	return errors.New("Invalid code base signature: it was not made by any of the trusted keys")
+}
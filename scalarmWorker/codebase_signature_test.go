@@ -0,0 +1,78 @@
+package scalarmWorker
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func setupSignedArchive(t *testing.T) (string, ed25519.PublicKey, ed25519.PrivateKey) {
+	dir, err := ioutil.TempDir("", "signature_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	archive := []byte("code base content")
+	ioutil.WriteFile(dir+"/code_base.zip", archive, 0666)
+	ioutil.WriteFile(dir+"/code_base.zip.sig", []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, archive))), 0666)
+
+	return dir, publicKey, privateKey
+}
+
+func TestVerifyCodeBaseSignatureShouldAcceptSignatureOfTrustedKey(t *testing.T) {
+	// === GIVEN ===
+	dir, publicKey, _ := setupSignedArchive(t)
+	defer os.RemoveAll(dir)
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	trustedKeys := []string{base64.StdEncoding.EncodeToString(otherKey), base64.StdEncoding.EncodeToString(publicKey)}
+
+	// === WHEN ===
+	err := VerifyCodeBaseSignature(SignatureEd25519, trustedKeys, dir+"/code_base.zip", dir+"/code_base.zip.sig")
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+}
+
+func TestVerifyCodeBaseSignatureShouldRejectTamperedArchive(t *testing.T) {
+	// === GIVEN ===
+	dir, publicKey, _ := setupSignedArchive(t)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/code_base.zip", []byte("code base content with a backdoor"), 0666)
+
+	// === WHEN ===
+	err := VerifyCodeBaseSignature(SignatureEd25519, []string{base64.StdEncoding.EncodeToString(publicKey)},
+		dir+"/code_base.zip", dir+"/code_base.zip.sig")
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Tampered code base should be rejected")
+	}
+}
+
+func TestVerifyCodeBaseSignatureShouldRejectUntrustedKey(t *testing.T) {
+	// === GIVEN ===
+	dir, _, _ := setupSignedArchive(t)
+	defer os.RemoveAll(dir)
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+
+	// === WHEN ===
+	err := VerifyCodeBaseSignature(SignatureEd25519, []string{base64.StdEncoding.EncodeToString(otherKey)},
+		dir+"/code_base.zip", dir+"/code_base.zip.sig")
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Signature of an untrusted key should be rejected")
+	}
+}
+
+func TestVerifyCodeBaseSignatureShouldRequireTrustedKeys(t *testing.T) {
+	if err := VerifyCodeBaseSignature(SignatureGPG, []string{}, "code_base.zip", "code_base.zip.sig"); err == nil {
+		t.Errorf("Verification without trusted keys should fail")
+	}
+}
@@ -0,0 +1,191 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+var verifyCodebaseLog = NewLogger("verify-codebase")
+
+var adapterProbeTimeout = 5 * time.Second
+
+// CodeBaseCheck is a result of verifying a single adapter from an experiment code base
+type CodeBaseCheck struct {
+	Adapter string
+	Passed  bool
+	Message string
+}
+
+type adapterSpec struct {
+	name     string
+	required bool
+}
+
+var codeBaseAdapters = []adapterSpec{
+	{"input_writer", false},
+	{"executor", true},
+	{"output_reader", false},
+	{"progress_monitor", false},
+}
+
+// CheckCodeBase verifies that adapters in codeBaseDir exist, have a valid interpreter and can be started
+// with a '--help' probe; they are already executable, PrepareCodeBase makes them so
+func CheckCodeBase(codeBaseDir string) []CodeBaseCheck {
+	checks := []CodeBaseCheck{}
+
+	for _, adapter := range codeBaseAdapters {
+		checks = append(checks, checkAdapter(codeBaseDir, adapter))
+	}
+
+	return checks
+}
+
+func checkAdapter(codeBaseDir string, adapter adapterSpec) CodeBaseCheck {
+	check := CodeBaseCheck{Adapter: adapter.name}
+	adapterPath := adapterFile(codeBaseDir, adapter.name)
+	if adapterPath == "" {
+		adapterPath = path.Join(codeBaseDir, adapter.name)
+	}
+
+	info, err := os.Stat(adapterPath)
+	if err != nil {
+		if adapter.required {
+			check.Message = "missing"
+		} else {
+			check.Passed = true
+			check.Message = "not provided (optional)"
+		}
+		return check
+	}
+
+	if info.IsDir() {
+		check.Message = "is a directory"
+		return check
+	}
+
+	if err = checkInterpreter(adapterPath); err != nil {
+		check.Message = err.Error()
+		return check
+	}
+
+	if err = probeAdapter(adapterPath); err != nil {
+		check.Message = err.Error()
+		return check
+	}
+
+	check.Passed = true
+	check.Message = "ok"
+	return check
+}
+
+// checkInterpreter validates the shebang line of a script, binaries are accepted as they are
+func checkInterpreter(adapterPath string) error {
+	interpreter, err := shebangInterpreter(adapterPath)
+	if err != nil || interpreter == "" {
+		return err
+	}
+
+	return interpreterAvailable(interpreter)
+}
+
+// probeAdapter starts the adapter with '--help' in a scratch directory, an adapter which is still running
+// after adapterProbeTimeout is considered to be working
+func probeAdapter(adapterPath string) error {
+	probeDir, err := ioutil.TempDir("", "scalarm_probe_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(probeDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), adapterProbeTimeout)
+	defer cancel()
+
+	output := &bytes.Buffer{}
+	probeCmd := adapterExec(ctx, adapterPath, "--help")
+	probeCmd.Dir = probeDir
+	probeCmd.Stdout = output
+	probeCmd.Stderr = output
+
+	err = probeCmd.Run()
+	if ctx.Err() != nil || err == nil {
+		return nil
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		// 126 and 127 are returned by shells when a command cannot be executed or found
+		if code := exitErr.ExitCode(); code != 126 && code != 127 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("could not be started: %v %s", err, strings.TrimSpace(output.String()))
+}
+
+// PrintCodeBaseChecks prints a pass/fail checklist and returns an error when any check failed
+func PrintCodeBaseChecks(checks []CodeBaseCheck) error {
+	failed := 0
+
+	for _, check := range checks {
+		mark := "PASS"
+		if !check.Passed {
+			mark = "FAIL"
+			failed++
+		}
+		verifyCodebaseLog.Infof("[%s] %s: %s", mark, check.Adapter, check.Message)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d code base check(s) failed", failed)
+	}
+
+	return nil
+}
+
+// VerifyCodeBase downloads the code base of the configured (or a random) experiment into a temporary
+// directory, prepares it as for a regular run and checks its adapters
+func (sim SimulationManager) VerifyCodeBase() error {
+	communicationTimeout := time.Duration(sim.Config.Timeout) * time.Second
+
+	is := NewInformationService(sim.Config, sim.HttpClient, communicationTimeout)
+
+	experimentManagers, err := is.GetExperimentManagers()
+	if err != nil {
+		return err
+	}
+
+	experimentID := sim.Config.ExperimentId
+	if experimentID == "" {
+		experimentID = sim.GetRandomExperimentID(experimentManagers, sim.HttpClient)
+	}
+	if experimentID == "" {
+		return errors.New("There is no experiment which code base could be verified")
+	}
+
+	codeBaseDir, err := ioutil.TempDir(sim.RootDirPath, "verify_code_base_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(codeBaseDir)
+
+	em := ExperimentManager{
+		HttpClient:           sim.HttpClient,
+		BaseUrls:             experimentManagers,
+		CommunicationTimeout: communicationTimeout,
+		Config:               sim.Config,
+		ExperimentId:         experimentID}
+
+	verifyCodebaseLog.Infof("Verifying code base of experiment %s", experimentID)
+	if err = sim.PrepareCodeBase(&em, codeBaseDir); err != nil {
+		return err
+	}
+
+	return PrintCodeBaseChecks(CheckCodeBase(codeBaseDir))
+}
@@ -0,0 +1,68 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func findCheck(checks []CodeBaseCheck, adapter string) CodeBaseCheck {
+	for _, check := range checks {
+		if check.Adapter == adapter {
+			return check
+		}
+	}
+	return CodeBaseCheck{}
+}
+
+func TestCheckCodeBaseShouldFailWithoutExecutor(t *testing.T) {
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+
+	checks := CheckCodeBase(codeBaseDir)
+
+	if findCheck(checks, "executor").Passed {
+		t.Errorf("Executor check should fail when there is no executor")
+	}
+
+	if !findCheck(checks, "input_writer").Passed {
+		t.Errorf("Input writer check should pass as it is optional")
+	}
+}
+
+func TestCheckCodeBaseShouldPassForWorkingAdapters(t *testing.T) {
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/bin/sh\nexit 1\n"), 0777)
+	ioutil.WriteFile(path.Join(codeBaseDir, "output_reader"), []byte("#!/usr/bin/env sh\necho ok\n"), 0777)
+
+	checks := CheckCodeBase(codeBaseDir)
+
+	for _, check := range checks {
+		if !check.Passed {
+			t.Errorf("Got: '%v' failed with '%v' - Expected to pass", check.Adapter, check.Message)
+		}
+	}
+}
+
+func TestCheckCodeBaseShouldDetectPackagingMistakes(t *testing.T) {
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(path.Join(codeBaseDir, "input_writer"), []byte("#!/bin/sh\r\necho ok\r\n"), 0777)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/usr/bin/env no_such_interpreter\n"), 0777)
+	ioutil.WriteFile(path.Join(codeBaseDir, "output_reader"), []byte("#!/bin/sh\necho ok\n"), 0666)
+	ioutil.WriteFile(path.Join(codeBaseDir, "progress_monitor"), []byte("#!/no/such/interpreter\n"), 0777)
+
+	checks := CheckCodeBase(codeBaseDir)
+
+	for _, check := range checks {
+		if check.Passed {
+			t.Errorf("Got: '%v' passed - Expected to fail", check.Adapter)
+		}
+	}
+
+	if PrintCodeBaseChecks(checks) == nil {
+		t.Errorf("Got: nil - Expected not nil")
+	}
+}
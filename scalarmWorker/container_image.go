@@ -0,0 +1,210 @@
+package scalarmWorker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// container runtimes able to pull images, in order of preference when none is configured
+var imagePullRuntimes = []string{"docker", "podman", "apptainer", "singularity"}
+
+// a pull taking longer is killed and the executor pulls the image itself
+var imagePullTimeout = 30 * time.Minute
+
+// container images of experiments with validators of the metadata they were read from, the metadata
+// is requested again only if it changed
+var containerImages struct {
+	images map[string]containerImage
+	mutex  sync.Mutex
+}
+
+type containerImage struct {
+	name         string
+	etag         string
+	lastModified string
+}
+
+var unsafeImageNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// GetContainerImage asks the Experiment Manager for the container image declared by the experiment,
+// an empty name is returned when it does not declare one or the Experiment Manager does not support it;
+// the metadata is requested conditionally and the image known before is returned when it did not change
+func (em *ExperimentManager) GetContainerImage() (string, error) {
+	emResponse := struct {
+		Status         string `json:"status"`
+		ContainerImage string `json:"container_image"`
+	}{}
+
+	containerImages.mutex.Lock()
+	cached, isCached := containerImages.images[em.ExperimentId]
+	containerImages.mutex.Unlock()
+
+	header := http.Header{}
+	if isCached {
+		if cached.etag != "" {
+			header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	conditional := *em
+	conditional.Context = WithRequestHeaders(em.context(), header)
+
+	path := "experiments/" + em.ExperimentId + "/metadata"
+	resp, err := conditional.execute(RequestInfo{"GET", nil, "", path})
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 304 && isCached {
+		return cached.name, nil
+	} else if resp.StatusCode == 404 {
+		return "", nil
+	} else if resp.StatusCode != 200 {
+		return "", errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if err := json.Unmarshal(body, &emResponse); err != nil {
+		return "", errors.New("Returned response body is not JSON.")
+	}
+
+	if etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"); etag != "" || lastModified != "" {
+		containerImages.mutex.Lock()
+		if containerImages.images == nil {
+			containerImages.images = map[string]containerImage{}
+		}
+		containerImages.images[em.ExperimentId] = containerImage{emResponse.ContainerImage, etag, lastModified}
+		containerImages.mutex.Unlock()
+	}
+
+	return emResponse.ContainerImage, nil
+}
+
+// DetectImagePullRuntime returns the first container runtime found in PATH
+func DetectImagePullRuntime() (string, error) {
+	for _, runtime := range imagePullRuntimes {
+		if _, err := exec.LookPath(runtime); err == nil {
+			return runtime, nil
+		}
+	}
+
+	return "", errors.New("None of " + strings.Join(imagePullRuntimes, ", ") + " found in PATH")
+}
+
+// PullContainerImage pulls image with runtime and returns the reference executors should use: the image name
+// for docker and podman, a SIF file in cacheDir for apptainer and singularity; SIF files are pulled once
+// for all workers sharing cacheDir; a pull is killed after imagePullTimeout
+func PullContainerImage(runtime string, image string, cacheDir string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), imagePullTimeout)
+	defer cancel()
+
+	switch runtime {
+	case "docker", "podman":
+		if output, err := exec.CommandContext(ctx, runtime, "pull", image).CombinedOutput(); err != nil {
+			return image, fmt.Errorf("%s pull %s failed: %v: %s", runtime, image, err, strings.TrimSpace(string(output)))
+		}
+		return image, nil
+
+	case "apptainer", "singularity":
+		source := image
+		if !strings.Contains(source, "://") {
+			source = "docker://" + image
+		}
+
+		if err := os.MkdirAll(cacheDir, 0777); err != nil {
+			return source, err
+		}
+		sifPath := filepath.Join(cacheDir, sifFileName(image))
+
+		unlock, err := lockFile(sifPath + ".lock")
+		if err != nil {
+			return source, err
+		}
+		defer unlock()
+
+		if _, err := os.Stat(sifPath); err == nil {
+			return sifPath, nil
+		}
+
+		// a partial image is never used, it is renamed once pulled
+		partialPath := sifPath + ".part"
+		os.Remove(partialPath)
+		if output, err := exec.CommandContext(ctx, runtime, "pull", partialPath, source).CombinedOutput(); err != nil {
+			os.Remove(partialPath)
+			return source, fmt.Errorf("%s pull %s failed: %v: %s", runtime, source, err, strings.TrimSpace(string(output)))
+		}
+
+		return sifPath, os.Rename(partialPath, sifPath)
+	}
+
+	return image, errors.New("Unsupported container runtime '" + runtime + "'")
+}
+
+// sifFileName derives a file name from an image reference, e.g. docker://python:3.11 -> python_3.11.sif
+func sifFileName(image string) string {
+	if i := strings.Index(image, "://"); i >= 0 {
+		image = image[i+3:]
+	}
+
+	return unsafeImageNameChars.ReplaceAllString(image, "_") + ".sif"
+}
+
+// prefetchContainerImage pulls the image declared by the experiment in the background and returns a function
+// waiting for the reference to the image, empty when the experiment does not declare one
+func (sim SimulationManager) prefetchContainerImage(em *ExperimentManager) func() string {
+	done := make(chan string, 1)
+
+	go func() {
+		image, err := em.GetContainerImage()
+		if err != nil {
+			simLog.Warnf("Could not get the container image of the experiment: %v", Redact(err.Error()))
+		}
+		if image == "" {
+			done <- ""
+			return
+		}
+
+		runtime := sim.Config.ContainerRuntime
+		if runtime == "" {
+			if runtime, err = DetectImagePullRuntime(); err != nil {
+				simLog.Warnf("Container image %s cannot be prefetched: %v", image, err)
+				done <- image
+				return
+			}
+		}
+
+		simLog.Infof("Pulling container image %s with %s ...", image, runtime)
+		reference, err := PullContainerImage(runtime, image, filepath.Join(sim.RootDirPath, "container_images"))
+		if err != nil {
+			simLog.Warnf("Prefetching the container image failed, it will be pulled by the executor: %v", err)
+		} else {
+			simLog.Infof("Container image ready: %s", reference)
+		}
+		done <- reference
+	}()
+
+	return func() string {
+		return <-done
+	}
+}
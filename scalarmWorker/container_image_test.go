@@ -0,0 +1,133 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetContainerImageShouldReturnImageDeclaredByExperiment(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/metadata" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"ok","container_image":"ghcr.io/scalarm/simulation:1.2"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	image, err := em.GetContainerImage()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if image != "ghcr.io/scalarm/simulation:1.2" {
+		t.Errorf("Got: '%v' - Expected '%v'", image, "ghcr.io/scalarm/simulation:1.2")
+	}
+}
+
+func TestGetContainerImageShouldRequestUnchangedMetadataConditionally(t *testing.T) {
+	// === GIVEN ===
+	containerImages.images = nil
+	defer func() { containerImages.images = nil }()
+
+	var conditionalRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditionalRequests++
+			w.WriteHeader(304)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintln(w, `{"status":"ok","container_image":"ghcr.io/scalarm/simulation:1.2"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	em.GetContainerImage()
+	image, err := em.GetContainerImage()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if image != "ghcr.io/scalarm/simulation:1.2" || conditionalRequests != 1 {
+		t.Errorf("Got: '%v' after %v conditional requests - Expected '%v' after 1", image, conditionalRequests,
+			"ghcr.io/scalarm/simulation:1.2")
+	}
+}
+
+func TestPullContainerImageShouldKillPullAfterTimeout(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "container_images")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\nexec sleep 30\n"), 0755)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	defer func(timeout time.Duration) { imagePullTimeout = timeout }(imagePullTimeout)
+	imagePullTimeout = 100 * time.Millisecond
+
+	// === WHEN ===
+	start := time.Now()
+	_, err := PullContainerImage("docker", "python:3.11", dir)
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Got: '%v' - Expected the pull to be killed after the timeout", elapsed)
+	}
+}
+
+func TestPullContainerImageShouldPullSIFFileOnce(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "container_images")
+	defer os.RemoveAll(dir)
+
+	// a fake apptainer counts pulls and creates the image file
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0755)
+	ioutil.WriteFile(filepath.Join(binDir, "apptainer"),
+		[]byte("#!/bin/sh\necho \"$3\" >> \""+filepath.Join(dir, "pulls")+"\"\ntouch \"$2\"\n"), 0755)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cacheDir := filepath.Join(dir, "cache")
+
+	// === WHEN ===
+	first, err := PullContainerImage("apptainer", "python:3.11", cacheDir)
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	second, _ := PullContainerImage("apptainer", "python:3.11", cacheDir)
+
+	// === THEN ===
+	expected := filepath.Join(cacheDir, "python_3.11.sif")
+	if first != expected || second != expected {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", first, second, expected)
+	}
+
+	pulls, _ := ioutil.ReadFile(filepath.Join(dir, "pulls"))
+	if string(pulls) != "docker://python:3.11\n" {
+		t.Errorf("Got: '%v' - Expected a single pull of docker://python:3.11", string(pulls))
+	}
+}
@@ -0,0 +1,396 @@
+package scalarmWorker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var coordinatorLog = NewLogger("coordinator")
+
+// environment variables with the socket of the node coordinator and the kind of slot of a worker,
+// set by the agent for its workers
+const (
+	coordinatorSocketEnv = "SCALARM_COORDINATOR_SOCKET"
+	workerSlotEnv        = "SCALARM_WORKER_SLOT"
+)
+
+// kinds of worker slots, a worker without a kind executes any simulation run
+const (
+	CPUSlot = "cpu"
+	GPUSlot = "gpu"
+)
+
+// headers with the kind of slot and the number of a worker asking the coordinator for a simulation run
+const (
+	slotHeader   = "X-Scalarm-Slot"
+	workerHeader = "X-Scalarm-Worker"
+)
+
+// Coordinator is the only client of Experiment Managers on a node: it fetches simulation runs in batches
+// and hands them out to local workers, which connect to it over a unix socket, then acknowledges
+// their results together
+type Coordinator struct {
+	SocketPath         string
+	Config             *SimulationManagerConfig
+	HttpClient         *http.Client
+	ExperimentManagers []string
+	BatchSize          int
+
+	experiments map[string]*coordinatedExperiment
+	mutex       sync.Mutex
+	server      *http.Server
+	// number of workers allowed to execute simulation runs, as hinted by Experiment Managers; 0 means all
+	concurrency int
+	// set by SetConcurrency, hints of Experiment Managers are then ignored
+	concurrencyPinned bool
+}
+
+// coordinatedExperiment holds simulation runs of an experiment fetched for workers with another kind of slot
+type coordinatedExperiment struct {
+	batch    *SimulationRunBatch
+	deferred map[string][]map[string]interface{}
+	mutex    sync.Mutex
+}
+
+// NewCoordinator creates a coordinator listening on socketPath, runs are fetched in batches of batchSize
+func NewCoordinator(socketPath string, config *SimulationManagerConfig, client *http.Client, batchSize int) *Coordinator {
+	return &Coordinator{
+		SocketPath:  socketPath,
+		Config:      config,
+		HttpClient:  client,
+		BatchSize:   batchSize,
+		experiments: map[string]*coordinatedExperiment{},
+	}
+}
+
+// Start looks up Experiment Managers, unless they are already known, and starts serving workers
+func (coordinator *Coordinator) Start() error {
+	if len(coordinator.ExperimentManagers) == 0 {
+		is := NewInformationService(coordinator.Config, coordinator.HttpClient, coordinator.timeout())
+
+		experimentManagers, err := is.GetExperimentManagers()
+		if err != nil {
+			return err
+		}
+		coordinator.ExperimentManagers = experimentManagers
+	}
+
+	os.Remove(coordinator.SocketPath)
+	listener, err := net.Listen("unix", coordinator.SocketPath)
+	if err != nil {
+		return err
+	}
+
+	// only the account running the agent and its workers can take simulation runs
+	if err = os.Chmod(coordinator.SocketPath, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	coordinator.server = &http.Server{Handler: coordinator}
+	go coordinator.server.Serve(listener)
+
+	coordinatorLog.Infof("Dispatching simulation runs to workers through %s", coordinator.SocketPath)
+	return nil
+}
+
+// Close stops serving workers, acknowledges completed simulation runs and rolls back runs
+// which were not handed out
+func (coordinator *Coordinator) Close() {
+	if coordinator.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), coordinator.timeout())
+		coordinator.server.Shutdown(ctx)
+		cancel()
+	}
+
+	// experiments are closed without holding the lock, closing them talks to Experiment Managers
+	coordinator.mutex.Lock()
+	experiments := coordinator.experiments
+	coordinator.experiments = map[string]*coordinatedExperiment{}
+	coordinator.mutex.Unlock()
+
+	for _, experiment := range experiments {
+		experiment.close()
+	}
+	os.Remove(coordinator.SocketPath)
+}
+
+func (coordinator *Coordinator) timeout() time.Duration {
+	if coordinator.Config.Timeout <= 0 {
+		return 60 * time.Second
+	}
+
+	return time.Duration(coordinator.Config.Timeout) * time.Second
+}
+
+// cooldown returns the number of seconds workers wait before asking for a simulation run again
+func (coordinator *Coordinator) cooldown() int {
+	if coordinator.Config.CooldownInterval <= 0 {
+		return 5
+	}
+
+	return coordinator.Config.CooldownInterval
+}
+
+// experiment returns the state of the experiment, creating it on first use
+func (coordinator *Coordinator) experiment(experimentID string) *coordinatedExperiment {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	experiment, ok := coordinator.experiments[experimentID]
+	if !ok {
+		em := &ExperimentManager{
+			HttpClient:           coordinator.HttpClient,
+			BaseUrls:             coordinator.ExperimentManagers,
+			CommunicationTimeout: coordinator.timeout(),
+			Config:               coordinator.Config,
+			ExperimentId:         experimentID}
+		experiment = &coordinatedExperiment{
+			batch:    NewSimulationRunBatch(em, coordinator.BatchSize),
+			deferred: map[string][]map[string]interface{}{},
+		}
+		coordinator.experiments[experimentID] = experiment
+	}
+
+	return experiment
+}
+
+// admits tells whether the worker may execute a simulation run under the current concurrency,
+// requests without a worker number are always admitted
+func (coordinator *Coordinator) admits(worker int) bool {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	return worker <= 0 || coordinator.concurrency <= 0 || worker <= coordinator.concurrency
+}
+
+// updateConcurrency applies a 'concurrency' hint from a response of an Experiment Manager, which throttles
+// or expands the number of workers executing simulation runs without restarting the agent
+func (coordinator *Coordinator) updateConcurrency(response map[string]interface{}) {
+	hint, ok := response["concurrency"].(float64)
+	if !ok {
+		return
+	}
+
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	if coordinator.concurrencyPinned {
+		return
+	}
+
+	if int(hint) != coordinator.concurrency {
+		coordinatorLog.Infof("Experiment manager set concurrency to %d", int(hint))
+		coordinator.concurrency = int(hint)
+	}
+}
+
+// SetConcurrency sets the number of workers executing simulation runs, overriding hints of Experiment
+// Managers; 0 lets all workers execute simulation runs and Experiment Managers set concurrency again
+func (coordinator *Coordinator) SetConcurrency(concurrency int) {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinatorLog.Infof("Concurrency set to %d", concurrency)
+	coordinator.concurrency = concurrency
+	coordinator.concurrencyPinned = concurrency > 0
+}
+
+// SlotOf returns the kind of slot required by a simulation run, based on 'gpus' in its execution constraints
+func SlotOf(simulationRun map[string]interface{}) string {
+	constraints, _ := simulationRun["execution_constraints"].(map[string]interface{})
+	if gpus, _ := constraints["gpus"].(float64); gpus > 0 {
+		return GPUSlot
+	}
+
+	return CPUSlot
+}
+
+// next returns a simulation run for a worker with the given kind of slot; runs for other slots fetched
+// meanwhile are kept for their workers, when none of up to a batch of runs matches the worker waits;
+// runs are fetched without holding the lock of deferred runs, so workers of other slots are not blocked
+func (experiment *coordinatedExperiment) next(slot string, cooldown int) (map[string]interface{}, error) {
+	if simulationRun, ok := experiment.takeDeferred(slot); ok {
+		return simulationRun, nil
+	}
+
+	if slot == "" {
+		return experiment.batch.Next(0)
+	}
+
+	for attempt := 0; attempt < experiment.batch.size || attempt == 0; attempt++ {
+		simulationRun, err := experiment.batch.Next(0)
+		if err != nil {
+			return nil, err
+		}
+
+		if status, _ := simulationRun["status"].(string); status != "ok" {
+			return simulationRun, nil
+		}
+
+		runSlot := SlotOf(simulationRun)
+		if runSlot == slot {
+			return simulationRun, nil
+		}
+		experiment.deferRun(runSlot, simulationRun)
+	}
+
+	return map[string]interface{}{"status": "wait", "duration_in_seconds": float64(cooldown)}, nil
+}
+
+// takeDeferred returns a simulation run kept for the given kind of slot, for any slot when it is empty
+func (experiment *coordinatedExperiment) takeDeferred(slot string) (map[string]interface{}, bool) {
+	experiment.mutex.Lock()
+	defer experiment.mutex.Unlock()
+
+	for kind, runs := range experiment.deferred {
+		if len(runs) > 0 && (slot == "" || kind == slot) {
+			experiment.deferred[kind] = runs[1:]
+			return runs[0], true
+		}
+	}
+
+	return nil, false
+}
+
+// deferRun keeps a simulation run for a worker with the given kind of slot
+func (experiment *coordinatedExperiment) deferRun(slot string, simulationRun map[string]interface{}) {
+	experiment.mutex.Lock()
+	defer experiment.mutex.Unlock()
+
+	experiment.deferred[slot] = append(experiment.deferred[slot], simulationRun)
+}
+
+// close acknowledges completed simulation runs and rolls back runs which were not handed out
+func (experiment *coordinatedExperiment) close() {
+	experiment.mutex.Lock()
+	deferred := experiment.deferred
+	experiment.deferred = map[string][]map[string]interface{}{}
+	experiment.mutex.Unlock()
+
+	experiment.batch.Close()
+
+	for _, runs := range deferred {
+		for _, simulationRun := range runs {
+			id, ok := simulationRun["simulation_id"].(float64)
+			if !ok {
+				coordinatorLog.Warnf("Could not roll back a simulation run without simulation_id: %v", simulationRun)
+				continue
+			}
+			simulationIndex := int(id)
+			coordinatorLog.Infof("Rolling back simulation run %v without a matching slot ...", simulationIndex)
+
+			if err := experiment.batch.em.RollbackSimulationRun(simulationIndex); err != nil {
+				coordinatorLog.Warnf("Could not roll back simulation run %v: %v", simulationIndex, err)
+			}
+		}
+	}
+}
+
+// ServeHTTP handles the subset of the Experiment Manager API used by workers to get and complete simulation runs
+func (coordinator *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// experiments/<id>/next_simulation, experiments/<id>/simulations/<index>/(mark_as_complete|rollback)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "experiments" {
+		http.NotFound(w, r)
+		return
+	}
+	experiment := coordinator.experiment(parts[1])
+
+	var response interface{}
+	var err error
+
+	switch {
+	case len(parts) == 3 && parts[2] == "next_simulation" && r.Method == "GET":
+		worker, _ := strconv.Atoi(r.Header.Get(workerHeader))
+		if !coordinator.admits(worker) {
+			response = map[string]interface{}{"status": "wait", "duration_in_seconds": float64(coordinator.cooldown())}
+			break
+		}
+
+		var simulationRun map[string]interface{}
+		if simulationRun, err = experiment.next(r.Header.Get(slotHeader), coordinator.cooldown()); err == nil {
+			coordinator.updateConcurrency(simulationRun)
+		}
+		response = simulationRun
+
+	case len(parts) == 5 && parts[2] == "simulations" && r.Method == "POST":
+		simulationIndex, convErr := strconv.Atoi(parts[3])
+		if convErr != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if parts[4] == "mark_as_complete" {
+			if err = r.ParseForm(); err == nil {
+				err = experiment.batch.Complete(simulationIndex, r.PostForm)
+			}
+		} else if parts[4] == "rollback" {
+			err = experiment.batch.em.RollbackSimulationRun(simulationIndex)
+		} else {
+			http.NotFound(w, r)
+			return
+		}
+		response = map[string]interface{}{"status": "ok"}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		coordinatorLog.Warnf("%s %s failed: %v", r.Method, r.URL.Path, Redact(err.Error()))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// workerTransport tells the coordinator which kind of slot and number the worker has
+type workerTransport struct {
+	transport http.RoundTripper
+	slot      string
+	worker    string
+}
+
+func (transport workerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	if transport.slot != "" {
+		request.Header.Set(slotHeader, transport.slot)
+	}
+	if transport.worker != "" {
+		request.Header.Set(workerHeader, transport.worker)
+	}
+
+	return transport.transport.RoundTrip(request)
+}
+
+// CoordinatedExperimentManager returns a copy of em which gets and completes simulation runs
+// through the coordinator listening on socketPath, only runs matching slot are handed out
+// when it is not empty; worker is the number of the worker in the agent
+func CoordinatedExperimentManager(em ExperimentManager, socketPath string, slot string, worker string) ExperimentManager {
+	transport := workerTransport{
+		transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+		slot:   slot,
+		worker: worker,
+	}
+
+	// the coordinator is reached with plain HTTP over the socket, whatever the host in the URL
+	em.HttpClient = &http.Client{Transport: transport}
+	em.BaseUrls = []string{"http://localhost"}
+
+	return em
+}
@@ -0,0 +1,223 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCoordinatorShouldDispatchRunsOfASingleBatchToWorkers(t *testing.T) {
+	// === GIVEN ===
+	fetches := 0
+	acknowledged := [][]SimulationRunCompletion{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulations":
+			fetches++
+			fmt.Fprintln(w, `{"status":"ok","simulations":[{"simulation_id":1,"input_parameters":{"x":1}},`+
+				`{"simulation_id":2,"input_parameters":{"x":2}}]}`)
+		case "/experiments/568e5bece138232e76000002/simulations/mark_as_complete":
+			completions := []SimulationRunCompletion{}
+			json.Unmarshal([]byte(r.FormValue("results")), &completions)
+			acknowledged = append(acknowledged, completions)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "coordinator")
+	defer os.RemoveAll(dir)
+
+	config := getSimConfig()
+	coordinator := NewCoordinator(filepath.Join(dir, "coordinator.sock"), config, getHttpClientMock(server.URL), 2)
+	coordinator.ExperimentManagers = []string{"system.scalarm.com"}
+
+	if err := coordinator.Start(); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	em := setupExperimentManager(config, nil)
+	workers := []ExperimentManager{
+		CoordinatedExperimentManager(em, coordinator.SocketPath, "", ""),
+		CoordinatedExperimentManager(em, coordinator.SocketPath, "", ""),
+	}
+
+	// === WHEN ===
+	for i, worker := range workers {
+		simulationRun, err := worker.GetNextSimulationRunConfig()
+		if err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+			return
+		}
+
+		// === THEN ===
+		if simulationRun["status"] != "ok" || simulationRun["simulation_id"] != float64(i+1) {
+			t.Errorf("Got: '%v' - Expected simulation run %v", simulationRun, i+1)
+		}
+	}
+
+	for i, worker := range workers {
+		data := url.Values{}
+		data.Set("status", "ok")
+		data.Add("reason", "")
+		data.Add("result", fmt.Sprintf(`{"y":%d}`, i+1))
+		if _, err := worker.MarkSimulationRunAsComplete(i+1, data); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+	}
+	coordinator.Close()
+
+	if fetches != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", fetches, 1)
+	}
+
+	completed := 0
+	for _, completions := range acknowledged {
+		completed += len(completions)
+	}
+	if completed != 2 {
+		t.Errorf("Got: '%v' - Expected 2 acknowledged results", acknowledged)
+	}
+
+	if _, err := os.Stat(coordinator.SocketPath); !os.IsNotExist(err) {
+		t.Errorf("Socket of the coordinator should be removed on close")
+	}
+}
+
+func TestCoordinatorShouldRollBackRunsReturnedByWorkers(t *testing.T) {
+	// === GIVEN ===
+	rolledBack := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rolledBack = append(rolledBack, r.URL.Path)
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "coordinator")
+	defer os.RemoveAll(dir)
+
+	config := getSimConfig()
+	coordinator := NewCoordinator(filepath.Join(dir, "coordinator.sock"), config, getHttpClientMock(server.URL), 2)
+	coordinator.ExperimentManagers = []string{"system.scalarm.com"}
+
+	if err := coordinator.Start(); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	defer coordinator.Close()
+
+	worker := CoordinatedExperimentManager(setupExperimentManager(config, nil), coordinator.SocketPath, "", "")
+
+	// === WHEN ===
+	err := worker.RollbackSimulationRun(7)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	expected := "/experiments/568e5bece138232e76000002/simulations/7/rollback"
+	if len(rolledBack) != 1 || rolledBack[0] != expected {
+		t.Errorf("Got: '%v' - Expected '%v'", rolledBack, expected)
+	}
+}
+
+func TestCoordinatorShouldMatchRunsToKindsOfSlots(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"ok","simulations":[{"simulation_id":1,"execution_constraints":{"gpus":1}},`+
+			`{"simulation_id":2,"execution_constraints":{}}]}`)
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "coordinator")
+	defer os.RemoveAll(dir)
+
+	config := getSimConfig()
+	coordinator := NewCoordinator(filepath.Join(dir, "coordinator.sock"), config, getHttpClientMock(server.URL), 2)
+	coordinator.ExperimentManagers = []string{"system.scalarm.com"}
+
+	if err := coordinator.Start(); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	defer coordinator.Close()
+
+	em := setupExperimentManager(config, nil)
+	cpuWorker := CoordinatedExperimentManager(em, coordinator.SocketPath, CPUSlot, "")
+	gpuWorker := CoordinatedExperimentManager(em, coordinator.SocketPath, GPUSlot, "")
+
+	// === WHEN ===
+	cpuRun, err := cpuWorker.GetNextSimulationRunConfig()
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	gpuRun, err := gpuWorker.GetNextSimulationRunConfig()
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	// === THEN ===
+	if cpuRun["simulation_id"] != float64(2) {
+		t.Errorf("Got: '%v' - Expected simulation run 2", cpuRun)
+	}
+
+	if gpuRun["simulation_id"] != float64(1) {
+		t.Errorf("Got: '%v' - Expected simulation run 1", gpuRun)
+	}
+}
+
+func TestCoordinatorShouldThrottleWorkersToConcurrencyHint(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"ok","simulation_id":1,"concurrency":1}`)
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "coordinator")
+	defer os.RemoveAll(dir)
+
+	config := getSimConfig()
+	config.CooldownInterval = 5
+	coordinator := NewCoordinator(filepath.Join(dir, "coordinator.sock"), config, getHttpClientMock(server.URL), 1)
+	coordinator.ExperimentManagers = []string{"system.scalarm.com"}
+
+	if err := coordinator.Start(); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	defer coordinator.Close()
+
+	em := setupExperimentManager(config, nil)
+	firstWorker := CoordinatedExperimentManager(em, coordinator.SocketPath, "", "1")
+	secondWorker := CoordinatedExperimentManager(em, coordinator.SocketPath, "", "2")
+
+	// === WHEN ===
+	if _, err := firstWorker.GetNextSimulationRunConfig(); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	simulationRun, err := secondWorker.GetNextSimulationRunConfig()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if simulationRun["status"] != "wait" || simulationRun["duration_in_seconds"] != float64(5) {
+		t.Errorf("Got: '%v' - Expected the second worker to wait", simulationRun)
+	}
+}
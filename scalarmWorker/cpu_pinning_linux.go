@@ -0,0 +1,26 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// pinCommand prefixes the command with taskset, so it and all its children run only on CPUs of the slot;
+// when the slot has a NUMA node, numactl binds their memory to the node as well
+func pinCommand(command []string, slot FleetSlot) ([]string, error) {
+	if slot.Node != nil {
+		numactl, err := exec.LookPath("numactl")
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]string{numactl, "--physcpubind=" + slot.CPUs, "--membind=" + strconv.Itoa(*slot.Node)}, command...), nil
+	}
+
+	taskset, err := exec.LookPath("taskset")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{taskset, "-c", slot.CPUs}, command...), nil
+}
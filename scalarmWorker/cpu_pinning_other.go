@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package scalarmWorker
+
+import (
+	"errors"
+)
+
+func pinCommand(command []string, slot FleetSlot) ([]string, error) {
+	return nil, errors.New("CPU pinning of workers is supported only on Linux")
+}
@@ -0,0 +1,87 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// only one helper invocation at a time, many requests can be rejected when credentials change
+var credentialHelperMutex sync.Mutex
+
+// helperCredentials is the output of 'get' in the format of docker credential helpers
+type helperCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// LoadCredentialsFromHelper runs '<credential_helper> get' with the Information Service address on stdin
+// and uses the returned user and secret for all following requests
+func (config *SimulationManagerConfig) LoadCredentialsFromHelper() error {
+	credentialHelperMutex.Lock()
+	defer credentialHelperMutex.Unlock()
+
+	stderr := &bytes.Buffer{}
+	helperCmd := exec.Command(config.CredentialHelper, "get")
+	helperCmd.Stdin = strings.NewReader(config.informationServiceName())
+	helperCmd.Stderr = stderr
+
+	output, err := helperCmd.Output()
+	if err != nil {
+		return fmt.Errorf("Credential helper %s failed: %v %s", config.CredentialHelper, err, strings.TrimSpace(stderr.String()))
+	}
+
+	credentials := helperCredentials{}
+	if err = json.Unmarshal(output, &credentials); err != nil {
+		return errors.New("Credential helper returned invalid JSON.")
+	}
+
+	if credentials.Username == "" || credentials.Secret == "" {
+		return errors.New("Credential helper did not return Username and Secret.")
+	}
+
+	config.SetCredentials(credentials.Username, credentials.Secret)
+	return nil
+}
+
+// retryWithHelperCredentials repeats a request rejected with 401 once with credentials obtained again
+// from the credential helper, the rejected response is returned when that is not possible
+func retryWithHelperCredentials(client *http.Client, request *http.Request, response *http.Response,
+	config *SimulationManagerConfig, timeout time.Duration) *http.Response {
+
+	if config.CredentialHelper == "" || response.StatusCode != http.StatusUnauthorized ||
+		(request.Body != nil && request.GetBody == nil) {
+		return response
+	}
+
+	simLog.Infof("Request was not authorized, getting credentials from the credential helper ...")
+	if err := config.LoadCredentialsFromHelper(); err != nil {
+		simLog.Warnf("%v", Redact(err.Error()))
+		return response
+	}
+
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return response
+		}
+		retry.Body = body
+	}
+	retry.SetBasicAuth(config.Credentials())
+
+	retryResponse, err := GetWithBudget(request.Context(), client, retry, config.retryBudget(timeout))
+	if err != nil {
+		return response
+	}
+
+	response.Body.Close()
+	return retryResponse
+}
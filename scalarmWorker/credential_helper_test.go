@@ -0,0 +1,85 @@
+package scalarmWorker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupCredentialHelper(t *testing.T, secret string) string {
+	dir, err := ioutil.TempDir("", "credential_helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helperPath := filepath.Join(dir, "credential_helper.sh")
+	script := fmt.Sprintf("#!/bin/sh\nread server\necho '{\"ServerURL\":\"'$server'\",\"Username\":\"helper_user\",\"Secret\":\"%s\"}'\n", secret)
+	if err := ioutil.WriteFile(helperPath, []byte(script), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	return helperPath
+}
+
+func TestLoadCredentialsFromHelperShouldSetCredentials(t *testing.T) {
+	// === GIVEN ===
+	helperPath := setupCredentialHelper(t, "helper_secret")
+	defer os.RemoveAll(filepath.Dir(helperPath))
+
+	config := getSimConfig()
+	config.CredentialHelper = helperPath
+
+	// === WHEN ===
+	err := config.LoadCredentialsFromHelper()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if user, password := config.Credentials(); user != "helper_user" || password != "helper_secret" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", user, password, "helper_user:helper_secret")
+	}
+}
+
+func TestExecuteScalarmRequestShouldRetryWithHelperCredentialsOn401(t *testing.T) {
+	// === GIVEN ===
+	helperPath := setupCredentialHelper(t, "rotated_secret")
+	defer os.RemoveAll(filepath.Dir(helperPath))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if _, password, _ := r.BasicAuth(); password != "rotated_secret" {
+			w.WriteHeader(401)
+			return
+		}
+		fmt.Fprintf(w, "received %s", body)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.CredentialHelper = helperPath
+	reqInfo := RequestInfo{"POST", strings.NewReader("status=ok"), "application/x-www-form-urlencoded", "progress_info"}
+
+	// === WHEN ===
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{"system.scalarm.com"}, config, getHttpClientMock(server.URL), 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "received status=ok" {
+		t.Errorf("Got: '%v' '%s' - Expected '%v' '%v'", resp.StatusCode, body, 200, "received status=ok")
+	}
+}
@@ -0,0 +1,158 @@
+package scalarmWorker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var credentialsLog = NewLogger("credentials")
+
+// credentials can be swapped by the refresher while requests are being prepared
+var credentialsMutex sync.RWMutex
+
+// Credentials returns the current user and password used to authenticate in Scalarm
+func (config *SimulationManagerConfig) Credentials() (string, string) {
+	credentialsMutex.RLock()
+	defer credentialsMutex.RUnlock()
+
+	return config.ExperimentManagerUser, config.ExperimentManagerPass
+}
+
+// SetCredentials replaces credentials used by all following requests
+func (config *SimulationManagerConfig) SetCredentials(user string, password string) {
+	RegisterCredentials(user, password)
+
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+
+	config.ExperimentManagerUser = user
+	config.ExperimentManagerPass = password
+}
+
+// refreshedCredentials is the JSON printed by the refresh command or returned by the refresh endpoint,
+// missing user or password means the current one is still valid
+type refreshedCredentials struct {
+	User      string `json:"experiment_manager_user"`
+	Password  string `json:"experiment_manager_pass"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// CredentialsRefresher renews short-lived credentials before they expire
+type CredentialsRefresher struct {
+	Config     *SimulationManagerConfig
+	HttpClient *http.Client
+	ExpiresAt  time.Time
+}
+
+// NewCredentialsRefresher creates a refresher of credentials expiring at credentials_expire_at
+func NewCredentialsRefresher(config *SimulationManagerConfig, client *http.Client) *CredentialsRefresher {
+	refresher := &CredentialsRefresher{Config: config, HttpClient: client}
+
+	if config.CredentialsExpireAt != "" {
+		if expiresAt, err := time.Parse(time.RFC3339, config.CredentialsExpireAt); err == nil {
+			refresher.ExpiresAt = expiresAt
+		}
+	}
+
+	return refresher
+}
+
+// Refresh obtains new credentials from the refresh command or endpoint and swaps them in the config
+func (refresher *CredentialsRefresher) Refresh() error {
+	var output []byte
+	var err error
+
+	if refresher.Config.CredentialsRefreshCommand != "" {
+		output, err = shellCommand(context.Background(), refresher.Config.CredentialsRefreshCommand).Output()
+	} else if refresher.Config.CredentialsRefreshUrl != "" {
+		output, err = refresher.fetchFromEndpoint()
+	} else {
+		return errors.New("Neither credentials_refresh_command nor credentials_refresh_url is configured")
+	}
+	if err != nil {
+		return err
+	}
+
+	credentials := refreshedCredentials{}
+	if err = json.Unmarshal(output, &credentials); err != nil {
+		return errors.New("Refreshed credentials are not valid JSON.")
+	}
+
+	user, password := refresher.Config.Credentials()
+	if credentials.User != "" {
+		user = credentials.User
+	}
+	if credentials.Password != "" {
+		password = credentials.Password
+	}
+	refresher.Config.SetCredentials(user, password)
+
+	refresher.ExpiresAt = time.Time{}
+	if credentials.ExpiresAt != "" {
+		if refresher.ExpiresAt, err = time.Parse(time.RFC3339, credentials.ExpiresAt); err != nil {
+			return fmt.Errorf("expires_at of refreshed credentials is not a valid RFC3339 time: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (refresher *CredentialsRefresher) fetchFromEndpoint() ([]byte, error) {
+	req, err := http.NewRequest("GET", refresher.Config.CredentialsRefreshUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(refresher.Config.Credentials())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := GetWithBudget(WorkerContext(), refresher.HttpClient, req,
+		refresher.Config.retryBudget(time.Duration(refresher.Config.Timeout)*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Credentials refresh endpoint response code: %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// nextRefresh returns how long to wait before credentials have to be refreshed
+func (refresher *CredentialsRefresher) nextRefresh() time.Duration {
+	margin := time.Duration(refresher.Config.CredentialsRefreshBefore) * time.Second
+	return time.Until(refresher.ExpiresAt.Add(-margin))
+}
+
+// Run refreshes credentials whenever they are about to expire, failed refreshes are retried every
+// minute until the credentials expire; it returns when the expiry of credentials is unknown
+func (refresher *CredentialsRefresher) Run() {
+	for !refresher.ExpiresAt.IsZero() {
+		if wait := refresher.nextRefresh(); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		credentialsLog.Infof("Refreshing credentials ...")
+		if err := refresher.Refresh(); err != nil {
+			credentialsLog.Warnf("Refresh failed: %v", Redact(err.Error()))
+			if time.Now().After(refresher.ExpiresAt) {
+				credentialsLog.Warnf("Credentials expired")
+			}
+			time.Sleep(1 * time.Minute)
+			continue
+		}
+
+		if refresher.ExpiresAt.IsZero() {
+			credentialsLog.Infof("Credentials refreshed, they do not expire")
+		} else {
+			credentialsLog.Infof("Credentials refreshed, valid until %v", refresher.ExpiresAt)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCredentialsRefresherShouldSwapCredentialsFromCommand(t *testing.T) {
+	// === GIVEN ===
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	config := getSimConfig()
+	config.CredentialsRefreshCommand = fmt.Sprintf(`echo '{"experiment_manager_pass":"new_pass","expires_at":"%s"}'`, expiresAt)
+	refresher := NewCredentialsRefresher(config, nil)
+
+	// === WHEN ===
+	err := refresher.Refresh()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if user, password := config.Credentials(); user != "user" || password != "new_pass" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", user, password, "user:new_pass")
+	}
+
+	if refresher.ExpiresAt.UTC().Format(time.RFC3339) != expiresAt {
+		t.Errorf("Got: '%v' - Expected '%v'", refresher.ExpiresAt, expiresAt)
+	}
+}
+
+func TestCredentialsRefresherShouldSwapCredentialsFromEndpoint(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "pass" {
+			w.WriteHeader(401)
+			return
+		}
+		fmt.Fprintln(w, `{"experiment_manager_user":"token_user","experiment_manager_pass":"refreshed_pass"}`)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.Timeout = 5
+	config.CredentialsRefreshUrl = server.URL + "/token"
+	refresher := NewCredentialsRefresher(config, http.DefaultClient)
+
+	// === WHEN ===
+	err := refresher.Refresh()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if user, password := config.Credentials(); user != "token_user" || password != "refreshed_pass" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", user, password, "token_user:token")
+	}
+
+	if !refresher.ExpiresAt.IsZero() {
+		t.Errorf("Got: '%v' - Expected credentials without expiry", refresher.ExpiresAt)
+	}
+}
+
+func TestCredentialsRefresherShouldRefreshBeforeExpiry(t *testing.T) {
+	config := getSimConfig()
+	config.CredentialsRefreshBefore = 600
+	refresher := NewCredentialsRefresher(config, nil)
+	refresher.ExpiresAt = time.Now().Add(15 * time.Minute)
+
+	if wait := refresher.nextRefresh(); wait > 5*time.Minute || wait < 4*time.Minute {
+		t.Errorf("Got: '%v' - Expected about 5 minutes", wait)
+	}
+}
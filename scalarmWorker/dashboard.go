@@ -0,0 +1,265 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// number of recent lines of SiM output and of the run log shown by the dashboard
+const (
+	dashboardOutputLines = 10
+	dashboardRunLogLines = 6
+)
+
+// how often the dashboard is redrawn
+var dashboardRefreshInterval = time.Second
+
+// state of the terminal dashboard, besides current runs and their phases kept for the admin API
+var dashboard struct {
+	mutex sync.Mutex
+	// progress, run logs and uploads of simulation runs being executed by their indexes
+	runs   map[int]*dashboardRunState
+	output []string
+}
+
+// dashboardRunState is what the dashboard shows about a simulation run besides its phase
+type dashboardRunState struct {
+	progress float64
+	runLog   string
+	uploads  []string
+}
+
+// StartDashboard shows a terminal UI with current simulation runs, their phases, progress, recent log lines and
+// upload status instead of the output of SiM, which is captured; the output is printed again when SiM exits
+func StartDashboard() error {
+	terminal := os.Stdout
+	if info, err := terminal.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("The dashboard requires a terminal")
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	SetLogOutput(writer)
+
+	captured := make(chan struct{})
+	go func() {
+		defer close(captured)
+		captureDashboardOutput(reader)
+	}()
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(dashboardRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				io.WriteString(terminal, "\033[H\033[2J"+renderDashboard(time.Now(), dashboardWidth()))
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	// the last output of SiM stays in the terminal after it exits
+	OnExit(func() {
+		close(stop)
+		SetLogOutput(terminal)
+		writer.Close()
+		// processes started by SiM may still hold the pipe
+		select {
+		case <-captured:
+		case <-time.After(time.Second):
+		}
+
+		dashboard.mutex.Lock()
+		defer dashboard.mutex.Unlock()
+		io.WriteString(terminal, "\033[H\033[2J"+strings.Join(dashboard.output, "\n")+"\n")
+	})
+
+	return nil
+}
+
+// captureDashboardOutput keeps recent lines of the output of SiM; lines have no length limit and the pipe
+// is drained until it is closed, also after a read error, so writes to the output never block
+func captureDashboardOutput(reader io.Reader) {
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		line, err := bufferedReader.ReadString('\n')
+		if line != "" {
+			dashboard.mutex.Lock()
+			dashboard.output = appendRecent(dashboard.output, strings.TrimRight(line, "\r\n"), dashboardOutputLines)
+			dashboard.mutex.Unlock()
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			io.Copy(ioutil.Discard, reader)
+			return
+		}
+	}
+}
+
+func appendRecent(lines []string, line string, limit int) []string {
+	lines = append(lines, line)
+	if len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+
+	return lines
+}
+
+// dashboardRun returns state of the simulation run shown by the dashboard, a run without state gets a new one
+func dashboardRun(simulationIndex int) *dashboardRunState {
+	if dashboard.runs == nil {
+		dashboard.runs = map[int]*dashboardRunState{}
+	}
+
+	run, ok := dashboard.runs[simulationIndex]
+	if !ok {
+		run = &dashboardRunState{progress: -1}
+		dashboard.runs[simulationIndex] = run
+	}
+
+	return run
+}
+
+// recordRunLog makes the dashboard show recent lines of the log of the simulation run
+func recordRunLog(simulationIndex int, runLog string) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	dashboardRun(simulationIndex).runLog = runLog
+}
+
+// recordProgress takes the progress of the simulation run in percent from the 'progress' field
+// of intermediate results, if there is one
+func recordProgress(simulationIndex int, results interface{}) {
+	fields, ok := results.(map[string]interface{})
+	if !ok {
+		return
+	}
+	progress, ok := numericValue(fields["progress"])
+	if !ok {
+		return
+	}
+
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	dashboardRun(simulationIndex).progress = progress
+}
+
+// recordUpload shows the outcome of uploading an artifact of the simulation run
+func recordUpload(simulationIndex int, artifact string, err error) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	status := "uploaded"
+	if err != nil {
+		status = "failed: " + Redact(err.Error())
+	}
+	run := dashboardRun(simulationIndex)
+	run.uploads = append(run.uploads, artifact+" "+status)
+}
+
+// renderDashboard returns the content of the dashboard with lines cut to the width of the terminal
+func renderDashboard(now time.Time, width int) string {
+	currentRuns.mutex.Lock()
+	phase := currentRuns.phase
+	currentRuns.mutex.Unlock()
+	runs := listCurrentRuns()
+
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	// state of finished runs is forgotten
+	executed := map[int]bool{}
+	for _, run := range runs {
+		executed[run.simulationIndex] = true
+	}
+	for simulationIndex := range dashboard.runs {
+		if !executed[simulationIndex] {
+			delete(dashboard.runs, simulationIndex)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("Scalarm Simulation Manager %s", WorkerVersion), ""}
+	if len(runs) == 0 {
+		lines = append(lines, "Simulation run:  -", "Phase:           "+phase)
+	}
+	for i, run := range runs {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+
+		state := dashboardRun(run.simulationIndex)
+		lines = append(lines,
+			fmt.Sprintf("Simulation run:  %d of experiment %s", run.simulationIndex, run.experimentID),
+			fmt.Sprintf("Phase:           %s", run.phase),
+			fmt.Sprintf("Running for:     %v", now.Sub(run.startedAt).Truncate(time.Second)))
+
+		if state.progress >= 0 {
+			lines = append(lines, fmt.Sprintf("Progress:        %s %.0f%%", progressBar(state.progress, 30), state.progress))
+		}
+		for i, upload := range state.uploads {
+			label := "                 "
+			if i == 0 {
+				label = "Uploads:         "
+			}
+			lines = append(lines, label+upload)
+		}
+
+		if state.runLog != "" {
+			lines = append(lines, "", "Run log ("+state.runLog+"):")
+			runLogLines, _ := LastLines(state.runLog, dashboardRunLogLines)
+			for _, line := range runLogLines {
+				lines = append(lines, "  "+Redact(line))
+			}
+		}
+	}
+
+	lines = append(lines, "", "Output:")
+	for _, line := range dashboard.output {
+		lines = append(lines, "  "+line)
+	}
+
+	// lines are cut between characters, not inside multi-byte ones
+	for i, line := range lines {
+		if runes := []rune(line); width > 0 && len(runes) > width {
+			lines[i] = string(runes[:width])
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	if filled < 0 {
+		filled = 0
+	} else if filled > width {
+		filled = width
+	}
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
+
+// dashboardWidth returns the width of the terminal from COLUMNS, 120 columns by default
+func dashboardWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	return 120
+}
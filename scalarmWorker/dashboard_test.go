@@ -0,0 +1,137 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRenderDashboardShouldShowCurrentRun(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "dashboard")
+	defer os.RemoveAll(dir)
+
+	runLog := filepath.Join(dir, RunLogName(7))
+	ioutil.WriteFile(runLog, []byte("step 1\nstep 2\n"), 0666)
+
+	addCurrentRun("e1", 7)
+	setRunPhase("e1", 7, "executor")
+
+	recordRunLog(7, runLog)
+	recordProgress(7, map[string]interface{}{"progress": 50.0})
+	recordUpload(7, "output.tar.gz", nil)
+	recordUpload(7, RunLogName(7), errors.New("Storage manager response code: 503"))
+
+	// === WHEN ===
+	content := renderDashboard(time.Now(), 0)
+
+	// === THEN ===
+	for _, expected := range []string{
+		"Simulation run:  7 of experiment e1",
+		"Phase:           executor",
+		"Progress:        [###############...............] 50%",
+		"Uploads:         output.tar.gz uploaded",
+		"simulation_7.log failed: Storage manager response code: 503",
+		"  step 2\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Got: '%v' - Expected '%v'", content, expected)
+		}
+	}
+
+	// === WHEN ===
+	removeCurrentRun("e1", 7)
+	addCurrentRun("e1", 8)
+	defer removeCurrentRun("e1", 8)
+	content = renderDashboard(time.Now(), 20)
+
+	// === THEN ===
+	if strings.Contains(content, "Progress") || strings.Contains(content, "Uploads") {
+		t.Errorf("Got: '%v' - Expected state of run 7 to be reset", content)
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if utf8.RuneCountInString(line) > 20 {
+			t.Errorf("Got: '%v' - Expected lines of at most %v characters", line, 20)
+		}
+	}
+}
+
+func TestRenderDashboardShouldShowAllCurrentRuns(t *testing.T) {
+	// === GIVEN ===
+	addCurrentRun("e1", 1)
+	addCurrentRun("e1", 2)
+	defer removeCurrentRun("e1", 1)
+	defer removeCurrentRun("e1", 2)
+	setRunPhase("e1", 1, "upload")
+	setRunPhase("e1", 2, "executor")
+
+	recordProgress(1, map[string]interface{}{"progress": 100.0})
+	recordProgress(2, map[string]interface{}{"progress": 10.0})
+
+	// === WHEN ===
+	content := renderDashboard(time.Now(), 0)
+
+	// === THEN ===
+	for _, expected := range []string{
+		"Simulation run:  1 of experiment e1\nPhase:           upload",
+		"Progress:        [##############################] 100%",
+		"Simulation run:  2 of experiment e1\nPhase:           executor",
+		"Progress:        [###...........................] 10%",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Got: '%v' - Expected '%v'", content, expected)
+		}
+	}
+}
+
+func TestRenderDashboardShouldCutLinesBetweenCharacters(t *testing.T) {
+	// === GIVEN ===
+	dashboard.mutex.Lock()
+	previousOutput := dashboard.output
+	dashboard.output = []string{"zażółć gęślą jaźń"}
+	dashboard.mutex.Unlock()
+	defer func() {
+		dashboard.mutex.Lock()
+		dashboard.output = previousOutput
+		dashboard.mutex.Unlock()
+	}()
+
+	// === WHEN ===
+	content := renderDashboard(time.Now(), 8)
+
+	// === THEN ===
+	if !utf8.ValidString(content) || !strings.Contains(content, "\n  zażółć\n") {
+		t.Errorf("Got: '%v' - Expected '%v'", content, "  zażółć")
+	}
+}
+
+func TestCaptureDashboardOutputShouldKeepLongLines(t *testing.T) {
+	// === GIVEN ===
+	dashboard.mutex.Lock()
+	previousOutput := dashboard.output
+	dashboard.output = nil
+	dashboard.mutex.Unlock()
+	defer func() {
+		dashboard.mutex.Lock()
+		dashboard.output = previousOutput
+		dashboard.mutex.Unlock()
+	}()
+
+	longLine := strings.Repeat("x", 128*1024)
+
+	// === WHEN ===
+	captureDashboardOutput(strings.NewReader(longLine + "\nlast line"))
+
+	// === THEN ===
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+	if len(dashboard.output) != 2 || dashboard.output[0] != longLine || dashboard.output[1] != "last line" {
+		t.Errorf("Got: '%v' lines - Expected '%v'", len(dashboard.output), 2)
+	}
+}
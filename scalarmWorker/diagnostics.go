@@ -0,0 +1,87 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+var diagnosticsLog = NewLogger("diagnostics")
+
+var runtimeLog = NewLogger("runtime")
+
+// checkLoopbackAddress accepts only 'host:port' addresses which are not reachable from other machines
+func checkLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return errors.New("diagnostics can be served only on a loopback address, got '" + host + "'")
+}
+
+// StartDiagnostics serves net/http/pprof handlers under /debug/pprof/ and POST /drain on a loopback address
+// and returns the address the server listens on
+func StartDiagnostics(address string) (net.Addr, error) {
+	if err := checkLoopbackAddress(address); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/drain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		RequestDrain("the diagnostics endpoint")
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	})
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			diagnosticsLog.Warnf("Server stopped: %v", err)
+		}
+	}()
+
+	diagnosticsLog.Infof("Serving pprof on http://%v/debug/pprof/", listener.Addr())
+	return listener.Addr(), nil
+}
+
+// RuntimeStats returns a one line summary of goroutines, heap and garbage collections of the worker
+func RuntimeStats() string {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	return fmt.Sprintf("goroutines: %d, heap alloc: %d KB, heap in use: %d KB, sys: %d KB, GC cycles: %d",
+		runtime.NumGoroutine(), memStats.HeapAlloc/1024, memStats.HeapInuse/1024, memStats.Sys/1024, memStats.NumGC)
+}
+
+// LogRuntimeStats prints RuntimeStats every interval for the lifetime of the worker
+func LogRuntimeStats(interval time.Duration) {
+	for range time.Tick(interval) {
+		runtimeLog.Infof("%s", RuntimeStats())
+	}
+}
@@ -0,0 +1,43 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStartDiagnosticsShouldServePprofOnLoopback(t *testing.T) {
+	// === WHEN ===
+	addr, err := StartDiagnostics("127.0.0.1:0")
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	resp, err := http.Get("http://" + addr.String() + "/debug/pprof/")
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Got: '%v' - Expected '%v'", resp.StatusCode, 200)
+	}
+}
+
+func TestStartDiagnosticsShouldRejectPublicAddresses(t *testing.T) {
+	for _, address := range []string{":6060", "0.0.0.0:6060", "example.com:6060"} {
+		if _, err := StartDiagnostics(address); err == nil {
+			t.Errorf("Diagnostics should not be served on '%v'", address)
+		}
+	}
+}
+
+func TestRuntimeStatsShouldReportGoroutines(t *testing.T) {
+	if stats := RuntimeStats(); !strings.Contains(stats, "goroutines: ") {
+		t.Errorf("Got: '%v' - Expected number of goroutines", stats)
+	}
+}
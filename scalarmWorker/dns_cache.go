@@ -0,0 +1,281 @@
+package scalarmWorker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// max time of connecting to a nameserver
+var dnsQueryTimeout = 2 * time.Second
+
+// DNSCache keeps addresses of Scalarm services for the TTL of their DNS records, at most for MaxTTL,
+// and resolves a name again when connections to all of its cached addresses fail, so workers follow
+// services moving behind load balancers without a restart
+type DNSCache struct {
+	MaxTTL time.Duration
+
+	// resolves a name to its addresses and the TTL of its records, 0 when the TTL is unknown
+	lookup  func(ctx context.Context, host string) ([]string, time.Duration, error)
+	dial    func(ctx context.Context, network, address string) (net.Conn, error)
+	entries map[string]dnsCacheEntry
+	mutex   sync.Mutex
+}
+
+type dnsCacheEntry struct {
+	addresses []string
+	expires   time.Time
+}
+
+// NewDNSCache creates a cache which keeps addresses at most for maxTTL
+func NewDNSCache(maxTTL time.Duration) *DNSCache {
+	return &DNSCache{
+		MaxTTL:  maxTTL,
+		lookup:  lookupHostWithTTL,
+		dial:    (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		entries: map[string]dnsCacheEntry{},
+	}
+}
+
+// DialContext connects to a cached address of the host, once all of them failed the host is resolved again
+func (cache *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return cache.dial(ctx, network, address)
+	}
+
+	addresses, err := cache.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := cache.dialAny(ctx, network, addresses, port)
+	if err == nil {
+		return conn, nil
+	}
+
+	httpLog.Warnf("Connections to %s failed, resolving it again", host)
+	cache.Invalidate(host)
+	freshAddresses, resolveErr := cache.resolve(ctx, host)
+	if resolveErr != nil || sameAddresses(addresses, freshAddresses) {
+		return nil, err
+	}
+
+	return cache.dialAny(ctx, network, freshAddresses, port)
+}
+
+func (cache *DNSCache) dialAny(ctx context.Context, network string, addresses []string, port string) (net.Conn, error) {
+	var err error
+	for _, ip := range addresses {
+		var conn net.Conn
+		if conn, err = cache.dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// resolve returns cached addresses of the host or looks them up
+func (cache *DNSCache) resolve(ctx context.Context, host string) ([]string, error) {
+	cache.mutex.Lock()
+	entry, ok := cache.entries[host]
+	cache.mutex.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addresses, nil
+	}
+
+	addresses, ttl, err := cache.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+
+	if ttl <= 0 || ttl > cache.MaxTTL {
+		ttl = cache.MaxTTL
+	}
+
+	cache.mutex.Lock()
+	cache.entries[host] = dnsCacheEntry{addresses: addresses, expires: time.Now().Add(ttl)}
+	cache.mutex.Unlock()
+
+	return addresses, nil
+}
+
+// Invalidate forgets addresses of the host
+func (cache *DNSCache) Invalidate(host string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.entries, host)
+}
+
+func sameAddresses(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupHostWithTTL resolves the host with the Go resolver, which reads /etc/hosts, resolv.conf and applies
+// search domains, and takes the lowest TTL of records in DNS responses it receives; names resolved without
+// DNS, e.g. from /etc/hosts, have an unknown TTL
+func lookupHostWithTTL(ctx context.Context, host string) ([]string, time.Duration, error) {
+	observer := &ttlObserver{}
+	resolver := observer.resolver((&net.Dialer{Timeout: dnsQueryTimeout}).DialContext)
+
+	addresses, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return addresses, observer.lowest(), nil
+}
+
+// ttlObserver keeps the lowest TTL of DNS responses of a lookup, queries for A and AAAA records run in parallel
+type ttlObserver struct {
+	ttl   time.Duration
+	found bool
+	mutex sync.Mutex
+}
+
+// resolver returns a Go resolver which connects to nameservers with dial and passes DNS responses to the observer
+func (observer *ttlObserver) resolver(dial func(ctx context.Context, network, address string) (net.Conn, error)) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := dial(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+
+			// responses over TCP are framed and may be read in parts, their TTLs are not taken
+			if packetConn, ok := conn.(net.PacketConn); ok {
+				return ttlPacketConn{ttlConn{conn, observer}, packetConn}, nil
+			}
+			return conn, nil
+		},
+	}
+}
+
+func (observer *ttlObserver) observe(response []byte) {
+	ttl, err := ParseDNSResponseTTL(response)
+	if err != nil {
+		return
+	}
+
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+
+	if !observer.found || ttl < observer.ttl {
+		observer.ttl = ttl
+		observer.found = true
+	}
+}
+
+func (observer *ttlObserver) lowest() time.Duration {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+
+	return observer.ttl
+}
+
+// ttlConn passes DNS responses read by the resolver to the observer
+type ttlConn struct {
+	net.Conn
+	observer *ttlObserver
+}
+
+func (conn ttlConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	if n > 0 {
+		conn.observer.observe(b[:n])
+	}
+
+	return n, err
+}
+
+// ttlPacketConn keeps a UDP connection a net.PacketConn, the resolver reads whole messages only from such connections
+type ttlPacketConn struct {
+	ttlConn
+	net.PacketConn
+}
+
+// ParseDNSResponseTTL returns the lowest TTL of A, AAAA and CNAME records in the answer section of a DNS response,
+// the response is matched with its query by the resolver
+func ParseDNSResponseTTL(response []byte) (time.Duration, error) {
+	if len(response) < 12 || response[2]&0x80 == 0 {
+		return 0, errors.New("Invalid DNS response")
+	}
+	if rcode := response[3] & 0x0f; rcode != 0 {
+		return 0, fmt.Errorf("DNS response code: %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(response[4:]))
+	answers := int(binary.BigEndian.Uint16(response[6:]))
+
+	offset := 12
+	for i := 0; i < questions; i++ {
+		if offset = skipDNSName(response, offset) + 4; offset > len(response) {
+			return 0, errors.New("Truncated DNS response")
+		}
+	}
+
+	var ttl uint32
+	found := false
+	for i := 0; i < answers; i++ {
+		offset = skipDNSName(response, offset)
+		if offset+10 > len(response) {
+			return 0, errors.New("Truncated DNS response")
+		}
+
+		recordType := binary.BigEndian.Uint16(response[offset:])
+		recordTTL := binary.BigEndian.Uint32(response[offset+4:])
+		length := int(binary.BigEndian.Uint16(response[offset+8:]))
+		offset += 10 + length
+
+		// A, CNAME and AAAA records
+		if recordType == 1 || recordType == 5 || recordType == 28 {
+			if !found || recordTTL < ttl {
+				ttl = recordTTL
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return 0, errors.New("No address records in DNS response")
+	}
+
+	return time.Duration(ttl) * time.Second, nil
+}
+
+// skipDNSName returns the offset after a possibly compressed name
+func skipDNSName(message []byte, offset int) int {
+	for offset < len(message) {
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			return offset + 1
+		case length&0xc0 == 0xc0:
+			return offset + 2
+		default:
+			offset += length + 1
+		}
+	}
+
+	return len(message) + 1
+}
@@ -0,0 +1,135 @@
+package scalarmWorker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDNSCacheShouldKeepAddressesForTheirTTL(t *testing.T) {
+	// === GIVEN ===
+	lookups := 0
+	cache := NewDNSCache(time.Minute)
+	cache.lookup = func(ctx context.Context, host string) ([]string, time.Duration, error) {
+		lookups++
+		return []string{"10.0.0.1"}, 50 * time.Millisecond, nil
+	}
+
+	// === WHEN ===
+	cache.resolve(context.Background(), "em.example.org")
+	cache.resolve(context.Background(), "em.example.org")
+	time.Sleep(100 * time.Millisecond)
+	cache.resolve(context.Background(), "em.example.org")
+
+	// === THEN ===
+	if lookups != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", lookups, 2)
+	}
+}
+
+func TestDNSCacheShouldResolveAgainWhenConnectionsFail(t *testing.T) {
+	// === GIVEN ===
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	addresses := []string{"10.255.255.1"}
+	cache := NewDNSCache(time.Hour)
+	cache.lookup = func(ctx context.Context, host string) ([]string, time.Duration, error) {
+		return addresses, 0, nil
+	}
+	cache.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address != listener.Addr().String() {
+			return nil, errors.New("connection refused")
+		}
+		return net.Dial(network, address)
+	}
+
+	cache.resolve(context.Background(), "em.example.org")
+	// the service moved
+	addresses = []string{"127.0.0.1"}
+
+	// === WHEN ===
+	conn, err := cache.DialContext(context.Background(), "tcp", net.JoinHostPort("em.example.org", port))
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	conn.Close()
+}
+
+func TestTTLObserverShouldTakeTTLsOfResponsesToTheResolver(t *testing.T) {
+	// === GIVEN ===
+	nameserver, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer nameserver.Close()
+	go func() {
+		query := make([]byte, 512)
+		for {
+			n, address, err := nameserver.ReadFrom(query)
+			if err != nil {
+				return
+			}
+
+			// the question is answered with an A record with a TTL of 42 seconds, other types have no records
+			questionEnd := skipDNSName(query[:n], 12) + 4
+			response := append([]byte{}, query[:questionEnd]...)
+			response[2] |= 0x80
+			binary.BigEndian.PutUint16(response[10:], 0)
+			if binary.BigEndian.Uint16(query[questionEnd-4:]) == 1 {
+				binary.BigEndian.PutUint16(response[6:], 1)
+				response = append(response, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 42, 0, 4, 10, 0, 0, 1)
+			}
+			nameserver.WriteTo(response, address)
+		}
+	}()
+
+	observer := &ttlObserver{}
+	resolver := observer.resolver(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, nameserver.LocalAddr().String())
+	})
+
+	// === WHEN ===
+	addresses, err := resolver.LookupHost(context.Background(), "em.example.org.")
+
+	// === THEN ===
+	if err != nil || len(addresses) != 1 || addresses[0] != "10.0.0.1" {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", addresses, err, "10.0.0.1")
+	}
+	if ttl := observer.lowest(); ttl != 42*time.Second {
+		t.Errorf("Got: '%v' - Expected '%v'", ttl, 42*time.Second)
+	}
+}
+
+func TestParseDNSResponseTTLShouldReturnLowestTTL(t *testing.T) {
+	// === GIVEN ===
+	// header of a response with one question and two answers, question for A records of em.example.org
+	response := []byte{0, 7, 0x81, 0x80, 0, 1, 0, 2, 0, 0, 0, 0}
+	response = append(response, 2, 'e', 'm', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'o', 'r', 'g', 0, 0, 1, 0, 1)
+	for _, ttl := range []uint32{300, 60} {
+		// compressed name pointing to the question, type A, class IN, TTL, 4 bytes of address
+		record := []byte{0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 0, 0, 4, 10, 0, 0, 1}
+		binary.BigEndian.PutUint32(record[6:], ttl)
+		response = append(response, record...)
+	}
+
+	// === WHEN ===
+	ttl, err := ParseDNSResponseTTL(response)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	if ttl != time.Minute {
+		t.Errorf("Got: '%v' - Expected '%v'", ttl, time.Minute)
+	}
+
+	// a query is not a response
+	response[2] &^= 0x80
+	if _, err := ParseDNSResponseTTL(response); err == nil {
+		t.Errorf("A query should be rejected")
+	}
+}
@@ -0,0 +1,235 @@
+package scalarmWorker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	psdisk "github.com/shirou/gopsutil/disk"
+)
+
+var doctorLog = NewLogger("doctor")
+
+const (
+	doctorTimeout         = 5 * time.Second
+	minFreeDiskSpace      = 512 * 1024 * 1024
+	certificateExpiryWarn = 14 * 24 * time.Hour
+)
+
+var doctorRequiredTools = platformTools
+
+// DoctorCheck is a single item of the checklist printed by the doctor command
+type DoctorCheck struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+func doctorPassed(name string, format string, args ...interface{}) DoctorCheck {
+	return DoctorCheck{name, true, fmt.Sprintf(format, args...)}
+}
+
+func doctorFailed(name string, format string, args ...interface{}) DoctorCheck {
+	return DoctorCheck{name, false, fmt.Sprintf(format, args...)}
+}
+
+// hostAndPort extracts 'host:port' from a Scalarm service address like 'example.com/information'
+func hostAndPort(serviceURL string, development bool) (string, string) {
+	hostPort := strings.SplitN(serviceURL, "/", 2)[0]
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+		port = "443"
+		if development {
+			port = "80"
+		}
+	}
+
+	return host, port
+}
+
+func checkDNS(host string) DoctorCheck {
+	if net.ParseIP(host) != nil {
+		return doctorPassed("DNS", "%s is an IP address", host)
+	}
+
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return doctorFailed("DNS", "could not resolve %s: %v", host, err)
+	}
+
+	return doctorPassed("DNS", "%s resolves to %s", host, strings.Join(addrs, ", "))
+}
+
+func checkTLS(host string, port string, client *http.Client, pinned bool) DoctorCheck {
+	tlsConfig := &tls.Config{}
+	if transport, ok := client.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+	tlsConfig.ServerName = host
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: doctorTimeout}, "tcp", net.JoinHostPort(host, port), tlsConfig)
+	if err != nil {
+		return doctorFailed("TLS", "handshake with %s failed: %v", host, err)
+	}
+	defer conn.Close()
+
+	certificates := conn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return doctorFailed("TLS", "%s did not present any certificate", host)
+	}
+
+	earliestExpiry := certificates[0].NotAfter
+	for _, certificate := range certificates {
+		if certificate.NotAfter.Before(earliestExpiry) {
+			earliestExpiry = certificate.NotAfter
+		}
+	}
+
+	verification := "verified"
+	if pinned {
+		verification = "matching a pinned fingerprint"
+	} else if tlsConfig.InsecureSkipVerify {
+		verification = "NOT verified (insecure_ssl), leaf fingerprint " + CertificateFingerprint(certificates[0])
+	}
+
+	if time.Now().After(earliestExpiry) {
+		return doctorFailed("TLS", "certificate chain of %s expired on %v", host, earliestExpiry)
+	}
+
+	if time.Now().Add(certificateExpiryWarn).After(earliestExpiry) {
+		return doctorPassed("TLS", "chain of %d certificate(s) %s, WARNING: expires soon (%v)", len(certificates), verification, earliestExpiry)
+	}
+
+	return doctorPassed("TLS", "chain of %d certificate(s) %s, expires %v", len(certificates), verification, earliestExpiry)
+}
+
+func checkReachability(name string, serviceURLs []string, development bool) DoctorCheck {
+	unreachable := []string{}
+
+	for _, serviceURL := range serviceURLs {
+		host, port := hostAndPort(serviceURL, development)
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), doctorTimeout)
+		if err != nil {
+			unreachable = append(unreachable, serviceURL)
+			continue
+		}
+		conn.Close()
+	}
+
+	if len(unreachable) > 0 {
+		return doctorFailed(name, "%d of %d unreachable: %s", len(unreachable), len(serviceURLs), strings.Join(unreachable, ", "))
+	}
+
+	return doctorPassed(name, "all %d reachable", len(serviceURLs))
+}
+
+func checkAuthentication(experimentManagers []string, config *SimulationManagerConfig, client *http.Client) DoctorCheck {
+	reqInfo := RequestInfo{"GET", nil, "", "experiments/random_experiment"}
+
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, experimentManagers, config, client, doctorTimeout)
+	if err != nil {
+		return doctorFailed("Authentication", "%v", err)
+	}
+	resp.Body.Close()
+
+	user, _ := config.Credentials()
+	if resp.StatusCode == 401 || resp.StatusCode == 403 {
+		return doctorFailed("Authentication", "Experiment Manager rejected credentials of '%s' (%d)", user, resp.StatusCode)
+	}
+
+	return doctorPassed("Authentication", "credentials of '%s' accepted", user)
+}
+
+func checkDiskSpace(dirPath string) DoctorCheck {
+	usage, err := psdisk.Usage(dirPath)
+	if err != nil {
+		return doctorFailed("Disk space", "could not check free space in %s: %v", dirPath, err)
+	}
+
+	if usage.Free < minFreeDiskSpace {
+		return doctorFailed("Disk space", "only %d MB free in %s", usage.Free/1024/1024, dirPath)
+	}
+
+	return doctorPassed("Disk space", "%d MB free in %s", usage.Free/1024/1024, dirPath)
+}
+
+func checkTools(tools []string) DoctorCheck {
+	missing := []string{}
+
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+
+	if len(missing) > 0 {
+		return doctorFailed("Tools", "missing: %s", strings.Join(missing, ", "))
+	}
+
+	return doctorPassed("Tools", "found %s", strings.Join(tools, ", "))
+}
+
+// PrintDoctorChecks prints a pass/fail checklist and returns an error when any check failed
+func PrintDoctorChecks(checks []DoctorCheck) error {
+	failedChecks := 0
+
+	for _, check := range checks {
+		mark := "PASS"
+		if !check.Passed {
+			mark = "FAIL"
+			failedChecks++
+		}
+		doctorLog.Infof("[%s] %s: %s", mark, check.Name, Redact(check.Message))
+	}
+
+	if failedChecks > 0 {
+		return fmt.Errorf("%d doctor check(s) failed", failedChecks)
+	}
+
+	return nil
+}
+
+// Doctor checks connectivity with Scalarm and the local environment
+func (sim SimulationManager) Doctor() error {
+	checks := []DoctorCheck{}
+	for _, address := range sim.Config.InformationServiceAddresses() {
+		host, port := hostAndPort(address, sim.Config.Development)
+
+		dnsCheck := checkDNS(host)
+		checks = append(checks, dnsCheck)
+
+		if !sim.Config.Development && dnsCheck.Passed {
+			checks = append(checks, checkTLS(host, port, sim.HttpClient, len(sim.Config.CertificateFingerprints) > 0))
+		}
+	}
+
+	is := NewInformationService(sim.Config, sim.HttpClient, doctorTimeout)
+
+	experimentManagers, err := is.GetExperimentManagers()
+	if err != nil {
+		checks = append(checks, doctorFailed("Information Service", "%v", err))
+	} else {
+		checks = append(checks, doctorPassed("Information Service", "%d Experiment Manager(s) registered", len(experimentManagers)))
+		checks = append(checks, checkReachability("Experiment Managers", experimentManagers, sim.Config.Development))
+		checks = append(checks, checkAuthentication(experimentManagers, sim.Config, sim.HttpClient))
+	}
+
+	storageManagers, err := is.GetStorageManagers()
+	if err != nil {
+		checks = append(checks, doctorFailed("Storage Managers", "%v", err))
+	} else {
+		checks = append(checks, checkReachability("Storage Managers", storageManagers, sim.Config.Development))
+	}
+
+	checks = append(checks, checkDiskSpace(sim.RootDirPath))
+	checks = append(checks, checkTools(doctorRequiredTools))
+
+	return PrintDoctorChecks(checks)
+}
@@ -0,0 +1,75 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostAndPortShouldUseDefaultPortsWhenMissing(t *testing.T) {
+	host, port := hostAndPort("www.example.com/information", false)
+	if host != "www.example.com" || port != "443" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", host, port, "www.example.com:443")
+	}
+
+	host, port = hostAndPort("www.example.com", true)
+	if host != "www.example.com" || port != "80" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", host, port, "www.example.com:80")
+	}
+
+	host, port = hostAndPort("127.0.0.1:11300", false)
+	if host != "127.0.0.1" || port != "11300" {
+		t.Errorf("Got: '%v:%v' - Expected '%v'", host, port, "127.0.0.1:11300")
+	}
+}
+
+func TestCheckToolsShouldReportMissingTools(t *testing.T) {
+	check := checkTools([]string{"sh", "surely_not_installed_tool"})
+
+	if check.Passed {
+		t.Errorf("Got: passed - Expected failed")
+	}
+
+	if !strings.Contains(check.Message, "surely_not_installed_tool") {
+		t.Errorf("Got: '%v' - Expected to mention the missing tool", check.Message)
+	}
+}
+
+func TestCheckReachabilityShouldDetectUnreachableManagers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	reachable := strings.TrimPrefix(server.URL, "http://")
+
+	if check := checkReachability("EM", []string{reachable}, true); !check.Passed {
+		t.Errorf("Got: '%v' - Expected passed", check.Message)
+	}
+
+	if check := checkReachability("EM", []string{reachable, "127.0.0.1:1"}, true); check.Passed {
+		t.Errorf("Got: passed - Expected failed")
+	}
+}
+
+func TestCheckAuthenticationShouldFailWhenCredentialsAreRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	}))
+	defer server.Close()
+
+	check := checkAuthentication([]string{"system.scalarm.com"}, getSimConfig(), getHttpClientMock(server.URL))
+
+	if check.Passed {
+		t.Errorf("Got: passed - Expected failed")
+	}
+}
+
+func TestPrintDoctorChecksShouldReturnErrorWhenAnyCheckFailed(t *testing.T) {
+	if err := PrintDoctorChecks([]DoctorCheck{doctorPassed("A", "ok")}); err != nil {
+		t.Errorf("Got: '%v' - Expected nil", err)
+	}
+
+	if err := PrintDoctorChecks([]DoctorCheck{doctorPassed("A", "ok"), doctorFailed("B", "broken")}); err == nil {
+		t.Errorf("Got: nil - Expected not nil")
+	}
+}
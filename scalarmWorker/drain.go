@@ -0,0 +1,47 @@
+package scalarmWorker
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// set when the worker should finish the current simulation run and exit
+var draining int32
+
+// RequestDrain makes the worker exit after completing and uploading the current simulation run
+func RequestDrain(source string) {
+	if atomic.CompareAndSwapInt32(&draining, 0, 1) {
+		simLog.Infof("Drain requested by %s, finishing the current simulation run before exit", source)
+	}
+}
+
+// Draining tells whether a drain was requested
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// drainRequested checks the drain file besides other triggers, the file is kept so all workers
+// sharing the root directory drain
+func (sim SimulationManager) drainRequested() bool {
+	if sim.Config.DrainFile != "" {
+		if _, err := os.Stat(sim.Config.DrainFile); err == nil {
+			RequestDrain("the drain file " + sim.Config.DrainFile)
+		}
+	}
+
+	return Draining()
+}
+
+// drain returns a prefetched simulation run, which was not started, and exits cleanly
+func (sim SimulationManager) drain(prefetch *SimulationRunPrefetch, experimentID string) {
+	if prefetch != nil {
+		prefetch.Rollback()
+	}
+
+	if sim.Config.DrainFile != "" {
+		simLog.Infof("Remove %s before starting workers again", sim.Config.DrainFile)
+	}
+
+	sim.emit("worker_drained", experimentID, 0, nil)
+	Exit(0)
+}
@@ -0,0 +1,56 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDrainShouldBeRequestedByTheDrainFile(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "drain")
+	defer os.RemoveAll(dir)
+	defer atomic.StoreInt32(&draining, 0)
+
+	config := getSimConfig()
+	config.DrainFile = filepath.Join(dir, "scalarm.drain")
+	sim := SimulationManager{Config: config}
+
+	// === WHEN ===
+	before := sim.drainRequested()
+	ioutil.WriteFile(config.DrainFile, nil, 0644)
+	after := sim.drainRequested()
+
+	// === THEN ===
+	if before || !after {
+		t.Errorf("Got: '%v', '%v' - Expected a drain only after the file is created", before, after)
+	}
+}
+
+func TestDrainShouldBeRequestedThroughDiagnostics(t *testing.T) {
+	// === GIVEN ===
+	defer atomic.StoreInt32(&draining, 0)
+
+	addr, err := StartDiagnostics("127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	// === WHEN ===
+	resp, err := http.Post("http://"+addr.String()+"/drain", "", nil)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 || !Draining() {
+		t.Errorf("Got: '%v' - Expected a drain to be requested", resp.StatusCode)
+	}
+}
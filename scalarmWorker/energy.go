@@ -0,0 +1,204 @@
+package scalarmWorker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sources of energy measurements
+const (
+	EnergySourceRAPL = "rapl"
+	EnergySourceIPMI = "ipmi"
+	EnergySourceAuto = "auto"
+)
+
+// directory with RAPL power capping zones exported by Linux
+var raplSysfsDir = "/sys/class/powercap"
+
+// reads the current power draw of the node in watts, replaced in tests
+var readIPMIPower = func() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "ipmitool", "dcmi", "power", "reading").Output()
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseIPMIPowerReading(string(output))
+}
+
+var ipmiPowerReadingRegexp = regexp.MustCompile(`Instantaneous power reading:\s*([0-9.]+)\s*Watts`)
+
+// EnergyMeter integrates energy consumed by the node while a simulation run executes; RAPL counters
+// of CPU packages are sampled to handle their wraparound, IPMI power readings are integrated over time
+type EnergyMeter struct {
+	Source string
+
+	zones   []raplZone
+	joules  float64
+	stop    chan struct{}
+	stopped chan struct{}
+	mutex   sync.Mutex
+}
+
+// raplZone is an energy counter of a single CPU package
+type raplZone struct {
+	energyPath string
+	maxRange   uint64 // in microjoules
+	last       uint64
+}
+
+// StartEnergyMeter starts measuring energy with the given source, RAPL is preferred by 'auto';
+// an error is returned when the source is not available on this node
+func StartEnergyMeter(source string, interval time.Duration) (*EnergyMeter, error) {
+	meter := &EnergyMeter{Source: source, stop: make(chan struct{}), stopped: make(chan struct{})}
+
+	var err error
+	switch source {
+	case EnergySourceRAPL:
+		err = meter.openRAPL()
+	case EnergySourceIPMI:
+		_, err = readIPMIPower()
+	case EnergySourceAuto:
+		meter.Source = EnergySourceRAPL
+		if err = meter.openRAPL(); err != nil {
+			meter.Source = EnergySourceIPMI
+			_, err = readIPMIPower()
+		}
+	default:
+		err = errors.New("Unknown energy source '" + source + "', use 'rapl', 'ipmi' or 'auto'")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go meter.run(interval)
+	return meter, nil
+}
+
+// openRAPL finds package zones, like intel-rapl:0, and reads their initial counters
+func (meter *EnergyMeter) openRAPL() error {
+	zoneDirs, _ := filepath.Glob(filepath.Join(raplSysfsDir, "intel-rapl:*"))
+	for _, zoneDir := range zoneDirs {
+		// subzones like intel-rapl:0:0 (cores, DRAM) are already counted by their package
+		if strings.Count(filepath.Base(zoneDir), ":") != 1 {
+			continue
+		}
+
+		maxRange, err := readUintFile(filepath.Join(zoneDir, "max_energy_range_uj"))
+		if err != nil {
+			return err
+		}
+
+		zone := raplZone{energyPath: filepath.Join(zoneDir, "energy_uj"), maxRange: maxRange}
+		if zone.last, err = readUintFile(zone.energyPath); err != nil {
+			return err
+		}
+		meter.zones = append(meter.zones, zone)
+	}
+
+	if len(meter.zones) == 0 {
+		return errors.New("No RAPL package zones in " + raplSysfsDir)
+	}
+
+	return nil
+}
+
+func (meter *EnergyMeter) run(interval time.Duration) {
+	defer close(meter.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastSample := time.Now()
+	var lastPower float64
+	if meter.Source == EnergySourceIPMI {
+		lastPower, _ = readIPMIPower()
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-meter.stop:
+		}
+
+		if meter.Source == EnergySourceRAPL {
+			meter.sampleRAPL()
+		} else if power, err := readIPMIPower(); err == nil {
+			// trapezoidal integration of power over the sampling period
+			now := time.Now()
+			meter.mutex.Lock()
+			meter.joules += (lastPower + power) / 2 * now.Sub(lastSample).Seconds()
+			meter.mutex.Unlock()
+			lastSample, lastPower = now, power
+		}
+
+		select {
+		case <-meter.stop:
+			return
+		default:
+		}
+	}
+}
+
+// sampleRAPL adds energy consumed since the last sample, a counter which wrapped around continues from 0
+func (meter *EnergyMeter) sampleRAPL() {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+
+	for i := range meter.zones {
+		zone := &meter.zones[i]
+
+		current, err := readUintFile(zone.energyPath)
+		if err != nil {
+			continue
+		}
+
+		delta := current - zone.last
+		if current < zone.last {
+			delta = zone.maxRange - zone.last + current
+		}
+		meter.joules += float64(delta) / 1e6
+		zone.last = current
+	}
+}
+
+// Stop takes the last sample and returns energy consumed since the start in joules
+func (meter *EnergyMeter) Stop() float64 {
+	close(meter.stop)
+	<-meter.stopped
+
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+
+	return meter.joules
+}
+
+// ParseIPMIPowerReading reads the power in watts from 'ipmitool dcmi power reading' output
+func ParseIPMIPowerReading(output string) (float64, error) {
+	match := ipmiPowerReadingRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("No power reading in ipmitool output: %s", strings.TrimSpace(output))
+	}
+
+	return strconv.ParseFloat(match[1], 64)
+}
+
+func readUintFile(filePath string) (uint64, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
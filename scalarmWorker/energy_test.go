@@ -0,0 +1,70 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnergyMeterShouldSumRAPLPackagesAcrossWraparound(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "powercap")
+	defer os.RemoveAll(dir)
+
+	previousDir := raplSysfsDir
+	raplSysfsDir = dir
+	defer func() { raplSysfsDir = previousDir }()
+
+	zones := map[string]string{"intel-rapl:0": "999000000", "intel-rapl:1": "5000000", "intel-rapl:0:0": "7"}
+	for zone, energy := range zones {
+		os.MkdirAll(filepath.Join(dir, zone), 0755)
+		ioutil.WriteFile(filepath.Join(dir, zone, "max_energy_range_uj"), []byte("1000000000\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, zone, "energy_uj"), []byte(energy+"\n"), 0644)
+	}
+
+	// === WHEN ===
+	meter, err := StartEnergyMeter(EnergySourceAuto, time.Hour)
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	// the first package wraps around after 1 J, the second one consumes 2 J
+	ioutil.WriteFile(filepath.Join(dir, "intel-rapl:0", "energy_uj"), []byte("0\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "intel-rapl:1", "energy_uj"), []byte("7000000\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "intel-rapl:0:0", "energy_uj"), []byte("900000000\n"), 0644)
+
+	joules := meter.Stop()
+
+	// === THEN ===
+	if meter.Source != EnergySourceRAPL {
+		t.Errorf("Got: '%v' - Expected '%v'", meter.Source, EnergySourceRAPL)
+	}
+
+	if joules != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", joules, 3)
+	}
+}
+
+func TestParseIPMIPowerReadingShouldReturnInstantaneousPower(t *testing.T) {
+	// === GIVEN ===
+	output := `
+    Instantaneous power reading:                   245 Watts
+    Minimum during sampling period:                 98 Watts
+    Maximum during sampling period:                412 Watts
+`
+
+	// === WHEN ===
+	power, err := ParseIPMIPowerReading(output)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if power != 245 {
+		t.Errorf("Got: '%v' - Expected '%v'", power, 245)
+	}
+}
@@ -0,0 +1,116 @@
+package scalarmWorker
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var eventsLog = NewLogger("events")
+
+// max time of writing a single event to a socket, a slow consumer must not stop computations
+var eventStreamTimeout = time.Second
+
+// Event is a single line of the event stream
+type Event struct {
+	Time            time.Time   `json:"time"`
+	Type            string      `json:"type"`
+	ExperimentID    string      `json:"experiment_id,omitempty"`
+	SimulationIndex int         `json:"simulation_id,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// EventStream writes lifecycle events of the worker as newline-delimited JSON to a file, a unix socket
+// ('unix://<path>') or a TCP socket ('tcp://<host>:<port>'); a broken connection is reopened on the next event
+type EventStream struct {
+	Target string
+	writer io.WriteCloser
+	mutex  sync.Mutex
+}
+
+// OpenEventStream opens the target of the event stream
+func OpenEventStream(target string) (*EventStream, error) {
+	stream := &EventStream{Target: target}
+	if err := stream.open(); err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+func (stream *EventStream) open() error {
+	var err error
+
+	if strings.HasPrefix(stream.Target, "unix://") {
+		stream.writer, err = net.DialTimeout("unix", strings.TrimPrefix(stream.Target, "unix://"), eventStreamTimeout)
+	} else if strings.HasPrefix(stream.Target, "tcp://") {
+		stream.writer, err = net.DialTimeout("tcp", strings.TrimPrefix(stream.Target, "tcp://"), eventStreamTimeout)
+	} else {
+		stream.writer, err = os.OpenFile(stream.Target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	}
+
+	return err
+}
+
+// Emit writes an event, errors are only logged; a nil stream ignores all events
+func (stream *EventStream) Emit(eventType string, experimentID string, simulationIndex int, data interface{}) {
+	if stream == nil {
+		return
+	}
+
+	line, err := marshalJSON(Event{
+		Time:            time.Now(),
+		Type:            eventType,
+		ExperimentID:    experimentID,
+		SimulationIndex: simulationIndex,
+		Data:            data,
+	})
+	if err != nil {
+		eventsLog.Warnf("Could not encode '%s' event: %v", eventType, err)
+		return
+	}
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if stream.writer == nil {
+		if err = stream.open(); err != nil {
+			return
+		}
+	}
+
+	if conn, ok := stream.writer.(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(eventStreamTimeout))
+	}
+
+	if _, err = io.WriteString(stream.writer, Redact(string(line))+"\n"); err != nil {
+		eventsLog.Warnf("Could not write '%s' event: %v", eventType, err)
+		stream.writer.Close()
+		stream.writer = nil
+	}
+}
+
+// Close closes the file or connection of the stream
+func (stream *EventStream) Close() {
+	if stream == nil {
+		return
+	}
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if stream.writer != nil {
+		stream.writer.Close()
+		stream.writer = nil
+	}
+}
+
+// emit writes an event to the event stream, annotates it in Grafana and counts it in Pushgateway metrics
+func (sim SimulationManager) emit(eventType string, experimentID string, simulationIndex int, data interface{}) {
+	sim.events.Emit(eventType, experimentID, simulationIndex, data)
+	sim.annotations.Annotate(eventType, experimentID, simulationIndex)
+	sim.metrics.Observe(eventType, experimentID, data)
+}
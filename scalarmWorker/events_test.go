@@ -0,0 +1,95 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEventStreamShouldAppendEventsToFileAsJSONLines(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "events")
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "events.ndjson")
+
+	stream, err := OpenEventStream(filePath)
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	// === WHEN ===
+	stream.Emit("simulation_run_started", "568e5bece138232e76000002", 3, map[string]interface{}{"x": 1})
+	stream.Emit("simulation_run_finished", "568e5bece138232e76000002", 3, nil)
+	stream.Close()
+
+	// === THEN ===
+	content, _ := ioutil.ReadFile(filePath)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Errorf("Got: '%v' - Expected 2 lines", len(lines))
+		return
+	}
+
+	event := Event{}
+	if err = json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if event.Type != "simulation_run_started" || event.SimulationIndex != 3 || event.ExperimentID != "568e5bece138232e76000002" {
+		t.Errorf("Got: '%+v' - Expected simulation_run_started event of simulation 3", event)
+	}
+}
+
+func TestEventStreamShouldWriteEventsToUnixSocket(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "events")
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "events.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("Unix sockets are not available: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	stream, err := OpenEventStream("unix://" + socketPath)
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	defer stream.Close()
+
+	// === WHEN ===
+	stream.Emit("worker_started", "", 0, nil)
+
+	// === THEN ===
+	if line := <-received; !strings.Contains(line, `"type":"worker_started"`) {
+		t.Errorf("Got: '%v' - Expected worker_started event", line)
+	}
+}
+
+func TestNilEventStreamShouldIgnoreEvents(t *testing.T) {
+	// === GIVEN ===
+	var stream *EventStream
+
+	// === WHEN ===
+	stream.Emit("worker_started", "", 0, nil)
+	stream.Close()
+}
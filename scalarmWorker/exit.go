@@ -0,0 +1,98 @@
+package scalarmWorker
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	exitHooksMutex sync.Mutex
+	exitHooks      = []func(){}
+	fatalHooks     = []func(err error){}
+
+	// set once SiM started exiting
+	exiting int32
+)
+
+// OnExit registers a function which is run when SiM terminates through Exit,
+// hooks are run in reverse order of registration
+func OnExit(hook func()) {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+
+	exitHooks = append(exitHooks, hook)
+}
+
+// Exit runs registered exit hooks, cancels the worker context and terminates SiM with the given status code;
+// when SiM is already exiting, e.g. an exit hook failed or a second signal came, it terminates SiM right away
+func Exit(code int) {
+	if !atomic.CompareAndSwapInt32(&exiting, 0, 1) {
+		os.Exit(code)
+	}
+
+	runExitHooksFrom(0)
+	CancelWorker()
+	os.Exit(code)
+}
+
+// Restart runs registered exit hooks and replaces SiM with a new instance started with the same arguments;
+// when SiM is already exiting, it returns and lets the exit finish
+func Restart() {
+	if !atomic.CompareAndSwapInt32(&exiting, 0, 1) {
+		return
+	}
+
+	runExitHooksFrom(0)
+	CancelWorker()
+	if err := restartProcess(); err != nil {
+		simLog.Warnf("Could not restart: %v", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// exitHooksMark returns the number of registered exit hooks, to later run only hooks registered after it
+func exitHooksMark() int {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+
+	return len(exitHooks)
+}
+
+// runExitHooksFrom runs and unregisters exit hooks registered after the mark
+func runExitHooksFrom(mark int) {
+	exitHooksMutex.Lock()
+	if mark > len(exitHooks) {
+		mark = len(exitHooks)
+	}
+	hooks := exitHooks[mark:]
+	exitHooks = exitHooks[:mark:mark]
+	exitHooksMutex.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// OnFatal registers a function which is run with the error when SiM terminates because of it
+func OnFatal(hook func(err error)) {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+
+	fatalHooks = append(fatalHooks, hook)
+}
+
+// runFatalHooks runs functions registered with OnFatal, right before SiM terminates with an error and exitCode
+func runFatalHooks(err error, exitCode int) {
+	writeFatalErrorFile(err, exitCode)
+
+	exitHooksMutex.Lock()
+	hooks := fatalHooks
+	fatalHooks = []func(err error){}
+	exitHooksMutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(err)
+	}
+}
@@ -1,11 +1,9 @@
 package scalarmWorker
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -24,6 +22,27 @@ type ExperimentManager struct {
 	Username             string
 	Password             string
 	ExperimentId         string
+	Context              context.Context // cancels requests, the worker context when not set
+}
+
+// context returns the context of requests sent by the manager
+func (em *ExperimentManager) context() context.Context {
+	if em.Context == nil {
+		return WorkerContext()
+	}
+
+	return em.Context
+}
+
+// execute sends a request to one of Experiment Managers and samples the offset of its clock from the response
+func (em *ExperimentManager) execute(reqInfo RequestInfo) (*http.Response, error) {
+	sentAt := time.Now()
+	resp, err := ExecuteScalarmRequest(em.context(), reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	if err == nil {
+		recordClockOffset(resp, sentAt, time.Now())
+	}
+
+	return resp, err
 }
 
 func (em *ExperimentManager) GetNextSimulationRunConfig() (map[string]interface{}, error) {
@@ -32,7 +51,7 @@ func (em *ExperimentManager) GetNextSimulationRunConfig() (map[string]interface{
 	path := "experiments/" + em.ExperimentId + "/next_simulation"
 	reqInfo := RequestInfo{"GET", nil, "", path}
 
-	resp, err := ExecuteScalarmRequest(reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	resp, err := em.execute(reqInfo)
 
 	if err != nil {
 		return nil, err
@@ -65,7 +84,7 @@ func (em *ExperimentManager) MarkSimulationRunAsComplete(simulationIndex int, ru
 	path := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/mark_as_complete"
 	reqInfo := RequestInfo{"POST", strings.NewReader(runResult.Encode()), "application/x-www-form-urlencoded", path}
 
-	resp, err := ExecuteScalarmRequest(reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	resp, err := em.execute(reqInfo)
 
 	if err != nil {
 		return nil, err
@@ -79,7 +98,7 @@ func (em *ExperimentManager) MarkSimulationRunAsComplete(simulationIndex int, ru
 			}
 
 			if err := json.Unmarshal(body, &emResponse); err != nil {
-				fmt.Printf("Receiving: %v\n", body)
+				simLog.Debugf("Receiving: %s", body)
 				return nil, errors.New("Returned response body is not JSON.")
 			}
 
@@ -107,42 +126,203 @@ func (em *ExperimentManager) MarkSimulationRunAsComplete(simulationIndex int, ru
 	}
 }
 
-func (em *ExperimentManager) DownloadExperimentCodeBase(codeBaseDir string) error {
-	var responseBody []byte
+// GetNextSimulationRunConfigs requests up to count simulation runs at once, parameter sets are returned
+// in the 'simulations' array of the response; errBatchNotSupported is returned when the Experiment Manager
+// does not provide this endpoint
+func (em *ExperimentManager) GetNextSimulationRunConfigs(count int) (map[string]interface{}, error) {
+	nextSimulationRunsConfig := map[string]interface{}{}
+
+	path := "experiments/" + em.ExperimentId + "/next_simulations?count=" + strconv.Itoa(count)
+	reqInfo := RequestInfo{"GET", nil, "", path}
+
+	resp, err := em.execute(reqInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
 
-	w, err := os.Create(path.Join(codeBaseDir, "code_base.zip"))
+	if resp.StatusCode == 404 {
+		return nil, errBatchNotSupported
+	} else if resp.StatusCode != 200 {
+		return nil, errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(body, &nextSimulationRunsConfig); err != nil {
+		return nil, errors.New("Returned response body is not JSON.")
+	}
+
+	return nextSimulationRunsConfig, nil
+}
+
+// MarkSimulationRunsAsComplete sends results of multiple simulation runs in a single request
+func (em *ExperimentManager) MarkSimulationRunsAsComplete(completions []SimulationRunCompletion) error {
+	emResponse := map[string]interface{}{}
+
+	jsonStr, err := marshalJSON(completions)
 	if err != nil {
 		return err
 	}
-	defer w.Close()
 
-	codeBaseURL := "experiments/" + em.ExperimentId + "/code_base"
-	codeBaseInfo := RequestInfo{"GET", nil, "", codeBaseURL}
+	requestData := url.Values{}
+	requestData.Set("results", string(jsonStr))
 
-	resp, err := ExecuteScalarmRequest(codeBaseInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	path := "experiments/" + em.ExperimentId + "/simulations/mark_as_complete"
+	reqInfo := RequestInfo{"POST", strings.NewReader(requestData.Encode()), "application/x-www-form-urlencoded", path}
+
+	resp, err := em.execute(reqInfo)
 	if err != nil {
 		return err
 	}
 
-	responseBody, err = ioutil.ReadAll(resp.Body)
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return errBatchNotSupported
+	} else if resp.StatusCode != 200 {
+		return errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	if _, err = io.Copy(w, bytes.NewReader(responseBody)); err != nil {
+	if err := json.Unmarshal(body, &emResponse); err != nil {
+		return errors.New("Returned response body is not JSON.")
+	}
+
+	if statusVal, ok := emResponse["status"]; ok {
+		if statusVal.(string) != "ok" {
+			if reasonVal, ok := emResponse["reason"]; ok {
+				return errors.New(reasonVal.(string))
+			}
+
+			return errors.New("Something went wrong but without any details")
+		}
+	}
+
+	return nil
+}
+
+// GetComputedResult asks the Experiment Manager for results of an already computed simulation run
+// with the same input parameters (as JSON), found is false when there is no such run
+func (em *ExperimentManager) GetComputedResult(inputParameters []byte) (json.RawMessage, bool, error) {
+	emResponse := struct {
+		Status string          `json:"status"`
+		Result json.RawMessage `json:"result"`
+	}{}
+
+	query := url.Values{}
+	query.Set("input_parameters", string(inputParameters))
+
+	path := "experiments/" + em.ExperimentId + "/simulations/computed_result?" + query.Encode()
+	reqInfo := RequestInfo{"GET", nil, "", path}
+
+	resp, err := em.execute(reqInfo)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, false, nil
+	} else if resp.StatusCode != 200 {
+		return nil, false, errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := json.Unmarshal(body, &emResponse); err != nil {
+		return nil, false, errors.New("Returned response body is not JSON.")
+	}
+
+	if emResponse.Status != "ok" || len(emResponse.Result) == 0 || string(emResponse.Result) == "null" {
+		return nil, false, nil
+	}
+
+	return emResponse.Result, true, nil
+}
+
+// RollbackSimulationRun returns a fetched but not completed simulation run to the queue of the experiment
+func (em *ExperimentManager) RollbackSimulationRun(simulationIndex int) error {
+	path := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/rollback"
+	reqInfo := RequestInfo{"POST", nil, "", path}
+
+	// rollbacks are sent by exit hooks, after the worker context is cancelled
+	detached := *em
+	detached.Context = context.Background()
+
+	resp, err := detached.execute(reqInfo)
+	if err != nil {
 		return err
 	}
 
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
 	return nil
 }
 
+func (em *ExperimentManager) DownloadExperimentCodeBase(codeBaseDir string) error {
+	codeBaseURL := "experiments/" + em.ExperimentId + "/code_base"
+
+	// large code bases are fetched in chunks with multiple connections if Experiment Managers support it,
+	// a download interrupted before is resumed with a single connection
+	_, partErr := os.Stat(path.Join(codeBaseDir, "code_base.zip.part"))
+	if em.Config.DownloadConnections > 1 && os.IsNotExist(partErr) {
+		err := DownloadInParallel(codeBaseURL, em.BaseUrls, path.Join(codeBaseDir, "code_base.zip"),
+			em.Config.DownloadConnections, em.Config, em.HttpClient, em.CommunicationTimeout)
+		if err == nil {
+			return nil
+		}
+
+		if err != errParallelDownloadNotPossible {
+			simLog.Warnf("Parallel download of the code base failed, using a single connection: %v", err)
+		}
+	}
+
+	// an interrupted download is resumed by the next attempt instead of starting from zero
+	return em.downloadResumable(codeBaseURL, path.Join(codeBaseDir, "code_base.zip"))
+}
+
 func (em *ExperimentManager) PostProgressInfo(simulationIndex int, results url.Values) error {
+	return em.postProgressInfo(simulationIndex, results)
+}
+
+// PostProgressInfoBatch sends multiple intermediate results of a simulation run in a single request
+func (em *ExperimentManager) PostProgressInfoBatch(simulationIndex int, entries []ProgressInfoEntry) error {
+	jsonStr, err := marshalJSON(entries)
+	if err != nil {
+		return err
+	}
+
+	requestData := url.Values{}
+	requestData.Set("batch", string(jsonStr))
+
+	return em.postProgressInfo(simulationIndex, requestData)
+}
+
+func (em *ExperimentManager) postProgressInfo(simulationIndex int, requestData url.Values) error {
 	emResponse := map[string]interface{}{}
+	setCPUModel(requestData)
 
 	progressInfoPath := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/progress_info"
-	reqInfo := RequestInfo{"POST", strings.NewReader(results.Encode()), "application/x-www-form-urlencoded", progressInfoPath}
+	reqInfo := RequestInfo{"POST", strings.NewReader(requestData.Encode()), "application/x-www-form-urlencoded", progressInfoPath}
 
-	resp, err := ExecuteScalarmRequest(reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	resp, err := em.execute(reqInfo)
 
 	if err != nil {
 		return err
@@ -163,6 +343,12 @@ func (em *ExperimentManager) PostProgressInfo(simulationIndex int, results url.V
 		return errors.New("Returned response body is not JSON.")
 	}
 
+	// the Experiment Manager can cancel the run in response to its progress
+	if cancelled, reason := isCancellationResponse(emResponse); cancelled {
+		cancelRun(em.ExperimentId, simulationIndex, reason)
+		return nil
+	}
+
 	if statusVal, ok := emResponse["status"]; ok {
 		if statusVal.(string) != "ok" {
 			if reasonVal, ok := emResponse["reason"]; ok {
@@ -178,14 +364,14 @@ func (em *ExperimentManager) PostProgressInfo(simulationIndex int, results url.V
 
 // ReportHostInfo sends information about the host where computations are executed
 func (em *ExperimentManager) ReportHostInfo(simulationIndex int, hostInfo *HostInfo) error {
-	jsonStr, _ := json.Marshal(hostInfo)
+	jsonStr, _ := marshalJSON(hostInfo)
 	requestData := url.Values{}
 	requestData.Set("host_info", string(jsonStr))
 
 	url := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/host_info"
 	reqInfo := RequestInfo{"POST", strings.NewReader(requestData.Encode()), "application/x-www-form-urlencoded", url}
 
-	resp, err := ExecuteScalarmRequest(reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	resp, err := em.execute(reqInfo)
 	if err != nil {
 		return err
 	}
@@ -200,14 +386,14 @@ func (em *ExperimentManager) ReportHostInfo(simulationIndex int, hostInfo *HostI
 }
 
 func (em *ExperimentManager) ReportPerformanceStats(simulationIndex int, perfStats *PerformanceStats) error {
-	jsonStr, _ := json.Marshal(perfStats)
+	jsonStr, _ := marshalJSON(perfStats)
 	requestData := url.Values{}
 	requestData.Set("stats", string(jsonStr))
 
 	url := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/performance_stats"
 	reqInfo := RequestInfo{"POST", strings.NewReader(requestData.Encode()), "application/x-www-form-urlencoded", url}
 
-	resp, err := ExecuteScalarmRequest(reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	resp, err := em.execute(reqInfo)
 	defer resp.Body.Close()
 
 	if err != nil {
@@ -205,3 +205,33 @@ func TestExperimentManagerShouldHandleScalarmErrorsWithoutReasonsWhenSendingMark
 		return
 	}
 }
+
+func TestExperimentManagerShouldSendCPUModelWithProgressInfo(t *testing.T) {
+	// === GIVEN ===
+	cachedHostInfo.once.Do(func() {})
+	defer func(info *HostInfo) { cachedHostInfo.info = info }(cachedHostInfo.info)
+	cachedHostInfo.info = &HostInfo{ModelName: "AMD EPYC 7742 64-Core Processor", Cores: 128, Mhz: 2250}
+
+	var cpuModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		cpuModel = r.PostForm.Get("cpu_model")
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	progress := url.Values{}
+	progress.Set("result", `{"x":1}`)
+
+	// === WHEN ===
+	err := em.PostProgressInfo(1, progress)
+
+	// === THEN ===
+	expected := `{"cores":128,"mhz":2250,"model_name":"AMD EPYC 7742 64-Core Processor"}`
+	if err != nil || cpuModel != expected {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", err, cpuModel, expected)
+	}
+}
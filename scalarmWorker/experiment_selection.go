@@ -0,0 +1,141 @@
+package scalarmWorker
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// how SiM picks the next experiment when experiment_id is not configured
+const (
+	ExperimentSelectionRandom      = "random"
+	ExperimentSelectionLeastLoaded = "least_loaded"
+)
+
+// ExperimentStats - progress of an experiment as reported by Experiment Manager
+type ExperimentStats struct {
+	All  int `json:"all"`
+	Sent int `json:"sent"`
+	Done int `json:"done_num"`
+}
+
+// Pending returns the number of simulation runs which were not sent to any worker yet
+func (stats *ExperimentStats) Pending() int {
+	return stats.All - stats.Sent - stats.Done
+}
+
+// GetRunningExperimentIDs asks Experiment Manager for running experiments of experiment_manager_user
+func (sim SimulationManager) GetRunningExperimentIDs(experimentManagers []string, client *http.Client) ([]string, error) {
+	experiments := struct {
+		Status  string   `json:"status"`
+		Running []string `json:"running"`
+		Reason  string   `json:"reason"`
+	}{}
+
+	if err := sim.getExperimentManagerJSON("experiments.json", experimentManagers, client, &experiments); err != nil {
+		return nil, err
+	}
+	if experiments.Status != "" && experiments.Status != "ok" {
+		return nil, errors.New("Listing experiments failed: " + experiments.Reason)
+	}
+
+	return experiments.Running, nil
+}
+
+// GetExperimentStats asks Experiment Manager how many simulation runs of the experiment are sent and done
+func (sim SimulationManager) GetExperimentStats(experimentManagers []string, client *http.Client, experimentID string) (*ExperimentStats, error) {
+	stats := new(ExperimentStats)
+	if err := sim.getExperimentManagerJSON("experiments/"+experimentID+"/stats", experimentManagers, client, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (sim SimulationManager) getExperimentManagerJSON(serviceMethod string, experimentManagers []string,
+	client *http.Client, v interface{}) error {
+
+	reqInfo := RequestInfo{"GET", nil, "", serviceMethod}
+	resp, err := ExecuteScalarmRequest(WorkerContext(), reqInfo, experimentManagers, sim.Config, client, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.New("Returned response body is not JSON.")
+	}
+
+	return nil
+}
+
+// SelectExperimentID picks one of running experiments of the user which were not executed by this SiM yet,
+// at random or the one with the fewest simulation runs in progress depending on experiment_selection;
+// Experiment Managers which cannot list experiments are asked for a random experiment instead
+func (sim SimulationManager) SelectExperimentID(experimentManagers []string, client *http.Client, executedExperiments *list.List) string {
+	simLog.Infof("Getting running experiments...")
+	running, err := sim.GetRunningExperimentIDs(experimentManagers, client)
+	if err != nil {
+		simLog.Warnf("Could not list running experiments - %v", Redact(err.Error()))
+		return sim.GetRandomExperimentID(experimentManagers, client)
+	}
+
+	// candidates are shuffled, so workers started together do not pick the same experiment
+	candidates := []string{}
+	for _, i := range randomPerm(len(running)) {
+		if !listIncludeString(executedExperiments, running[i]) {
+			candidates = append(candidates, running[i])
+		}
+	}
+	simLog.Infof("%v running experiments, %v not executed yet", len(running), len(candidates))
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	if sim.Config.ExperimentSelection != ExperimentSelectionLeastLoaded {
+		return candidates[0]
+	}
+
+	selected := ""
+	var selectedStats *ExperimentStats
+	statsAvailable := false
+	for _, experimentID := range candidates {
+		stats, err := sim.GetExperimentStats(experimentManagers, client, experimentID)
+		if err != nil {
+			simLog.Warnf("Could not get stats of experiment %v - %v", experimentID, Redact(err.Error()))
+			continue
+		}
+		statsAvailable = true
+		if stats.Pending() <= 0 {
+			continue
+		}
+		if selectedStats == nil || stats.Sent < selectedStats.Sent {
+			selected, selectedStats = experimentID, stats
+		}
+	}
+
+	if !statsAvailable {
+		// without stats every candidate is as good as another
+		return candidates[0]
+	}
+	if selectedStats == nil {
+		return ""
+	}
+	simLog.Infof("Experiment %v is the least loaded - %v simulation runs in progress, %v pending",
+		selected, selectedStats.Sent, selectedStats.Pending())
+
+	return selected
+}
@@ -0,0 +1,94 @@
+package scalarmWorker
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupExperimentListServer(listing bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments.json":
+			if !listing {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, `{"status":"ok","running":["a","b","c"]}`)
+		case "/experiments/a/stats":
+			fmt.Fprintln(w, `{"all":100,"sent":5,"done_num":10}`)
+		case "/experiments/b/stats":
+			fmt.Fprintln(w, `{"all":100,"sent":1,"done_num":10}`)
+		case "/experiments/c/stats":
+			fmt.Fprintln(w, `{"all":100,"sent":0,"done_num":100}`)
+		case "/experiments/random_experiment":
+			fmt.Fprint(w, "d")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestSelectExperimentIDShouldPickTheLeastLoadedExperimentWithPendingRuns(t *testing.T) {
+	// === GIVEN ===
+	server := setupExperimentListServer(true)
+	defer server.Close()
+
+	config := getSimConfig()
+	config.ExperimentSelection = ExperimentSelectionLeastLoaded
+	sim := SimulationManager{Config: config}
+
+	// === WHEN ===
+	experimentID := sim.SelectExperimentID([]string{"system.scalarm.com"}, getHttpClientMock(server.URL), list.New())
+
+	// === THEN ===
+	if experimentID != "b" {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentID, "b")
+	}
+}
+
+func TestSelectExperimentIDShouldSkipExecutedExperiments(t *testing.T) {
+	// === GIVEN ===
+	server := setupExperimentListServer(true)
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig()}
+	executedExperiments := list.New()
+	executedExperiments.PushBack("a")
+	executedExperiments.PushBack("c")
+
+	// === WHEN ===
+	experimentID := sim.SelectExperimentID([]string{"system.scalarm.com"}, getHttpClientMock(server.URL), executedExperiments)
+
+	// === THEN ===
+	if experimentID != "b" {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentID, "b")
+	}
+
+	// === WHEN ===
+	executedExperiments.PushBack("b")
+	experimentID = sim.SelectExperimentID([]string{"system.scalarm.com"}, getHttpClientMock(server.URL), executedExperiments)
+
+	// === THEN ===
+	if experimentID != "" {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentID, "")
+	}
+}
+
+func TestSelectExperimentIDShouldAskForRandomExperimentWhenListingIsNotSupported(t *testing.T) {
+	// === GIVEN ===
+	server := setupExperimentListServer(false)
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig()}
+
+	// === WHEN ===
+	experimentID := sim.SelectExperimentID([]string{"system.scalarm.com"}, getHttpClientMock(server.URL), list.New())
+
+	// === THEN ===
+	if experimentID != "d" {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentID, "d")
+	}
+}
@@ -0,0 +1,69 @@
+package scalarmWorker
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// PauseOnFailure keeps the simulation directory of a failed adapter intact and lets the user inspect it:
+// an interactive shell is opened in the directory when SiM runs in a terminal, otherwise
+// SiM waits for an interrupt signal
+func PauseOnFailure(adapter string, cmd *exec.Cmd) {
+	signals := pauseSignals()
+	defer stopPauseSignals(signals)
+
+	simLog.Infof("Pausing after '%s' failure, the simulation directory is kept intact for inspection", adapter)
+	simLog.Infof("Simulation directory: %s", cmd.Dir)
+	simLog.Infof("To reproduce the failure execute: cd \"%s\" && %s", cmd.Dir, strings.Join(cmd.Args, " "))
+
+	if stdinInfo, err := os.Stdin.Stat(); err == nil && stdinInfo.Mode()&os.ModeCharDevice != 0 {
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = defaultShell()
+		}
+
+		simLog.Infof("Opening '%s' in the simulation directory, exit the shell to terminate SiM", shell)
+		shellCmd := exec.Command(shell)
+		shellCmd.Dir = cmd.Dir
+		shellCmd.Stdin = os.Stdin
+		shellCmd.Stdout = os.Stdout
+		shellCmd.Stderr = os.Stderr
+
+		if err = shellCmd.Run(); err != nil {
+			simLog.Warnf("Shell finished with an error - %v", err)
+		}
+		return
+	}
+
+	simLog.Infof("Press Ctrl+C (or send SIGTERM) to terminate SiM")
+	<-signals
+}
+
+// pauseSignals marks SiM as paused and returns the channel of signals received during the pause, they are passed
+// to it by handleShutdownSignals when it runs, in a terminal they are also sent to the shell
+func pauseSignals() chan os.Signal {
+	signals := make(chan os.Signal, 1)
+
+	failurePause.mutex.Lock()
+	defer failurePause.mutex.Unlock()
+
+	if failurePause.handled {
+		failurePause.pauses[signals] = struct{}{}
+	} else {
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	}
+
+	return signals
+}
+
+// stopPauseSignals ends the pause started by pauseSignals
+func stopPauseSignals(signals chan os.Signal) {
+	failurePause.mutex.Lock()
+	defer failurePause.mutex.Unlock()
+
+	delete(failurePause.pauses, signals)
+	signal.Stop(signals)
+}
@@ -0,0 +1,63 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPauseOnFailureShouldWaitForSignalWithoutTerminal(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "pause")
+	defer os.RemoveAll(dir)
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	os.Stdin, _ = ioutil.TempFile(dir, "stdin")
+
+	// SIGTERM sent before the pause waits for it does not terminate the test
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGTERM)
+	defer signal.Stop(guard)
+
+	cmd := exec.Command("./executor", "input.json")
+	cmd.Dir = dir
+
+	finished := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-finished:
+				return
+			case <-time.After(50 * time.Millisecond):
+				syscall.Kill(os.Getpid(), syscall.SIGTERM)
+			}
+		}
+	}()
+
+	// === WHEN ===
+	output := captureLog(t, func() {
+		PauseOnFailure("executor", cmd)
+	})
+	close(finished)
+
+	// === THEN ===
+	for _, line := range []string{
+		"Pausing after 'executor' failure",
+		"Simulation directory: " + dir,
+		"To reproduce the failure execute: cd \"" + dir + "\" && ./executor input.json",
+		"Press Ctrl+C (or send SIGTERM) to terminate SiM",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("Got: '%v' - Expected '%v'", output, line)
+		}
+	}
+	if strings.Contains(output, "Opening") {
+		t.Errorf("Got: '%v' - Expected no shell without a terminal", output)
+	}
+}
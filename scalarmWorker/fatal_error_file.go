@@ -0,0 +1,166 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// file written into the directory SiM was started in when it exits after a fatal error
+const fatalErrorFile = "error.json"
+
+// classes of fatal errors written to error.json
+const (
+	FatalErrorConfig     = "config"
+	FatalErrorNetwork    = "network"
+	FatalErrorService    = "service"
+	FatalErrorAdapter    = "adapter"
+	FatalErrorFilesystem = "filesystem"
+	FatalErrorInternal   = "internal"
+)
+
+// directory and time SiM was started in and at
+var (
+	startDir, _ = os.Getwd()
+	startedAt   = time.Now()
+)
+
+// FatalErrorReport is the content of error.json, wrappers of SiM read it to decide whether to resubmit the job
+type FatalErrorReport struct {
+	Class           string `json:"class"`
+	Message         string `json:"message"`
+	Retryable       bool   `json:"retryable"`
+	Phase           string `json:"phase,omitempty"`
+	ExperimentID    string `json:"experiment_id,omitempty"`
+	SimulationIndex int    `json:"simulation_id,omitempty"`
+	ExitCode        int    `json:"exit_code"`
+	StartedAt       string `json:"started_at"`
+	FailedAt        string `json:"failed_at"`
+}
+
+// AdapterFailure is the error of an adapter script which failed during a simulation run
+type AdapterFailure struct {
+	Adapter         string
+	ExperimentID    string
+	SimulationIndex int
+	Err             error
+}
+
+func (failure *AdapterFailure) Error() string {
+	return fmt.Sprintf("'%s' of simulation run %d of experiment %s failed: %v", failure.Adapter,
+		failure.SimulationIndex, failure.ExperimentID, failure.Err)
+}
+
+func (failure *AdapterFailure) Unwrap() error {
+	return failure.Err
+}
+
+// setCurrentPhase records the phase of the worker outside of simulation runs, e.g. code_base, reported when SiM fails in it
+func setCurrentPhase(phase string) {
+	currentRuns.mutex.Lock()
+	defer currentRuns.mutex.Unlock()
+
+	currentRuns.phase = phase
+}
+
+// NewFatalErrorReport describes the error which terminates SiM with exitCode in the current phase and simulation run
+func NewFatalErrorReport(err error, exitCode int) *FatalErrorReport {
+	report := &FatalErrorReport{
+		Message:   Redact(err.Error()),
+		ExitCode:  exitCode,
+		StartedAt: startedAt.UTC().Format(time.RFC3339),
+		FailedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	report.Class, report.Retryable = classifyFatalError(err)
+
+	currentRuns.mutex.Lock()
+	report.Phase = currentRuns.phase
+	currentRuns.mutex.Unlock()
+
+	// with concurrent simulation runs only an adapter failure tells which of them SiM failed in
+	if runs := listCurrentRuns(); len(runs) == 1 {
+		report.Phase = runs[0].phase
+		report.ExperimentID = runs[0].experimentID
+		report.SimulationIndex = runs[0].simulationIndex
+	}
+
+	// errors before the worker loop starts come from the config file and the command line
+	if report.Phase == "" {
+		report.Phase = "startup"
+		if report.Class == FatalErrorInternal {
+			report.Class = FatalErrorConfig
+		}
+	}
+
+	var adapterFailure *AdapterFailure
+	if errors.As(err, &adapterFailure) {
+		report.Phase = adapterFailure.Adapter
+		report.ExperimentID = adapterFailure.ExperimentID
+		report.SimulationIndex = adapterFailure.SimulationIndex
+	}
+
+	return report
+}
+
+// classifyFatalError tells the class of the error and whether resubmitting the job may help
+func classifyFatalError(err error) (string, bool) {
+	var adapterFailure *AdapterFailure
+	var requestErr *RequestError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var pathErr *os.PathError
+
+	message := err.Error()
+
+	switch {
+	case errors.As(err, &adapterFailure):
+		return FatalErrorAdapter, false
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return FatalErrorConfig, false
+	case errors.As(err, &requestErr), ClassifyFailure(err) != FailureOther:
+		return FatalErrorNetwork, !isPermanentFailure(err)
+	case strings.HasPrefix(message, "Experiment manager response code") ||
+		strings.HasPrefix(message, "Storage manager response code") ||
+		strings.HasPrefix(message, "Information service response code"):
+		return FatalErrorService, isRetryableResponse(message)
+	case errors.As(err, &pathErr):
+		return FatalErrorFilesystem, isRecoverable(err)
+	}
+
+	return FatalErrorInternal, false
+}
+
+// isRetryableResponse tells whether a service may answer differently later, i.e. its response code is
+// a server error, a timeout or a rate limit; other client errors, e.g. 403 or 404, repeat after a resubmission
+func isRetryableResponse(message string) bool {
+	code, err := strconv.Atoi(strings.TrimSpace(message[strings.LastIndex(message, ":")+1:]))
+	if err != nil {
+		return true
+	}
+
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// writeFatalErrorFile writes error.json into the directory SiM was started in
+func writeFatalErrorFile(err error, exitCode int) {
+	content, marshalErr := json.MarshalIndent(NewFatalErrorReport(err, exitCode), "", "  ")
+	if marshalErr != nil {
+		return
+	}
+
+	if writeErr := ioutil.WriteFile(filepath.Join(startDir, fatalErrorFile), append(content, '\n'), 0644); writeErr != nil {
+		simLog.Warnf("Could not write %s: %v", fatalErrorFile, writeErr)
+	}
+}
+
+// clearFatalErrorFile removes error.json left by a previous execution of SiM
+func clearFatalErrorFile() {
+	os.Remove(filepath.Join(startDir, fatalErrorFile))
+}
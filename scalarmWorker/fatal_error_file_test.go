@@ -0,0 +1,92 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyFatalErrorShouldTellWhetherResubmittingMayHelp(t *testing.T) {
+	// === GIVEN ===
+	var syntaxErr error = json.Unmarshal([]byte("{"), &map[string]interface{}{})
+	cases := []struct {
+		err       error
+		class     string
+		retryable bool
+	}{
+		{&AdapterFailure{"executor", "e1", 3, errors.New("exit status 2")}, FatalErrorAdapter, false},
+		{syntaxErr, FatalErrorConfig, false},
+		{errors.New("Experiment manager response code: 500"), FatalErrorService, true},
+		{errors.New("Experiment manager response code: 429"), FatalErrorService, true},
+		{errors.New("Storage manager response code: 403"), FatalErrorService, false},
+		{errors.New("Information service response code: 404"), FatalErrorService, false},
+		{&os.PathError{Op: "open", Path: "input.json", Err: os.ErrPermission}, FatalErrorFilesystem, false},
+		{errors.New("Unexpected"), FatalErrorInternal, false},
+	}
+
+	for _, c := range cases {
+		// === WHEN ===
+		class, retryable := classifyFatalError(c.err)
+
+		// === THEN ===
+		if class != c.class || retryable != c.retryable {
+			t.Errorf("Got: '%v, %v' - Expected '%v, %v'", class, retryable, c.class, c.retryable)
+		}
+	}
+}
+
+func TestWriteFatalErrorFileShouldDescribeTheFailedSimulationRun(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "fatal_error")
+	defer os.RemoveAll(dir)
+
+	previousStartDir := startDir
+	startDir = dir
+	defer func() { startDir = previousStartDir }()
+
+	setCurrentPhase("executor")
+	defer setCurrentPhase("")
+
+	// === WHEN ===
+	writeFatalErrorFile(&AdapterFailure{"output_reader", "e1", 3, errors.New("exit status 2")}, 2)
+
+	// === THEN ===
+	report := FatalErrorReport{}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, fatalErrorFile))
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Got: '%v' - Expected '%v'", err, nil)
+	}
+
+	if report.Class != FatalErrorAdapter || report.Phase != "output_reader" {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", report.Class, report.Phase, FatalErrorAdapter, "output_reader")
+	}
+
+	if report.ExperimentID != "e1" || report.SimulationIndex != 3 || report.ExitCode != 2 {
+		t.Errorf("Got: '%+v' - Expected '%v'", report, "simulation run 3 of e1 with exit code 2")
+	}
+
+	if report.StartedAt == "" || report.FailedAt == "" {
+		t.Errorf("Got: '%v, %v' - Expected '%v'", report.StartedAt, report.FailedAt, "timestamps")
+	}
+
+	// === WHEN ===
+	clearFatalErrorFile()
+
+	// === THEN ===
+	if _, err := os.Stat(filepath.Join(dir, fatalErrorFile)); !os.IsNotExist(err) {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "error.json removed")
+	}
+}
+
+func TestNewFatalErrorReportShouldTreatStartupErrorsAsConfigErrors(t *testing.T) {
+	// === WHEN ===
+	report := NewFatalErrorReport(errors.New("Could not open file config.json."), 1)
+
+	// === THEN ===
+	if report.Class != FatalErrorConfig || report.Phase != "startup" || report.Retryable {
+		t.Errorf("Got: '%+v' - Expected '%v'", report, "non-retryable config error at startup")
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"os"
+	"syscall"
+)
+
+// sameFilesystem tells whether both paths are on the same device
+func sameFilesystem(path1 string, path2 string) bool {
+	info1, err1 := os.Stat(path1)
+	info2, err2 := os.Stat(path2)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	stat1, ok1 := info1.Sys().(*syscall.Stat_t)
+	stat2, ok2 := info2.Sys().(*syscall.Stat_t)
+
+	return ok1 && ok2 && uint64(stat1.Dev) == uint64(stat2.Dev)
+}
+
+// ownedByCurrentUser tells whether the file belongs to the user running SiM
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	return ok && int(stat.Uid) == os.Getuid()
+}
@@ -0,0 +1,13 @@
+package scalarmWorker
+
+import "os"
+
+// devices are not compared on Windows, code bases from the store are always copied
+func sameFilesystem(path1 string, path2 string) bool {
+	return false
+}
+
+// owners are not checked on Windows, where the temporary directory belongs to the user anyway
+func ownedByCurrentUser(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on lockPath, waiting until other processes release it;
+// the returned function releases the lock
+func lockFile(lockPath string) (func(), error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,6 @@
+package scalarmWorker
+
+// files are not locked on Windows, workers sharing a directory may download the same file twice
+func lockFile(lockPath string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,103 @@
+package scalarmWorker
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+// FileUpload is a request body which streams a file as multipart form data straight from disk,
+// without buffering the whole file in memory; ExecuteScalarmRequest opens a new stream for
+// every attempt of sending the request and sends it with chunked transfer encoding
+type FileUpload struct {
+	FilePath string
+	// the file is sent gzipped as '<name>.gz' with 'Content-Encoding: gzip' in the headers of the part
+	Compress bool
+	boundary string
+	stream   io.ReadCloser
+}
+
+// NewFileUpload prepares an upload of the file under filePath as the 'file' form field
+func NewFileUpload(filePath string) *FileUpload {
+	return &FileUpload{
+		FilePath: filePath,
+		boundary: multipart.NewWriter(nil).Boundary(),
+	}
+}
+
+// ContentType returns the multipart content type with the boundary used by all streams of this upload
+func (upload *FileUpload) ContentType() string {
+	return "multipart/form-data; boundary=" + upload.boundary
+}
+
+// Open starts a new stream of the multipart body, the file is copied into the stream by a goroutine
+// which stops when the stream is read till the end or closed
+func (upload *FileUpload) Open() (io.ReadCloser, error) {
+	file, err := os.Open(upload.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	multipartWriter := multipart.NewWriter(writer)
+	multipartWriter.SetBoundary(upload.boundary)
+
+	go func() {
+		defer file.Close()
+
+		var part io.Writer
+		var err error
+		if upload.Compress {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s.gz"`, filepath.Base(upload.FilePath)))
+			header.Set("Content-Type", "application/gzip")
+			header.Set("Content-Encoding", "gzip")
+			part, err = multipartWriter.CreatePart(header)
+		} else {
+			part, err = multipartWriter.CreateFormFile("file", filepath.Base(upload.FilePath))
+		}
+
+		if err == nil && upload.Compress {
+			gzipWriter := gzip.NewWriter(part)
+			if _, err = copyWithPooledBuffer(gzipWriter, file); err == nil {
+				err = gzipWriter.Close()
+			}
+		} else if err == nil {
+			_, err = copyWithPooledBuffer(part, file)
+		}
+		if err == nil {
+			err = multipartWriter.Close()
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	return reader, nil
+}
+
+// Read allows FileUpload to be used as a plain io.Reader, the stream is opened on the first read
+func (upload *FileUpload) Read(p []byte) (int, error) {
+	if upload.stream == nil {
+		stream, err := upload.Open()
+		if err != nil {
+			return 0, err
+		}
+		upload.stream = stream
+	}
+
+	return upload.stream.Read(p)
+}
+
+// isTextArtifact tells if a file is a log or a report which is worth compressing before upload
+func isTextArtifact(filePath string) bool {
+	switch filepath.Ext(filePath) {
+	case ".txt", ".log", ".json", ".csv", ".out":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package scalarmWorker
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileUploadShouldStreamFileAsMultipartForm(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_upload.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("simulation output"), 0666)
+
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil || header.Filename != "test_upload.txt" || r.Method != "PUT" {
+			w.WriteHeader(500)
+			return
+		}
+		content, _ := ioutil.ReadAll(file)
+		received = string(content)
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	upload := NewFileUpload(filePath)
+	reqInfo := RequestInfo{"PUT", upload, upload.ContentType(), "experiments/1/simulations/1"}
+
+	// === WHEN ===
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{"system.scalarm.com"}, getSimConfig(), getHttpClientMock(server.URL), 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 || received != "simulation output" {
+		t.Errorf("Got: '%v' (%v) - Expected '%v'", received, resp.StatusCode, "simulation output")
+	}
+}
+
+func TestFileUploadShouldOpenIndependentStreams(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_upload.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("simulation output"), 0666)
+
+	upload := NewFileUpload(filePath)
+
+	// === WHEN ===
+	first, _ := upload.Open()
+	firstContent, _ := ioutil.ReadAll(first)
+	second, _ := upload.Open()
+	secondContent, _ := ioutil.ReadAll(second)
+
+	// === THEN ===
+	if len(firstContent) == 0 || string(firstContent) != string(secondContent) {
+		t.Errorf("Got: '%s' - Expected '%s'", secondContent, firstContent)
+	}
+}
+
+func TestFileUploadShouldSendCompressedFile(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_upload.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("simulation output"), 0666)
+
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil || header.Filename != "test_upload.txt.gz" || header.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(500)
+			return
+		}
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		content, _ := ioutil.ReadAll(reader)
+		received = string(content)
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	upload := NewFileUpload(filePath)
+	upload.Compress = true
+	reqInfo := RequestInfo{"PUT", upload, upload.ContentType(), "experiments/1/simulations/1/stdout"}
+
+	// === WHEN ===
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{"system.scalarm.com"}, getSimConfig(), getHttpClientMock(server.URL), 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 || received != "simulation output" {
+		t.Errorf("Got: '%v' (%v) - Expected '%v'", received, resp.StatusCode, "simulation output")
+	}
+}
+
+func TestFileUploadShouldBeSentWithChunkedEncoding(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_upload_chunked.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, make([]byte, 4*1024*1024), 0666)
+
+	var contentLength int64
+	var transferEncoding []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength, transferEncoding = r.ContentLength, r.TransferEncoding
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	upload := NewFileUpload(filePath)
+	reqInfo := RequestInfo{"PUT", upload, upload.ContentType(), "experiments/1/simulations/1"}
+
+	// === WHEN ===
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{"system.scalarm.com"}, getSimConfig(), getHttpClientMock(server.URL), 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	// a body built in memory would be sent with its length
+	if contentLength != -1 || len(transferEncoding) != 1 || transferEncoding[0] != "chunked" {
+		t.Errorf("Got: '%v, %v' - Expected '%v'", contentLength, transferEncoding, "a chunked stream of unknown length")
+	}
+}
@@ -0,0 +1,322 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// directories with the CPU and NUMA topology exported by Linux
+var (
+	cpuSysfsDir  = "/sys/devices/system/cpu"
+	nodeSysfsDir = "/sys/devices/system/node"
+)
+
+// FleetConfig describes workers launched by a single agent and CPUs each of them is pinned to
+type FleetConfig struct {
+	// number of workers, by default all online CPUs divided by cores_per_worker
+	Workers int `json:"workers"`
+	// number of CPUs of every worker, by default all online CPUs divided by workers
+	CoresPerWorker int `json:"cores_per_worker"`
+	// generated slots do not cross NUMA nodes and memory of workers is bound to the node of their CPUs
+	NUMA bool `json:"numa"`
+	// base of per-worker scratch directories, e.g. a tmpfs like /dev/shm
+	ScratchDir string `json:"scratch_dir"`
+	// GPU devices of GPU slots, e.g. ["0", "1"] makes the first two workers GPU slots and the rest CPU slots
+	GPUs []string `json:"gpus"`
+	// explicit CPU sets, when given workers and cores_per_worker are ignored
+	Slots []FleetSlot `json:"slots"`
+}
+
+// FleetSlot is a set of CPUs of a single worker in the taskset/cpuset list format, e.g. "0-3,64-67",
+// optionally with a NUMA node to which memory of the worker is bound; a slot of the 'gpu' kind
+// executes only simulation runs requiring GPUs, a 'cpu' slot only the other ones
+type FleetSlot struct {
+	CPUs       string `json:"cpus"`
+	Node       *int   `json:"node,omitempty"`
+	ScratchDir string `json:"scratch_dir,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	GPUs       string `json:"gpus,omitempty"`
+}
+
+// CPU is a logical processor of the node
+type CPU struct {
+	ID      int
+	Package int
+	Core    int
+	Node    int
+}
+
+// LoadFleetConfig reads a fleet config file
+func LoadFleetConfig(filePath string) (*FleetConfig, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &FleetConfig{}
+	if err = json.Unmarshal(content, fleet); err != nil {
+		return nil, errors.New("Incorrect JSON in " + filePath + ": " + err.Error())
+	}
+
+	return fleet, nil
+}
+
+// DetectCPUs lists online CPUs ordered by NUMA node, socket and physical core, so hyper-threads of a core
+// are next to each other; without the Linux topology CPUs are numbered from 0 to NumCPU-1
+func DetectCPUs() []CPU {
+	cpus := []CPU{}
+
+	online, err := ioutil.ReadFile(filepath.Join(cpuSysfsDir, "online"))
+	ids, parseErr := ParseCPUList(strings.TrimSpace(string(online)))
+	if err != nil || parseErr != nil || len(ids) == 0 {
+		for id := 0; id < runtime.NumCPU(); id++ {
+			cpus = append(cpus, CPU{ID: id, Core: id})
+		}
+		return cpus
+	}
+
+	nodes := detectNUMANodes()
+
+	for _, id := range ids {
+		topologyDir := filepath.Join(cpuSysfsDir, fmt.Sprintf("cpu%d", id), "topology")
+		cpus = append(cpus, CPU{
+			ID:      id,
+			Package: readTopologyValue(filepath.Join(topologyDir, "physical_package_id"), 0),
+			Core:    readTopologyValue(filepath.Join(topologyDir, "core_id"), id),
+			Node:    nodes[id],
+		})
+	}
+
+	sort.SliceStable(cpus, func(i, j int) bool {
+		if cpus[i].Node != cpus[j].Node {
+			return cpus[i].Node < cpus[j].Node
+		}
+		if cpus[i].Package != cpus[j].Package {
+			return cpus[i].Package < cpus[j].Package
+		}
+		if cpus[i].Core != cpus[j].Core {
+			return cpus[i].Core < cpus[j].Core
+		}
+		return cpus[i].ID < cpus[j].ID
+	})
+
+	return cpus
+}
+
+// detectNUMANodes maps CPU ids to their NUMA nodes, CPUs of machines without NUMA belong to node 0
+func detectNUMANodes() map[int]int {
+	nodes := map[int]int{}
+
+	nodeDirs, _ := filepath.Glob(filepath.Join(nodeSysfsDir, "node[0-9]*"))
+	for _, nodeDir := range nodeDirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(nodeDir), "node"))
+		if err != nil {
+			continue
+		}
+
+		cpuList, err := ioutil.ReadFile(filepath.Join(nodeDir, "cpulist"))
+		if err != nil {
+			continue
+		}
+
+		// memory-only nodes have an empty list
+		ids, _ := ParseCPUList(strings.TrimSpace(string(cpuList)))
+		for _, id := range ids {
+			nodes[id] = node
+		}
+	}
+
+	return nodes
+}
+
+func readTopologyValue(filePath string, defaultValue int) int {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// Plan returns CPU sets of workers, slots generated from cpus never share a physical core when
+// cores_per_worker is a multiple of the number of threads per core
+func (fleet *FleetConfig) Plan(cpus []CPU) ([]FleetSlot, error) {
+	slots := fleet.Slots
+
+	if len(slots) > 0 {
+		for _, slot := range slots {
+			if _, err := ParseCPUList(slot.CPUs); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		workers, coresPerWorker := fleet.Workers, fleet.CoresPerWorker
+		switch {
+		case workers <= 0 && coresPerWorker <= 0:
+			workers, coresPerWorker = len(cpus), 1
+		case workers <= 0:
+			workers = len(cpus) / coresPerWorker
+		case coresPerWorker <= 0:
+			coresPerWorker = len(cpus) / workers
+		}
+
+		if workers < 1 || coresPerWorker < 1 || workers*coresPerWorker > len(cpus) {
+			return nil, fmt.Errorf("%d workers with %d CPUs each do not fit into %d online CPUs", workers, coresPerWorker, len(cpus))
+		}
+
+		groups := [][]CPU{cpus}
+		if fleet.NUMA {
+			groups = groupByNode(cpus)
+		}
+
+		// workers are spread evenly over NUMA nodes, CPUs left at the end of a node are not used
+		candidates := make([][]FleetSlot, len(groups))
+		for i, group := range groups {
+			for start := 0; start+coresPerWorker <= len(group); start += coresPerWorker {
+				ids := []int{}
+				for _, cpu := range group[start : start+coresPerWorker] {
+					ids = append(ids, cpu.ID)
+				}
+
+				slot := FleetSlot{CPUs: FormatCPUList(ids)}
+				if fleet.NUMA {
+					node := group[start].Node
+					slot.Node = &node
+				}
+				candidates[i] = append(candidates[i], slot)
+			}
+		}
+
+		// by default the node is filled with as many whole slots as fit into its NUMA nodes
+		if fleet.Workers <= 0 {
+			workers = 0
+			for _, nodeSlots := range candidates {
+				workers += len(nodeSlots)
+			}
+		}
+
+		for round := 0; len(slots) < workers; round++ {
+			added := false
+			for _, nodeSlots := range candidates {
+				if round < len(nodeSlots) && len(slots) < workers {
+					slots = append(slots, nodeSlots[round])
+					added = true
+				}
+			}
+
+			if !added {
+				return nil, fmt.Errorf("%d workers with %d CPUs each do not fit into NUMA nodes of the node", workers, coresPerWorker)
+			}
+		}
+	}
+
+	if len(fleet.GPUs) > len(slots) {
+		return nil, fmt.Errorf("%d GPU slots do not fit into %d workers", len(fleet.GPUs), len(slots))
+	}
+
+	planned := []FleetSlot{}
+	for worker, slot := range slots {
+		if fleet.ScratchDir != "" && slot.ScratchDir == "" {
+			slot.ScratchDir = filepath.Join(fleet.ScratchDir, fmt.Sprintf("worker_%d", worker+1))
+		}
+
+		if len(fleet.GPUs) > 0 && slot.Kind == "" {
+			slot.Kind = CPUSlot
+			if worker < len(fleet.GPUs) {
+				slot.Kind, slot.GPUs = GPUSlot, fleet.GPUs[worker]
+			}
+		}
+
+		if slot.Kind != "" && slot.Kind != CPUSlot && slot.Kind != GPUSlot {
+			return nil, errors.New("Unknown kind of slot '" + slot.Kind + "'")
+		}
+		planned = append(planned, slot)
+	}
+	slots = planned
+
+	return slots, nil
+}
+
+// groupByNode splits CPUs ordered by DetectCPUs into NUMA nodes
+func groupByNode(cpus []CPU) [][]CPU {
+	groups := [][]CPU{}
+
+	for i, cpu := range cpus {
+		if i == 0 || cpu.Node != cpus[i-1].Node {
+			groups = append(groups, []CPU{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], cpu)
+	}
+
+	return groups
+}
+
+// ParseCPUList parses a CPU list like "0-3,8,10-11"
+func ParseCPUList(list string) ([]int, error) {
+	ids := []int{}
+
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, errors.New("Invalid CPU list '" + list + "'")
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, errors.New("Invalid CPU list '" + list + "'")
+			}
+		}
+
+		for id := first; id <= last; id++ {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("Empty CPU list")
+	}
+
+	return ids, nil
+}
+
+// FormatCPUList formats CPU ids as a list with ranges, e.g. "0-3,8"
+func FormatCPUList(ids []int) string {
+	sorted := append([]int{}, ids...)
+	sort.Ints(sorted)
+
+	parts := []string{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+
+		if i == j {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,139 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectCPUsShouldOrderHyperThreadsOfACoreTogether(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "cpu")
+	defer os.RemoveAll(dir)
+
+	oldSysfsDir := cpuSysfsDir
+	cpuSysfsDir = dir
+	defer func() { cpuSysfsDir = oldSysfsDir }()
+
+	// 2 cores with 2 threads each, the second thread of every core is numbered after all cores
+	ioutil.WriteFile(filepath.Join(dir, "online"), []byte("0-3\n"), 0644)
+	for id, core := range []int{0, 1, 0, 1} {
+		topologyDir := filepath.Join(dir, fmt.Sprintf("cpu%d", id), "topology")
+		os.MkdirAll(topologyDir, 0755)
+		ioutil.WriteFile(filepath.Join(topologyDir, "core_id"), []byte(fmt.Sprintf("%d\n", core)), 0644)
+		ioutil.WriteFile(filepath.Join(topologyDir, "physical_package_id"), []byte("0\n"), 0644)
+	}
+
+	// === WHEN ===
+	slots, err := (&FleetConfig{CoresPerWorker: 2}).Plan(DetectCPUs())
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	expected := []FleetSlot{{CPUs: "0,2"}, {CPUs: "1,3"}}
+	if !reflect.DeepEqual(slots, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", slots, expected)
+	}
+}
+
+func TestFleetPlanShouldRejectWorkersWhichDoNotFit(t *testing.T) {
+	// === GIVEN ===
+	cpus := []CPU{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}}
+
+	// === WHEN ===
+	_, err := (&FleetConfig{Workers: 3, CoresPerWorker: 2}).Plan(cpus)
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
+
+func TestCPUListShouldBeParsedAndFormattedWithRanges(t *testing.T) {
+	// === WHEN ===
+	ids, err := ParseCPUList("8,0-3,10-11")
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if list := FormatCPUList(ids); list != "0-3,8,10-11" {
+		t.Errorf("Got: '%v' - Expected '%v'", list, "0-3,8,10-11")
+	}
+
+	if _, err = ParseCPUList("3-1"); err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
+
+func TestFleetPlanShouldSpreadWorkersOverNUMANodes(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "cpu")
+	defer os.RemoveAll(dir)
+
+	oldCPUSysfsDir, oldNodeSysfsDir := cpuSysfsDir, nodeSysfsDir
+	cpuSysfsDir, nodeSysfsDir = filepath.Join(dir, "cpu"), filepath.Join(dir, "node")
+	defer func() { cpuSysfsDir, nodeSysfsDir = oldCPUSysfsDir, oldNodeSysfsDir }()
+
+	// 2 nodes with 3 CPUs each
+	os.MkdirAll(cpuSysfsDir, 0755)
+	ioutil.WriteFile(filepath.Join(cpuSysfsDir, "online"), []byte("0-5\n"), 0644)
+	for node, cpuList := range []string{"0-2", "3-5"} {
+		nodeDir := filepath.Join(nodeSysfsDir, fmt.Sprintf("node%d", node))
+		os.MkdirAll(nodeDir, 0755)
+		ioutil.WriteFile(filepath.Join(nodeDir, "cpulist"), []byte(cpuList+"\n"), 0644)
+	}
+
+	fleet := &FleetConfig{CoresPerWorker: 2, NUMA: true, ScratchDir: "/dev/shm/scalarm"}
+
+	// === WHEN ===
+	slots, err := fleet.Plan(DetectCPUs())
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	// the third CPU of every node would be shared by two nodes, so it is left unused
+	if len(slots) != 2 {
+		t.Errorf("Got: '%v' - Expected 2 slots", slots)
+		return
+	}
+
+	for i, expected := range []string{"0-1", "3-4"} {
+		if slots[i].CPUs != expected || slots[i].Node == nil || *slots[i].Node != i {
+			t.Errorf("Got: '%+v' - Expected CPUs '%v' on node %v", slots[i], expected, i)
+		}
+
+		if scratchDir := filepath.Join("/dev/shm/scalarm", fmt.Sprintf("worker_%d", i+1)); slots[i].ScratchDir != scratchDir {
+			t.Errorf("Got: '%v' - Expected '%v'", slots[i].ScratchDir, scratchDir)
+		}
+	}
+}
+
+func TestFleetPlanShouldTurnFirstWorkersIntoGPUSlots(t *testing.T) {
+	// === GIVEN ===
+	cpus := []CPU{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}}
+	fleet := &FleetConfig{Workers: 3, CoresPerWorker: 1, GPUs: []string{"0"}}
+
+	// === WHEN ===
+	slots, err := fleet.Plan(cpus)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	expected := []FleetSlot{{CPUs: "0", Kind: GPUSlot, GPUs: "0"}, {CPUs: "1", Kind: CPUSlot}, {CPUs: "2", Kind: CPUSlot}}
+	if !reflect.DeepEqual(slots, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", slots, expected)
+	}
+}
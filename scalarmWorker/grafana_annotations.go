@@ -0,0 +1,168 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var grafanaLog = NewLogger("grafana")
+
+// events shown on Grafana dashboards, other events are too frequent to be useful there
+var annotatedEvents = map[string]bool{
+	"worker_started":          true,
+	"worker_stopped":          true,
+	"worker_drained":          true,
+	"simulation_run_started":  true,
+	"simulation_run_finished": true,
+	"adapter_failed":          true,
+}
+
+// max number of annotations waiting to be posted, further ones are dropped while Grafana is slow
+const annotationsQueueSize = 64
+
+// time Grafana has to accept an annotation, and to accept pending ones when SiM exits
+var annotationTimeout = 5 * time.Second
+
+// GrafanaAnnotations posts lifecycle events of the worker to the annotations API of Grafana in the background,
+// so cluster metrics can be correlated with what the workers were doing at the time
+type GrafanaAnnotations struct {
+	Url          string
+	ApiKey       string
+	DashboardUID string
+	Hostname     string
+	HttpClient   *http.Client
+
+	queue  chan map[string]interface{}
+	sent   chan struct{}
+	closed bool
+	mutex  sync.Mutex
+}
+
+// NewGrafanaAnnotations creates annotations for Grafana from the config, nil when it is not configured
+func NewGrafanaAnnotations(config *SimulationManagerConfig) *GrafanaAnnotations {
+	if config.GrafanaUrl == "" {
+		return nil
+	}
+
+	RegisterSecret(config.GrafanaApiKey)
+	hostname, _ := os.Hostname()
+
+	annotations := &GrafanaAnnotations{
+		Url:          strings.TrimSuffix(config.GrafanaUrl, "/"),
+		ApiKey:       config.GrafanaApiKey,
+		DashboardUID: config.GrafanaDashboardUID,
+		Hostname:     hostname,
+		HttpClient:   &http.Client{Timeout: annotationTimeout},
+		queue:        make(chan map[string]interface{}, annotationsQueueSize),
+		sent:         make(chan struct{}),
+	}
+	go annotations.send()
+
+	return annotations
+}
+
+// send posts queued annotations one by one, in order of events, until the queue is closed
+func (annotations *GrafanaAnnotations) send() {
+	defer close(annotations.sent)
+
+	for annotation := range annotations.queue {
+		if err := annotations.post(annotation); err != nil {
+			grafanaLog.Warnf("Could not annotate '%v': %v", annotation["text"], Redact(err.Error()))
+		}
+	}
+}
+
+// Close waits until queued annotations are posted, at most for annotationTimeout; nil annotations are ignored
+func (annotations *GrafanaAnnotations) Close() {
+	if annotations == nil {
+		return
+	}
+
+	annotations.mutex.Lock()
+	if !annotations.closed {
+		annotations.closed = true
+		close(annotations.queue)
+	}
+	annotations.mutex.Unlock()
+
+	select {
+	case <-annotations.sent:
+	case <-time.After(annotationTimeout):
+		grafanaLog.Warnf("Some annotations were not posted in %v", annotationTimeout)
+	}
+}
+
+// Annotate queues an annotation of the event without waiting for Grafana, errors are only logged;
+// nil annotations ignore all events
+func (annotations *GrafanaAnnotations) Annotate(eventType string, experimentID string, simulationIndex int) {
+	if annotations == nil || !annotatedEvents[eventType] {
+		return
+	}
+
+	tags := []string{"scalarm", eventType, "host:" + annotations.Hostname}
+	text := fmt.Sprintf("%s on %s", eventType, annotations.Hostname)
+	if experimentID != "" {
+		tags = append(tags, "experiment:"+experimentID)
+		text += ", experiment " + experimentID
+	}
+	if simulationIndex > 0 {
+		text += ", simulation run " + strconv.Itoa(simulationIndex)
+	}
+
+	annotation := map[string]interface{}{
+		"time": time.Now().UnixNano() / int64(time.Millisecond),
+		"tags": tags,
+		"text": text,
+	}
+	if annotations.DashboardUID != "" {
+		annotation["dashboardUID"] = annotations.DashboardUID
+	}
+
+	// events emitted while SiM exits are not annotated once the queue is closed
+	annotations.mutex.Lock()
+	defer annotations.mutex.Unlock()
+	if annotations.closed {
+		return
+	}
+
+	select {
+	case annotations.queue <- annotation:
+	default:
+		grafanaLog.Warnf("Too many annotations are waiting for Grafana, '%s' is dropped", eventType)
+	}
+}
+
+func (annotations *GrafanaAnnotations) post(annotation map[string]interface{}) error {
+	body, err := marshalJSON(annotation)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", annotations.Url+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if annotations.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+annotations.ApiKey)
+	}
+
+	resp, err := annotations.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Grafana response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
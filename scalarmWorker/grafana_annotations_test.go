@@ -0,0 +1,82 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGrafanaAnnotationsShouldPostTaggedLifecycleEvents(t *testing.T) {
+	// === GIVEN ===
+	requests := []map[string]interface{}{}
+	authorization := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/annotations" {
+			w.WriteHeader(404)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+
+		annotation := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&annotation)
+		requests = append(requests, annotation)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.GrafanaUrl = server.URL + "/"
+	config.GrafanaApiKey = "glsa_token"
+	config.GrafanaDashboardUID = "scalarm-fleet"
+	annotations := NewGrafanaAnnotations(config)
+
+	// === WHEN ===
+	annotations.Annotate("simulation_run_started", "568e5bece138232e76000002", 3)
+	annotations.Annotate("artifact_uploaded", "568e5bece138232e76000002", 3)
+	annotations.Close()
+
+	// === THEN ===
+	if len(requests) != 1 {
+		t.Errorf("Got: '%v' - Expected only the start of the run to be annotated", requests)
+		return
+	}
+
+	if authorization != "Bearer glsa_token" {
+		t.Errorf("Got: '%v' - Expected '%v'", authorization, "Bearer glsa_token")
+	}
+
+	if requests[0]["dashboardUID"] != "scalarm-fleet" {
+		t.Errorf("Got: '%v' - Expected '%v'", requests[0]["dashboardUID"], "scalarm-fleet")
+	}
+
+	tags, _ := requests[0]["tags"].([]interface{})
+	if len(tags) != 4 || tags[1] != "simulation_run_started" || tags[3] != "experiment:568e5bece138232e76000002" {
+		t.Errorf("Got: '%v' - Expected tags of the event", tags)
+	}
+}
+
+func TestGrafanaAnnotationsShouldNotWaitForGrafana(t *testing.T) {
+	// === GIVEN ===
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := getSimConfig()
+	config.GrafanaUrl = server.URL
+	annotations := NewGrafanaAnnotations(config)
+
+	// === WHEN ===
+	start := time.Now()
+	for i := 0; i < annotationsQueueSize+2; i++ {
+		annotations.Annotate("simulation_run_started", "568e5bece138232e76000002", i+1)
+	}
+
+	// === THEN ===
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Got: '%v' - Expected annotations queued without waiting", elapsed)
+	}
+}
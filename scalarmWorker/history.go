@@ -0,0 +1,256 @@
+package scalarmWorker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// pure Go SQLite driver, SiM is built without cgo
+	_ "modernc.org/sqlite"
+)
+
+var historyLog = NewLogger("history")
+
+// schema of the history database, every simulation run is recorded when it is fetched and once more when it finishes
+const historySchema = `CREATE TABLE IF NOT EXISTS history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	time INTEGER NOT NULL,
+	worker TEXT NOT NULL DEFAULT '',
+	event TEXT NOT NULL,
+	experiment_id TEXT NOT NULL,
+	simulation_id INTEGER NOT NULL,
+	status TEXT NOT NULL DEFAULT '',
+	reason_code TEXT NOT NULL DEFAULT '',
+	started_at INTEGER NOT NULL DEFAULT 0,
+	finished_at INTEGER NOT NULL DEFAULT 0,
+	phases TEXT NOT NULL DEFAULT '',
+	artifacts TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS history_experiment_id ON history (experiment_id)`
+
+// time workers of a node wait for each other when they write to the history at the same time
+const historyBusyTimeout = 10 * time.Second
+
+// HistoryEntry is a single row of the local history, a simulation run is recorded when it is fetched
+// and once more when it finishes
+type HistoryEntry struct {
+	Time            int64             `json:"time"`
+	Worker          string            `json:"worker,omitempty"`
+	Event           string            `json:"event"`
+	ExperimentID    string            `json:"experiment_id"`
+	SimulationIndex int               `json:"simulation_id"`
+	Status          string            `json:"status,omitempty"`
+	ReasonCode      string            `json:"reason_code,omitempty"`
+	StartedAt       int64             `json:"started_at,omitempty"`
+	FinishedAt      int64             `json:"finished_at,omitempty"`
+	Phases          []PhaseTiming     `json:"phases,omitempty"`
+	Artifacts       map[string]string `json:"artifacts,omitempty"`
+}
+
+// HistoryFilter selects entries returned by ReadHistory, zero values match everything
+type HistoryFilter struct {
+	ExperimentID string
+	Status       string
+	Since        time.Time
+	Limit        int
+}
+
+// History records executed simulation runs in a SQLite database shared by all workers of the node;
+// every entry is committed on its own, so the history survives crashes of single workers
+type History struct {
+	Path   string
+	Worker string
+
+	db      *sql.DB
+	openErr error
+	once    sync.Once
+}
+
+// HistoryPath returns the path of the history database, relative paths are resolved against the SiM directory;
+// an empty path means the history is not recorded
+func HistoryPath(config *SimulationManagerConfig, rootDirPath string) string {
+	if config.HistoryFile == "" {
+		return ""
+	}
+
+	if !filepath.IsAbs(config.HistoryFile) {
+		return filepath.Join(rootDirPath, config.HistoryFile)
+	}
+
+	return config.HistoryFile
+}
+
+// NewHistory creates a history stored in the given database, workers launched by an agent are identified by their number;
+// it is nil, recording nothing, when the path is empty
+func NewHistory(filePath string) *History {
+	if filePath == "" {
+		return nil
+	}
+
+	return &History{Path: filePath, Worker: os.Getenv("SCALARM_WORKER_ID")}
+}
+
+// openHistoryDatabase opens the database in filePath, creating its table if it does not exist yet
+func openHistoryDatabase(filePath string) (*sql.DB, error) {
+	// the path is escaped, "?" or "#" in it would start the query or fragment of the URI
+	dsn := url.URL{Scheme: "file", Path: filePath, RawQuery: fmt.Sprintf("_pragma=busy_timeout(%d)", historyBusyTimeout/time.Millisecond)}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// RecordFetched records a simulation run which has just been fetched
+func (history *History) RecordFetched(experimentID string, simulationIndex int) {
+	history.record(HistoryEntry{
+		Event:           "fetched",
+		ExperimentID:    experimentID,
+		SimulationIndex: simulationIndex,
+		StartedAt:       serverNow().Unix(),
+	})
+}
+
+// RecordFinished records timings, the status and uploaded artifacts of a finished simulation run
+func (history *History) RecordFinished(report *RunReport) {
+	finishedAt := report.FinishedAt
+	if finishedAt == 0 {
+		finishedAt = serverNow().Unix()
+	}
+
+	history.record(HistoryEntry{
+		Event:           "finished",
+		ExperimentID:    report.ExperimentID,
+		SimulationIndex: report.SimulationIndex,
+		Status:          report.Status,
+		ReasonCode:      report.ReasonCode,
+		StartedAt:       report.StartedAt,
+		FinishedAt:      finishedAt,
+		Phases:          report.Phases,
+		Artifacts:       report.Artifacts,
+	})
+}
+
+// record inserts an entry, errors are only logged; a nil history ignores all entries
+func (history *History) record(entry HistoryEntry) {
+	if history == nil {
+		return
+	}
+
+	history.once.Do(func() {
+		history.db, history.openErr = openHistoryDatabase(history.Path)
+	})
+	if history.openErr != nil {
+		historyLog.Warnf("Could not open %s: %v", history.Path, history.openErr)
+		return
+	}
+
+	phases, artifacts := "", ""
+	if len(entry.Phases) > 0 {
+		content, _ := marshalJSON(entry.Phases)
+		phases = string(content)
+	}
+	if len(entry.Artifacts) > 0 {
+		content, _ := marshalJSON(entry.Artifacts)
+		artifacts = string(content)
+	}
+
+	_, err := history.db.Exec(`INSERT INTO history (time, worker, event, experiment_id, simulation_id, status,
+		reason_code, started_at, finished_at, phases, artifacts) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		time.Now().Unix(), history.Worker, entry.Event, entry.ExperimentID, entry.SimulationIndex, entry.Status,
+		entry.ReasonCode, entry.StartedAt, entry.FinishedAt, phases, artifacts)
+	if err != nil {
+		historyLog.Warnf("Could not write to %s: %v", history.Path, err)
+	}
+}
+
+// Close closes the database of the history, if it was opened
+func (history *History) Close() {
+	if history != nil && history.db != nil {
+		history.db.Close()
+	}
+}
+
+// ReadHistory returns entries of the history database matching the filter, the most recent ones when limited
+func ReadHistory(filePath string, filter HistoryFilter) ([]HistoryEntry, error) {
+	// the database is not created by queries
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+
+	db, err := openHistoryDatabase(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if filter.ExperimentID != "" {
+		conditions = append(conditions, "experiment_id = ?")
+		args = append(args, filter.ExperimentID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+
+	limit := -1
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	args = append(args, limit)
+
+	// the most recent entries are selected and returned in the order they were recorded
+	rows, err := db.Query(`SELECT time, worker, event, experiment_id, simulation_id, status, reason_code, started_at,
+		finished_at, phases, artifacts FROM history WHERE `+strings.Join(conditions, " AND ")+` ORDER BY id DESC LIMIT ?`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		entry := HistoryEntry{}
+		phases, artifacts := "", ""
+		if err = rows.Scan(&entry.Time, &entry.Worker, &entry.Event, &entry.ExperimentID, &entry.SimulationIndex,
+			&entry.Status, &entry.ReasonCode, &entry.StartedAt, &entry.FinishedAt, &phases, &artifacts); err != nil {
+			return nil, err
+		}
+
+		if phases != "" {
+			json.Unmarshal([]byte(phases), &entry.Phases)
+		}
+		if artifacts != "" {
+			json.Unmarshal([]byte(artifacts), &entry.Artifacts)
+		}
+
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
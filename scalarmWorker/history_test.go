@@ -0,0 +1,135 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestHistoryShouldRecordFetchedAndFinishedRuns(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+
+	history := &History{Path: filepath.Join(dir, "history.db"), Worker: "2"}
+	defer history.Close()
+
+	failed := NewRunReport("568e5bece138232e76000002", 1, []byte(`{"x":1}`))
+	failed.Fail(ReasonExecutorFailed, "exit status 1")
+
+	succeeded := NewRunReport("568e5bece138232e76000002", 2, []byte(`{"x":2}`))
+	succeeded.Status = "ok"
+	succeeded.Artifacts["binary_results"] = "5ac4d1f0"
+
+	// === WHEN ===
+	history.RecordFetched("568e5bece138232e76000002", 1)
+	history.RecordFinished(failed)
+	history.RecordFetched("568e5bece138232e76000002", 2)
+	history.RecordFinished(succeeded)
+	history.RecordFetched("568e5bece138232e76000003", 1)
+
+	all, err := ReadHistory(history.Path, HistoryFilter{})
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	errors, _ := ReadHistory(history.Path, HistoryFilter{ExperimentID: "568e5bece138232e76000002", Status: "error"})
+	latest, _ := ReadHistory(history.Path, HistoryFilter{Limit: 2})
+
+	// === THEN ===
+	if len(all) != 5 {
+		t.Errorf("Got: '%v' - Expected '%v' entries", len(all), 5)
+	}
+
+	if len(errors) != 1 || errors[0].SimulationIndex != 1 || errors[0].ReasonCode != ReasonExecutorFailed || errors[0].Worker != "2" {
+		t.Errorf("Got: '%+v' - Expected the failed run 1", errors)
+	}
+
+	if len(latest) != 2 || latest[0].Artifacts["binary_results"] != "5ac4d1f0" || latest[1].Event != "fetched" {
+		t.Errorf("Got: '%+v' - Expected the 2 most recent entries", latest)
+	}
+}
+
+func TestHistoryShouldRecordRunsOfWorkersSharingTheDatabase(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+
+	historyPath := filepath.Join(dir, "history.db")
+	var wg sync.WaitGroup
+
+	// === WHEN ===
+	for worker := 1; worker <= 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			history := &History{Path: historyPath, Worker: strconv.Itoa(worker)}
+			defer history.Close()
+
+			for i := 0; i < 10; i++ {
+				history.RecordFetched("568e5bece138232e76000002", worker*100+i)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	entries, err := ReadHistory(historyPath, HistoryFilter{Limit: 100})
+
+	// === THEN ===
+	if err != nil || len(entries) != 40 {
+		t.Errorf("Got: '%v', '%v' - Expected '%v' entries", err, len(entries), 40)
+	}
+}
+
+func TestHistoryPathShouldBeResolvedAgainstRootDir(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+
+	// === WHEN ===
+	defaultPath := HistoryPath(config, "/opt/sim")
+	config.HistoryFile = "logs/history.db"
+	relativePath := HistoryPath(config, "/opt/sim")
+
+	// === THEN ===
+	if defaultPath != "" || NewHistory(defaultPath) != nil {
+		t.Errorf("Got: '%v' - Expected no history by default", defaultPath)
+	}
+
+	if relativePath != "/opt/sim/logs/history.db" {
+		t.Errorf("Got: '%v' - Expected '%v'", relativePath, "/opt/sim/logs/history.db")
+	}
+}
+
+func TestHistoryShouldBeStoredInPathWithUriCharacters(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+
+	runsDir := filepath.Join(dir, "runs?mode=ro#100%")
+	os.Mkdir(runsDir, 0755)
+
+	history := &History{Path: filepath.Join(runsDir, "history.db")}
+	defer history.Close()
+
+	// === WHEN ===
+	history.RecordFetched("568e5bece138232e76000002", 1)
+
+	entries, err := ReadHistory(history.Path, HistoryFilter{})
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Got: '%v' - Expected '%v' entries", len(entries), 1)
+	}
+
+	if _, err := os.Stat(history.Path); err != nil {
+		t.Errorf("Got: '%v' - Expected the database in '%v'", err, history.Path)
+	}
+}
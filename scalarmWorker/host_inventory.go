@@ -0,0 +1,179 @@
+package scalarmWorker
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pscpu "github.com/shirou/gopsutil/cpu"
+	pshost "github.com/shirou/gopsutil/host"
+	psmem "github.com/shirou/gopsutil/mem"
+)
+
+// max time of a single probe, e.g. 'docker --version' hanging on an unresponsive daemon
+var inventoryProbeTimeout = 5 * time.Second
+
+// container runtimes and interpreters looked up in PATH, their versions are reported by '--version'
+var (
+	inventoryContainerRuntimes = []string{"docker", "podman", "singularity", "apptainer", "ch-run", "enroot"}
+	inventoryInterpreters      = []string{"python3", "python", "Rscript", "julia", "java", "ruby", "perl", "node", "octave", "matlab"}
+)
+
+// GPU is a single GPU device reported by nvidia-smi
+type GPU struct {
+	Index         int    `json:"index"`
+	Name          string `json:"name"`
+	MemoryTotal   uint64 `json:"memory_total"` // in bytes
+	DriverVersion string `json:"driver_version"`
+}
+
+// HostInventory describes hardware and software of the node, collected once at startup
+type HostInventory struct {
+	HostInfo
+	Hostname          string            `json:"hostname"`
+	MemoryTotal       uint64            `json:"memory_total"` // in bytes
+	GPUs              []GPU             `json:"gpus"`
+	ContainerRuntimes map[string]string `json:"container_runtimes"`
+	Interpreters      map[string]string `json:"interpreters"`
+	Benchmark         *BenchmarkResult  `json:"benchmark,omitempty"`
+}
+
+// CollectHostInventory gathers the inventory of the node, parts which cannot be detected are left empty
+func CollectHostInventory() *HostInventory {
+	inventory := &HostInventory{
+		GPUs:              []GPU{},
+		ContainerRuntimes: map[string]string{},
+		Interpreters:      map[string]string{},
+	}
+
+	ps := PsUtil{getHostInfo: pshost.Info, getCPUInfo: pscpu.Info}
+	if hostInfo, err := ExtractHostInfo(&ps); err == nil {
+		inventory.HostInfo = *hostInfo
+	}
+	inventory.Timestamp = time.Now().Unix()
+	inventory.Hostname, _ = os.Hostname()
+
+	if memory, err := psmem.VirtualMemory(); err == nil {
+		inventory.MemoryTotal = memory.Total
+	}
+
+	if output, err := probeCommand("nvidia-smi", "--query-gpu=index,name,memory.total,driver_version",
+		"--format=csv,noheader,nounits"); err == nil {
+		inventory.GPUs = ParseNvidiaSmiGPUs(output)
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	probeVersions := func(commands []string, versions map[string]string) {
+		for _, command := range commands {
+			wg.Add(1)
+			go func(command string) {
+				defer wg.Done()
+
+				if version, err := probeCommand(command, "--version"); err == nil {
+					mutex.Lock()
+					versions[command] = firstLine(version)
+					mutex.Unlock()
+				}
+			}(command)
+		}
+	}
+	probeVersions(inventoryContainerRuntimes, inventory.ContainerRuntimes)
+	probeVersions(inventoryInterpreters, inventory.Interpreters)
+	wg.Wait()
+
+	return inventory
+}
+
+// probeCommand runs a command found in PATH and returns its output, some tools print versions to stderr
+func probeCommand(command string, args ...string) (string, error) {
+	executable, err := exec.LookPath(command)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryProbeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, executable, args...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+func firstLine(text string) string {
+	return strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+}
+
+// ParseNvidiaSmiGPUs parses 'nvidia-smi --query-gpu=index,name,memory.total,driver_version
+// --format=csv,noheader,nounits' output, memory is given in MiB
+func ParseNvidiaSmiGPUs(output string) []GPU {
+	gpus := []GPU{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			continue
+		}
+
+		index, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+		memory, _ := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 64)
+
+		gpus = append(gpus, GPU{
+			Index:         index,
+			Name:          strings.TrimSpace(fields[1]),
+			MemoryTotal:   memory * 1024 * 1024,
+			DriverVersion: strings.TrimSpace(fields[3]),
+		})
+	}
+
+	return gpus
+}
+
+// ReportHostInventory registers the inventory of the node executing simulation runs of the experiment
+func (em *ExperimentManager) ReportHostInventory(inventory *HostInventory) error {
+	jsonStr, _ := marshalJSON(inventory)
+	requestData := url.Values{}
+	requestData.Set("host_inventory", string(jsonStr))
+
+	url := "experiments/" + em.ExperimentId + "/host_inventory"
+	reqInfo := RequestInfo{"POST", strings.NewReader(requestData.Encode()), "application/x-www-form-urlencoded", url}
+
+	resp, err := ExecuteScalarmRequest(em.context(), reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
+
+// logHostInventory prints a summary of the inventory
+func logHostInventory(inventory *HostInventory) {
+	simLog.Infof("Host: %s, %s %s, kernel %s, %d x %s, memory: %d MB, GPUs: %d", inventory.Hostname,
+		inventory.Platform, inventory.PlatformVersion, inventory.KernelVersion, inventory.Cores, inventory.ModelName,
+		inventory.MemoryTotal/1024/1024, len(inventory.GPUs))
+
+	for name, version := range inventory.ContainerRuntimes {
+		simLog.Infof("Container runtime %s: %s", name, version)
+	}
+	for name, version := range inventory.Interpreters {
+		simLog.Infof("Interpreter %s: %s", name, version)
+	}
+}
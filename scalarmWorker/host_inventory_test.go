@@ -0,0 +1,64 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseNvidiaSmiGPUsShouldReadIndexNameMemoryAndDriver(t *testing.T) {
+	// === GIVEN ===
+	output := "0, NVIDIA A100-SXM4-40GB, 40960, 535.104.05\n1, NVIDIA A100-SXM4-40GB, 40960, 535.104.05\n"
+
+	// === WHEN ===
+	gpus := ParseNvidiaSmiGPUs(output)
+
+	// === THEN ===
+	if len(gpus) != 2 {
+		t.Errorf("Got: '%v' - Expected '%v' GPUs", len(gpus), 2)
+		return
+	}
+
+	expected := GPU{Index: 1, Name: "NVIDIA A100-SXM4-40GB", MemoryTotal: 40960 * 1024 * 1024, DriverVersion: "535.104.05"}
+	if gpus[1] != expected {
+		t.Errorf("Got: '%+v' - Expected '%+v'", gpus[1], expected)
+	}
+}
+
+func TestReportHostInventoryShouldPostInventoryOfExperiment(t *testing.T) {
+	// === GIVEN ===
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/host_inventory" {
+			w.WriteHeader(404)
+			return
+		}
+
+		json.Unmarshal([]byte(r.FormValue("host_inventory")), &received)
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	inventory := &HostInventory{
+		HostInfo:     HostInfo{OS: "linux", ModelName: "AMD EPYC 7742"},
+		Hostname:     "node-17",
+		GPUs:         []GPU{},
+		Interpreters: map[string]string{"python3": "Python 3.11.4"},
+	}
+
+	// === WHEN ===
+	err := em.ReportHostInventory(inventory)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	interpreters, _ := received["interpreters"].(map[string]interface{})
+	if received["hostname"] != "node-17" || received["modelName"] != "AMD EPYC 7742" || interpreters["python3"] != "Python 3.11.4" {
+		t.Errorf("Got: '%v' - Expected the inventory of node-17", received)
+	}
+}
@@ -0,0 +1,145 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// classes of Scalarm endpoints which can get their own extra headers
+const (
+	RequestClassInformationService = "information_service"
+	RequestClassCodeBase           = "code_base"
+	RequestClassSimulations        = "simulations"
+	RequestClassProgress           = "progress"
+	RequestClassUploads            = "uploads"
+	RequestClassInputFiles         = "input_files"
+	RequestClassExperiments        = "experiments"
+)
+
+var requestClasses = []string{RequestClassInformationService, RequestClassCodeBase, RequestClassSimulations,
+	RequestClassProgress, RequestClassUploads, RequestClassInputFiles, RequestClassExperiments}
+
+// headers set by SiM itself, overriding them would break authentication or request bodies
+var reservedHeaders = []string{"Authorization", "Content-Type", "Content-Length", "Content-Encoding", "Host", "Range"}
+
+var headerNameRegexp = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// RequestClass tells which class of Scalarm endpoints the request belongs to
+func RequestClass(reqInfo RequestInfo) string {
+	method := reqInfo.ServiceMethod
+	if i := strings.Index(method, "?"); i >= 0 {
+		method = method[:i]
+	}
+
+	if _, ok := reqInfo.Body.(*FileUpload); ok || reqInfo.HttpMethod == "PUT" {
+		return RequestClassUploads
+	}
+
+	switch {
+	case method == "experiment_managers" || method == "storage_managers":
+		return RequestClassInformationService
+	case strings.HasSuffix(method, "/code_base") || strings.HasSuffix(method, "/code_base_signature"):
+		return RequestClassCodeBase
+	case strings.Contains(method, "/input_files/"):
+		return RequestClassInputFiles
+	case strings.HasSuffix(method, "/progress_info") || strings.HasSuffix(method, "/host_info") ||
+		strings.HasSuffix(method, "/performance_stats") || strings.HasSuffix(method, "/host_inventory"):
+		return RequestClassProgress
+	case strings.HasSuffix(method, "/next_simulation") || strings.HasSuffix(method, "/next_simulations") ||
+		strings.Contains(method, "/simulations/"):
+		return RequestClassSimulations
+	}
+
+	return RequestClassExperiments
+}
+
+// applyCustomHeaders adds headers configured for all Scalarm requests and then those of the request class,
+// which take precedence; environment variables in values, like ${TENANT_ID}, are expanded. Reserved headers
+// are skipped, also when the configuration was not validated
+func applyCustomHeaders(req *http.Request, reqInfo RequestInfo, config *SimulationManagerConfig) {
+	for name, value := range config.HttpHeaders {
+		if !isReservedHeader(name) {
+			req.Header.Set(name, os.ExpandEnv(value))
+		}
+	}
+
+	for name, value := range config.HttpHeadersByClass[RequestClass(reqInfo)] {
+		if !isReservedHeader(name) {
+			req.Header.Set(name, os.ExpandEnv(value))
+		}
+	}
+}
+
+func isReservedHeader(name string) bool {
+	for _, reserved := range reservedHeaders {
+		if strings.EqualFold(name, reserved) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// registerCustomHeaderSecrets makes Redact hide values of custom headers, which often carry API keys
+// or tokens required by a gateway
+func registerCustomHeaderSecrets(config *SimulationManagerConfig) {
+	for _, value := range config.HttpHeaders {
+		RegisterSecret(os.ExpandEnv(value))
+	}
+
+	for _, headers := range config.HttpHeadersByClass {
+		for _, value := range headers {
+			RegisterSecret(os.ExpandEnv(value))
+		}
+	}
+}
+
+// checkCustomHeaders reports invalid header names, reserved headers and unknown request classes
+func checkCustomHeaders(config *SimulationManagerConfig) []error {
+	errs := []error{}
+
+	checkHeaders := func(option string, headers map[string]string) {
+		names := []string{}
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if !headerNameRegexp.MatchString(name) {
+				errs = append(errs, errors.New(option+": '"+name+"' is not a valid header name"))
+				continue
+			}
+			if isReservedHeader(name) {
+				errs = append(errs, errors.New(option+": "+name+" is set by SiM and cannot be overridden"))
+			}
+		}
+	}
+
+	checkHeaders("http_headers", config.HttpHeaders)
+
+	classes := []string{}
+	for class := range config.HttpHeadersByClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		known := false
+		for _, requestClass := range requestClasses {
+			known = known || class == requestClass
+		}
+		if !known {
+			errs = append(errs, errors.New("http_headers_by_class: unknown request class '"+class+"', use one of "+
+				strings.Join(requestClasses, ", ")))
+			continue
+		}
+		checkHeaders("http_headers_by_class."+class, config.HttpHeadersByClass[class])
+	}
+
+	return errs
+}
@@ -0,0 +1,91 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestCustomHeadersShouldBeAddedToAllRequestsAndOverriddenByClass(t *testing.T) {
+	// === GIVEN ===
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		w.WriteHeader(200)
+		w.Write([]byte(`["siteA.com"]`))
+	}))
+	defer server.Close()
+
+	os.Setenv("SCALARM_TEST_TENANT", "tenant-1")
+	defer os.Unsetenv("SCALARM_TEST_TENANT")
+
+	config := getSimConfig()
+	config.HttpHeaders = map[string]string{"X-Tenant": "${SCALARM_TEST_TENANT}", "X-Route": "default"}
+	config.HttpHeadersByClass = map[string]map[string]string{RequestClassInformationService: {"X-Route": "is"}}
+
+	// === WHEN ===
+	is := setupInformationService(config, getHttpClientMock(server.URL))
+	is.GetExperimentManagers()
+
+	// === THEN ===
+	isHeaders := <-headers
+	if isHeaders.Get("X-Tenant") != "tenant-1" || isHeaders.Get("X-Route") != "is" {
+		t.Errorf("Got: '%v' - Expected '%v'", isHeaders, "X-Tenant: tenant-1, X-Route: is")
+	}
+	if isHeaders.Get("Authorization") == "" {
+		t.Errorf("Basic auth should still be set")
+	}
+}
+
+func TestRequestClassShouldDependOnEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"experiment_managers":                                    RequestClassInformationService,
+		"experiments/1/code_base":                                RequestClassCodeBase,
+		"experiments/1/next_simulation":                          RequestClassSimulations,
+		"experiments/1/simulations/2/mark_as_complete":           RequestClassSimulations,
+		"experiments/1/simulations/computed_result?parameters=1": RequestClassSimulations,
+		"experiments/1/simulations/2/progress_info":              RequestClassProgress,
+		"experiments/1/input_files/data.csv":                     RequestClassInputFiles,
+		"experiments/random_experiment":                          RequestClassExperiments,
+	}
+
+	for serviceMethod, expected := range cases {
+		if class := RequestClass(RequestInfo{"GET", nil, "", serviceMethod}); class != expected {
+			t.Errorf("Got: '%v' - Expected '%v' for %s", class, expected, serviceMethod)
+		}
+	}
+
+	if class := RequestClass(RequestInfo{"PUT", nil, "", "experiments/1/simulations/2"}); class != RequestClassUploads {
+		t.Errorf("Got: '%v' - Expected '%v'", class, RequestClassUploads)
+	}
+}
+
+func TestCheckCustomHeadersShouldRejectReservedHeadersAndUnknownClasses(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+	config.HttpHeaders = map[string]string{"authorization": "Bearer x", "X-Trace": "1", "Bad Name": "1"}
+	config.HttpHeadersByClass = map[string]map[string]string{"unknown": {"X-A": "1"}, RequestClassUploads: {"X-B": "1"}}
+
+	// === WHEN ===
+	errs := checkCustomHeaders(config)
+
+	// === THEN ===
+	if len(errs) != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", errs, "3 errors")
+	}
+}
+
+func TestCustomHeadersShouldNotOverrideReservedHeaders(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+	config.HttpHeaders = map[string]string{"Authorization": "Bearer x", "host": "evil.example.com", "X-Trace": "1"}
+
+	// === WHEN ===
+	req, _ := NewScalarmRequest(RequestInfo{"GET", nil, "", "experiments"}, "localhost", config)
+
+	// === THEN ===
+	if req.Header.Get("Authorization") == "Bearer x" || req.Header.Get("Host") != "" || req.Header.Get("X-Trace") != "1" {
+		t.Errorf("Got: '%v' - Expected '%v'", req.Header, "only X-Trace added")
+	}
+}
@@ -1,16 +1,21 @@
 package scalarmWorker
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"math/rand"
+	"net"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 	//	"io/ioutil"
-	"errors"
 )
 
+// logger of requests sent to Scalarm services
+var httpLog = NewLogger("http")
+
 type RequestInfo struct {
 	HttpMethod    string
 	Body          io.Reader
@@ -19,69 +24,250 @@ type RequestInfo struct {
 }
 
 func Fatal(err error) {
-	fmt.Printf("[Fatal error] %s\n", err.Error())
-	os.Exit(1)
-}
+	simLog.Errorf("Fatal error: %s", Redact(err.Error()))
 
-func ExecuteScalarmRequest(reqInfo RequestInfo, serviceUrls []string, config *SimulationManagerConfig,
-	client *http.Client, timeout time.Duration) (*http.Response, error) {
+	// the supervisor restarts the worker loop after recoverable errors, other ones terminate SiM
+	// once simulation runs being executed finished
+	if atomic.LoadInt32(&supervised) == 1 {
+		panic(fatalError{err})
+	}
 
+	runFatalHooks(err, 1)
+	Exit(1)
+}
+
+// NewScalarmRequest prepares a request to the given Scalarm service with credentials and common headers
+func NewScalarmRequest(reqInfo RequestInfo, serviceUrl string, config *SimulationManagerConfig) (*http.Request, error) {
 	protocol := "https"
 	if config.Development {
 		protocol = "http"
 	}
 
-	// 1. shuffle service url
-	perm := rand.Perm(len(serviceUrls))
+	requestUrl := fmt.Sprintf("%s://%s/%s", protocol, serviceUrl, reqInfo.ServiceMethod)
+	// local services, like the offline queue or the coordinator of the agent, are reached with plain HTTP
+	if isLocalHttpService(serviceUrl) {
+		requestUrl = serviceUrl + "/" + reqInfo.ServiceMethod
+	}
 
-	for _, v := range perm {
-		// 2. get next service url and prepare a request
-		serviceUrl := serviceUrls[v]
-		fmt.Printf("[SiM] %s://%s/%s\n", protocol, serviceUrl, reqInfo.ServiceMethod)
-		req, err := http.NewRequest(reqInfo.HttpMethod, fmt.Sprintf("%s://%s/%s", protocol, serviceUrl, reqInfo.ServiceMethod), reqInfo.Body)
-		if err != nil {
-			Fatal(err)
+	req, err := http.NewRequest(reqInfo.HttpMethod, requestUrl, reqInfo.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// file uploads are streamed from disk, each attempt gets its own stream
+	if upload, ok := reqInfo.Body.(*FileUpload); ok {
+		if req.Body, err = upload.Open(); err != nil {
+			return nil, err
 		}
-		req.SetBasicAuth(config.ExperimentManagerUser, config.ExperimentManagerPass)
+		req.GetBody = upload.Open
+		req.ContentLength = -1
+	}
+	if chunk, ok := reqInfo.Body.(*FileChunk); ok {
+		if req.Body, err = chunk.Open(); err != nil {
+			return nil, err
+		}
+		req.GetBody = chunk.Open
+		req.ContentLength = chunk.Size
+		req.Header.Set("Content-Range", chunk.ContentRange())
+	}
+	req.SetBasicAuth(config.Credentials())
 
-		req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept", "application/json")
 
-		if reqInfo.Body != nil {
-			req.Header.Set("Content-Type", reqInfo.ContentType)
+	if reqInfo.Body != nil {
+		req.Header.Set("Content-Type", reqInfo.ContentType)
+	}
+
+	applyCustomHeaders(req, reqInfo, config)
+
+	return req, nil
+}
+
+// isLocalHttpService tells whether the service address is a plain HTTP URL of a loopback host, other
+// addresses are hosts with optional ports reached with HTTPS unless development is set
+func isLocalHttpService(serviceUrl string) bool {
+	if !strings.HasPrefix(serviceUrl, "http://") {
+		return false
+	}
+	parsedUrl, err := url.Parse(serviceUrl)
+	if err != nil {
+		return false
+	}
+	host := parsedUrl.Hostname()
+	ip := net.ParseIP(host)
+
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// RequestAttempt is a failed attempt to execute a request against one instance of a Scalarm service
+type RequestAttempt struct {
+	URL   string
+	Class string
+	Err   error
+}
+
+// RequestError reports every instance of a Scalarm service which was tried and why each attempt failed,
+// so callers can decide whether to back off, spool data or exit
+type RequestError struct {
+	ServiceMethod string
+	Attempts      []RequestAttempt
+}
+
+func (e *RequestError) Error() string {
+	message := "Could not execute request against Scalarm service"
+	if len(e.Attempts) == 0 {
+		return message + ": no service address available"
+	}
+
+	// attempts are grouped by URL, the last error of each one is reported
+	urls := []string{}
+	lastAttempts := map[string]RequestAttempt{}
+	counts := map[string]int{}
+	for _, attempt := range e.Attempts {
+		if counts[attempt.URL] == 0 {
+			urls = append(urls, attempt.URL)
 		}
-		// 3. execute request with timeout
-		response, err := GetWithTimeout(client, req, timeout)
-		// 4. if response body is nil go to 2.
-		if err == nil {
-			return response, nil
+		counts[attempt.URL]++
+		lastAttempts[attempt.URL] = attempt
+	}
+
+	failures := []string{}
+	for _, attemptURL := range urls {
+		// url.Error repeats the URL of the attempt
+		cause := lastAttempts[attemptURL].Err
+		if urlErr, ok := cause.(*url.Error); ok {
+			cause = urlErr.Err
+		}
+		failure := attemptURL + ": [" + lastAttempts[attemptURL].Class + "] " + cause.Error()
+		if counts[attemptURL] > 1 {
+			failure += fmt.Sprintf(" (%d attempts)", counts[attemptURL])
 		}
+		failures = append(failures, failure)
 	}
 
-	return nil, errors.New("Could not execute request against Scalarm service")
+	return message + ": " + strings.Join(failures, "; ")
 }
 
-// Calling Get multiple time until valid response or exceed 'communicationTimeout' period
-func GetWithTimeout(client *http.Client, request *http.Request, communicationTimeout time.Duration) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	communicationFailed := true
-	communicationStart := time.Now()
+// Unwrap returns the error of the last attempt, so network errors are recognized as recoverable
+func (e *RequestError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
 
-	for communicationStart.Add(communicationTimeout).After(time.Now()) {
-		resp, err = client.Do(request)
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// ExecuteScalarmRequest tries instances of a Scalarm service in random order until one of them responds,
+// all attempts share a single budget: timeout is the total deadline and max_attempts limits their number;
+// cancelling ctx aborts the request in flight and ends retries
+func ExecuteScalarmRequest(ctx context.Context, reqInfo RequestInfo, serviceUrls []string, config *SimulationManagerConfig,
+	client *http.Client, timeout time.Duration) (*http.Response, error) {
 
+	requestErr := &RequestError{ServiceMethod: reqInfo.ServiceMethod}
+	if len(serviceUrls) == 0 {
+		return nil, requestErr
+	}
+
+	// 1. shuffle service url, instances which fail permanently are not tried again
+	candidates := randomPerm(len(serviceUrls))
+	budget := config.retryBudget(timeout)
+	next := 0
+	// bodies of later attempts are taken from the first request, a failed attempt drains readers like strings.Reader
+	var firstRequest *http.Request
+
+	for len(candidates) > 0 && ctx.Err() == nil && budget.Next() {
+		// 2. get next service url and prepare a request, after a failure the next instance is tried
+		// and once all of them failed there is a pause, growing with every round, before the next one
+		if next >= len(candidates) {
+			next = 0
+			budget.Pause(ctx)
+		}
+		serviceUrl := serviceUrls[candidates[next]]
+		req, err := NewScalarmRequest(reqInfo, serviceUrl, config)
 		if err != nil {
-			time.Sleep(1 * time.Second)
-			fmt.Printf("[SiM] %v\n", err)
+			requestErr.Attempts = append(requestErr.Attempts, RequestAttempt{serviceUrl, FailureOther, err})
+			candidates = append(candidates[:next], candidates[next+1:]...)
+			continue
+		}
+		if firstRequest == nil {
+			firstRequest = req
+		} else if firstRequest.GetBody != nil {
+			if req.Body, err = firstRequest.GetBody(); err != nil {
+				requestErr.Attempts = append(requestErr.Attempts, RequestAttempt{serviceUrl, FailureOther, err})
+				return nil, requestErr
+			}
+			req.GetBody, req.ContentLength = firstRequest.GetBody, firstRequest.ContentLength
+		}
+		req = req.WithContext(ctx)
+		if header, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+			for name, values := range header {
+				req.Header[name] = values
+			}
+		}
+		httpLog.Debugf("%s %s", req.Method, Redact(req.URL.String()))
+
+		// 3. execute request
+		response, err := client.Do(req)
+		// 4. if there is no response go to 2.
+		if err == nil {
+			response = honorRetryAfter(client, req, response, budget.Remaining())
+			return retryWithHelperCredentials(client, req, response, config, timeout), nil
+		}
+
+		class := ClassifyFailure(err)
+		httpLog.Warnf("Request failed (%s): %v", class, Redact(err.Error()))
+		requestErr.Attempts = append(requestErr.Attempts, RequestAttempt{Redact(req.URL.String()), class, err})
+
+		if isPermanentFailure(err) {
+			candidates = append(candidates[:next], candidates[next+1:]...)
 		} else {
-			communicationFailed = false
-			break
+			next++
 		}
 	}
 
-	if communicationFailed {
-		return nil, err
+	return nil, requestErr
+}
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders makes ExecuteScalarmRequest add the headers, e.g. Range, to requests sent with the context
+func WithRequestHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, requestHeadersKey{}, header)
+}
+
+// GetWithTimeout repeats a request with the default retry policy until it gets a response, communicationTimeout passes
+// or ctx is cancelled
+func GetWithTimeout(ctx context.Context, client *http.Client, request *http.Request,
+	communicationTimeout time.Duration) (*http.Response, error) {
+	return GetWithBudget(ctx, client, request, NewRetryBudget(communicationTimeout, 0))
+}
+
+// GetWithBudget repeats a request until it gets a response or the budget is spent; failures are classified
+// and a name which does not resolve or a rejected certificate end retries at once, so does cancelling ctx
+func GetWithBudget(ctx context.Context, client *http.Client, request *http.Request, budget *RetryBudget) (*http.Response, error) {
+	var err error
+
+	request = request.WithContext(ctx)
+	for attempt := 0; budget.Next(); attempt++ {
+		// a body consumed by a failed attempt has to be recreated
+		if attempt > 0 && request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, doErr := client.Do(request)
+		if doErr == nil {
+			return resp, nil
+		}
+		err = doErr
+
+		httpLog.Warnf("Request failed (%s): %v", ClassifyFailure(err), Redact(err.Error()))
+		if isPermanentFailure(err) || ctx.Err() != nil {
+			break
+		}
+		budget.Pause(ctx)
 	}
 
-	return resp, nil
+	return nil, err
 }
@@ -3,36 +3,168 @@ package scalarmWorker
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// looks up SRV records of Information Service, replaced in tests
+var lookupSRV = net.LookupSRV
+
+// SRV records of Information Service are looked up again after this time
+const srvCacheTTL = 5 * time.Minute
+
+// targets of SRV records by record name, kept when a later lookup fails
+var srvTargets struct {
+	targets  map[string][]string
+	lookedUp map[string]time.Time
+	mutex    sync.Mutex
+}
+
 type InformationService struct {
-	HttpClient           *http.Client
-	BaseUrl              string
+	HttpClient *http.Client
+	BaseUrl    string
+	// instances of Information Service tried in random order, BaseUrl is used when empty
+	BaseUrls             []string
 	CommunicationTimeout time.Duration
 	Config               *SimulationManagerConfig
+	// responses cached on disk, used instead of asking Information Service while they are fresh
+	Cache *ServiceCache
 }
 
-func (is *InformationService) GetExperimentManagers() ([]string, error) {
-	iSReqInfo := RequestInfo{"GET", nil, "application/json", "experiment_managers"}
+// NewInformationService prepares a client failing over between all configured instances of Information Service
+func NewInformationService(config *SimulationManagerConfig, client *http.Client, timeout time.Duration) InformationService {
+	addresses := config.InformationServiceAddresses()
+	TrustServiceHosts(addresses...)
+
+	return InformationService{
+		HttpClient:           client,
+		BaseUrls:             addresses,
+		CommunicationTimeout: timeout,
+		Config:               config}
+}
+
+// InformationServiceAddresses returns instances of Information Service from information_service_url,
+// information_service_urls and targets of information_service_srv records, without duplicates
+func (config *SimulationManagerConfig) InformationServiceAddresses() []string {
+	addresses := []string{}
+	seen := map[string]bool{}
+	add := func(address string) {
+		address = strings.TrimSpace(address)
+		if address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
 
-	resp, err := ExecuteScalarmRequest(iSReqInfo, []string{is.BaseUrl}, is.Config, is.HttpClient, is.CommunicationTimeout)
+	add(config.InformationServiceUrl)
+	for _, address := range config.InformationServiceUrls {
+		add(address)
+	}
+
+	if config.InformationServiceSRV != "" {
+		for _, target := range lookupSRVTargets(config.InformationServiceSRV) {
+			add(target)
+		}
+	}
+
+	return addresses
+}
+
+// lookupSRVTargets returns targets (host:port) of SRV records of the name, they are cached for srvCacheTTL;
+// when a lookup fails, targets found before are used, or only configured addresses when there are none
+func lookupSRVTargets(name string) []string {
+	srvTargets.mutex.Lock()
+	defer srvTargets.mutex.Unlock()
+
+	if srvTargets.targets == nil {
+		srvTargets.targets = map[string][]string{}
+		srvTargets.lookedUp = map[string]time.Time{}
+	}
 
+	targets, cached := srvTargets.targets[name]
+	if cached && time.Since(srvTargets.lookedUp[name]) < srvCacheTTL {
+		return targets
+	}
+
+	_, records, err := lookupSRV("", "", name)
 	if err != nil {
-		return nil, err
-	} else {
-		return ParseInformationServiceResponse(resp)
+		if cached {
+			simLog.Warnf("Could not look up SRV records of %s, using targets found before: %v", name, err)
+		} else {
+			simLog.Warnf("Could not look up SRV records of %s, using configured addresses only: %v", name, err)
+		}
+		return targets
+	}
+
+	targets = []string{}
+	for _, record := range records {
+		targets = append(targets, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+	}
+	srvTargets.targets[name] = targets
+	srvTargets.lookedUp[name] = time.Now()
+
+	return targets
+}
+
+// informationServiceName identifies Information Service to credential helpers
+func (config *SimulationManagerConfig) informationServiceName() string {
+	if config.InformationServiceUrl != "" {
+		return config.InformationServiceUrl
+	} else if len(config.InformationServiceUrls) > 0 {
+		return config.InformationServiceUrls[0]
 	}
+
+	return config.InformationServiceSRV
+}
+
+func (is *InformationService) urls() []string {
+	if len(is.BaseUrls) > 0 {
+		return is.BaseUrls
+	}
+
+	return []string{is.BaseUrl}
+}
+
+func (is *InformationService) GetExperimentManagers() ([]string, error) {
+	return is.getServices("experiment_managers")
 }
 
 func (is *InformationService) GetStorageManagers() ([]string, error) {
-	iSReqInfo := RequestInfo{"GET", nil, "application/json", "storage_managers"}
+	return is.getServices("storage_managers")
+}
+
+// getServices returns fresh cached addresses of the service, which are then refreshed in the background,
+// or asks Information Service for them
+func (is *InformationService) getServices(service string) ([]string, error) {
+	if is.Cache != nil {
+		if addresses, ok := is.Cache.Load(is.cacheKey(service)); ok {
+			simLog.Infof("Using cached addresses of %s: %v", service, addresses)
+			if startServiceRefresh(is.cacheKey(service)) {
+				go is.refresh(service)
+			}
+			return addresses, nil
+		}
+	}
+
+	addresses, err := is.fetchServices(service)
+	if err == nil && is.Cache != nil {
+		if err := is.Cache.Store(is.cacheKey(service), addresses); err != nil {
+			simLog.Warnf("Could not cache addresses of %s: %v", service, err)
+		}
+	}
 
-	resp, err := ExecuteScalarmRequest(iSReqInfo, []string{is.BaseUrl}, is.Config, is.HttpClient, is.CommunicationTimeout)
+	return addresses, err
+}
+
+func (is *InformationService) fetchServices(service string) ([]string, error) {
+	iSReqInfo := RequestInfo{"GET", nil, "application/json", service}
+
+	resp, err := ExecuteScalarmRequest(WorkerContext(), iSReqInfo, is.urls(), is.Config, is.HttpClient, is.CommunicationTimeout)
 
 	if err != nil {
 		return nil, err
@@ -41,6 +173,75 @@ func (is *InformationService) GetStorageManagers() ([]string, error) {
 	}
 }
 
+// cache keys of services whose cached addresses are being refreshed, there is one refresh of each at a time
+var serviceRefreshes struct {
+	running map[string]bool
+	mutex   sync.Mutex
+}
+
+// startServiceRefresh tells whether a refresh of the cache key should be started, false while one is running
+func startServiceRefresh(key string) bool {
+	serviceRefreshes.mutex.Lock()
+	defer serviceRefreshes.mutex.Unlock()
+
+	if serviceRefreshes.running == nil {
+		serviceRefreshes.running = map[string]bool{}
+	}
+	if serviceRefreshes.running[key] {
+		return false
+	}
+	serviceRefreshes.running[key] = true
+
+	return true
+}
+
+func finishServiceRefresh(key string) {
+	serviceRefreshes.mutex.Lock()
+	defer serviceRefreshes.mutex.Unlock()
+
+	delete(serviceRefreshes.running, key)
+}
+
+// refresh updates cached addresses of the service, retrying with growing intervals until Information Service responds,
+// the cache is closed or the worker loop is cancelled
+func (is *InformationService) refresh(service string) {
+	defer finishServiceRefresh(is.cacheKey(service))
+
+	interval := is.CommunicationTimeout
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		addresses, err := is.fetchServices(service)
+		if err == nil {
+			if err = is.Cache.Store(is.cacheKey(service), addresses); err != nil {
+				simLog.Warnf("Could not cache addresses of %s: %v", service, err)
+			}
+			return
+		}
+
+		simLog.Warnf("Could not refresh cached addresses of %s, next attempt in %v: %v", service, interval,
+			Redact(err.Error()))
+		select {
+		case <-time.After(interval):
+		case <-is.Cache.closed:
+			return
+		case <-WorkerContext().Done():
+			return
+		}
+
+		if interval *= 2; interval > maxServiceCacheRefreshInterval {
+			interval = maxServiceCacheRefreshInterval
+		}
+	}
+}
+
+// cacheKey identifies responses of the configured Information Service, so a changed config does not use stale ones
+func (is *InformationService) cacheKey(service string) string {
+	return is.Config.informationServiceName() + "/" + service
+}
+
 func ParseInformationServiceResponse(resp *http.Response) ([]string, error) {
 	var experimentManagers []string
 
@@ -52,7 +253,7 @@ func ParseInformationServiceResponse(resp *http.Response) ([]string, error) {
 			return nil, err
 		}
 
-		fmt.Printf("[SiM] Response body: %s.\n", body)
+		simLog.Debugf("Response body: %s.", body)
 
 		if err := json.Unmarshal(body, &experimentManagers); err != nil {
 			return nil, errors.New("Returned response body is not JSON.")
@@ -1,10 +1,13 @@
 package scalarmWorker
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -162,8 +165,101 @@ func TestInformationServiceShouldErrorNoServiceAvailable(t *testing.T) {
 		t.Errorf("Error expected but got nil")
 	}
 
-	expected_error := "Could not execute request against Scalarm service"
-	if err.Error() != expected_error {
+	expected_error := "Could not execute request against Scalarm service: http://someveryincorrecturl/experiment_managers: "
+	if !strings.HasPrefix(err.Error(), expected_error) {
 		t.Errorf("Got: '%v' - Expected '%v'", err.Error(), expected_error)
 	}
+
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || len(requestErr.Attempts) == 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "a RequestError with failed attempts")
+	}
+}
+
+func TestInformationServiceShouldFailOverToAnotherInstance(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		fmt.Fprintln(w, `["siteA.com"]`)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.Development = true
+	config.InformationServiceUrl = "127.0.0.1:1"
+	config.InformationServiceUrls = []string{strings.TrimPrefix(server.URL, "http://")}
+
+	is := NewInformationService(config, &http.Client{}, 10*time.Second)
+
+	// === WHEN ===
+	experimentManagers, err := is.GetExperimentManagers()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if !reflect.DeepEqual(experimentManagers, []string{"siteA.com"}) {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentManagers, []string{"siteA.com"})
+	}
+}
+
+func TestInformationServiceAddressesShouldIncludeSRVTargets(t *testing.T) {
+	// === GIVEN ===
+	defer func(original func(string, string, string) (string, []*net.SRV, error)) { lookupSRV = original }(lookupSRV)
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return name, []*net.SRV{{Target: "is1.example.org.", Port: 11300}, {Target: "is2.example.org.", Port: 11300}}, nil
+	}
+
+	config := getSimConfig()
+	config.InformationServiceUrl = "is1.example.org:11300"
+	config.InformationServiceSRV = "_scalarm-is._tcp.example.org"
+
+	// === WHEN ===
+	addresses := config.InformationServiceAddresses()
+
+	// === THEN ===
+	expected := []string{"is1.example.org:11300", "is2.example.org:11300"}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", addresses, expected)
+	}
+}
+
+func TestInformationServiceAddressesShouldKeepSRVTargetsWhenLookupFails(t *testing.T) {
+	// === GIVEN ===
+	defer func(original func(string, string, string) (string, []*net.SRV, error)) { lookupSRV = original }(lookupSRV)
+	lookups := 0
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		lookups++
+		if lookups > 1 {
+			return "", nil, errors.New("no such host")
+		}
+		return name, []*net.SRV{{Target: "is2.example.org.", Port: 11300}}, nil
+	}
+
+	config := getSimConfig()
+	config.InformationServiceUrl = "is1.example.org:11300"
+	config.InformationServiceSRV = "_scalarm-is._tcp.cached.example.org"
+
+	// === WHEN ===
+	config.InformationServiceAddresses()
+	cachedAddresses := config.InformationServiceAddresses()
+
+	srvTargets.mutex.Lock()
+	srvTargets.lookedUp[config.InformationServiceSRV] = time.Now().Add(-srvCacheTTL)
+	srvTargets.mutex.Unlock()
+	staleAddresses := config.InformationServiceAddresses()
+
+	config.InformationServiceSRV = "_scalarm-is._tcp.missing.example.org"
+	fallbackAddresses := config.InformationServiceAddresses()
+
+	// === THEN ===
+	expected := []string{"is1.example.org:11300", "is2.example.org:11300"}
+	if lookups != 3 || !reflect.DeepEqual(cachedAddresses, expected) || !reflect.DeepEqual(staleAddresses, expected) {
+		t.Errorf("Got: %v lookups, '%v', '%v' - Expected 3 lookups, '%v'", lookups, cachedAddresses, staleAddresses, expected)
+	}
+
+	if !reflect.DeepEqual(fallbackAddresses, []string{"is1.example.org:11300"}) {
+		t.Errorf("Got: '%v' - Expected '%v'", fallbackAddresses, []string{"is1.example.org:11300"})
+	}
 }
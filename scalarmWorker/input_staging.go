@@ -0,0 +1,311 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileReferenceKey marks input parameter values like {"$file": "<storage id or URL>"}
+const fileReferenceKey = "$file"
+
+// InputStager downloads files referenced by input parameters; every referenced file is downloaded once
+// into CacheDir and linked into simulation directories which need it
+type InputStager struct {
+	CacheDir        string
+	ExperimentID    string
+	StorageManagers []string
+	Config          *SimulationManagerConfig
+	HttpClient      *http.Client
+	Timeout         time.Duration
+}
+
+// StageInputFiles replaces every file reference in parameters with the absolute path of the file
+// staged in the 'input_files' directory of the simulation
+func (stager *InputStager) StageInputFiles(parameters map[string]interface{}, simulationDirPath string) error {
+	for name, value := range parameters {
+		staged, err := stager.stageValue(value, simulationDirPath)
+		if err != nil {
+			return fmt.Errorf("parameter '%s': %v", name, err)
+		}
+		parameters[name] = staged
+	}
+
+	return nil
+}
+
+func (stager *InputStager) stageValue(value interface{}, simulationDirPath string) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if reference, ok := fileReference(typedValue); ok {
+			return stager.stageFile(reference, reference, "", simulationDirPath)
+		}
+		for key, nested := range typedValue {
+			staged, err := stager.stageValue(nested, simulationDirPath)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[key] = staged
+		}
+	case []interface{}:
+		for i, nested := range typedValue {
+			staged, err := stager.stageValue(nested, simulationDirPath)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[i] = staged
+		}
+	}
+
+	return value, nil
+}
+
+// fileReference recognizes an object with '$file' as its only key
+func fileReference(value map[string]interface{}) (string, bool) {
+	if len(value) != 1 {
+		return "", false
+	}
+
+	reference, ok := value[fileReferenceKey].(string)
+	return reference, ok && reference != ""
+}
+
+// InputBlob is a binary input of a simulation run listed in 'input_files' of next_simulation
+type InputBlob struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+// StageInputBlobs downloads binary inputs listed in 'input_files' of next_simulation from Storage Managers
+// into the 'input_files' directory of the simulation, their SHA-256 checksums are verified when given
+func (stager *InputStager) StageInputBlobs(inputFiles interface{}, simulationDirPath string) error {
+	if inputFiles == nil {
+		return nil
+	}
+
+	blobs := []InputBlob{}
+	jsonStr, err := marshalJSON(inputFiles)
+	if err == nil {
+		err = json.Unmarshal(jsonStr, &blobs)
+	}
+	if err != nil {
+		return errors.New("input_files is not a list of files: " + err.Error())
+	}
+
+	for _, blob := range blobs {
+		if blob.ID == "" {
+			return errors.New("input file without id")
+		}
+
+		name := blob.Name
+		if name == "" {
+			name = blob.ID
+		}
+
+		if _, err = stager.stageFile(blob.ID, name, blob.Sha256, simulationDirPath); err != nil {
+			return fmt.Errorf("input file '%s': %v", blob.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (stager *InputStager) stageFile(reference string, name string, checksum string, simulationDirPath string) (string, error) {
+	// the checksum is a part of the cache key, so a new version of a file with the same id is downloaded again
+	sum := sha256.Sum256([]byte(reference + "\x00" + strings.ToLower(checksum)))
+	cachedPath := filepath.Join(stager.CacheDir, hex.EncodeToString(sum[:]))
+
+	if err := os.MkdirAll(stager.CacheDir, 0777); err != nil {
+		return "", err
+	}
+
+	if err := stager.cacheFile(reference, cachedPath, checksum); err != nil {
+		return "", err
+	}
+
+	inputFilesDir := filepath.Join(simulationDirPath, "input_files")
+	stagedPath, err := filepath.Abs(filepath.Join(inputFilesDir, stagedFileName(name)))
+	if err != nil {
+		return "", err
+	}
+
+	// another file with the same name staged for the run is not overwritten, this one goes
+	// into a directory named after its cache key
+	if _, err := os.Lstat(stagedPath); err == nil {
+		stagedPath = filepath.Join(filepath.Dir(stagedPath), hex.EncodeToString(sum[:8]), filepath.Base(stagedPath))
+	}
+
+	// every run gets its own copy, adapters writing to a staged file must not change the cached one
+	if err := copyFile(cachedPath, stagedPath); err != nil {
+		return "", err
+	}
+
+	return stagedPath, nil
+}
+
+// cacheFile downloads the file into the cache unless it is there already, workers sharing the cache
+// wait for each other instead of downloading the same file twice
+func (stager *InputStager) cacheFile(reference string, cachedPath string, checksum string) error {
+	unlock, err := lockFile(cachedPath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(cachedPath); !os.IsNotExist(err) {
+		return nil
+	}
+	simLog.Infof("Staging input file %s ...", Redact(reference))
+
+	return stager.download(reference, cachedPath, checksum)
+}
+
+// stagedFileName returns the last element of a URL or storage id which is safe to use as a file name
+func stagedFileName(reference string) string {
+	reference = strings.SplitN(strings.SplitN(reference, "?", 2)[0], "#", 2)[0]
+	name := path.Base(strings.Replace(reference, "\\", "/", -1))
+
+	if name == "." || name == ".." || name == "/" || name == "" {
+		sum := sha256.Sum256([]byte(reference))
+		return hex.EncodeToString(sum[:8])
+	}
+
+	return name
+}
+
+// number of attempts of downloading an input file, every attempt resumes the previous one
+var inputDownloadAttempts = 3
+
+// download fetches a URL directly, without Scalarm credentials, or a storage id from Storage Managers;
+// content is written to a '.part' file, which is resumed by later attempts, and moved into the cache
+// only when it is complete and matches the checksum
+func (stager *InputStager) download(reference string, cachedPath string, checksum string) error {
+	if err := os.MkdirAll(filepath.Dir(cachedPath), 0777); err != nil {
+		return err
+	}
+
+	partPath := cachedPath + ".part"
+
+	var err error
+	for attempt := 0; attempt < inputDownloadAttempts; attempt++ {
+		if err = stager.downloadPart(reference, partPath, attempt); err == nil {
+			break
+		}
+		simLog.Warnf("Download of %s interrupted: %v", Redact(reference), Redact(err.Error()))
+	}
+	if err != nil {
+		// a partially downloaded file is kept, so the download is resumed by the next simulation run
+		if info, statErr := os.Stat(partPath); statErr == nil && info.Size() == 0 {
+			os.Remove(partPath)
+		}
+		return err
+	}
+
+	if checksum != "" {
+		if err = verifyChecksum(partPath, checksum); err != nil {
+			os.Remove(partPath)
+			return err
+		}
+	}
+
+	return os.Rename(partPath, cachedPath)
+}
+
+// downloadPart appends the missing part of the file to partPath, the whole file is downloaded
+// again when the server does not support ranges
+func (stager *InputStager) downloadPart(reference string, partPath string, attempt int) error {
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	var req *http.Request
+	if strings.HasPrefix(reference, "http://") || strings.HasPrefix(reference, "https://") {
+		req, err = http.NewRequest("GET", reference, nil)
+	} else {
+		if len(stager.StorageManagers) == 0 {
+			return errors.New("No storage manager available")
+		}
+		reqInfo := RequestInfo{"GET", nil, "", "experiments/" + stager.ExperimentID + "/input_files/" + reference}
+		req, err = NewScalarmRequest(reqInfo, stager.StorageManagers[attempt%len(stager.StorageManagers)], stager.Config)
+	}
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := GetWithBudget(WorkerContext(), stager.HttpClient, req, stager.Config.retryBudget(stager.Timeout))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		simLog.Infof("Resuming download of %s from byte %d", Redact(reference), offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the previous attempt already received the whole file
+		return nil
+	case http.StatusOK:
+		if err = file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	default:
+		return errors.New("Could not download " + reference + ", response code: " + resp.Status)
+	}
+
+	_, err = copyWithPooledBuffer(file, resp.Body)
+	return err
+}
+
+// verifyChecksum compares the SHA-256 checksum of the file with the expected hex encoded value
+func verifyChecksum(filePath string, expected string) error {
+	actual, err := fileSHA256(filePath)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(actual, strings.TrimPrefix(expected, "sha256:")) {
+		return errors.New("Checksum mismatch: expected " + expected + ", got " + actual)
+	}
+
+	return nil
+}
+
+// fileSHA256 returns the hex encoded SHA-256 checksum of the file
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = copyWithPooledBuffer(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,268 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cachedFiles lists files in the cache directory except locks
+func cachedFiles(cacheDir string) []string {
+	files := []string{}
+	entries, _ := ioutil.ReadDir(cacheDir)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".lock") {
+			files = append(files, entry.Name())
+		}
+	}
+
+	return files
+}
+
+func TestInputStagerShouldReplaceReferencesWithPathsOfCachedFiles(t *testing.T) {
+	// === GIVEN ===
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/input_files/mesh.dat":
+			fmt.Fprint(w, "mesh")
+		case "/data/weights.csv":
+			fmt.Fprint(w, "weights")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	rootDir, _ := ioutil.TempDir("", "input_staging")
+	defer os.RemoveAll(rootDir)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(rootDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	newParameters := func() map[string]interface{} {
+		return map[string]interface{}{
+			"x":    1.0,
+			"mesh": map[string]interface{}{"$file": "mesh.dat"},
+			"models": []interface{}{
+				map[string]interface{}{"weights": map[string]interface{}{"$file": "http://data.example.com/data/weights.csv"}},
+			},
+		}
+	}
+
+	// === WHEN ===
+	for _, simulation := range []string{"simulation_1", "simulation_2"} {
+		simulationDir := filepath.Join(rootDir, simulation)
+		parameters := newParameters()
+
+		if err := stager.StageInputFiles(parameters, simulationDir); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+			return
+		}
+
+		// === THEN ===
+		meshPath := filepath.Join(simulationDir, "input_files", "mesh.dat")
+		if parameters["mesh"] != meshPath {
+			t.Errorf("Got: '%v' - Expected '%v'", parameters["mesh"], meshPath)
+		}
+
+		weights := parameters["models"].([]interface{})[0].(map[string]interface{})["weights"]
+		weightsPath := filepath.Join(simulationDir, "input_files", "weights.csv")
+		if weights != weightsPath {
+			t.Errorf("Got: '%v' - Expected '%v'", weights, weightsPath)
+		}
+
+		if content, _ := ioutil.ReadFile(weightsPath); string(content) != "weights" {
+			t.Errorf("Got: '%s' - Expected '%v'", content, "weights")
+		}
+
+		if parameters["x"] != 1.0 {
+			t.Errorf("Got: '%v' - Expected '%v'", parameters["x"], 1.0)
+		}
+	}
+
+	if len(requests) != 2 || requests["/data/weights.csv"] != 1 {
+		t.Errorf("Got: '%v' - Expected every file to be downloaded once", requests)
+	}
+}
+
+func TestInputStagerShouldReturnErrorWhenFileIsMissing(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer server.Close()
+
+	rootDir, _ := ioutil.TempDir("", "input_staging")
+	defer os.RemoveAll(rootDir)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(rootDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	// === WHEN ===
+	err := stager.StageInputFiles(map[string]interface{}{"mesh": map[string]interface{}{"$file": "mesh.dat"}},
+		filepath.Join(rootDir, "simulation_1"))
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+
+	if entries := cachedFiles(stager.CacheDir); len(entries) != 0 {
+		t.Errorf("Got: '%v' - Expected empty cache", entries)
+	}
+}
+
+func TestInputStagerShouldResumeInterruptedDownloadOfInputBlob(t *testing.T) {
+	// === GIVEN ===
+	content := strings.Repeat("initial conditions ", 1000)
+	sum := sha256.Sum256([]byte(content))
+
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/input_files/5a1b" {
+			w.WriteHeader(404)
+			return
+		}
+
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// the connection is closed in the middle of the file
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			fmt.Fprint(w, content[:len(content)/2])
+			return
+		}
+
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", len(content)/2, len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, content[len(content)/2:])
+	}))
+	defer server.Close()
+
+	rootDir, _ := ioutil.TempDir("", "input_staging")
+	defer os.RemoveAll(rootDir)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(rootDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	inputFiles := []interface{}{
+		map[string]interface{}{"id": "5a1b", "name": "conditions.bin", "sha256": hex.EncodeToString(sum[:])},
+	}
+
+	// === WHEN ===
+	err := stager.StageInputBlobs(inputFiles, filepath.Join(rootDir, "simulation_1"))
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	expectedRange := fmt.Sprintf("bytes=%d-", len(content)/2)
+	if len(ranges) != 2 || ranges[1] != expectedRange {
+		t.Errorf("Got: '%v' - Expected the second request with range '%v'", ranges, expectedRange)
+	}
+
+	staged, _ := ioutil.ReadFile(filepath.Join(rootDir, "simulation_1", "input_files", "conditions.bin"))
+	if string(staged) != content {
+		t.Errorf("Got: %v bytes - Expected the whole file of %v bytes", len(staged), len(content))
+	}
+}
+
+func TestInputStagerShouldRejectInputBlobWithInvalidChecksum(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "tampered")
+	}))
+	defer server.Close()
+
+	rootDir, _ := ioutil.TempDir("", "input_staging")
+	defer os.RemoveAll(rootDir)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(rootDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	sum := sha256.Sum256([]byte("original"))
+	inputFiles := []interface{}{map[string]interface{}{"id": "5a1b", "sha256": hex.EncodeToString(sum[:])}}
+
+	// === WHEN ===
+	err := stager.StageInputBlobs(inputFiles, filepath.Join(rootDir, "simulation_1"))
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+
+	if entries := cachedFiles(stager.CacheDir); len(entries) != 0 {
+		t.Errorf("Got: '%v' - Expected empty cache", entries)
+	}
+}
+
+func TestInputStagerShouldStageCopiesWithoutCollisions(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	rootDir, _ := ioutil.TempDir("", "input_staging")
+	defer os.RemoveAll(rootDir)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(rootDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	simulationDir := filepath.Join(rootDir, "simulation_1")
+
+	// === WHEN ===
+	firstPath, firstErr := stager.stageFile("http://data.example.com/a/mesh.dat", "mesh.dat", "", simulationDir)
+	secondPath, secondErr := stager.stageFile("http://data.example.com/b/mesh.dat", "mesh.dat", "", simulationDir)
+	ioutil.WriteFile(firstPath, []byte("changed by an adapter"), 0666)
+	thirdPath, _ := stager.stageFile("http://data.example.com/a/mesh.dat", "mesh.dat", "", filepath.Join(rootDir, "simulation_2"))
+
+	// === THEN ===
+	if firstErr != nil || secondErr != nil || firstPath == secondPath {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", firstPath, secondPath, "two different paths")
+	}
+	if content, _ := ioutil.ReadFile(secondPath); string(content) != "/b/mesh.dat" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "/b/mesh.dat")
+	}
+	if content, _ := ioutil.ReadFile(thirdPath); string(content) != "/a/mesh.dat" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "/a/mesh.dat")
+	}
+}
@@ -0,0 +1,51 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// directory of a code base with templates of input files rendered by SiM instead of an input_writer
+const inputTemplatesDir = "input_templates"
+
+// RenderInputTemplates renders every file from templatesDir with Go text/template into the same relative
+// path in simulationDirPath, the '.tmpl' extension is removed; input parameters are available as fields
+// of the template data, e.g. {{.x}}, and a parameter missing in input.json is an error
+func RenderInputTemplates(templatesDir string, simulationDirPath string, parameters map[string]interface{}) error {
+	return filepath.Walk(templatesDir, func(templatePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(templatesDir, templatePath)
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := template.New(relativePath).Option("missingkey=error").ParseFiles(templatePath)
+		if err != nil {
+			return err
+		}
+
+		outputPath := filepath.Join(simulationDirPath, strings.TrimSuffix(relativePath, ".tmpl"))
+		if err = os.MkdirAll(filepath.Dir(outputPath), 0777); err != nil {
+			return err
+		}
+
+		output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		err = tmpl.ExecuteTemplate(output, filepath.Base(templatePath), parameters)
+		output.Close()
+		if err != nil {
+			return errors.New("Could not render " + relativePath + ": " + err.Error())
+		}
+
+		return nil
+	})
+}
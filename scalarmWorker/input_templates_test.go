@@ -0,0 +1,58 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderInputTemplatesShouldWriteFilesWithInputParameters(t *testing.T) {
+	// === GIVEN ===
+	templatesDir, _ := ioutil.TempDir("", "input_templates")
+	defer os.RemoveAll(templatesDir)
+	simulationDir, _ := ioutil.TempDir("", "simulation")
+	defer os.RemoveAll(simulationDir)
+
+	os.MkdirAll(filepath.Join(templatesDir, "conf"), 0777)
+	ioutil.WriteFile(filepath.Join(templatesDir, "model.in.tmpl"), []byte("steps = {{.steps}}\nname = {{.name}}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(templatesDir, "conf", "run.sh"), []byte("#!/bin/sh\necho {{.steps}}\n"), 0755)
+
+	parameters := map[string]interface{}{"steps": 100.0, "name": "test"}
+
+	// === WHEN ===
+	err := RenderInputTemplates(templatesDir, simulationDir, parameters)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if content, _ := ioutil.ReadFile(filepath.Join(simulationDir, "model.in")); string(content) != "steps = 100\nname = test\n" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "steps = 100\nname = test\n")
+	}
+
+	info, err := os.Stat(filepath.Join(simulationDir, "conf", "run.sh"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Rendered file should keep the mode of its template")
+	}
+}
+
+func TestRenderInputTemplatesShouldReturnErrorForMissingParameter(t *testing.T) {
+	// === GIVEN ===
+	templatesDir, _ := ioutil.TempDir("", "input_templates")
+	defer os.RemoveAll(templatesDir)
+	simulationDir, _ := ioutil.TempDir("", "simulation")
+	defer os.RemoveAll(simulationDir)
+
+	ioutil.WriteFile(filepath.Join(templatesDir, "model.in.tmpl"), []byte("steps = {{.steps}}\n"), 0644)
+
+	// === WHEN ===
+	err := RenderInputTemplates(templatesDir, simulationDir, map[string]interface{}{"name": "test"})
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
@@ -1,21 +1,27 @@
 package scalarmWorker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
 	"time"
 )
 
+var progressInfoLog = NewLogger("progress_info")
+
 // IntermediateMonitoring - executes progress monitor of a simulation run and stops when it gets a signal from the main thread
-func (sim SimulationManager) IntermediateMonitoring(messages chan struct{}, finished chan struct{}, codeBaseDir string, experimentManagers []string, simIndex int,
+// or ctx is cancelled; its failures are failures of the simulation run executed by the pool
+func (sim SimulationManager) IntermediateMonitoring(ctx context.Context, pool *RunPool, messages chan struct{}, finished chan struct{}, capabilities *CodeBaseCapabilities, experimentManagers []string, simIndex int,
 	simulationDirPath string, client *http.Client, experimentID string) {
 
+	// runs in its own goroutine, errors are raised in the worker loop once runs being executed finished
+	defer pool.RecoverFailure()
+
 	communicationTimeout := 30 * time.Second
 
 	em := ExperimentManager{
@@ -23,29 +29,44 @@ func (sim SimulationManager) IntermediateMonitoring(messages chan struct{}, fini
 		BaseUrls:             experimentManagers,
 		CommunicationTimeout: communicationTimeout,
 		Config:               sim.Config,
-		ExperimentId:         experimentID}
+		ExperimentId:         experimentID,
+		Context:              ctx}
+
+	// chatty progress monitors can send their results in batches instead of one request per result
+	var batcher *ProgressInfoBatcher
+	if sim.Config.ProgressBatchSize > 1 {
+		batcher = NewProgressInfoBatcher(&em, simIndex, sim.Config.ProgressBatchSize,
+			time.Duration(sim.Config.ProgressBatchInterval)*time.Second)
+	}
 
-	if _, err := os.Stat(path.Join(codeBaseDir, "progress_monitor")); err == nil {
+	if capabilities.ProgressMonitor {
 		for {
-			progressMonitorCmd := exec.Command("sh", "-c", path.Join(codeBaseDir, "progress_monitor >>_stdout.txt 2>&1"))
-			progressMonitorCmd.Dir = simulationDirPath
-
-			if err = progressMonitorCmd.Run(); err != nil {
-				fmt.Println("[SiM] An error occurred during 'progress_monitor' execution.")
-				fmt.Println("[SiM] Please check if 'progress_monitor' executes correctly on the selected infrastructure.")
-				fmt.Printf("[Fatal error] occured during '%v' execution \n", strings.Join(progressMonitorCmd.Args, " "))
-				fmt.Printf("[Fatal error] %s\n", err.Error())
-				PrintStdoutLog()
-				os.Exit(1)
+			progressMonitorCmd := sim.adapterCommand(adapterCall(capabilities.Dir, "progress_monitor")+" >>"+runLogName(simulationDirPath, simIndex)+" 2>>"+stderrLog, simulationDirPath)
+
+			if err := progressMonitorCmd.Run(); err != nil {
+				// the monitor was killed together with the worker loop
+				if ctx.Err() != nil {
+					finished <- struct{}{}
+					return
+				}
+				simLog.Errorf("An error occurred during 'progress_monitor' execution.")
+				simLog.Errorf("Please check if 'progress_monitor' executes correctly on the selected infrastructure.")
+				simLog.Errorf("Fatal error occured during '%v' execution: %s", strings.Join(progressMonitorCmd.Args, " "), err.Error())
+				PrintStdoutLog(path.Join(simulationDirPath, runLogName(simulationDirPath, simIndex)), sim.Config.StdoutLogLines)
+				if sim.Config.PauseOnFailure {
+					PauseOnFailure("progress_monitor", progressMonitorCmd)
+				}
+				Fatal(&AdapterFailure{"progress_monitor", experimentID, simIndex, err})
 			}
 
 			intermediateResults := new(SimulationRunResults)
 
-			if _, err := os.Stat("intermediate_result.json"); os.IsNotExist(err) {
+			intermediateResultPath := path.Join(simulationDirPath, "intermediate_result.json")
+			if _, err := os.Stat(intermediateResultPath); os.IsNotExist(err) {
 				intermediateResults.Status = "error"
 				intermediateResults.Reason = fmt.Sprintf("No 'intermediate_result.json' file found: %s", err.Error())
 			} else {
-				file, err := os.Open("intermediate_result.json")
+				file, err := os.Open(intermediateResultPath)
 
 				if err != nil {
 					intermediateResults.Status = "error"
@@ -63,32 +84,51 @@ func (sim SimulationManager) IntermediateMonitoring(messages chan struct{}, fini
 			}
 
 			if intermediateResults.Status == "ok" {
+				recordProgress(simIndex, intermediateResults.Results)
+			}
+
+			if intermediateResults.Status == "ok" && batcher != nil {
+				if err := batcher.Add(intermediateResults); err != nil {
+					Fatal(err)
+				}
+			} else if intermediateResults.Status == "ok" {
 				data := url.Values{}
 				data.Set("status", intermediateResults.Status)
 				data.Add("reason", intermediateResults.Reason)
-				b, _ := json.Marshal(intermediateResults.Results)
+				b, _ := marshalJSON(intermediateResults.Results)
 				data.Add("result", string(b))
 
-				fmt.Printf("[SiM][progress_info] Results: %v\n", data)
+				progressInfoLog.Debugf("Results: %v", data)
 
-				err = em.PostProgressInfo(simIndex, data)
+				err := em.PostProgressInfo(simIndex, data)
 
 				if err != nil {
 					Fatal(err)
 				}
 			}
 
-			time.Sleep(10 * time.Second)
 			select {
+			case <-time.After(10 * time.Second):
+			case <-ctx.Done():
+			}
+			select {
+			case <-ctx.Done():
+				finished <- struct{}{}
+				return
 			case _ = <-messages:
-				fmt.Printf("[SiM][progress_info] Our work is finished\n")
+				progressInfoLog.Infof("Our work is finished")
+				if batcher != nil {
+					if err := batcher.Flush(); err != nil {
+						Fatal(err)
+					}
+				}
 				finished <- struct{}{}
 				return
 			default:
 			}
 		}
 	} else {
-		fmt.Printf("[SiM][progress_info] There is no progress monitor script\n")
+		progressInfoLog.Infof("There is no progress monitor script")
 		finished <- struct{}{}
 	}
 }
@@ -0,0 +1,138 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// levels of log messages, messages below the configured level are not printed
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+var logLevels = []string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}
+
+var logging = struct {
+	level  int
+	output io.Writer
+	mutex  sync.Mutex
+}{level: 1, output: os.Stdout}
+
+// logger of messages which do not belong to any module
+var simLog = NewLogger("")
+
+// Logger prints messages of a module of SiM with a timestamp, level and the prefix of the module,
+// e.g. [SiM][progress_info]; output goes to the standard output unless the dashboard captures it
+type Logger struct {
+	prefix string
+}
+
+// NewLogger creates a logger of the module, messages of the empty module are prefixed with [SiM] only
+func NewLogger(module string) *Logger {
+	prefix := "[SiM]"
+	if module != "" {
+		prefix += "[" + module + "]"
+	}
+
+	return &Logger{prefix: prefix}
+}
+
+func logLevelIndex(level string) int {
+	for i, name := range logLevels {
+		if name == level {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// IsLogLevel tells whether the level is one of debug, info, warn and error
+func IsLogLevel(level string) bool {
+	return logLevelIndex(level) >= 0
+}
+
+// SetLogLevel sets the minimum level of printed messages
+func SetLogLevel(level string) error {
+	index := logLevelIndex(level)
+	if index < 0 {
+		return errors.New("log_level has to be 'debug', 'info', 'warn' or 'error'")
+	}
+
+	logging.mutex.Lock()
+	defer logging.mutex.Unlock()
+
+	logging.level = index
+	return nil
+}
+
+// LogLevel returns the minimum level of printed messages
+func LogLevel() string {
+	logging.mutex.Lock()
+	defer logging.mutex.Unlock()
+
+	return logLevels[logging.level]
+}
+
+// Debugf prints details useful when diagnosing SiM
+func (logger *Logger) Debugf(format string, args ...interface{}) {
+	logger.logf(LogLevelDebug, format, args...)
+}
+
+// Infof prints progress of SiM
+func (logger *Logger) Infof(format string, args ...interface{}) {
+	logger.logf(LogLevelInfo, format, args...)
+}
+
+// Warnf prints failures SiM recovers from
+func (logger *Logger) Warnf(format string, args ...interface{}) {
+	logger.logf(LogLevelWarn, format, args...)
+}
+
+// Errorf prints failures which stop a simulation run or SiM
+func (logger *Logger) Errorf(format string, args ...interface{}) {
+	logger.logf(LogLevelError, format, args...)
+}
+
+// SetLogOutput makes loggers and other output of SiM, e.g. tailed run logs, go to writer instead of
+// the standard output; it returns the previous output
+func SetLogOutput(writer io.Writer) io.Writer {
+	logging.mutex.Lock()
+	defer logging.mutex.Unlock()
+
+	previous := logging.output
+	logging.output = writer
+	return previous
+}
+
+// LogOutput is the current output of SiM, writes from concurrent goroutines are not interleaved
+type LogOutput struct{}
+
+func (LogOutput) Write(p []byte) (int, error) {
+	logging.mutex.Lock()
+	defer logging.mutex.Unlock()
+
+	return logging.output.Write(p)
+}
+
+func (logger *Logger) logf(level string, format string, args ...interface{}) {
+	logging.mutex.Lock()
+	defer logging.mutex.Unlock()
+
+	if logLevelIndex(level) < logging.level {
+		return
+	}
+
+	// credentials are redacted from every message, also from those whose call sites did not redact them
+	message := Redact(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+	fmt.Fprintf(logging.output, "%s %-5s %s %s\n", time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		strings.ToUpper(level), logger.prefix, message)
+}
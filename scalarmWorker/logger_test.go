@@ -0,0 +1,76 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func captureLog(t *testing.T, log func()) string {
+	output := &bytes.Buffer{}
+	previous := SetLogOutput(output)
+	log()
+	SetLogOutput(previous)
+
+	return output.String()
+}
+
+func TestLoggerShouldPrintLevelAndModulePrefix(t *testing.T) {
+	// === WHEN ===
+	output := captureLog(t, func() {
+		NewLogger("progress_info").Warnf("Could not send %d results\n", 3)
+	})
+
+	// === THEN ===
+	if !strings.HasSuffix(output, " WARN  [SiM][progress_info] Could not send 3 results\n") {
+		t.Errorf("Got: '%v' - Expected '%v'", output, "<timestamp> WARN  [SiM][progress_info] Could not send 3 results")
+	}
+}
+
+func TestLoggerShouldSkipMessagesBelowLogLevel(t *testing.T) {
+	// === GIVEN ===
+	defer SetLogLevel(LogLevelInfo)
+
+	if err := SetLogLevel(LogLevelWarn); err != nil {
+		t.Fatal(err)
+	}
+
+	// === WHEN ===
+	output := captureLog(t, func() {
+		simLog.Debugf("debug")
+		simLog.Infof("info")
+		simLog.Warnf("warn")
+		simLog.Errorf("error")
+	})
+
+	// === THEN ===
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "WARN  [SiM] warn") || !strings.HasSuffix(lines[1], "ERROR [SiM] error") {
+		t.Errorf("Got: '%v' - Expected '%v'", lines, "warn and error messages")
+	}
+}
+
+func TestSetLogLevelShouldRejectUnknownLevels(t *testing.T) {
+	// === WHEN ===
+	err := SetLogLevel("verbose")
+
+	// === THEN ===
+	if err == nil || LogLevel() != LogLevelInfo {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", err, LogLevel(), "an error and the info level")
+	}
+}
+
+func TestLoggerShouldRedactSecrets(t *testing.T) {
+	// === GIVEN ===
+	RegisterSecret("logged-password")
+
+	// === WHEN ===
+	output := captureLog(t, func() {
+		simLog.Errorf("Fatal error occured during '%v' execution", "./progress_monitor --password logged-password")
+	})
+
+	// === THEN ===
+	if strings.Contains(output, "logged-password") || !strings.Contains(output, redactedValue) {
+		t.Errorf("Got: '%v' - Expected '%v'", output, "the password replaced with "+redactedValue)
+	}
+}
@@ -0,0 +1,113 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// how often resident memory of the executor is sampled
+var memorySampleInterval = time.Second
+
+// MemoryLimit returns the limit of resident memory of the executor of a simulation run in bytes, given in MB
+// by the 'memory_limit' execution constraint, 0 means there is no limit
+func MemoryLimit(simulationRun map[string]interface{}) uint64 {
+	constraints, _ := simulationRun["execution_constraints"].(map[string]interface{})
+
+	var megabytes float64
+	switch limit := constraints["memory_limit"].(type) {
+	case float64:
+		megabytes = limit
+	case string:
+		megabytes, _ = strconv.ParseFloat(limit, 64)
+	}
+	if megabytes <= 0 {
+		return 0
+	}
+
+	return uint64(megabytes * 1024 * 1024)
+}
+
+// processTreeRSS returns resident memory of a process and all of its children in bytes
+func processTreeRSS(pid int) (uint64, error) {
+	process, err := newProcessFunc(int32(pid))
+	if err != nil {
+		return 0, err
+	}
+
+	memInfo, err := process.MemoryInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	rss := memInfo.RSS
+	for _, child := range collectChildrenProcesses(process) {
+		if childMemInfo, err := child.MemoryInfo(); err == nil {
+			rss += childMemInfo.RSS
+		}
+	}
+
+	return rss, nil
+}
+
+// MemoryWatch samples resident memory of the executor and its children, recording the peak, and stops
+// the executor, which runs in its own process group, once it exceeds Limit
+type MemoryWatch struct {
+	Limit uint64
+
+	peak     uint64
+	exceeded int32
+	done     chan struct{}
+}
+
+// StartMemoryWatch starts sampling memory of a started executor until it exits; without a limit only the peak
+// is recorded
+func StartMemoryWatch(cmd *exec.Cmd, limit uint64, exited <-chan struct{}) *MemoryWatch {
+	watch := &MemoryWatch{Limit: limit, done: make(chan struct{})}
+
+	go func() {
+		defer close(watch.done)
+
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+
+		for {
+			if rss, err := processTreeRSS(cmd.Process.Pid); err == nil {
+				if rss > atomic.LoadUint64(&watch.peak) {
+					atomic.StoreUint64(&watch.peak, rss)
+				}
+				if limit > 0 && rss > limit {
+					atomic.StoreInt32(&watch.exceeded, 1)
+					simLog.Warnf("The executor uses %d MB of memory, exceeding its limit of %d MB, stopping it ...",
+						rss/(1024*1024), limit/(1024*1024))
+					terminateProcessGroup(cmd, cancellationGrace, exited)
+					return
+				}
+			}
+
+			select {
+			case <-exited:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return watch
+}
+
+// Wait waits until sampling stops, after the executor exited
+func (watch *MemoryWatch) Wait() {
+	<-watch.done
+}
+
+// Peak returns the highest resident memory of the executor and its children seen so far, in bytes
+func (watch *MemoryWatch) Peak() uint64 {
+	return atomic.LoadUint64(&watch.peak)
+}
+
+// Exceeded tells whether the executor was stopped because of its memory limit
+func (watch *MemoryWatch) Exceeded() bool {
+	return atomic.LoadInt32(&watch.exceeded) == 1
+}
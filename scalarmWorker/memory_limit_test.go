@@ -0,0 +1,84 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimitShouldBeParsedFromExecutionConstraints(t *testing.T) {
+	cases := []struct {
+		simulationRun map[string]interface{}
+		expected      uint64
+	}{
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"memory_limit": 512.0}}, 512 * 1024 * 1024},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"memory_limit": "2"}}, 2 * 1024 * 1024},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"memory_limit": 0.0}}, 0},
+		{map[string]interface{}{}, 0},
+	}
+
+	for _, c := range cases {
+		if limit := MemoryLimit(c.simulationRun); limit != c.expected {
+			t.Errorf("Got: '%v' - Expected '%v' for %v", limit, c.expected, c.simulationRun)
+		}
+	}
+}
+
+func TestMemoryWatchShouldStopExecutorExceedingItsLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30; wait")
+	startInProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+
+	// === WHEN ===
+	start := time.Now()
+	watch := StartMemoryWatch(cmd, 1, exited)
+	cmd.Wait()
+	close(exited)
+	watch.Wait()
+
+	// === THEN ===
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("The executor should be stopped right after exceeding its memory limit")
+	}
+	if !watch.Exceeded() {
+		t.Errorf("Got: '%v' - Expected '%v'", watch.Exceeded(), true)
+	}
+	if watch.Peak() == 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", watch.Peak(), "peak RSS of the executor")
+	}
+}
+
+func TestMemoryWatchShouldRecordPeakWithoutLimit(t *testing.T) {
+	defer func(interval time.Duration) { memorySampleInterval = interval }(memorySampleInterval)
+	memorySampleInterval = 10 * time.Millisecond
+
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "sleep 1")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+
+	// === WHEN ===
+	watch := StartMemoryWatch(cmd, 0, exited)
+	cmd.Wait()
+	close(exited)
+	watch.Wait()
+
+	// === THEN ===
+	if watch.Exceeded() {
+		t.Errorf("Got: '%v' - Expected '%v'", watch.Exceeded(), false)
+	}
+	if watch.Peak() == 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", watch.Peak(), "peak RSS of the executor")
+	}
+}
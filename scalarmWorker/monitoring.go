@@ -2,8 +2,9 @@ package scalarmWorker
 
 import (
 	"errors"
-	"fmt"
+	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	pscpu "github.com/shirou/gopsutil/cpu"
@@ -64,7 +65,7 @@ func ExtractHostInfo(ps *PsUtil) (*HostInfo, error) {
 
 	coreStats, err := ps.getCPUInfo()
 	if err != nil {
-		fmt.Printf("[pscpu.Info()] error: %v,\n", err)
+		simLog.Warnf("pscpu.Info() error: %v", err)
 		return nil, err
 	}
 
@@ -82,7 +83,7 @@ func ExtractHostInfo(ps *PsUtil) (*HostInfo, error) {
 
 	host, err := ps.getHostInfo()
 	if err != nil {
-		fmt.Printf("[pshost.Info()] error: %v,\n", err)
+		simLog.Warnf("pshost.Info() error: %v", err)
 		return nil, err
 	}
 
@@ -97,6 +98,40 @@ func ExtractHostInfo(ps *PsUtil) (*HostInfo, error) {
 	return info, nil
 }
 
+// the host does not change while SiM runs, its info is extracted on the first use
+var cachedHostInfo struct {
+	info *HostInfo
+	once sync.Once
+}
+
+// currentHostInfo returns information about the host extracted once, nil when it cannot be extracted
+func currentHostInfo() *HostInfo {
+	cachedHostInfo.once.Do(func() {
+		ps := PsUtil{getHostInfo: pshost.Info, getCPUInfo: pscpu.Info}
+		cachedHostInfo.info, _ = ExtractHostInfo(&ps)
+	})
+
+	return cachedHostInfo.info
+}
+
+// setCPUModel adds the processor of the host to results or progress information of a simulation run
+// as the 'cpu_model' JSON field
+func setCPUModel(data url.Values) {
+	hostInfo := currentHostInfo()
+	if hostInfo == nil {
+		return
+	}
+
+	model, err := marshalJSON(map[string]interface{}{
+		"model_name": hostInfo.ModelName,
+		"cores":      hostInfo.Cores,
+		"mhz":        hostInfo.Mhz,
+	})
+	if err == nil {
+		data.Set("cpu_model", string(model))
+	}
+}
+
 // PerformanceStats keeps basic performance-related information
 type PerformanceStats struct {
 	Timestamp int64 `json:"timestamp"`
@@ -276,13 +311,13 @@ func RunProcessMonitoring(pid int, sim *SimulationManager, em *ExperimentManager
 
 	hostInfo, err := ExtractHostInfo(&ps)
 	if err != nil {
-		fmt.Printf("[SiM] Could not extract host info - %v\n", err)
+		simLog.Warnf("Could not extract host info - %v", err)
 		return
 	}
 
 	err = em.ReportHostInfo(simulationIndex, hostInfo)
 	if err != nil {
-		fmt.Printf("[SiM] An error occurred during 'ReportHostInfo' - %v\n", err)
+		simLog.Warnf("An error occurred during 'ReportHostInfo' - %v", err)
 	}
 
 	if sim.Config.MonitoringInterval > 0 {
@@ -294,7 +329,7 @@ func RunProcessMonitoring(pid int, sim *SimulationManager, em *ExperimentManager
 			// this gets current stats
 			currentPerformanceStats, err := CollectPerformanceStats(pid, &ps)
 			if err != nil {
-				fmt.Printf("[SiM] Could not extract performance statistics - %v\n", err)
+				simLog.Warnf("Could not extract performance statistics - %v", err)
 				return
 			}
 			// aggregate last and current stats
@@ -305,7 +340,7 @@ func RunProcessMonitoring(pid int, sim *SimulationManager, em *ExperimentManager
 			// report aggregated stats
 			err = em.ReportPerformanceStats(simulationIndex, aggregatedPerformanceStats)
 			if err != nil {
-				fmt.Printf("[SiM] An error occurred during 'ReportPerformanceStats' - %v\n", err)
+				simLog.Warnf("An error occurred during 'ReportPerformanceStats' - %v", err)
 			}
 
 			time.Sleep(time.Duration(sim.Config.MonitoringInterval) * time.Second)
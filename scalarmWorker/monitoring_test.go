@@ -2,6 +2,7 @@ package scalarmWorker
 
 import (
 	"errors"
+	"net/url"
 	"os"
 	"os/exec"
 	"testing"
@@ -187,6 +188,24 @@ func TestExtractHostShouldReturnCPUErrorWhenCPUErrorOccurs(t *testing.T) {
 	}
 }
 
+func TestSetCPUModelShouldReuseHostInfo(t *testing.T) {
+	// === GIVEN ===
+	cachedHostInfo.once.Do(func() {})
+	defer func(info *HostInfo) { cachedHostInfo.info = info }(cachedHostInfo.info)
+	cachedHostInfo.info = &HostInfo{ModelName: "Intel(R) Xeon(R) CPU E5-2680 v3 @ 2.50GHz", Cores: 24, Mhz: 2500}
+
+	data := url.Values{}
+
+	// === WHEN ===
+	setCPUModel(data)
+
+	// === THEN ===
+	expected := `{"cores":24,"mhz":2500,"model_name":"Intel(R) Xeon(R) CPU E5-2680 v3 @ 2.50GHz"}`
+	if data.Get("cpu_model") != expected {
+		t.Errorf("Got: '%v' - Expected '%v'", data.Get("cpu_model"), expected)
+	}
+}
+
 func TestExtractPerformanceStatsShouldReturnFilledStructWhenNoErrorsOccur(t *testing.T) {
 	ps := new(PsUtil)
 	ps.getCPUTimes = fakeTimesStats
@@ -0,0 +1,26 @@
+package scalarmWorker
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolateNetwork starts the command in a new network namespace without any interfaces up, so it has
+// no network access at all while SiM keeps its connectivity; without root privileges a user namespace
+// mapping only the current user is created as well
+func isolateNetwork(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+
+	if os.Geteuid() != 0 {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Geteuid(), HostID: os.Geteuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getegid(), HostID: os.Getegid(), Size: 1}}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsolateNetworkShouldHideAllInterfaces(t *testing.T) {
+	// === GIVEN ===
+	cmd := exec.Command("cat", "/proc/self/net/dev")
+	isolateNetwork(cmd)
+
+	// === WHEN ===
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Skipf("network namespaces are not available: %v %s", err, output)
+	}
+
+	// === THEN ===
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.Contains(line, ":") && !strings.HasPrefix(strings.TrimSpace(line), "lo:") {
+			t.Errorf("Got: '%v' - Expected only the loopback interface", line)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package scalarmWorker
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func isolateNetwork(cmd *exec.Cmd) error {
+	return errors.New("Network isolation of the executor is supported only on Linux")
+}
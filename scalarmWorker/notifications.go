@@ -0,0 +1,122 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var notificationsLog = NewLogger("notifications")
+
+// max time of sending a single notification, a dead target must not delay the exit of the worker
+var notificationTimeout = 10 * time.Second
+
+// Notifier sends short messages about fatal conditions and finished experiments to a Slack webhook
+// and by email, so owners of experiments learn about dead workers quickly
+type Notifier struct {
+	SlackWebhook string
+	SMTPServer   string
+	SMTPUser     string
+	SMTPPass     string
+	EmailFrom    string
+	EmailTo      []string
+	Hostname     string
+	// notifications are not sent with the client of Scalarm, whose TLS config may pin Scalarm certificates
+	HttpClient *http.Client
+}
+
+// NewNotifier creates a notifier for targets from the config, nil when none is configured
+func NewNotifier(config *SimulationManagerConfig) *Notifier {
+	if config.NotifySlackWebhook == "" && (config.NotifySMTPServer == "" || len(config.NotifyEmailTo) == 0) {
+		return nil
+	}
+
+	// the webhook URL is a credential of the Slack channel
+	RegisterSecret(config.NotifySlackWebhook)
+	RegisterSecret(config.NotifySMTPPass)
+
+	hostname, _ := os.Hostname()
+
+	notifier := &Notifier{
+		SlackWebhook: config.NotifySlackWebhook,
+		SMTPServer:   config.NotifySMTPServer,
+		SMTPUser:     config.NotifySMTPUser,
+		SMTPPass:     config.NotifySMTPPass,
+		EmailFrom:    config.NotifyEmailFrom,
+		EmailTo:      config.NotifyEmailTo,
+		Hostname:     hostname,
+		HttpClient:   &http.Client{Timeout: notificationTimeout},
+	}
+	if notifier.EmailFrom == "" {
+		notifier.EmailFrom = "scalarm-worker@" + hostname
+	}
+
+	return notifier
+}
+
+// Notify sends the message to all targets, errors are only logged; a nil notifier sends nothing
+func (notifier *Notifier) Notify(subject string, message string) {
+	if notifier == nil {
+		return
+	}
+
+	subject = fmt.Sprintf("[SiM@%s] %s", notifier.Hostname, subject)
+	message = Redact(message)
+
+	if notifier.SlackWebhook != "" {
+		if err := notifier.notifySlack(subject, message); err != nil {
+			notificationsLog.Warnf("Could not notify Slack: %v", Redact(err.Error()))
+		}
+	}
+
+	if notifier.SMTPServer != "" && len(notifier.EmailTo) > 0 {
+		if err := notifier.sendEmail(subject, message); err != nil {
+			notificationsLog.Warnf("Could not send email: %v", Redact(err.Error()))
+		}
+	}
+}
+
+func (notifier *Notifier) notifySlack(subject string, message string) error {
+	body, err := marshalJSON(map[string]string{"text": "*" + subject + "*\n" + message})
+	if err != nil {
+		return err
+	}
+
+	resp, err := notifier.HttpClient.Post(notifier.SlackWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Slack response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
+
+func (notifier *Notifier) sendEmail(subject string, message string) error {
+	var auth smtp.Auth
+	if notifier.SMTPUser != "" {
+		host, _, err := net.SplitHostPort(notifier.SMTPServer)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", notifier.SMTPUser, notifier.SMTPPass, host)
+	}
+
+	email := "From: " + notifier.EmailFrom + "\r\n" +
+		"To: " + strings.Join(notifier.EmailTo, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" +
+		strings.Replace(message, "\n", "\r\n", -1) + "\r\n"
+
+	return smtp.SendMail(notifier.SMTPServer, auth, notifier.EmailFrom, notifier.EmailTo, []byte(email))
+}
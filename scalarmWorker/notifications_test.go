@@ -0,0 +1,50 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotifierShouldPostRedactedMessagesToSlack(t *testing.T) {
+	// === GIVEN ===
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.NotifySlackWebhook = server.URL + "/services/T000/B000/secret"
+	RegisterCredentials(config.ExperimentManagerUser, "notification-pass")
+
+	notifier := NewNotifier(config)
+
+	// === WHEN ===
+	notifier.Notify("Worker died", "Could not log in with notification-pass")
+
+	// === THEN ===
+	text := received["text"]
+	if !strings.Contains(text, "Worker died") || !strings.Contains(text, "Could not log in") {
+		t.Errorf("Got: '%v' - Expected the subject and the message", text)
+	}
+
+	if strings.Contains(text, "notification-pass") {
+		t.Errorf("Got: '%v' - Expected the password to be redacted", text)
+	}
+}
+
+func TestNotifierShouldNotBeCreatedWithoutTargets(t *testing.T) {
+	// === WHEN ===
+	notifier := NewNotifier(getSimConfig())
+
+	// === THEN ===
+	if notifier != nil {
+		t.Errorf("Got: '%v' - Expected nil", notifier)
+	}
+
+	// a nil notifier ignores messages
+	notifier.Notify("Experiment finished", "")
+}
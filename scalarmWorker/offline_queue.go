@@ -0,0 +1,373 @@
+package scalarmWorker
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var offlineLog = NewLogger("offline")
+
+// experiment id used in the offline mode when none is configured
+const offlineExperimentID = "offline"
+
+// suffixes of files written next to a simulation run definition in the offline directory
+const (
+	offlineClaimSuffix  = ".claimed"
+	offlineResultSuffix = ".result.json"
+	offlineReportSuffix = ".report.json"
+	offlineOutputSuffix = ".output.tar.gz"
+	offlineStdoutSuffix = ".stdout.txt"
+	offlineStderrSuffix = ".stderr.txt"
+	// directory of extra output files, which keep their paths relative to the simulation directory in it
+	offlineOutputFilesSuffix = ".output_files"
+)
+
+// OfflineQueue serves the subset of the Information Service, Experiment Manager and Storage Manager API
+// used by workers from a local directory, so air-gapped clusters can execute pre-exported simulation runs:
+//
+//	<dir>/code_base.zip            code base of the experiment, with optional code_base.zip.sig
+//	<dir>/input_files/             files referenced by input parameters
+//	<dir>/<name>.json              simulation run definitions in the format of next_simulation responses
+//
+// Results of a run are written next to its definition as <name>.result.json, with output.tar.gz, stdout, stderr
+// and the run report in <name>.output.tar.gz, <name>.stdout.txt, <name>.stderr.txt and <name>.report.json, extra
+// output files under <name>.output_files/; a run being executed is claimed with <name>.claimed, so workers sharing
+// the directory do not execute it twice
+type OfflineQueue struct {
+	Dir          string
+	ExperimentID string
+	// address under which the queue is served, returned as the address of all Scalarm services; it ends
+	// with a random token generated for every start, so other accounts on the node cannot use the queue
+	Address string
+
+	token string
+
+	// definitions of simulation runs handed out by this queue
+	runs  map[int]string
+	mutex sync.Mutex
+}
+
+// NewOfflineQueue creates a queue of simulation runs defined in dir
+func NewOfflineQueue(dir string, experimentID string) *OfflineQueue {
+	return &OfflineQueue{Dir: dir, ExperimentID: experimentID, runs: map[int]string{}}
+}
+
+// StartOfflineQueue serves the queue on a loopback address, which is returned
+func StartOfflineQueue(queue *OfflineQueue) (string, error) {
+	if _, err := os.Stat(filepath.Join(queue.Dir, "code_base.zip")); err != nil {
+		return "", errors.New("The offline directory does not contain code_base.zip: " + err.Error())
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	queue.token = hex.EncodeToString(random)
+	RegisterSecret(queue.token)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	queue.Address = "http://" + listener.Addr().String() + "/" + queue.token
+
+	go http.Serve(listener, queue)
+
+	return queue.Address, nil
+}
+
+// useOfflineQueue makes the worker get simulation runs from offline_dir instead of Scalarm services
+func (sim SimulationManager) useOfflineQueue() {
+	if sim.Config.ExperimentId == "" {
+		sim.Config.ExperimentId = offlineExperimentID
+	}
+
+	queue := NewOfflineQueue(sim.Config.OfflineDir, sim.Config.ExperimentId)
+	address, err := StartOfflineQueue(queue)
+	if err != nil {
+		Fatal(err)
+	}
+	simLog.Infof("Offline mode, simulation runs are read from %s", sim.Config.OfflineDir)
+
+	sim.Config.InformationServiceUrl = address
+	sim.Config.InformationServiceUrls = nil
+	sim.Config.InformationServiceSRV = ""
+}
+
+// ServeHTTP handles requests which workers send to Scalarm services
+func (queue *OfflineQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if subtle.ConstantTimeCompare([]byte(parts[0]), []byte(queue.token)) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	parts = parts[1:]
+	if len(parts) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && (parts[0] == "experiment_managers" || parts[0] == "storage_managers"):
+		queue.respond(w, []string{queue.Address}, nil)
+		return
+	case len(parts) == 1 && parts[0] == "experiments.json":
+		queue.respond(w, map[string]interface{}{"status": "ok", "running": []string{queue.ExperimentID}}, nil)
+		return
+	case len(parts) == 2 && parts[0] == "experiments" && parts[1] == "random_experiment":
+		w.Write([]byte(queue.ExperimentID))
+		return
+	case len(parts) < 3 || parts[0] != "experiments" || parts[1] != queue.ExperimentID:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && parts[2] == "code_base":
+		http.ServeFile(w, r, filepath.Join(queue.Dir, "code_base.zip"))
+	case len(parts) == 3 && parts[2] == "code_base_signature":
+		http.ServeFile(w, r, filepath.Join(queue.Dir, "code_base.zip.sig"))
+	case len(parts) >= 4 && parts[2] == "input_files":
+		name := path.Clean("/" + strings.Join(parts[3:], "/"))
+		http.ServeFile(w, r, filepath.Join(queue.Dir, "input_files", filepath.FromSlash(name)))
+	case len(parts) == 3 && parts[2] == "next_simulation" && r.Method == "GET":
+		simulationRun, err := queue.next()
+		queue.respond(w, simulationRun, err)
+	case len(parts) >= 4 && parts[2] == "simulations" && r.Method == "PUT":
+		queue.store(w, r, parts[3:])
+	case len(parts) == 5 && parts[2] == "simulations" && r.Method == "POST" && parts[4] == "mark_as_complete":
+		queue.respond(w, map[string]interface{}{"status": "ok"}, queue.complete(parts[3], r))
+	case len(parts) == 5 && parts[2] == "simulations" && r.Method == "POST" && parts[4] == "rollback":
+		queue.respond(w, map[string]interface{}{"status": "ok"}, queue.rollback(parts[3]))
+	case r.Method == "POST":
+		// progress info, host info and performance stats are not kept
+		queue.respond(w, map[string]interface{}{"status": "ok"}, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (queue *OfflineQueue) respond(w http.ResponseWriter, response interface{}, err error) {
+	if err != nil {
+		offlineLog.Warnf("%v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// definitions lists simulation run definitions in the order of their file names
+func (queue *OfflineQueue) definitions() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(queue.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []string{}
+	for _, definition := range paths {
+		if !strings.HasSuffix(definition, offlineResultSuffix) && !strings.HasSuffix(definition, offlineReportSuffix) {
+			definitions = append(definitions, definition)
+		}
+	}
+	sort.Strings(definitions)
+
+	return definitions, nil
+}
+
+// next claims the first simulation run which has neither results nor a claim
+func (queue *OfflineQueue) next() (map[string]interface{}, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	definitions, err := queue.definitions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, definition := range definitions {
+		base := strings.TrimSuffix(definition, ".json")
+		if _, err := os.Stat(base + offlineResultSuffix); err == nil {
+			continue
+		}
+
+		claim, err := os.OpenFile(base+offlineClaimSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(claim, "%d\n", os.Getpid())
+		claim.Close()
+
+		simulationRun := map[string]interface{}{}
+		content, err := ioutil.ReadFile(definition)
+		if err == nil {
+			err = json.Unmarshal(content, &simulationRun)
+		}
+		simulationIndex, ok := simulationRun["simulation_id"].(float64)
+		if err != nil || !ok {
+			offlineLog.Infof("Skipping %s, it is not a simulation run definition with simulation_id", definition)
+			continue
+		}
+
+		if _, ok := simulationRun["status"]; !ok {
+			simulationRun["status"] = "ok"
+		}
+		queue.runs[int(simulationIndex)] = base
+
+		return simulationRun, nil
+	}
+
+	return map[string]interface{}{"status": "all_sent"}, nil
+}
+
+// handedOut returns the definition of a simulation run handed out by this queue, without the .json suffix
+func (queue *OfflineQueue) handedOut(index string) (string, error) {
+	simulationIndex, err := strconv.Atoi(index)
+	if err != nil {
+		return "", err
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	base, ok := queue.runs[simulationIndex]
+	if !ok {
+		return "", fmt.Errorf("Simulation run %d was not handed out", simulationIndex)
+	}
+
+	return base, nil
+}
+
+// complete writes results of a simulation run next to its definition and releases its claim
+func (queue *OfflineQueue) complete(index string, r *http.Request) error {
+	base, err := queue.handedOut(index)
+	if err != nil {
+		return err
+	}
+	if err = r.ParseForm(); err != nil {
+		return err
+	}
+
+	simulationIndex, _ := strconv.Atoi(index)
+	results := map[string]interface{}{"simulation_id": simulationIndex}
+	for name := range r.PostForm {
+		results[name] = r.PostForm.Get(name)
+	}
+	if result := r.PostForm.Get("result"); json.Valid([]byte(result)) {
+		results["result"] = json.RawMessage(result)
+	}
+
+	content, err := marshalJSON(results)
+	if err != nil {
+		return err
+	}
+	// a result file is complete once it exists
+	if err = ioutil.WriteFile(base+offlineResultSuffix+".part", content, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(base+offlineResultSuffix+".part", base+offlineResultSuffix); err != nil {
+		return err
+	}
+
+	return os.Remove(base + offlineClaimSuffix)
+}
+
+// rollback releases the claim of a simulation run, so it is handed out again
+func (queue *OfflineQueue) rollback(index string) error {
+	base, err := queue.handedOut(index)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(base + offlineClaimSuffix)
+}
+
+// store writes an uploaded file of a simulation run next to its definition
+func (queue *OfflineQueue) store(w http.ResponseWriter, r *http.Request, parts []string) {
+	suffix := offlineOutputSuffix
+	if len(parts) == 2 && parts[1] == "stdout" {
+		suffix = offlineStdoutSuffix
+	} else if len(parts) == 2 && parts[1] == "stderr" {
+		suffix = offlineStderrSuffix
+	} else if len(parts) == 2 && parts[1] == "report" {
+		suffix = offlineReportSuffix
+	} else if len(parts) >= 3 && parts[1] == "output_files" {
+		name := path.Clean("/" + strings.Join(parts[2:], "/"))
+		suffix = offlineOutputFilesSuffix + filepath.FromSlash(name)
+	} else if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	base, err := queue.handedOut(parts[0])
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(base+suffix), 0755)
+	}
+	if err == nil {
+		err = saveUploadedFile(r, base+suffix)
+	}
+
+	queue.respond(w, map[string]interface{}{"status": "ok", "path": base + suffix}, err)
+}
+
+// saveUploadedFile writes the 'file' part of a multipart upload, gzipped parts are decompressed
+func saveUploadedFile(r *http.Request, filePath string) error {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return errors.New("The upload has no file")
+		} else if err != nil {
+			return err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		var content io.Reader = part
+		if part.Header.Get("Content-Encoding") == "gzip" {
+			gzipReader, err := gzip.NewReader(part)
+			if err != nil {
+				return err
+			}
+			defer gzipReader.Close()
+			content = gzipReader
+		}
+
+		file, err := os.Create(filePath + ".part")
+		if err != nil {
+			return err
+		}
+		_, err = copyWithPooledBuffer(file, content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(filePath + ".part")
+			return err
+		}
+
+		return os.Rename(filePath+".part", filePath)
+	}
+}
@@ -0,0 +1,146 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOfflineQueueShouldHandOutRunsAndWriteResultsNextToThem(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "offline")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "code_base.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "run_1.json"), []byte(`{"simulation_id": 1, "input_parameters": {"x": 1}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "run_2.json"), []byte(`{"simulation_id": 2, "input_parameters": {"x": 2}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "run_2.result.json"), []byte(`{"status": "ok"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "stdout.txt"), []byte("hello"), 0644)
+
+	queue := NewOfflineQueue(dir, offlineExperimentID)
+	address, err := StartOfflineQueue(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the queue is reached with plain HTTP also when development is not set
+	config := getSimConfig()
+	config.Development = false
+	em := ExperimentManager{HttpClient: &http.Client{}, BaseUrls: []string{address}, CommunicationTimeout: 5 * time.Second,
+		Config: config, ExperimentId: offlineExperimentID}
+	sim := SimulationManager{Config: config, HttpClient: em.HttpClient}
+
+	// === WHEN ===
+	simulationRun, err := em.GetNextSimulationRunConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, uploadErr := sim.uploadFile(filepath.Join(dir, "stdout.txt"), "experiments/offline/simulations/1/stdout",
+		[]string{address}, 5*time.Second)
+	_, completeErr := em.MarkSimulationRunAsComplete(1, url.Values{"status": {"ok"}, "result": {`{"y": 2}`}})
+	nextRun, _ := em.GetNextSimulationRunConfig()
+	withoutToken, _ := http.Get(strings.TrimSuffix(address, "/"+queue.token) + "/experiments/offline/next_simulation")
+
+	// === THEN ===
+	if simulationRun["status"] != "ok" || simulationRun["simulation_id"].(float64) != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", simulationRun, "simulation run 1")
+	}
+	if uploadErr != nil || completeErr != nil {
+		t.Errorf("Returned errors should be nil, but they are '%v', '%v'", uploadErr, completeErr)
+	}
+
+	result, _ := ioutil.ReadFile(filepath.Join(dir, "run_1.result.json"))
+	if !strings.Contains(string(result), `"result":{"y":2}`) {
+		t.Errorf("Got: '%s' - Expected '%v'", result, `"result":{"y":2}`)
+	}
+	if stdout, _ := ioutil.ReadFile(filepath.Join(dir, "run_1.stdout.txt")); string(stdout) != "hello" {
+		t.Errorf("Got: '%s' - Expected '%v'", stdout, "hello")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run_1.claimed")); !os.IsNotExist(err) {
+		t.Errorf("The claim of the completed run should be removed")
+	}
+	if nextRun["status"] != "all_sent" {
+		t.Errorf("Got: '%v' - Expected '%v'", nextRun["status"], "all_sent")
+	}
+	if withoutToken == nil || withoutToken.StatusCode != http.StatusNotFound {
+		t.Errorf("Got: '%v' - Expected '%v'", withoutToken, "404 for requests without the token")
+	}
+}
+
+func TestOfflineQueueShouldStoreStderrNextToTheRun(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "offline")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "code_base.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "run_1.json"), []byte(`{"simulation_id": 1, "input_parameters": {"x": 1}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "stderr.txt"), []byte("segmentation fault"), 0644)
+
+	queue := NewOfflineQueue(dir, offlineExperimentID)
+	address, err := StartOfflineQueue(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := getSimConfig()
+	em := ExperimentManager{HttpClient: &http.Client{}, BaseUrls: []string{address}, CommunicationTimeout: 5 * time.Second,
+		Config: config, ExperimentId: offlineExperimentID}
+	sim := SimulationManager{Config: config, HttpClient: em.HttpClient}
+	em.GetNextSimulationRunConfig()
+
+	// === WHEN ===
+	_, uploadErr := sim.uploadFile(filepath.Join(dir, "stderr.txt"), "experiments/offline/simulations/1/stderr",
+		[]string{address}, 5*time.Second)
+
+	// === THEN ===
+	if uploadErr != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", uploadErr)
+	}
+	if stderr, _ := ioutil.ReadFile(filepath.Join(dir, "run_1.stderr.txt")); string(stderr) != "segmentation fault" {
+		t.Errorf("Got: '%s' - Expected '%v'", stderr, "segmentation fault")
+	}
+}
+
+func TestOfflineQueueShouldStoreOutputFilesUnderTheRun(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "offline")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "code_base.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "run_1.json"), []byte(`{"simulation_id": 1, "input_parameters": {"x": 1}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "plot.png"), []byte("png"), 0644)
+
+	queue := NewOfflineQueue(dir, offlineExperimentID)
+	address, err := StartOfflineQueue(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := getSimConfig()
+	em := ExperimentManager{HttpClient: &http.Client{}, BaseUrls: []string{address}, CommunicationTimeout: 5 * time.Second,
+		Config: config, ExperimentId: offlineExperimentID}
+	sim := SimulationManager{Config: config, HttpClient: em.HttpClient}
+	em.GetNextSimulationRunConfig()
+
+	// === WHEN ===
+	_, uploadErr := sim.uploadFile(filepath.Join(dir, "plot.png"), outputFileUploadUrl(offlineExperimentID, 1, "plots/x 1.png"),
+		[]string{address}, 5*time.Second)
+	_, escapingErr := sim.uploadFile(filepath.Join(dir, "plot.png"), outputFileUploadUrl(offlineExperimentID, 1, "../../escaped.png"),
+		[]string{address}, 5*time.Second)
+
+	// === THEN ===
+	if uploadErr != nil || escapingErr != nil {
+		t.Errorf("Returned errors should be nil, but they are '%v', '%v'", uploadErr, escapingErr)
+	}
+	if plot, _ := ioutil.ReadFile(filepath.Join(dir, "run_1.output_files", "plots", "x 1.png")); string(plot) != "png" {
+		t.Errorf("Got: '%s' - Expected '%v'", plot, "png")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run_1.output_files", "escaped.png")); err != nil {
+		t.Errorf("Got: '%v' - Expected the file kept under the run", err)
+	}
+}
@@ -0,0 +1,90 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// manifest with glob patterns of extra output files, one per line, in the code base or written by the executor
+const outputFilesManifest = "output_files.txt"
+
+// ReadOutputFilesManifest returns patterns listed in the manifest, empty lines and lines starting with '#'
+// are skipped; a missing manifest lists no patterns
+func ReadOutputFilesManifest(manifestPath string) []string {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+
+	return patterns
+}
+
+// OutputFiles returns sorted paths, relative to the simulation directory and with '/' as the separator,
+// of regular files matching any of the patterns; symbolic links, files outside the simulation directory, also
+// through linked directories, and the skipped ones, e.g. output.tar.gz which is uploaded anyway, are left out
+func OutputFiles(simulationDirPath string, patterns []string, skip []string) []string {
+	realSimulationDirPath, err := filepath.EvalSymlinks(simulationDirPath)
+	if err != nil {
+		return []string{}
+	}
+
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(simulationDirPath, filepath.FromSlash(pattern)))
+		if err != nil {
+			simLog.Warnf("Invalid output file pattern '%s': %v", pattern, err)
+			continue
+		}
+
+		for _, match := range matches {
+			info, err := os.Lstat(match)
+			if err != nil || !info.Mode().IsRegular() || !withinDir(simulationDirPath, match) {
+				continue
+			}
+			if realPath, err := filepath.EvalSymlinks(match); err != nil || !withinDir(realSimulationDirPath, realPath) {
+				continue
+			}
+			if relativePath, err := filepath.Rel(simulationDirPath, match); err == nil {
+				found[filepath.ToSlash(relativePath)] = true
+			}
+		}
+	}
+
+	for _, name := range skip {
+		delete(found, name)
+	}
+
+	outputFiles := []string{}
+	for name := range found {
+		outputFiles = append(outputFiles, name)
+	}
+	sort.Strings(outputFiles)
+
+	return outputFiles
+}
+
+// outputFileUploadUrl returns the Storage Manager service method an extra output file is uploaded to
+func outputFileUploadUrl(experimentID string, simulationIndex int, name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return fmt.Sprintf("experiments/%s/simulations/%v/output_files/%s", experimentID, simulationIndex,
+		strings.Join(segments, "/"))
+}
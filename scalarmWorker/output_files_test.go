@@ -0,0 +1,62 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputFilesShouldMatchPatternsWithinSimulationDir(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "output_files")
+	defer os.RemoveAll(dir)
+
+	simulationDir := filepath.Join(dir, "simulation_1")
+	os.MkdirAll(filepath.Join(simulationDir, "plots", "nested"), 0777)
+	for _, name := range []string{"plots/a.png", "plots/b.png", "plots/notes.txt", "summary.csv", "output.tar.gz"} {
+		ioutil.WriteFile(filepath.Join(simulationDir, filepath.FromSlash(name)), []byte(name), 0666)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "secret.csv"), []byte("secret"), 0666)
+	os.Symlink(filepath.Join(dir, "secret.csv"), filepath.Join(simulationDir, "linked.csv"))
+	os.Symlink(dir, filepath.Join(simulationDir, "parent"))
+
+	patterns := []string{"plots/*.png", "*.csv", "plots/*", "../*.csv", "parent/*.csv", "*.tar.gz", "[invalid"}
+
+	// === WHEN ===
+	outputFiles := OutputFiles(simulationDir, patterns, []string{"output.tar.gz"})
+
+	// === THEN ===
+	expected := "plots/a.png,plots/b.png,plots/notes.txt,summary.csv"
+	if strings.Join(outputFiles, ",") != expected {
+		t.Errorf("Got: '%v' - Expected '%v'", strings.Join(outputFiles, ","), expected)
+	}
+}
+
+func TestReadOutputFilesManifestShouldSkipCommentsAndEmptyLines(t *testing.T) {
+	// === GIVEN ===
+	manifest := "./test_output_files.txt"
+	defer os.Remove(manifest)
+	ioutil.WriteFile(manifest, []byte("# plots\nplots/*.png\n\n  summary.csv  \n"), 0666)
+
+	// === WHEN ===
+	patterns := ReadOutputFilesManifest(manifest)
+	missing := ReadOutputFilesManifest("./missing_output_files.txt")
+
+	// === THEN ===
+	if strings.Join(patterns, ",") != "plots/*.png,summary.csv" || len(missing) != 0 {
+		t.Errorf("Got: '%v', %v - Expected '%v', %v", patterns, missing, "plots/*.png,summary.csv", "[]")
+	}
+}
+
+func TestOutputFileUploadUrlShouldEscapePathSegments(t *testing.T) {
+	// === WHEN ===
+	serviceMethod := outputFileUploadUrl("e1", 3, "plots/final plot.png")
+
+	// === THEN ===
+	expected := "experiments/e1/simulations/3/output_files/plots/final%20plot.png"
+	if serviceMethod != expected {
+		t.Errorf("Got: '%v' - Expected '%v'", serviceMethod, expected)
+	}
+}
@@ -0,0 +1,160 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// files smaller than this are downloaded with a single connection
+var parallelDownloadThreshold int64 = 16 * 1024 * 1024
+
+var errParallelDownloadNotPossible = errors.New("Parallel download is not possible")
+
+// DownloadInParallel fetches serviceMethod with concurrent Range requests spread over the given services
+// and reassembles the content in filePath; errParallelDownloadNotPossible is returned when the file
+// is small or services do not support ranges. Chunks are written to filePath.part, after a failure it is
+// cut to the chunks received from its beginning, so a resumable download continues from there
+func DownloadInParallel(serviceMethod string, serviceUrls []string, filePath string, connections int,
+	config *SimulationManagerConfig, client *http.Client, timeout time.Duration) error {
+
+	headInfo := RequestInfo{"HEAD", nil, "", serviceMethod}
+	resp, err := ExecuteScalarmRequest(WorkerContext(), headInfo, serviceUrls, config, client, timeout)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	size := resp.ContentLength
+	if resp.StatusCode != 200 || resp.Header.Get("Accept-Ranges") != "bytes" || size < parallelDownloadThreshold {
+		return errParallelDownloadNotPossible
+	}
+	etag := resp.Header.Get("ETag")
+
+	partPath := filePath + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = file.Truncate(size); err != nil {
+		return err
+	}
+
+	simLog.Infof("Downloading %d bytes with %d connections ...", size, connections)
+
+	chunkSize := (size + int64(connections) - 1) / int64(connections)
+	errs := make(chan error, connections)
+	received := make([]bool, connections)
+	var wg sync.WaitGroup
+
+	for i := 0; i < connections; i++ {
+		start := int64(i) * chunkSize
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		if start > end {
+			received[i] = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(chunk int, start int64, end int64) {
+			defer wg.Done()
+
+			var chunkErr error
+			// every attempt goes to the next service, starting from a different one for each chunk
+			for attempt := 0; attempt < 2*len(serviceUrls); attempt++ {
+				serviceUrl := serviceUrls[(chunk+attempt)%len(serviceUrls)]
+				if chunkErr = downloadRange(serviceMethod, serviceUrl, file, start, end, etag, config, client, timeout); chunkErr == nil {
+					received[chunk] = true
+					return
+				}
+				simLog.Warnf("Could not download bytes %d-%d from %s: %v", start, end, serviceUrl, chunkErr)
+			}
+			errs <- chunkErr
+		}(i, start, end)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for chunkErr := range errs {
+		keepReceivedPrefix(file, partPath, received, chunkSize, size, etag)
+		return chunkErr
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partPath, filePath)
+}
+
+// keepReceivedPrefix cuts the partial file to the chunks received from its beginning and keeps its ETag,
+// the same files a resumable download leaves after an interruption; without an ETag nothing is kept,
+// since it could not be verified that the rest comes from the same content
+func keepReceivedPrefix(file *os.File, partPath string, received []bool, chunkSize int64, size int64, etag string) {
+	prefix := int64(0)
+	for _, chunkReceived := range received {
+		if !chunkReceived {
+			break
+		}
+		prefix += chunkSize
+	}
+	if prefix > size {
+		prefix = size
+	}
+	if etag == "" {
+		prefix = 0
+	}
+
+	file.Truncate(prefix)
+	if prefix > 0 {
+		simLog.Infof("Keeping %d bytes of the parallel download for resuming it", prefix)
+		ioutil.WriteFile(partPath+".etag", []byte(etag), 0666)
+	}
+}
+
+// downloadRange fetches bytes from start to end (inclusive) of the content with the ETag, if known, and writes
+// them at the same offset in file
+func downloadRange(serviceMethod string, serviceUrl string, file *os.File, start int64, end int64, etag string,
+	config *SimulationManagerConfig, client *http.Client, timeout time.Duration) error {
+
+	req, err := NewScalarmRequest(RequestInfo{"GET", nil, "", serviceMethod}, serviceUrl, config)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	// a changed file is sent whole and rejected below instead of mixing chunks of two versions
+	if etag != "" {
+		req.Header.Set("If-Range", etag)
+	}
+
+	resp, err := GetWithBudget(WorkerContext(), client, req, config.retryBudget(timeout))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+
+	written, err := copyWithPooledBuffer(io.NewOffsetWriter(file, start), io.LimitReader(resp.Body, end-start+1))
+	if err != nil {
+		return err
+	}
+
+	if written != end-start+1 {
+		return fmt.Errorf("received %d bytes instead of %d", written, end-start+1)
+	}
+
+	return nil
+}
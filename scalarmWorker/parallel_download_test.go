@@ -0,0 +1,121 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadInParallelShouldReassembleChunks(t *testing.T) {
+	// === GIVEN ===
+	defer func(threshold int64) { parallelDownloadThreshold = threshold }(parallelDownloadThreshold)
+	parallelDownloadThreshold = 1024
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	var rangeRequests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&rangeRequests, 1)
+		}
+		http.ServeContent(w, r, "code_base.zip", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	filePath := "./test_parallel_download.zip"
+	defer os.Remove(filePath)
+
+	// === WHEN ===
+	err := DownloadInParallel("experiments/1/code_base", []string{"siteA.com", "siteB.com"}, filePath, 3,
+		getSimConfig(), getHttpClientMock(server.URL), 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	downloaded, _ := ioutil.ReadFile(filePath)
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("Downloaded file differs from the original one")
+	}
+
+	if rangeRequests != 3 {
+		t.Errorf("Got: '%v' - Expected '%v' range requests", rangeRequests, 3)
+	}
+}
+
+func TestDownloadInParallelShouldSkipSmallFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "code_base.zip", time.Now(), bytes.NewReader([]byte("small")))
+	}))
+	defer server.Close()
+
+	err := DownloadInParallel("experiments/1/code_base", []string{"siteA.com"}, "./not_created.zip", 3,
+		getSimConfig(), getHttpClientMock(server.URL), 5*time.Second)
+
+	if err != errParallelDownloadNotPossible {
+		t.Errorf("Got: '%v' - Expected '%v'", err, errParallelDownloadNotPossible)
+	}
+}
+
+func TestDownloadInParallelShouldKeepReceivedChunksForResuming(t *testing.T) {
+	// === GIVEN ===
+	defer func(threshold int64) { parallelDownloadThreshold = threshold }(parallelDownloadThreshold)
+	parallelDownloadThreshold = 1024
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	chunkSize := (int64(len(content)) + 2) / 3
+	var failing int32 = 1
+	ifRanges := make(chan string, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start int64
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+		if atomic.LoadInt32(&failing) == 1 && start >= 2*chunkSize {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if atomic.LoadInt32(&failing) == 0 {
+			ifRanges <- r.Header.Get("If-Range")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "code_base.zip", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "parallel_download")
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "code_base.zip")
+
+	config := getSimConfig()
+	em := &ExperimentManager{HttpClient: getHttpClientMock(server.URL), BaseUrls: []string{"siteA.com"},
+		CommunicationTimeout: 5 * time.Second, Config: config, ExperimentId: "1"}
+
+	// === WHEN ===
+	parallelErr := DownloadInParallel("experiments/1/code_base", []string{"siteA.com"}, filePath, 3,
+		config, em.HttpClient, 5*time.Second)
+	partInfo, _ := os.Stat(filePath + ".part")
+	atomic.StoreInt32(&failing, 0)
+	resumeErr := em.downloadResumable("experiments/1/code_base", filePath)
+
+	// === THEN ===
+	if parallelErr == nil || partInfo == nil || partInfo.Size() != 2*chunkSize {
+		t.Fatalf("Got: '%v', %v - Expected '%v'", parallelErr, partInfo, "an error and 2 chunks kept")
+	}
+	if resumeErr != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", resumeErr)
+	}
+	if downloaded, _ := ioutil.ReadFile(filePath); !bytes.Equal(downloaded, content) {
+		t.Errorf("Got: '%v' bytes - Expected '%v' bytes", len(downloaded), len(content))
+	}
+	if ifRange := <-ifRanges; ifRange != `"v1"` {
+		t.Errorf("Got: '%v' - Expected '%v'", ifRange, `"v1"`)
+	}
+}
@@ -0,0 +1,151 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// types of input parameters in a parameter space definition
+const (
+	ParameterInteger = "integer"
+	ParameterFloat   = "float"
+	ParameterString  = "string"
+)
+
+// ParameterDefinition describes an input parameter of an experiment: its type, range and allowed values
+type ParameterDefinition struct {
+	ID            string        `json:"id"`
+	Type          string        `json:"type"`
+	Min           *float64      `json:"min"`
+	Max           *float64      `json:"max"`
+	AllowedValues []interface{} `json:"allowed_values"`
+	Optional      bool          `json:"optional"`
+}
+
+// ParameterSpace is the definition of input parameters of an experiment, used to reject invalid input
+// parameters of simulation runs before they are executed
+type ParameterSpace struct {
+	Parameters []ParameterDefinition `json:"parameters"`
+}
+
+// GetParameterSpace fetches the definition of input parameters of the experiment, nil when the Experiment Manager
+// does not provide it
+func (em *ExperimentManager) GetParameterSpace() (*ParameterSpace, error) {
+	emResponse := struct {
+		Status string `json:"status"`
+		ParameterSpace
+	}{}
+
+	path := "experiments/" + em.ExperimentId + "/parameter_space"
+	reqInfo := RequestInfo{"GET", nil, "", path}
+
+	resp, err := ExecuteScalarmRequest(em.context(), reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, nil
+	} else if resp.StatusCode != 200 {
+		return nil, errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(body, &emResponse); err != nil {
+		return nil, errors.New("Returned response body is not JSON.")
+	}
+
+	if emResponse.Status != "ok" {
+		return nil, errors.New("Parameter space of the experiment is not available: " + emResponse.Status)
+	}
+
+	return &emResponse.ParameterSpace, nil
+}
+
+// Validate checks that all required input parameters are present with values of their type, within their range
+// and among their allowed values; all problems are reported in a single error
+func (space *ParameterSpace) Validate(inputParameters map[string]interface{}) error {
+	if space == nil {
+		return nil
+	}
+
+	problems := []string{}
+	for _, parameter := range space.Parameters {
+		value, ok := inputParameters[parameter.ID]
+		if !ok || value == nil {
+			if !parameter.Optional {
+				problems = append(problems, fmt.Sprintf("%s is missing", parameter.ID))
+			}
+			continue
+		}
+
+		if err := parameter.check(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", parameter.ID, err))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func (parameter ParameterDefinition) check(value interface{}) error {
+	if parameter.Type == ParameterInteger || parameter.Type == ParameterFloat {
+		number, ok := numericValue(value)
+		if !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		if parameter.Type == ParameterInteger && number != math.Trunc(number) {
+			return fmt.Errorf("%v is not an integer", value)
+		}
+		if parameter.Min != nil && number < *parameter.Min {
+			return fmt.Errorf("%v is below the minimum %v", value, *parameter.Min)
+		}
+		if parameter.Max != nil && number > *parameter.Max {
+			return fmt.Errorf("%v is above the maximum %v", value, *parameter.Max)
+		}
+	} else if parameter.Type == ParameterString {
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+	}
+
+	if len(parameter.AllowedValues) > 0 {
+		for _, allowed := range parameter.AllowedValues {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not one of the allowed values %v", value, parameter.AllowedValues)
+	}
+
+	return nil
+}
+
+// numericValue accepts JSON numbers and numbers sent as strings
+func numericValue(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
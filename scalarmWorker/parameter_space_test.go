@@ -0,0 +1,92 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetParameterSpaceShouldReturnDefinitionOfInputParameters(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/parameter_space" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"ok","parameters":[{"id":"x","type":"integer","min":0,"max":10},`+
+			`{"id":"mode","type":"string","allowed_values":["fast","exact"],"optional":true}]}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	space, err := em.GetParameterSpace()
+
+	// === THEN ===
+	if err != nil || space == nil {
+		t.Fatalf("Got: '%v', '%v' - Expected a parameter space", space, err)
+	}
+
+	if len(space.Parameters) != 2 || *space.Parameters[0].Max != 10 || !space.Parameters[1].Optional {
+		t.Errorf("Got: '%+v' - Expected '%v'", space.Parameters, "x and optional mode")
+	}
+}
+
+func TestGetParameterSpaceShouldReturnNilWhenNotProvided(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	space, err := em.GetParameterSpace()
+
+	// === THEN ===
+	if err != nil || space != nil {
+		t.Errorf("Got: '%v', '%v' - Expected '%v'", space, err, nil)
+	}
+}
+
+func TestParameterSpaceValidateShouldReportAllProblems(t *testing.T) {
+	// === GIVEN ===
+	min, max := 0.0, 10.0
+	space := &ParameterSpace{Parameters: []ParameterDefinition{
+		{ID: "x", Type: ParameterInteger, Min: &min, Max: &max},
+		{ID: "y", Type: ParameterFloat},
+		{ID: "mode", Type: ParameterString, AllowedValues: []interface{}{"fast", "exact"}},
+		{ID: "seed", Type: ParameterInteger, Optional: true},
+	}}
+
+	cases := []struct {
+		inputParameters map[string]interface{}
+		expected        string
+	}{
+		{map[string]interface{}{"x": 3.0, "y": "0.5", "mode": "fast"}, ""},
+		{map[string]interface{}{"x": 11.0, "y": 1.0, "mode": "fast"}, "x: 11 is above the maximum 10"},
+		{map[string]interface{}{"x": 1.5, "y": "a", "mode": "slow"},
+			"mode: slow is not one of the allowed values [fast exact]; x: 1.5 is not an integer; y: a is not a number"},
+		{map[string]interface{}{"y": 1.0, "mode": "exact"}, "x is missing"},
+	}
+
+	for _, c := range cases {
+		// === WHEN ===
+		err := space.Validate(c.inputParameters)
+
+		// === THEN ===
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != c.expected {
+			t.Errorf("Got: '%v' - Expected '%v'", message, c.expected)
+		}
+	}
+
+	var missing *ParameterSpace
+	if err := missing.Validate(map[string]interface{}{}); err != nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, nil)
+	}
+}
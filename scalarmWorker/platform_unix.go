@@ -0,0 +1,65 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+)
+
+// tools required to run adapters, checked by the doctor command
+var platformTools = []string{"sh"}
+
+// shellCommand runs a command line with sh
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// defaultShell returns the interactive shell used when $SHELL is not set
+func defaultShell() string {
+	return "sh"
+}
+
+// adapterFile returns the path of an adapter of a code base, an empty string when there is no such file
+func adapterFile(dir string, name string) string {
+	adapterPath := path.Join(dir, name)
+	if info, err := os.Stat(adapterPath); err != nil || info.IsDir() {
+		return ""
+	}
+
+	return adapterPath
+}
+
+// adapterInvocation returns a command line which starts the adapter
+func adapterInvocation(adapterPath string) string {
+	return adapterPath
+}
+
+// adapterExec starts the adapter directly, without a shell
+func adapterExec(ctx context.Context, adapterPath string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, adapterPath, args...)
+}
+
+// makeExecutable sets executable permissions for everyone on all entries of dir, like chmod a+x dir/*
+func makeExecutable(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(dir, entry.Name())
+		info, err := os.Stat(entryPath)
+		if err != nil {
+			return err
+		}
+		if err = os.Chmod(entryPath, info.Mode()|0111); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
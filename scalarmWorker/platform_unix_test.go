@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestMakeExecutableShouldSetExecutablePermissions(t *testing.T) {
+	// === GIVEN ===
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/bin/sh\necho ok\n"), 0600)
+
+	// === WHEN ===
+	err := makeExecutable(codeBaseDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	info, _ := os.Stat(path.Join(codeBaseDir, "executor"))
+	if info.Mode().Perm() != 0711 {
+		t.Errorf("Got: '%v' - Expected '%v'", info.Mode().Perm(), os.FileMode(0711))
+	}
+}
+
+func TestAdapterCallShouldStartAdapterWithShell(t *testing.T) {
+	// === GIVEN ===
+	codeBaseDir, _ := ioutil.TempDir("", "test_code_base")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/bin/sh\necho \"$1\"\n"), 0755)
+
+	// === WHEN ===
+	output, err := shellCommand(context.Background(), adapterCall(codeBaseDir, "executor")+" input.json").Output()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	if string(output) != "input.json\n" {
+		t.Errorf("Got: '%v' - Expected '%v'", string(output), "input.json\n")
+	}
+	if adapterFile(codeBaseDir, "output_reader") != "" {
+		t.Errorf("Got: '%v' - Expected '%v'", adapterFile(codeBaseDir, "output_reader"), "")
+	}
+}
@@ -0,0 +1,76 @@
+package scalarmWorker
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// tools required to run adapters, checked by the doctor command
+var platformTools = []string{"cmd"}
+
+// extensions of adapters tried in order, a file without extension, e.g. a shell script kept for Linux
+// workers, is used only when there is none of them
+var adapterExtensions = []string{".exe", ".cmd", ".bat", ".ps1", ""}
+
+// shellCommand runs a command line with cmd.exe, the command line is passed verbatim so its quotes
+// and redirections are interpreted by cmd.exe
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, defaultShell())
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /S /C "` + command + `"`}
+
+	return cmd
+}
+
+// defaultShell returns the interactive shell used when $SHELL is not set
+func defaultShell() string {
+	if comspec := os.Getenv("ComSpec"); comspec != "" {
+		return comspec
+	}
+
+	return "cmd.exe"
+}
+
+// adapterFile returns the path of an adapter of a code base, which can be an executable, a batch file
+// or a PowerShell script, an empty string when there is no such file
+func adapterFile(dir string, name string) string {
+	for _, extension := range adapterExtensions {
+		adapterPath := filepath.Join(filepath.FromSlash(dir), name+extension)
+		if info, err := os.Stat(adapterPath); err == nil && !info.IsDir() {
+			return adapterPath
+		}
+	}
+
+	return ""
+}
+
+// adapterInvocation returns a command line which starts the adapter, PowerShell scripts are run with powershell
+func adapterInvocation(adapterPath string) string {
+	adapterPath = filepath.FromSlash(adapterPath)
+	if strings.EqualFold(filepath.Ext(adapterPath), ".ps1") {
+		return `powershell -NoProfile -NonInteractive -ExecutionPolicy Bypass -File "` + adapterPath + `"`
+	}
+
+	return `"` + adapterPath + `"`
+}
+
+// adapterExec starts the adapter without cmd.exe, except for batch files which cannot be started otherwise
+func adapterExec(ctx context.Context, adapterPath string, args ...string) *exec.Cmd {
+	switch strings.ToLower(filepath.Ext(adapterPath)) {
+	case ".ps1":
+		return exec.CommandContext(ctx, "powershell",
+			append([]string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", adapterPath}, args...)...)
+	case ".cmd", ".bat":
+		return exec.CommandContext(ctx, defaultShell(), append([]string{"/C", adapterPath}, args...)...)
+	}
+
+	return exec.CommandContext(ctx, adapterPath, args...)
+}
+
+// permissions do not make files executable on Windows
+func makeExecutable(dir string) error {
+	return nil
+}
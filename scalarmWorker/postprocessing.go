@@ -0,0 +1,42 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// PostProcessResults pipes results of a simulation run as JSON through cmd and returns the JSON object
+// printed by the command, which can e.g. convert units or add derived metrics without changing code bases
+func PostProcessResults(cmd *exec.Cmd, results interface{}) (map[string]interface{}, error) {
+	input, err := marshalJSON(results)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		// only the end of the error output is kept in the reason sent to Scalarm
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			lines := strings.Split(message, "\n")
+			if len(lines) > 5 {
+				lines = lines[len(lines)-5:]
+			}
+			return nil, errors.New(err.Error() + ": " + strings.Join(lines, "; "))
+		}
+		return nil, err
+	}
+
+	processed := map[string]interface{}{}
+	if err = json.Unmarshal(stdout.Bytes(), &processed); err != nil {
+		return nil, errors.New("Post-processing command did not print a JSON object: " + err.Error())
+	}
+
+	return processed, nil
+}
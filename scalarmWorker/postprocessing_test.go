@@ -0,0 +1,52 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPostProcessResultsShouldReturnObjectPrintedByCommand(t *testing.T) {
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", `read results; echo "{\"raw\": $results, \"unit\": \"m\"}"`)
+
+	// === WHEN ===
+	processed, err := PostProcessResults(cmd, map[string]interface{}{"length": 1.5})
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	raw, _ := processed["raw"].(map[string]interface{})
+	if processed["unit"] != "m" || raw["length"] != 1.5 {
+		t.Errorf("Got: '%v' - Expected '%v'", processed, `{"raw":{"length":1.5},"unit":"m"}`)
+	}
+}
+
+func TestPostProcessResultsShouldReturnErrorOutputOfFailedCommand(t *testing.T) {
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "echo 'unknown unit' >&2; exit 3")
+
+	// === WHEN ===
+	_, err := PostProcessResults(cmd, map[string]interface{}{"length": 1.5})
+
+	// === THEN ===
+	if err == nil || !strings.Contains(err.Error(), "unknown unit") {
+		t.Errorf("Got: '%v' - Expected an error with 'unknown unit'", err)
+	}
+}
+
+func TestPostProcessResultsShouldRejectOutputWhichIsNotJSONObject(t *testing.T) {
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "echo done")
+
+	// === WHEN ===
+	_, err := PostProcessResults(cmd, map[string]interface{}{"length": 1.5})
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
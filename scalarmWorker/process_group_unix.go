@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// startInProcessGroup makes the command the leader of a new process group, so processes started
+// by the shell wrapping an adapter can be stopped together with it
+func startInProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminateProcessGroup sends SIGTERM to the process group of a started command and SIGKILL
+// when it still runs after grace
+func terminateProcessGroup(cmd *exec.Cmd, grace time.Duration, exited <-chan struct{}) {
+	pgid := -cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGTERM)
+
+	select {
+	case <-exited:
+	case <-time.After(grace):
+		syscall.Kill(pgid, syscall.SIGKILL)
+	}
+}
+
+// killProcessGroup sends SIGKILL to the process group of a started command
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
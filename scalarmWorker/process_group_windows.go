@@ -0,0 +1,18 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"time"
+)
+
+// process groups are not used on Windows, only the shell wrapping an adapter is stopped
+func startInProcessGroup(cmd *exec.Cmd) {
+}
+
+func terminateProcessGroup(cmd *exec.Cmd, grace time.Duration, exited <-chan struct{}) {
+	cmd.Process.Kill()
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
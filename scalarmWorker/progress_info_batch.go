@@ -0,0 +1,75 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProgressInfoEntry is a single intermediate result of a simulation run waiting to be sent in a batch
+type ProgressInfoEntry struct {
+	Status     string          `json:"status"`
+	Reason     string          `json:"reason,omitempty"`
+	Result     json.RawMessage `json:"result"`
+	ReportedAt time.Time       `json:"reported_at"`
+}
+
+// ProgressInfoBatcher accumulates intermediate results and sends them together when size entries
+// are collected or interval passed since the last batch was sent
+type ProgressInfoBatcher struct {
+	em              *ExperimentManager
+	simulationIndex int
+	size            int
+	interval        time.Duration
+	entries         []ProgressInfoEntry
+	lastFlush       time.Time
+}
+
+func NewProgressInfoBatcher(em *ExperimentManager, simulationIndex int, size int, interval time.Duration) *ProgressInfoBatcher {
+	return &ProgressInfoBatcher{
+		em:              em,
+		simulationIndex: simulationIndex,
+		size:            size,
+		interval:        interval,
+		entries:         []ProgressInfoEntry{},
+		lastFlush:       time.Now(),
+	}
+}
+
+// Add stores intermediate results and sends the batch if it is full or old enough
+func (batcher *ProgressInfoBatcher) Add(results *SimulationRunResults) error {
+	result, err := marshalJSON(results.Results)
+	if err != nil {
+		return err
+	}
+
+	batcher.entries = append(batcher.entries, ProgressInfoEntry{
+		Status:     results.Status,
+		Reason:     results.Reason,
+		Result:     result,
+		ReportedAt: time.Now(),
+	})
+
+	if len(batcher.entries) >= batcher.size || time.Since(batcher.lastFlush) >= batcher.interval {
+		return batcher.Flush()
+	}
+
+	return nil
+}
+
+// Flush sends all accumulated intermediate results, nothing is sent when there are none
+func (batcher *ProgressInfoBatcher) Flush() error {
+	batcher.lastFlush = time.Now()
+
+	if len(batcher.entries) == 0 {
+		return nil
+	}
+
+	progressInfoLog.Infof("Sending %d intermediate results", len(batcher.entries))
+
+	if err := batcher.em.PostProgressInfoBatch(batcher.simulationIndex, batcher.entries); err != nil {
+		return err
+	}
+
+	batcher.entries = []ProgressInfoEntry{}
+	return nil
+}
@@ -0,0 +1,57 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProgressInfoBatcherShouldSendFullBatchInSingleRequest(t *testing.T) {
+	// === GIVEN ===
+	batches := [][]ProgressInfoEntry{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/simulations/3/progress_info" {
+			w.WriteHeader(404)
+			return
+		}
+
+		batch := []ProgressInfoEntry{}
+		json.Unmarshal([]byte(r.FormValue("batch")), &batch)
+		batches = append(batches, batch)
+
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batcher := NewProgressInfoBatcher(&em, 3, 3, time.Hour)
+
+	// === WHEN ===
+	for i := 0; i < 4; i++ {
+		if err := batcher.Add(&SimulationRunResults{Status: "ok", Results: map[string]interface{}{"step": i}}); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+	}
+
+	// === THEN ===
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Errorf("Got: '%v' - Expected a single batch with 3 entries", batches)
+		return
+	}
+
+	if string(batches[0][2].Result) != `{"step":2}` {
+		t.Errorf("Got: '%s' - Expected '%v'", batches[0][2].Result, `{"step":2}`)
+	}
+
+	// === WHEN ===
+	batcher.Flush()
+	batcher.Flush()
+
+	// === THEN ===
+	if len(batches) != 2 || len(batches[1]) != 1 {
+		t.Errorf("Got: '%v' - Expected the remaining entry to be sent once", batches)
+	}
+}
@@ -0,0 +1,198 @@
+package scalarmWorker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkerVersion is the version of SiM, set by the main package
+var WorkerVersion = ""
+
+// WorkerCommit is the commit SiM was built from, it can be set with
+// -ldflags "-X github.com/scalarm/scalarm_simulation_manager_go/scalarmWorker.WorkerCommit=<sha>";
+// otherwise the revision recorded by the Go toolchain is used
+var WorkerCommit = ""
+
+// name of the provenance file added to output.tar.gz
+const provenanceFile = "provenance.json"
+
+// environment variables of batch systems identifying the job a worker runs in
+var provenanceJobVariables = []string{"SLURM_JOB_ID", "PBS_JOBID", "LSB_JOBID", "JOB_ID", "SCALARM_WORKER_ID"}
+
+// Provenance describes where and how results of a simulation run were computed
+type Provenance struct {
+	WorkerVersion        string            `json:"worker_version"`
+	WorkerCommit         string            `json:"worker_commit,omitempty"`
+	CodeBaseSHA256       string            `json:"code_base_sha256,omitempty"`
+	Hostname             string            `json:"hostname"`
+	ContainerImage       string            `json:"container_image,omitempty"`
+	ContainerImageDigest string            `json:"container_image_digest,omitempty"`
+	StartedAt            string            `json:"started_at"`
+	FinishedAt           string            `json:"finished_at"`
+	Environment          map[string]string `json:"environment"`
+}
+
+// NewProvenance collects provenance shared by all simulation runs of an experiment: the worker, the code base
+// in codeBaseDir and the container image, if the experiment declares one
+func NewProvenance(codeBaseDir string, containerImage string, containerRuntime string) *Provenance {
+	provenance := &Provenance{
+		WorkerVersion:  WorkerVersion,
+		WorkerCommit:   workerCommit(),
+		ContainerImage: containerImage,
+		Environment: map[string]string{
+			"os":         runtime.GOOS,
+			"arch":       runtime.GOARCH,
+			"cpus":       strconv.Itoa(runtime.NumCPU()),
+			"go_version": runtime.Version(),
+		},
+	}
+	provenance.Hostname, _ = os.Hostname()
+
+	if checksum, err := fileSHA256(filepath.Join(codeBaseDir, "code_base.zip")); err == nil {
+		provenance.CodeBaseSHA256 = checksum
+	}
+
+	if containerImage != "" {
+		provenance.ContainerImageDigest = containerImageDigest(containerImage, containerRuntime)
+	}
+
+	for _, variable := range provenanceJobVariables {
+		if value := os.Getenv(variable); value != "" {
+			provenance.Environment[strings.ToLower(variable)] = value
+		}
+	}
+
+	return provenance
+}
+
+// ForRun returns a copy of the provenance of a simulation run which started and finished at the given local times,
+// reported in the time of Experiment Manager
+func (provenance *Provenance) ForRun(startedAt time.Time, finishedAt time.Time) *Provenance {
+	run := *provenance
+	run.StartedAt = ServerTime(startedAt).UTC().Format(time.RFC3339)
+	run.FinishedAt = ServerTime(finishedAt).UTC().Format(time.RFC3339)
+
+	return &run
+}
+
+func workerCommit() string {
+	if WorkerCommit != "" {
+		return WorkerCommit
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+// containerImageDigest returns the checksum of a SIF file or the repository digest of an image pulled
+// by docker or podman, empty when it cannot be determined
+func containerImageDigest(reference string, containerRuntime string) string {
+	if _, err := os.Stat(reference); err == nil {
+		if checksum, err := fileSHA256(reference); err == nil {
+			return "sha256:" + checksum
+		}
+		return ""
+	}
+
+	if containerRuntime == "" {
+		containerRuntime, _ = DetectImagePullRuntime()
+	}
+	if containerRuntime != "docker" && containerRuntime != "podman" {
+		return ""
+	}
+
+	digest, err := probeCommand(containerRuntime, "image", "inspect", "--format", "{{index .RepoDigests 0}}", reference)
+	if err != nil {
+		return ""
+	}
+
+	return firstLine(digest)
+}
+
+// returned by addFileToTarGz when the archive already has an entry with the name of the file
+var errEntryExists = errors.New("Archive already has the entry")
+
+// addFileToTarGz rewrites the gzipped tar archive with an extra file; when the archive already has an entry
+// with the same name, e.g. written by the simulation itself, it is left as it is and errEntryExists is returned
+func addFileToTarGz(archivePath string, name string, content []byte) error {
+	source, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	gzipReader, err := gzip.NewReader(source)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	partialPath := archivePath + ".part"
+	target, err := os.Create(partialPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partialPath)
+	defer target.Close()
+
+	gzipWriter := gzip.NewWriter(target)
+	tarReader := tar.NewReader(gzipReader)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if path.Clean(header.Name) == name {
+			return errEntryExists
+		}
+
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = copyWithPooledBuffer(tarWriter, tarReader); err != nil {
+			return err
+		}
+	}
+
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err = tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err = tarWriter.Write(content); err != nil {
+		return err
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return err
+	}
+	if err = target.Close(); err != nil {
+		return err
+	}
+	source.Close()
+
+	return os.Rename(partialPath, archivePath)
+}
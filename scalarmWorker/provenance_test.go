@@ -0,0 +1,112 @@
+package scalarmWorker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewProvenanceShouldRecordCodeBaseChecksumAndRunTimes(t *testing.T) {
+	// === GIVEN ===
+	codeBaseDir, _ := ioutil.TempDir("", "provenance")
+	defer os.RemoveAll(codeBaseDir)
+	ioutil.WriteFile(filepath.Join(codeBaseDir, "code_base.zip"), []byte("zip"), 0644)
+
+	WorkerVersion = "17.04"
+	defer func() { WorkerVersion = "" }()
+
+	// times are reported as they are until the clock offset of Experiment Manager is known
+	resetClockOffset()
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// === WHEN ===
+	provenance := NewProvenance(codeBaseDir, "", "").ForRun(start, start.Add(time.Minute))
+
+	// === THEN ===
+	checksum := "4a70fe9aa6436e02c2dea340fbd1e352e4ef2d8ce6ca52ad25d4b95471fc8bf2"
+	if provenance.CodeBaseSHA256 != checksum {
+		t.Errorf("Got: '%v' - Expected '%v'", provenance.CodeBaseSHA256, checksum)
+	}
+	if provenance.WorkerVersion != "17.04" || provenance.Hostname == "" || provenance.Environment["os"] == "" {
+		t.Errorf("Got: '%+v' - Expected '%v'", provenance, "worker version, hostname and environment")
+	}
+	if provenance.StartedAt != "2020-01-02T03:04:05Z" || provenance.FinishedAt != "2020-01-02T03:05:05Z" {
+		t.Errorf("Got: '%v - %v' - Expected '%v'", provenance.StartedAt, provenance.FinishedAt, "2020-01-02T03:04:05Z - 2020-01-02T03:05:05Z")
+	}
+}
+
+func TestAddFileToTarGzShouldKeepExistingEntries(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "provenance")
+	defer os.RemoveAll(dir)
+	archivePath := filepath.Join(dir, "output.tar.gz")
+
+	file, _ := os.Create(archivePath)
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "results.csv", Mode: 0644, Size: 3})
+	tarWriter.Write([]byte("1,2"))
+	tarWriter.Close()
+	gzipWriter.Close()
+	file.Close()
+
+	// === WHEN ===
+	err := addFileToTarGz(archivePath, provenanceFile, []byte("{}"))
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	file, _ = os.Open(archivePath)
+	defer file.Close()
+	gzipReader, _ := gzip.NewReader(file)
+	tarReader := tar.NewReader(gzipReader)
+
+	entries := map[string]string{}
+	for header, err := tarReader.Next(); err == nil; header, err = tarReader.Next() {
+		content, _ := ioutil.ReadAll(tarReader)
+		entries[header.Name] = string(content)
+	}
+
+	if entries["results.csv"] != "1,2" || entries[provenanceFile] != "{}" {
+		t.Errorf("Got: '%v' - Expected '%v'", entries, "results.csv and provenance.json")
+	}
+}
+
+func TestAddFileToTarGzShouldNotReplaceEntryOfSimulation(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "provenance")
+	defer os.RemoveAll(dir)
+	archivePath := filepath.Join(dir, "output.tar.gz")
+
+	file, _ := os.Create(archivePath)
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "./" + provenanceFile, Mode: 0644, Size: 2})
+	tarWriter.Write([]byte("[]"))
+	tarWriter.Close()
+	gzipWriter.Close()
+	file.Close()
+	original, _ := ioutil.ReadFile(archivePath)
+
+	// === WHEN ===
+	err := addFileToTarGz(archivePath, provenanceFile, []byte("{}"))
+
+	// === THEN ===
+	if err != errEntryExists {
+		t.Errorf("Got: '%v' - Expected '%v'", err, errEntryExists)
+	}
+
+	if content, _ := ioutil.ReadFile(archivePath); string(content) != string(original) {
+		t.Errorf("The archive should not be changed")
+	}
+
+	if _, err := os.Stat(archivePath + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Got: '%v' - Expected the partial archive removed", err)
+	}
+}
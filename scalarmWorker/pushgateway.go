@@ -0,0 +1,202 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var pushgatewayLog = NewLogger("pushgateway")
+
+// job label of metrics pushed when pushgateway_job is not set
+const defaultPushgatewayJob = "scalarm_simulation_manager"
+
+// PushgatewayMetrics counts simulation runs and failures of the worker and pushes them to a Prometheus
+// Pushgateway, since short-lived batch jobs cannot be scraped; metrics of a worker are grouped by job,
+// worker and instance labels and are replaced with every push
+type PushgatewayMetrics struct {
+	Url        string
+	Job        string
+	Worker     string
+	Instance   string
+	HttpClient *http.Client
+
+	startedAt time.Time
+	// simulation runs by experiment and status
+	runs map[[2]string]float64
+	// total duration in seconds and number of finished simulation runs by experiment
+	durationSum     map[string]float64
+	durationCount   map[string]float64
+	failedUploads   float64
+	adapterFailures float64
+	finished        bool
+	mutex           sync.Mutex
+}
+
+// NewPushgatewayMetrics creates metrics pushed to the Pushgateway from the config, nil when it is not configured
+func NewPushgatewayMetrics(config *SimulationManagerConfig) *PushgatewayMetrics {
+	if config.PushgatewayUrl == "" {
+		return nil
+	}
+
+	job := config.PushgatewayJob
+	if job == "" {
+		job = defaultPushgatewayJob
+	}
+	hostname, _ := os.Hostname()
+
+	return &PushgatewayMetrics{
+		Url:           strings.TrimSuffix(config.PushgatewayUrl, "/"),
+		Job:           job,
+		Worker:        pushgatewayWorker(hostname),
+		Instance:      hostname,
+		HttpClient:    &http.Client{Timeout: notificationTimeout},
+		startedAt:     time.Now(),
+		runs:          map[[2]string]float64{},
+		durationSum:   map[string]float64{},
+		durationCount: map[string]float64{},
+	}
+}
+
+// pushgatewayWorker identifies the worker by its id or batch job, or by the host and pid
+func pushgatewayWorker(hostname string) string {
+	for _, variable := range provenanceJobVariables {
+		if value := os.Getenv(variable); value != "" {
+			return value
+		}
+	}
+
+	return hostname + "-" + strconv.Itoa(os.Getpid())
+}
+
+// Observe updates metrics with a worker event; nil metrics ignore all events
+func (metrics *PushgatewayMetrics) Observe(eventType string, experimentID string, data interface{}) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	switch eventType {
+	case "simulation_run_finished":
+		report, ok := data.(*RunReport)
+		if !ok {
+			return
+		}
+		metrics.runs[[2]string{experimentID, report.Status}]++
+
+		finishedAt := report.FinishedAt
+		if finishedAt == 0 {
+			finishedAt = serverNow().Unix()
+		}
+		metrics.durationSum[experimentID] += float64(finishedAt - report.StartedAt)
+		metrics.durationCount[experimentID]++
+	case "artifact_upload_failed":
+		metrics.failedUploads++
+	case "adapter_failed":
+		metrics.adapterFailures++
+	}
+}
+
+// Text returns metrics in the Prometheus text exposition format
+func (metrics *PushgatewayMetrics) Text() string {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	var text bytes.Buffer
+
+	fmt.Fprintln(&text, "# TYPE scalarm_worker_simulation_runs_total counter")
+	keys := [][2]string{}
+	for key := range metrics.runs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&text, "scalarm_worker_simulation_runs_total{experiment_id=%s,status=%s} %v\n",
+			metricLabel(key[0]), metricLabel(key[1]), metrics.runs[key])
+	}
+
+	fmt.Fprintln(&text, "# TYPE scalarm_worker_simulation_run_duration_seconds summary")
+	experiments := []string{}
+	for experimentID := range metrics.durationCount {
+		experiments = append(experiments, experimentID)
+	}
+	sort.Strings(experiments)
+	for _, experimentID := range experiments {
+		fmt.Fprintf(&text, "scalarm_worker_simulation_run_duration_seconds_sum{experiment_id=%s} %v\n",
+			metricLabel(experimentID), metrics.durationSum[experimentID])
+		fmt.Fprintf(&text, "scalarm_worker_simulation_run_duration_seconds_count{experiment_id=%s} %v\n",
+			metricLabel(experimentID), metrics.durationCount[experimentID])
+	}
+
+	finished := 0
+	if metrics.finished {
+		finished = 1
+	}
+	fmt.Fprintf(&text, "# TYPE scalarm_worker_failed_uploads_total counter\nscalarm_worker_failed_uploads_total %v\n", metrics.failedUploads)
+	fmt.Fprintf(&text, "# TYPE scalarm_worker_adapter_failures_total counter\nscalarm_worker_adapter_failures_total %v\n", metrics.adapterFailures)
+	fmt.Fprintf(&text, "# TYPE scalarm_worker_start_time_seconds gauge\nscalarm_worker_start_time_seconds %d\n", metrics.startedAt.Unix())
+	fmt.Fprintf(&text, "# TYPE scalarm_worker_finished gauge\nscalarm_worker_finished %d\n", finished)
+
+	return text.String()
+}
+
+// metricLabel quotes a label value of the text exposition format
+func metricLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// Push replaces metrics of the worker in the Pushgateway
+func (metrics *PushgatewayMetrics) Push() error {
+	pushUrl := fmt.Sprintf("%s/metrics/job/%s/worker/%s/instance/%s", metrics.Url, url.PathEscape(metrics.Job),
+		url.PathEscape(metrics.Worker), url.PathEscape(metrics.Instance))
+
+	req, err := http.NewRequest("PUT", pushUrl, strings.NewReader(metrics.Text()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := metrics.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("Pushgateway response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
+
+// PushEvery pushes metrics every interval for the lifetime of the worker
+func (metrics *PushgatewayMetrics) PushEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := metrics.Push(); err != nil {
+			pushgatewayLog.Warnf("Could not push metrics: %v", Redact(err.Error()))
+		}
+	}
+}
+
+// Finish pushes final metrics of the worker, marked with scalarm_worker_finished 1
+func (metrics *PushgatewayMetrics) Finish() {
+	metrics.mutex.Lock()
+	metrics.finished = true
+	metrics.mutex.Unlock()
+
+	if err := metrics.Push(); err != nil {
+		pushgatewayLog.Warnf("Could not push final metrics: %v", Redact(err.Error()))
+	}
+}
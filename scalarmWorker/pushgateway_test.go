@@ -0,0 +1,62 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushgatewayMetricsShouldPushCountersOfSimulationRuns(t *testing.T) {
+	// === GIVEN ===
+	pushes := []string{}
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		pushes = append(pushes, string(body))
+		paths = append(paths, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.PushgatewayUrl = server.URL + "/"
+	metrics := NewPushgatewayMetrics(config)
+	metrics.Worker, metrics.Instance = "worker-1", "node-1"
+
+	report := NewRunReport("e1", 1, nil)
+	report.Status = "ok"
+	metrics.Observe("simulation_run_finished", "e1", report)
+	metrics.Observe("simulation_run_finished", "e1", report)
+	metrics.Observe("artifact_upload_failed", "e1", nil)
+
+	// === WHEN ===
+	metrics.Finish()
+
+	// === THEN ===
+	if len(paths) != 1 || paths[0] != "PUT /metrics/job/scalarm_simulation_manager/worker/worker-1/instance/node-1" {
+		t.Fatalf("Got: '%v' - Expected '%v'", paths, "a single push of the worker")
+	}
+
+	for _, expected := range []string{
+		`scalarm_worker_simulation_runs_total{experiment_id="e1",status="ok"} 2`,
+		`scalarm_worker_simulation_run_duration_seconds_count{experiment_id="e1"} 2`,
+		"scalarm_worker_failed_uploads_total 1\n",
+		"scalarm_worker_adapter_failures_total 0\n",
+		"scalarm_worker_finished 1\n",
+	} {
+		if !strings.Contains(pushes[0], expected) {
+			t.Errorf("Got: '%v' - Expected '%v'", pushes[0], expected)
+		}
+	}
+}
+
+func TestMetricLabelShouldEscapeQuotes(t *testing.T) {
+	// === WHEN ===
+	label := metricLabel(`a"b\c`)
+
+	// === THEN ===
+	if label != `"a\"b\\c"` {
+		t.Errorf("Got: '%v' - Expected '%v'", label, `"a\"b\\c"`)
+	}
+}
@@ -0,0 +1,67 @@
+package scalarmWorker
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// source of randomized decisions of SiM, like the order in which service URLs are tried;
+// it is seeded from a secure source unless random_seed is configured
+var (
+	randomSeed   = secureSeed()
+	randomSource = rand.New(rand.NewSource(randomSeed))
+	randomMutex  sync.Mutex
+)
+
+func secureSeed() int64 {
+	var seed [8]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+
+	return int64(binary.LittleEndian.Uint64(seed[:]))
+}
+
+// SeedRandom makes randomized behavior of SiM reproducible
+func SeedRandom(seed int64) {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+
+	randomSeed = seed
+	randomSource = rand.New(rand.NewSource(seed))
+}
+
+// RandomSeed returns the seed in use, logged so that a reported behavior can be reproduced
+func RandomSeed() int64 {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+
+	return randomSeed
+}
+
+// WorkerSeed derives the seed of a worker started by an agent from the configured one,
+// so workers of a node do not try services in the same order
+func WorkerSeed(seed int64, workerID string) int64 {
+	worker, _ := strconv.ParseInt(workerID, 10, 64)
+	return seed + worker
+}
+
+// randomPerm returns a random permutation of [0, n)
+func randomPerm(n int) []int {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+
+	return randomSource.Perm(n)
+}
+
+// randomFloat returns a random number in [0, 1)
+func randomFloat() float64 {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+
+	return randomSource.Float64()
+}
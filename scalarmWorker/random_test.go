@@ -0,0 +1,35 @@
+package scalarmWorker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeedRandomShouldMakeShufflingReproducible(t *testing.T) {
+	// === GIVEN ===
+	SeedRandom(42)
+	expected := [][]int{randomPerm(10), randomPerm(10)}
+
+	// === WHEN ===
+	SeedRandom(42)
+	actual := [][]int{randomPerm(10), randomPerm(10)}
+
+	// === THEN ===
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", actual, expected)
+	}
+	if RandomSeed() != 42 {
+		t.Errorf("Got: '%v' - Expected '%v'", RandomSeed(), 42)
+	}
+
+	SeedRandom(secureSeed())
+}
+
+func TestWorkerSeedShouldDifferBetweenWorkers(t *testing.T) {
+	if WorkerSeed(42, "") != 42 {
+		t.Errorf("Got: '%v' - Expected '%v'", WorkerSeed(42, ""), 42)
+	}
+	if WorkerSeed(42, "3") != 45 {
+		t.Errorf("Got: '%v' - Expected '%v'", WorkerSeed(42, "3"), 45)
+	}
+}
@@ -0,0 +1,84 @@
+package scalarmWorker
+
+import (
+	"encoding/base64"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "[REDACTED]"
+
+// secrets shorter than this are not redacted, replacing them would garble regular output
+const minSecretLength = 4
+
+var (
+	secretsMutex sync.RWMutex
+	secrets      = []string{}
+)
+
+type secretPattern struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// patterns of credentials which are redacted even if they were never registered
+var secretPatterns = []secretPattern{
+	// Authorization headers
+	{regexp.MustCompile(`(?i)(authorization:\s*\w+\s+)\S+`), "${1}" + redactedValue},
+	// form fields, query parameters and JSON values of whole keys only, so e.g. max_tokens or bypass are kept
+	{regexp.MustCompile(`(?i)(\b(?:(?:\w+_)?(?:password|passwd|pass|secret|api_key)|(?:access_|auth_|api_|refresh_)?token)["']?\s*[=:]\s*["']?)[^"'&\s,}]+`), "${1}" + redactedValue},
+	// credentials in URLs
+	{regexp.MustCompile(`(://[^/:@\s]+:)[^/@\s]+@`), "${1}" + redactedValue + "@"},
+}
+
+// RegisterSecret makes Redact replace every occurrence of secret, also in its base64 form
+// used by basic authentication headers
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
+	secrets = append(secrets, secret, base64.StdEncoding.EncodeToString([]byte(secret)))
+}
+
+// RegisterCredentials registers the password and the basic authentication token built from user and password
+func RegisterCredentials(user string, password string) {
+	RegisterSecret(password)
+	if password != "" {
+		RegisterSecret(user + ":" + password)
+	}
+}
+
+// Redact replaces registered secrets and anything looking like a credential with [REDACTED]
+func Redact(text string) string {
+	secretsMutex.RLock()
+	for _, secret := range secrets {
+		text = strings.Replace(text, secret, redactedValue, -1)
+	}
+	secretsMutex.RUnlock()
+
+	for _, secretPattern := range secretPatterns {
+		text = secretPattern.pattern.ReplaceAllString(text, secretPattern.replacement)
+	}
+
+	return text
+}
+
+// RedactingWriter redacts everything written through it; a secret split between two writes is not detected,
+// so it should wrap writers receiving whole lines or files
+type RedactingWriter struct {
+	Writer io.Writer
+}
+
+func (w RedactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.Writer, Redact(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,88 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// max number of redirects followed by a single request
+const maxRedirects = 10
+
+// hosts of Scalarm services, redirects are followed only between them
+var (
+	trustedHosts      = map[string]bool{}
+	trustedHostsMutex sync.RWMutex
+)
+
+// TrustServiceHosts adds hosts of service addresses, like 'system.scalarm.com/information' or full URLs,
+// to hosts between which redirects are followed
+func TrustServiceHosts(addresses ...string) {
+	trustedHostsMutex.Lock()
+	defer trustedHostsMutex.Unlock()
+
+	for _, address := range addresses {
+		if i := strings.Index(address, "://"); i >= 0 {
+			address = address[i+3:]
+		}
+		if i := strings.Index(address, "/"); i >= 0 {
+			address = address[:i]
+		}
+		if address != "" {
+			trustedHosts[strings.ToLower(address)] = true
+		}
+	}
+}
+
+// isTrustedHost accepts a host with a port when it is trusted with or without the port
+func isTrustedHost(request *http.Request) bool {
+	trustedHostsMutex.RLock()
+	defer trustedHostsMutex.RUnlock()
+
+	return trustedHosts[strings.ToLower(request.URL.Host)] || trustedHosts[strings.ToLower(request.URL.Hostname())]
+}
+
+// RedirectPolicy returns the redirect policy of clients talking to Scalarm: redirects are followed only
+// between hosts of Scalarm services and redirect_trusted_hosts, credentials are applied again to each of them,
+// while redirects to other hosts, from HTTPS to HTTP or turning a request with a body into GET are not
+// followed and logged as warnings, the redirect response is returned instead
+func RedirectPolicy(config *SimulationManagerConfig) func(*http.Request, []*http.Request) error {
+	TrustServiceHosts(config.InformationServiceUrl)
+	TrustServiceHosts(config.InformationServiceUrls...)
+	TrustServiceHosts(config.RedirectTrustedHosts...)
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("Stopped after 10 redirects")
+		}
+
+		original := via[0]
+		previous := via[len(via)-1]
+
+		if req.Method != original.Method {
+			httpLog.Warnf("%s %s was redirected to %s as %s, the request body would be lost; "+
+				"point SiM at the new address", original.Method, Redact(previous.URL.String()), Redact(req.URL.String()), req.Method)
+			return http.ErrUseLastResponse
+		}
+
+		if original.URL.Scheme == "https" && req.URL.Scheme != "https" {
+			httpLog.Warnf("%s was redirected to %s without TLS, the redirect is not followed",
+				Redact(previous.URL.String()), Redact(req.URL.String()))
+			return http.ErrUseLastResponse
+		}
+
+		if !isTrustedHost(req) {
+			httpLog.Warnf("%s was redirected to %s which is not a Scalarm service, update the service "+
+				"address or add the host to redirect_trusted_hosts", Redact(previous.URL.String()), Redact(req.URL.String()))
+			return http.ErrUseLastResponse
+		}
+
+		// credentials are dropped by Go when the redirect leaves the original domain
+		if _, _, ok := original.BasicAuth(); ok {
+			req.SetBasicAuth(config.Credentials())
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupRedirect(status int, target string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target+r.URL.Path, status)
+	}))
+}
+
+func TestRedirectPolicyShouldFollowRedirectsToTrustedHostsWithCredentials(t *testing.T) {
+	// === GIVEN ===
+	authorized := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, authorized = r.BasicAuth()
+		w.WriteHeader(200)
+	}))
+	defer target.Close()
+
+	source := setupRedirect(307, target.URL)
+	defer source.Close()
+
+	config := getSimConfig()
+	config.Development = true
+	config.RedirectTrustedHosts = []string{strings.TrimPrefix(target.URL, "http://")}
+	client := &http.Client{CheckRedirect: RedirectPolicy(config)}
+
+	// === WHEN ===
+	req, _ := NewScalarmRequest(RequestInfo{"POST", strings.NewReader("status=ok"), "application/x-www-form-urlencoded",
+		"experiments/1/simulations/1/mark_as_complete"}, strings.TrimPrefix(source.URL, "http://"), config)
+	resp, err := client.Do(req)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 || !authorized {
+		t.Errorf("Got: '%v' - Expected '%v'", resp.StatusCode, "200 with credentials")
+	}
+}
+
+func TestRedirectPolicyShouldNotFollowRedirectsToOtherHosts(t *testing.T) {
+	// === GIVEN ===
+	targetHit := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHit = true
+		w.WriteHeader(200)
+	}))
+	defer target.Close()
+
+	source := setupRedirect(307, target.URL)
+	defer source.Close()
+
+	config := getSimConfig()
+	config.Development = true
+	client := &http.Client{CheckRedirect: RedirectPolicy(config)}
+
+	// === WHEN ===
+	req, _ := NewScalarmRequest(RequestInfo{"GET", nil, "", "experiments/1/next_simulation"},
+		strings.TrimPrefix(source.URL, "http://"), config)
+	resp, err := client.Do(req)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 307 || targetHit {
+		t.Errorf("Got: '%v' - Expected '%v'", resp.StatusCode, 307)
+	}
+}
+
+func TestRedirectPolicyShouldNotTurnRequestsWithBodiesIntoGet(t *testing.T) {
+	// === GIVEN ===
+	targetHit := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHit = true
+		w.WriteHeader(200)
+	}))
+	defer target.Close()
+
+	source := setupRedirect(302, target.URL)
+	defer source.Close()
+
+	config := getSimConfig()
+	config.Development = true
+	config.RedirectTrustedHosts = []string{strings.TrimPrefix(target.URL, "http://")}
+	client := &http.Client{CheckRedirect: RedirectPolicy(config)}
+
+	// === WHEN ===
+	req, _ := NewScalarmRequest(RequestInfo{"POST", strings.NewReader("status=ok"), "application/x-www-form-urlencoded",
+		"experiments/1/simulations/1/mark_as_complete"}, strings.TrimPrefix(source.URL, "http://"), config)
+	resp, err := client.Do(req)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 302 || targetHit {
+		t.Errorf("Got: '%v' - Expected '%v'", resp.StatusCode, 302)
+	}
+}
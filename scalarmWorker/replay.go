@@ -0,0 +1,149 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var replayLog = NewLogger("replay")
+
+// ReplaySource describes what is needed to re-execute a simulation run locally
+type ReplaySource struct {
+	InputParameters []byte
+	CodeBaseDir     string
+}
+
+// LoadReplaySource reads input parameters and a code base location from a saved simulation directory
+// (experiment_<id>/simulation_<index>) or from a run report (report.json)
+func LoadReplaySource(source string, rootDirPath string) (*ReplaySource, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		inputParameters, err := ioutil.ReadFile(path.Join(source, "input.json"))
+		if err != nil {
+			return nil, errors.New("Could not read input.json from " + source + ": " + err.Error())
+		}
+
+		absSource, _ := filepath.Abs(source)
+		return &ReplaySource{
+			InputParameters: inputParameters,
+			CodeBaseDir:     path.Join(filepath.Dir(absSource), "code_base"),
+		}, nil
+	}
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(RunReport)
+	if err = json.Unmarshal(content, report); err != nil {
+		return nil, errors.New("Incorrect JSON in the run report " + source + ".")
+	}
+
+	if len(report.InputParameters) == 0 {
+		return nil, errors.New("The run report " + source + " does not contain input parameters.")
+	}
+
+	// the report is indented, input.json is written in a compact form
+	inputParameters := &bytes.Buffer{}
+	if err = json.Compact(inputParameters, report.InputParameters); err != nil {
+		return nil, err
+	}
+
+	return &ReplaySource{
+		InputParameters: inputParameters.Bytes(),
+		CodeBaseDir:     path.Join(rootDirPath, "experiment_"+report.ExperimentID, "code_base"),
+	}, nil
+}
+
+// Replay re-executes the adapters pipeline (input templates, input_writer, executor, output_reader) in a new directory
+// with the same input.json as the given simulation run; nothing is sent to Scalarm
+func Replay(source string, rootDirPath string) error {
+	replaySource, err := LoadReplaySource(source, rootDirPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(path.Join(replaySource.CodeBaseDir, "executor")); err != nil {
+		return errors.New("Could not find executor in the code base " + replaySource.CodeBaseDir)
+	}
+
+	replayDirPath, err := ioutil.TempDir(rootDirPath, "replay_")
+	if err != nil {
+		return err
+	}
+	replayLog.Infof("Replaying simulation run in %s", replayDirPath)
+
+	if err = ioutil.WriteFile(path.Join(replayDirPath, "input.json"), replaySource.InputParameters, 0777); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if err = os.Chdir(replayDirPath); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	templatesDir := path.Join(replaySource.CodeBaseDir, inputTemplatesDir)
+	if _, err := os.Stat(templatesDir); err == nil {
+		replayLog.Infof("Rendering input templates ...")
+		parameters := map[string]interface{}{}
+		if err = json.Unmarshal(replaySource.InputParameters, &parameters); err != nil {
+			return err
+		}
+		if err = RenderInputTemplates(templatesDir, replayDirPath, parameters); err != nil {
+			return err
+		}
+	}
+
+	adapters := []struct {
+		name     string
+		args     string
+		optional bool
+	}{
+		{"input_writer", " input.json >>_stdout.txt 2>>_stderr.txt", true},
+		{"executor", " >>_stdout.txt 2>>_stderr.txt", false},
+		{"output_reader", " >>_stdout.txt 2>>_stderr.txt", true},
+	}
+
+	for _, adapter := range adapters {
+		if adapterFile(replaySource.CodeBaseDir, adapter.name) == "" && adapter.optional {
+			continue
+		}
+
+		replayLog.Infof("Running %s ...", adapter.name)
+		adapterCmd := shellCommand(context.Background(), adapterCall(replaySource.CodeBaseDir, adapter.name)+adapter.args)
+		adapterCmd.Dir = replayDirPath
+
+		if err = adapterCmd.Run(); err != nil {
+			replayLog.Warnf("'%v' failed: %v", strings.Join(adapterCmd.Args, " "), err)
+			PrintStdoutLog(path.Join(replayDirPath, "_stdout.txt"), defaultStdoutLogLines)
+			return errors.New("Replay failed during '" + adapter.name + "' execution")
+		}
+	}
+
+	output, err := ioutil.ReadFile(path.Join(replayDirPath, "output.json"))
+	if err != nil {
+		replayLog.Infof("No output.json file found")
+	} else {
+		replayLog.Infof("output.json: %s", output)
+	}
+
+	replayLog.Infof("Finished, results are kept in %s", replayDirPath)
+	return nil
+}
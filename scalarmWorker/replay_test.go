@@ -0,0 +1,89 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadReplaySourceShouldReadSimulationDirectory(t *testing.T) {
+	// === GIVEN ===
+	defer os.RemoveAll("./experiment_replay")
+	os.MkdirAll("./experiment_replay/simulation_1", 0777)
+	ioutil.WriteFile("./experiment_replay/simulation_1/input.json", []byte(`{"parameter1":1}`), 0666)
+
+	// === WHEN ===
+	source, err := LoadReplaySource("./experiment_replay/simulation_1", ".")
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected nil", err)
+	}
+
+	if string(source.InputParameters) != `{"parameter1":1}` {
+		t.Errorf("Got: '%s' - Expected '%v'", source.InputParameters, `{"parameter1":1}`)
+	}
+
+	expectedCodeBaseDir, _ := filepath.Abs("./experiment_replay/code_base")
+	if source.CodeBaseDir != expectedCodeBaseDir {
+		t.Errorf("Got: '%v' - Expected '%v'", source.CodeBaseDir, expectedCodeBaseDir)
+	}
+}
+
+func TestLoadReplaySourceShouldReadRunReport(t *testing.T) {
+	// === GIVEN ===
+	reportsDir := "./test_replay_reports"
+	defer os.RemoveAll(reportsDir)
+
+	reportPath, _ := NewRunReport("abc", 3, []byte(`{"parameter1":2}`)).Write(reportsDir)
+
+	// === WHEN ===
+	source, err := LoadReplaySource(reportPath, "/scalarm")
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected nil", err)
+	}
+
+	if string(source.InputParameters) != `{"parameter1":2}` {
+		t.Errorf("Got: '%s' - Expected '%v'", source.InputParameters, `{"parameter1":2}`)
+	}
+
+	if source.CodeBaseDir != "/scalarm/experiment_abc/code_base" {
+		t.Errorf("Got: '%v' - Expected '%v'", source.CodeBaseDir, "/scalarm/experiment_abc/code_base")
+	}
+}
+
+func TestReplayShouldExecuteAdaptersWithSavedInput(t *testing.T) {
+	// === GIVEN ===
+	wd, _ := os.Getwd()
+	rootDir, _ := ioutil.TempDir(wd, "test_replay_root")
+	defer os.RemoveAll(rootDir)
+
+	codeBaseDir := path.Join(rootDir, "experiment_1", "code_base")
+	simulationDir := path.Join(rootDir, "experiment_1", "simulation_1")
+	os.MkdirAll(codeBaseDir, 0777)
+	os.MkdirAll(simulationDir, 0777)
+	ioutil.WriteFile(path.Join(simulationDir, "input.json"), []byte(`{"parameter1":1}`), 0666)
+	ioutil.WriteFile(path.Join(codeBaseDir, "executor"), []byte("#!/bin/sh\ncp input.json output.json\n"), 0777)
+
+	// === WHEN ===
+	err := Replay(simulationDir, rootDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected nil", err)
+	}
+
+	outputs, _ := filepath.Glob(path.Join(rootDir, "replay_*", "output.json"))
+	if len(outputs) != 1 {
+		t.Fatalf("Got: %v output files - Expected 1", len(outputs))
+	}
+
+	output, _ := ioutil.ReadFile(outputs[0])
+	if string(output) != `{"parameter1":1}` {
+		t.Errorf("Got: '%s' - Expected '%v'", output, `{"parameter1":1}`)
+	}
+}
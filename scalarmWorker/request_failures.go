@@ -0,0 +1,62 @@
+package scalarmWorker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// classes of failed requests, reported with every failed attempt
+const (
+	FailureDNS               = "dns"
+	FailureTLS               = "tls"
+	FailureConnectionRefused = "connection_refused"
+	FailureConnectionReset   = "connection_reset"
+	FailureTimeout           = "timeout"
+	FailureOther             = "other"
+)
+
+// ClassifyFailure tells why a request failed, so a firewall or a wrong address can be told from
+// an overloaded server
+func ClassifyFailure(err error) string {
+	var dnsErr *net.DNSError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var verificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return FailureDNS
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &certificateInvalidErr), errors.As(err, &hostnameErr),
+		errors.As(err, &verificationErr), errors.As(err, &recordHeaderErr):
+		return FailureTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return FailureConnectionReset
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	}
+
+	return FailureOther
+}
+
+// isPermanentFailure tells whether repeating a request against the same instance is pointless:
+// its name does not resolve or its certificate is rejected
+func isPermanentFailure(err error) bool {
+	switch ClassifyFailure(err) {
+	case FailureDNS:
+		var dnsErr *net.DNSError
+		return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+	case FailureTLS:
+		return true
+	}
+
+	return false
+}
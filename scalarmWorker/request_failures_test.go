@@ -0,0 +1,129 @@
+package scalarmWorker
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyFailureShouldTellCausesApart(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{&url.Error{Op: "Get", URL: "https://em", Err: &net.DNSError{Name: "em", IsNotFound: true}}, FailureDNS},
+		{&url.Error{Op: "Get", URL: "https://em", Err: x509.UnknownAuthorityError{}}, FailureTLS},
+		{&url.Error{Op: "Get", URL: "https://em", Err: refused}, FailureConnectionRefused},
+		{&url.Error{Op: "Get", URL: "https://em", Err: timeoutError{}}, FailureTimeout},
+		{errors.New("something else"), FailureOther},
+	}
+
+	for _, c := range cases {
+		if class := ClassifyFailure(c.err); class != c.expected {
+			t.Errorf("Got: '%v' - Expected '%v' for %v", class, c.expected, c.err)
+		}
+	}
+
+	if isPermanentFailure(refused) || !isPermanentFailure(x509.UnknownAuthorityError{}) {
+		t.Errorf("Only DNS and TLS failures should be permanent")
+	}
+}
+
+func TestExecuteScalarmRequestShouldNotRetryRejectedCertificates(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	config.Development = false
+	reqInfo := RequestInfo{"GET", nil, "", "experiment_managers"}
+
+	// === WHEN ===
+	start := time.Now()
+	_, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{server.Listener.Addr().String()}, config, &http.Client{}, time.Minute)
+
+	// === THEN ===
+	requestErr, ok := err.(*RequestError)
+	if !ok {
+		t.Fatalf("Got: '%v' - Expected '%v'", err, "a RequestError")
+	}
+	if len(requestErr.Attempts) != 1 || requestErr.Attempts[0].Class != FailureTLS {
+		t.Errorf("Got: '%v' - Expected '%v'", requestErr.Attempts, "a single TLS failure")
+	}
+	if !strings.Contains(err.Error(), "[tls]") {
+		t.Errorf("Got: '%v' - Expected '%v'", err.Error(), "the failure class")
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("A rejected certificate should end retries at once")
+	}
+}
+
+func TestExecuteScalarmRequestShouldSendBodyAgainToNextInstance(t *testing.T) {
+	// === GIVEN ===
+	var mutex sync.Mutex
+	requests := 0
+	results := []string{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		first := requests == 1
+		mutex.Unlock()
+
+		// the first instance drops the connection after reading headers
+		if first {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+
+		r.ParseForm()
+		mutex.Lock()
+		results = append(results, r.PostForm.Get("result"))
+		mutex.Unlock()
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	config := getSimConfig()
+	config.Development = true
+	data := url.Values{"status": {"ok"}, "result": {`{"x":1}`}}
+	reqInfo := RequestInfo{"POST", strings.NewReader(data.Encode()), "application/x-www-form-urlencoded",
+		"experiments/e1/simulations/1/mark_as_complete.json"}
+	serviceUrls := []string{first.Listener.Addr().String(), second.Listener.Addr().String()}
+
+	// === WHEN ===
+	response, err := ExecuteScalarmRequest(context.Background(), reqInfo, serviceUrls, config, &http.Client{}, 10*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected '%v'", err, nil)
+	}
+	response.Body.Close()
+
+	if len(results) != 1 || results[0] != `{"x":1}` {
+		t.Errorf("Got: '%v' - Expected '%v'", results, `{"x":1}`)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package scalarmWorker
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartProcess replaces the process with a new instance of SiM, keeping its pid for batch systems
+func restartProcess() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(executable, os.Args, os.Environ())
+}
@@ -0,0 +1,19 @@
+package scalarmWorker
+
+import (
+	"os"
+	"os/exec"
+)
+
+// processes cannot be replaced on Windows, a new instance of SiM is started before this one exits
+func restartProcess() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	return cmd.Start()
+}
@@ -0,0 +1,54 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ResultCache keeps results of successful simulation runs on disk, keyed by the experiment and a hash
+// of input parameters, so a parameter point revisited by a design of experiments is not computed again
+type ResultCache struct {
+	Dir string
+}
+
+func (cache *ResultCache) entryPath(experimentID string, inputParameters []byte) string {
+	sum := sha256.Sum256(inputParameters)
+	return filepath.Join(cache.Dir, experimentID, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns cached results for input parameters (as JSON) of a simulation run of the experiment
+func (cache *ResultCache) Load(experimentID string, inputParameters []byte) (json.RawMessage, bool) {
+	content, err := ioutil.ReadFile(cache.entryPath(experimentID, inputParameters))
+	if err != nil || !json.Valid(content) {
+		return nil, false
+	}
+
+	return json.RawMessage(content), true
+}
+
+// Store saves results of a simulation run, the entry is written to a temporary file first
+// so an interrupted write never leaves corrupted results in the cache
+func (cache *ResultCache) Store(experimentID string, inputParameters []byte, results []byte) error {
+	entryPath := cache.entryPath(experimentID, inputParameters)
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0777); err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(entryPath), "entry_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(results)
+	tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), entryPath)
+}
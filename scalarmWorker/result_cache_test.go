@@ -0,0 +1,71 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestResultCacheShouldReturnStoredResultsOfSameParameters(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "result_cache")
+	defer os.RemoveAll(dir)
+	cache := &ResultCache{Dir: dir}
+
+	// === WHEN ===
+	if err := cache.Store("568e5bece138232e76000002", []byte(`{"x":1}`), []byte(`{"y":2}`)); err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	// === THEN ===
+	if result, found := cache.Load("568e5bece138232e76000002", []byte(`{"x":1}`)); !found || string(result) != `{"y":2}` {
+		t.Errorf("Got: '%s' (%v) - Expected '%v'", result, found, `{"y":2}`)
+	}
+
+	if _, found := cache.Load("568e5bece138232e76000002", []byte(`{"x":2}`)); found {
+		t.Errorf("Results of other parameters should not be found")
+	}
+
+	if _, found := cache.Load("568e5bece138232e76000003", []byte(`{"x":1}`)); found {
+		t.Errorf("Results of other experiments should not be found")
+	}
+}
+
+func TestExperimentManagerShouldReturnComputedResultOfIdenticalParameters(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/simulations/computed_result" {
+			w.WriteHeader(500)
+			return
+		}
+
+		if r.URL.Query().Get("input_parameters") == `{"x":1}` {
+			fmt.Fprintln(w, `{"status":"ok","result":{"y":2}}`)
+		} else {
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	result, found, err := em.GetComputedResult([]byte(`{"x":1}`))
+	_, otherFound, otherErr := em.GetComputedResult([]byte(`{"x":2}`))
+
+	// === THEN ===
+	if err != nil || otherErr != nil {
+		t.Errorf("Returned errors should be nil, but they are '%v', '%v'", err, otherErr)
+	}
+
+	if !found || string(result) != `{"y":2}` {
+		t.Errorf("Got: '%s' (%v) - Expected '%v'", result, found, `{"y":2}`)
+	}
+
+	if otherFound {
+		t.Errorf("Results of other parameters should not be found")
+	}
+}
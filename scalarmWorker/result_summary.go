@@ -0,0 +1,293 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// file of a code base which lists values extracted from HDF5 and NetCDF output files into results
+const resultSummaryFile = "result_summary.json"
+
+// helper binaries from the HDF5 and NetCDF distributions used to read output files
+var (
+	h5dumpCommand = "h5dump"
+	ncdumpCommand = "ncdump"
+)
+
+// ResultSummary describes a single value extracted from a binary output file into results
+type ResultSummary struct {
+	// path of the output file relative to the simulation directory
+	File string `json:"file"`
+	// 'hdf5' or 'netcdf', guessed from the file extension when empty
+	Format string `json:"format"`
+	// path of an HDF5 dataset or name of a NetCDF variable, empty for global attributes
+	Dataset string `json:"dataset"`
+	// name of an attribute of the dataset or of the file when set
+	Attribute string `json:"attribute"`
+	// key of the value in results
+	Key string `json:"key"`
+	// 'mean', 'min', 'max', 'sum', 'first' or 'last'; without it a single value or a list of values is stored
+	Reduce string `json:"reduce"`
+}
+
+// LoadResultSummaries reads the list of summarized values from a code base file
+func LoadResultSummaries(filePath string) ([]ResultSummary, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []ResultSummary{}
+	if err = json.Unmarshal(content, &summaries); err != nil {
+		return nil, errors.New("Incorrect JSON in " + filePath + ": " + err.Error())
+	}
+
+	for _, summary := range summaries {
+		if summary.File == "" || summary.Key == "" || (summary.Dataset == "" && summary.Attribute == "") {
+			return nil, errors.New("Every entry of " + resultSummaryFile + " requires file, key and dataset or attribute")
+		}
+	}
+
+	return summaries, nil
+}
+
+// SummarizeResults extracts values described by summaries from output files in simulationDirPath
+func SummarizeResults(summaries []ResultSummary, simulationDirPath string) (map[string]interface{}, error) {
+	results := map[string]interface{}{}
+
+	for _, summary := range summaries {
+		filePath := filepath.Join(simulationDirPath, summary.File)
+
+		format := summary.Format
+		if format == "" {
+			switch strings.ToLower(filepath.Ext(summary.File)) {
+			case ".h5", ".hdf5", ".he5":
+				format = "hdf5"
+			case ".nc", ".nc4", ".cdf":
+				format = "netcdf"
+			}
+		}
+
+		var values []interface{}
+		var err error
+
+		switch format {
+		case "hdf5":
+			values, err = readHDF5Values(filePath, summary.Dataset, summary.Attribute)
+		case "netcdf":
+			values, err = readNetCDFValues(filePath, summary.Dataset, summary.Attribute)
+		default:
+			err = errors.New("unknown format of " + summary.File)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", summary.Key, err)
+		}
+
+		if results[summary.Key], err = reduceValues(values, summary.Reduce); err != nil {
+			return nil, fmt.Errorf("%s: %v", summary.Key, err)
+		}
+	}
+
+	return results, nil
+}
+
+// readHDF5Values reads the data of a dataset or attribute from the output of h5dump
+func readHDF5Values(filePath string, dataset string, attribute string) ([]interface{}, error) {
+	args := []string{"-y", "-w", "0"}
+	if attribute != "" {
+		args = append(args, "-a", strings.TrimSuffix(dataset, "/")+"/"+attribute)
+	} else {
+		args = append(args, "-d", dataset)
+	}
+
+	output, err := runDumpCommand(h5dumpCommand, append(args, filePath)...)
+	if err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(output, "DATA {")
+	if start < 0 {
+		return nil, errors.New("no data in the output of " + h5dumpCommand)
+	}
+
+	return parseDumpValues(output[start+len("DATA {"):], "}")
+}
+
+// readNetCDFValues reads the data of a variable or an attribute from the output of ncdump
+func readNetCDFValues(filePath string, variable string, attribute string) ([]interface{}, error) {
+	var output, prefix string
+	var err error
+
+	if attribute != "" {
+		output, err = runDumpCommand(ncdumpCommand, "-h", filePath)
+		prefix = variable + ":" + attribute + " ="
+	} else {
+		output, err = runDumpCommand(ncdumpCommand, "-v", variable, filePath)
+		prefix = variable + " ="
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// values of variables are printed after the header, which also mentions their names
+	section := output
+	if dataStart := strings.Index(output, "\ndata:"); attribute == "" && dataStart >= 0 {
+		section = output[dataStart:]
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(section, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			start := offset + strings.Index(line, prefix) + len(prefix)
+			return parseDumpValues(section[start:], ";")
+		}
+		offset += len(line)
+	}
+
+	return nil, errors.New("'" + prefix + "' not found in the output of " + ncdumpCommand)
+}
+
+func runDumpCommand(command string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(command + " failed: " + message)
+		}
+		return "", errors.New(command + " failed: " + err.Error())
+	}
+
+	return stdout.String(), nil
+}
+
+// parseDumpValues parses comma separated numbers and quoted strings until the terminator,
+// type suffixes of NetCDF numbers (e.g. 1.5f) are dropped and fill values ('_') are skipped
+func parseDumpValues(text string, terminator string) ([]interface{}, error) {
+	values := []interface{}{}
+	token := strings.Builder{}
+	quoted := false
+	terminated := false
+
+	addToken := func() error {
+		raw := strings.TrimSpace(token.String())
+		token.Reset()
+
+		if raw == "" || raw == "_" {
+			return nil
+		}
+
+		if strings.HasPrefix(raw, "\"") {
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				return errors.New("invalid string value: " + raw)
+			}
+			values = append(values, value)
+			return nil
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimRight(raw, "fdsbLU"), 64)
+		if err != nil {
+			return errors.New("invalid numeric value: " + raw)
+		}
+		values = append(values, value)
+		return nil
+	}
+
+	for i := 0; i < len(text) && !terminated; i++ {
+		c := text[i]
+
+		switch {
+		case quoted && c == '\\' && i+1 < len(text):
+			token.WriteByte(c)
+			i++
+			c = text[i]
+		case c == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(text[i:], terminator):
+			terminated = true
+			continue
+		case !quoted && c == ',':
+			if err := addToken(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		token.WriteByte(c)
+	}
+
+	if !terminated {
+		return nil, errors.New("unexpected end of data")
+	}
+
+	if err := addToken(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// reduceValues aggregates numeric values into a single number
+func reduceValues(values []interface{}, reduce string) (interface{}, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no values")
+	}
+
+	switch reduce {
+	case "":
+		if len(values) == 1 {
+			return values[0], nil
+		}
+		return values, nil
+	case "first":
+		return values[0], nil
+	case "last":
+		return values[len(values)-1], nil
+	}
+
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		number, ok := value.(float64)
+		if !ok {
+			return nil, errors.New("'" + reduce + "' requires numeric values")
+		}
+		numbers[i] = number
+	}
+
+	result := numbers[0]
+	switch reduce {
+	case "sum", "mean":
+		for _, number := range numbers[1:] {
+			result += number
+		}
+		if reduce == "mean" {
+			result /= float64(len(numbers))
+		}
+	case "min":
+		for _, number := range numbers[1:] {
+			if number < result {
+				result = number
+			}
+		}
+	case "max":
+		for _, number := range numbers[1:] {
+			if number > result {
+				result = number
+			}
+		}
+	default:
+		return nil, errors.New("unknown reduction '" + reduce + "'")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeDumpCommand creates a script which prints output instead of h5dump or ncdump
+func fakeDumpCommand(dir string, name string, output string) string {
+	scriptPath := filepath.Join(dir, name)
+	ioutil.WriteFile(scriptPath, []byte("#!/bin/sh\ncat <<'EOF'\n"+output+"\nEOF\n"), 0755)
+	return scriptPath
+}
+
+func TestSummarizeResultsShouldReadHDF5DatasetsAndAttributes(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "result_summary")
+	defer os.RemoveAll(dir)
+
+	defer func(command string) { h5dumpCommand = command }(h5dumpCommand)
+	h5dumpCommand = fakeDumpCommand(dir, "h5dump", `HDF5 "output.h5" {
+DATASET "/fields/temperature" {
+   DATATYPE  H5T_IEEE_F64LE
+   DATASPACE  SIMPLE { ( 2, 2 ) / ( 2, 2 ) }
+   DATA {
+   280.5, 281.5,
+   282.5, 283.5
+   }
+}
+}`)
+
+	summaries := []ResultSummary{
+		{File: "output.h5", Dataset: "/fields/temperature", Key: "mean_temperature", Reduce: "mean"},
+		{File: "output.h5", Dataset: "/fields/temperature", Key: "temperature"},
+	}
+
+	// === WHEN ===
+	results, err := SummarizeResults(summaries, dir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if results["mean_temperature"] != 282.0 {
+		t.Errorf("Got: '%v' - Expected '%v'", results["mean_temperature"], 282.0)
+	}
+
+	if values, _ := results["temperature"].([]interface{}); len(values) != 4 || values[3] != 283.5 {
+		t.Errorf("Got: '%v' - Expected all 4 values", results["temperature"])
+	}
+}
+
+func TestSummarizeResultsShouldReadNetCDFVariablesAndAttributes(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "result_summary")
+	defer os.RemoveAll(dir)
+
+	defer func(command string) { ncdumpCommand = command }(ncdumpCommand)
+	ncdumpCommand = fakeDumpCommand(dir, "ncdump", `netcdf output {
+dimensions:
+	time = 3 ;
+variables:
+	float pressure(time) ;
+		pressure:units = "hPa" ;
+	float pressure_max ;
+
+// global attributes:
+		:model = "wrf, v4" ;
+data:
+
+ pressure = 1000.5f, 990.f,
+    _ ;
+}`)
+
+	summaries := []ResultSummary{
+		{File: "output.nc", Dataset: "pressure", Key: "max_pressure", Reduce: "max"},
+		{File: "output.nc", Dataset: "pressure", Attribute: "units", Key: "pressure_units"},
+		{File: "output.nc", Attribute: "model", Key: "model"},
+	}
+
+	// === WHEN ===
+	results, err := SummarizeResults(summaries, dir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if results["max_pressure"] != 1000.5 {
+		t.Errorf("Got: '%v' - Expected '%v'", results["max_pressure"], 1000.5)
+	}
+
+	if results["pressure_units"] != "hPa" {
+		t.Errorf("Got: '%v' - Expected '%v'", results["pressure_units"], "hPa")
+	}
+
+	if results["model"] != "wrf, v4" {
+		t.Errorf("Got: '%v' - Expected '%v'", results["model"], "wrf, v4")
+	}
+}
+
+func TestSummarizeResultsShouldReturnErrorForUnknownFormat(t *testing.T) {
+	// === WHEN ===
+	_, err := SummarizeResults([]ResultSummary{{File: "output.bin", Dataset: "x", Key: "x"}}, ".")
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
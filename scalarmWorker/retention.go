@@ -0,0 +1,240 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var janitorLog = NewLogger("janitor")
+
+// values of cleanup_policy, on-success is the default
+const (
+	CleanupAlways    = "always"
+	CleanupOnSuccess = "on-success"
+	CleanupNever     = "never"
+)
+
+// file marking a directory of a finished simulation run which is kept by the retention policy,
+// it contains the status of the run
+const retentionMarker = ".scalarm_retained"
+
+// file marking a directory of a finished simulation run whose uploads were not confirmed by the Storage Manager,
+// it lists the failed uploads; such directories are only removed by the emergency cleanup
+const pendingUploadsMarker = ".scalarm_pending_uploads"
+
+// how often the janitor enforces the retention policy besides after every simulation run
+var janitorInterval = time.Minute
+
+// RetentionPolicy decides which directories of finished simulation runs are kept
+type RetentionPolicy struct {
+	// number of the most recent directories kept regardless of the status of their runs
+	KeepLast int
+	// directories of failed runs are kept besides the most recent ones
+	KeepFailed bool
+	// max total size of kept directories in bytes, the oldest ones are removed first; 0 means no limit
+	MaxSize int64
+	// all directories are kept, limits above do not apply (cleanup_policy never)
+	KeepAll bool
+	// max total size of all kept directories in bytes, including those with pending uploads, above which
+	// the oldest ones are removed anyway; 0 means no limit
+	EmergencySize int64
+}
+
+// RetainedDir is a directory of a finished simulation run kept by the retention policy
+type RetainedDir struct {
+	Path   string
+	Failed bool
+	// uploads of the run were not confirmed, the directory may hold the only copy of its results
+	PendingUploads bool
+	FinishedAt     time.Time
+	Size           int64
+}
+
+// retentionPolicy returns the policy from the config, nil when directories are removed right after runs
+func (sim SimulationManager) retentionPolicy() *RetentionPolicy {
+	policy := &RetentionPolicy{
+		KeepLast:      sim.Config.KeepSimulationDirs,
+		KeepFailed:    sim.Config.KeepFailedSimulationDirs,
+		MaxSize:       int64(sim.Config.SimulationDirsMaxSize) * 1024 * 1024,
+		KeepAll:       sim.Config.CleanupPolicy == CleanupNever,
+		EmergencySize: int64(sim.Config.EmergencyCleanupSize) * 1024 * 1024,
+	}
+
+	if policy.KeepLast <= 0 && !policy.KeepFailed && policy.MaxSize <= 0 && !policy.KeepAll && policy.EmergencySize <= 0 {
+		return nil
+	}
+
+	return policy
+}
+
+// finishSimulationDir removes the directory of a finished simulation run or, under a retention policy,
+// marks it with the status of the run and lets the janitor decide whether it is kept
+func (sim SimulationManager) finishSimulationDir(simulationDirPath string, status string) {
+	sim.cleanUpSimulationDir(simulationDirPath, status, nil)
+}
+
+// cleanUpSimulationDir finishes the directory of a simulation run according to cleanup_policy: with on-success,
+// the default, a directory with failed uploads is kept, SiM does not upload its files again, until it is removed
+// by hand or by the emergency cleanup; with always it is finished like any other
+func (sim SimulationManager) cleanUpSimulationDir(simulationDirPath string, status string, failedUploads []string) {
+	if len(failedUploads) > 0 {
+		if sim.Config.CleanupPolicy == CleanupAlways {
+			simLog.Warnf("Removing %s although uploads of %s failed", simulationDirPath,
+				strings.Join(failedUploads, ", "))
+		} else {
+			err := ioutil.WriteFile(filepath.Join(simulationDirPath, pendingUploadsMarker),
+				[]byte(strings.Join(failedUploads, "\n")+"\n"), 0644)
+			if err == nil {
+				err = markSimulationDir(simulationDirPath, status)
+			}
+			if err != nil {
+				janitorLog.Warnf("Could not mark %s, it is kept: %v", simulationDirPath, err)
+				return
+			}
+
+			simLog.Infof("Keeping %s, uploads of %s were not confirmed", simulationDirPath,
+				strings.Join(failedUploads, ", "))
+			sim.enforceRetention()
+			return
+		}
+	}
+
+	if sim.retentionPolicy() == nil {
+		sim.removeSimulationDir(simulationDirPath)
+		return
+	}
+
+	if err := markSimulationDir(simulationDirPath, status); err != nil {
+		janitorLog.Warnf("Could not mark %s: %v", simulationDirPath, err)
+		sim.removeSimulationDir(simulationDirPath)
+		return
+	}
+
+	sim.enforceRetention()
+}
+
+// markSimulationDir makes the directory subject to the retention policy
+func markSimulationDir(simulationDirPath string, status string) error {
+	return ioutil.WriteFile(filepath.Join(simulationDirPath, retentionMarker), []byte(status+"\n"), 0644)
+}
+
+// runJanitor enforces the retention policy periodically, also on directories left by other workers
+func (sim SimulationManager) runJanitor() {
+	for range time.Tick(janitorInterval) {
+		sim.enforceRetention()
+	}
+}
+
+// enforceRetention removes directories which are not kept by the policy, workers sharing the root
+// directory enforce it one at a time
+func (sim SimulationManager) enforceRetention() {
+	policy := sim.retentionPolicy()
+	if policy == nil {
+		return
+	}
+
+	unlock, err := lockFile(filepath.Join(sim.RootDirPath, "retention.lock"))
+	if err != nil {
+		janitorLog.Warnf("%v", err)
+		return
+	}
+	defer unlock()
+
+	roots := []string{sim.RootDirPath}
+	if scratchDir := sim.scratchDir(); scratchDir != "" {
+		roots = append(roots, scratchDir)
+	}
+
+	for _, dir := range policy.Expired(FindRetainedDirs(roots)) {
+		janitorLog.Infof("Removing %s", dir.Path)
+		sim.removeSimulationDir(dir.Path)
+	}
+}
+
+// FindRetainedDirs lists marked simulation directories of all experiments in roots, the most recent first
+func FindRetainedDirs(roots []string) []RetainedDir {
+	dirs := []RetainedDir{}
+
+	for _, root := range roots {
+		markers, _ := filepath.Glob(filepath.Join(root, "experiment_*", "simulation_*", retentionMarker))
+		for _, marker := range markers {
+			info, err := os.Stat(marker)
+			if err != nil {
+				continue
+			}
+			status, _ := ioutil.ReadFile(marker)
+
+			dir := RetainedDir{
+				Path:       filepath.Dir(marker),
+				Failed:     strings.TrimSpace(string(status)) != "ok",
+				FinishedAt: info.ModTime(),
+			}
+			if _, err := os.Stat(filepath.Join(dir.Path, pendingUploadsMarker)); err == nil {
+				dir.PendingUploads = true
+			}
+			filepath.Walk(dir.Path, func(_ string, info os.FileInfo, err error) error {
+				if err == nil && info.Mode().IsRegular() {
+					dir.Size += info.Size()
+				}
+				return nil
+			})
+			dirs = append(dirs, dir)
+		}
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return dirs[i].FinishedAt.After(dirs[j].FinishedAt)
+	})
+
+	return dirs
+}
+
+// Expired returns directories, ordered from the most recent, which are not kept by the policy; directories
+// with pending uploads are kept by any policy and removed, the oldest first, only when all kept directories
+// exceed the emergency size
+func (policy *RetentionPolicy) Expired(dirs []RetainedDir) []RetainedDir {
+	kept := make([]bool, len(dirs))
+	var keptSize int64
+
+	for i, dir := range dirs {
+		switch {
+		case dir.PendingUploads || policy.KeepAll:
+			kept[i] = true
+		default:
+			kept[i] = i < policy.KeepLast || (dir.Failed && policy.KeepFailed) ||
+				(policy.KeepLast <= 0 && !policy.KeepFailed)
+
+			if kept[i] && policy.MaxSize > 0 && keptSize+dir.Size > policy.MaxSize {
+				kept[i] = false
+			}
+		}
+
+		if kept[i] {
+			keptSize += dir.Size
+		}
+	}
+
+	if policy.EmergencySize > 0 {
+		for i := len(dirs) - 1; i >= 0 && keptSize > policy.EmergencySize; i-- {
+			if kept[i] {
+				janitorLog.Warnf("Kept directories exceed emergency_cleanup_size, removing %s"+
+					"(pending uploads: %v)\n", dirs[i].Path, dirs[i].PendingUploads)
+				kept[i] = false
+				keptSize -= dirs[i].Size
+			}
+		}
+	}
+
+	expired := []RetainedDir{}
+	for i, dir := range dirs {
+		if !kept[i] {
+			expired = append(expired, dir)
+		}
+	}
+
+	return expired
+}
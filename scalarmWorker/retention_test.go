@@ -0,0 +1,149 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyShouldKeepRecentAndFailedDirsWithinSizeCap(t *testing.T) {
+	// === GIVEN ===
+	now := time.Now()
+	dirs := []RetainedDir{
+		{Path: "simulation_5", FinishedAt: now, Size: 10},
+		{Path: "simulation_4", FinishedAt: now.Add(-time.Minute), Size: 10, Failed: true},
+		{Path: "simulation_3", FinishedAt: now.Add(-2 * time.Minute), Size: 10},
+		{Path: "simulation_2", FinishedAt: now.Add(-3 * time.Minute), Size: 10, Failed: true},
+		{Path: "simulation_1", FinishedAt: now.Add(-4 * time.Minute), Size: 10, Failed: true},
+	}
+
+	cases := []struct {
+		policy   RetentionPolicy
+		expected []string
+	}{
+		{RetentionPolicy{KeepLast: 2}, []string{"simulation_3", "simulation_2", "simulation_1"}},
+		{RetentionPolicy{KeepFailed: true}, []string{"simulation_5", "simulation_3"}},
+		{RetentionPolicy{KeepLast: 1, KeepFailed: true, MaxSize: 30}, []string{"simulation_3", "simulation_1"}},
+		{RetentionPolicy{MaxSize: 25}, []string{"simulation_3", "simulation_2", "simulation_1"}},
+	}
+
+	for _, c := range cases {
+		// === WHEN ===
+		expired := c.policy.Expired(dirs)
+
+		// === THEN ===
+		paths := []string{}
+		for _, dir := range expired {
+			paths = append(paths, dir.Path)
+		}
+
+		if len(paths) != len(c.expected) {
+			t.Errorf("Got: '%v' - Expected '%v' for %+v", paths, c.expected, c.policy)
+			continue
+		}
+		for i := range paths {
+			if paths[i] != c.expected[i] {
+				t.Errorf("Got: '%v' - Expected '%v' for %+v", paths, c.expected, c.policy)
+				break
+			}
+		}
+	}
+}
+
+func TestFinishSimulationDirShouldKeepOnlyFailedRuns(t *testing.T) {
+	// === GIVEN ===
+	rootDir, _ := ioutil.TempDir("", "retention")
+	defer os.RemoveAll(rootDir)
+
+	config := getSimConfig()
+	config.KeepFailedSimulationDirs = true
+	sim := SimulationManager{Config: config, RootDirPath: rootDir}
+
+	failedDir := filepath.Join(rootDir, "experiment_568e5bece138232e76000002", "simulation_1")
+	succeededDir := filepath.Join(rootDir, "experiment_568e5bece138232e76000002", "simulation_2")
+	os.MkdirAll(failedDir, 0755)
+	os.MkdirAll(succeededDir, 0755)
+
+	// === WHEN ===
+	sim.finishSimulationDir(failedDir, "error")
+	sim.finishSimulationDir(succeededDir, "ok")
+
+	// === THEN ===
+	if _, err := os.Stat(failedDir); err != nil {
+		t.Errorf("Directory of the failed run should be kept, but got '%v'", err)
+	}
+
+	if _, err := os.Stat(succeededDir); !os.IsNotExist(err) {
+		t.Errorf("Directory of the successful run should be removed")
+	}
+}
+
+func TestRetentionPolicyShouldKeepDirsWithPendingUploadsUntilEmergencySize(t *testing.T) {
+	// === GIVEN ===
+	now := time.Now()
+	dirs := []RetainedDir{
+		{Path: "simulation_3", FinishedAt: now, Size: 10},
+		{Path: "simulation_2", FinishedAt: now.Add(-time.Minute), Size: 10, PendingUploads: true},
+		{Path: "simulation_1", FinishedAt: now.Add(-2 * time.Minute), Size: 10, PendingUploads: true},
+	}
+
+	cases := []struct {
+		policy   RetentionPolicy
+		expected []string
+	}{
+		{RetentionPolicy{KeepLast: 1, MaxSize: 10}, []string{}},
+		{RetentionPolicy{KeepAll: true}, []string{}},
+		{RetentionPolicy{KeepLast: 1, EmergencySize: 25}, []string{"simulation_1"}},
+		{RetentionPolicy{KeepAll: true, EmergencySize: 5}, []string{"simulation_3", "simulation_2", "simulation_1"}},
+	}
+
+	for _, c := range cases {
+		// === WHEN ===
+		expired := c.policy.Expired(dirs)
+
+		// === THEN ===
+		paths := []string{}
+		for _, dir := range expired {
+			paths = append(paths, dir.Path)
+		}
+
+		if strings.Join(paths, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("Got: '%v' - Expected '%v' for %+v", paths, c.expected, c.policy)
+		}
+	}
+}
+
+func TestCleanUpSimulationDirShouldKeepDirWithFailedUploads(t *testing.T) {
+	// === GIVEN ===
+	rootDir, _ := ioutil.TempDir("", "retention")
+	defer os.RemoveAll(rootDir)
+
+	sim := SimulationManager{Config: getSimConfig(), RootDirPath: rootDir}
+
+	failedDir := filepath.Join(rootDir, "experiment_568e5bece138232e76000002", "simulation_1")
+	uploadedDir := filepath.Join(rootDir, "experiment_568e5bece138232e76000002", "simulation_2")
+	os.MkdirAll(failedDir, 0755)
+	os.MkdirAll(uploadedDir, 0755)
+
+	// === WHEN ===
+	sim.cleanUpSimulationDir(failedDir, "ok", []string{"output.tar.gz"})
+	sim.cleanUpSimulationDir(uploadedDir, "ok", nil)
+
+	// === THEN ===
+	pending, err := ioutil.ReadFile(filepath.Join(failedDir, pendingUploadsMarker))
+	if err != nil || string(pending) != "output.tar.gz\n" {
+		t.Errorf("Got: '%s', '%v' - Expected '%v'", pending, err, "output.tar.gz")
+	}
+
+	if _, err := os.Stat(uploadedDir); !os.IsNotExist(err) {
+		t.Errorf("Directory of the uploaded run should be removed")
+	}
+
+	dirs := FindRetainedDirs([]string{rootDir})
+	if len(dirs) != 1 || !dirs[0].PendingUploads {
+		t.Errorf("Got: '%+v' - Expected '%v'", dirs, "a directory with pending uploads")
+	}
+}
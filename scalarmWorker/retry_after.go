@@ -0,0 +1,101 @@
+package scalarmWorker
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longest wait requested by a Scalarm service which is honored, longer hints are shortened to it
+var maxRetryAfter = 10 * time.Minute
+
+// ParseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	return boundRetryAfter(wait), true
+}
+
+func boundRetryAfter(wait time.Duration) time.Duration {
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+
+	return wait
+}
+
+// honorRetryAfter repeats a request rejected with 429 or 503 after the time given in its Retry-After header,
+// as long as the communication timeout allows, waits are capped at its remaining time; the last response is returned
+func honorRetryAfter(client *http.Client, request *http.Request, response *http.Response, timeout time.Duration) *http.Response {
+	deadline := time.Now().Add(timeout)
+
+	for response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		wait, ok := ParseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+		remaining := time.Until(deadline)
+		if !ok || remaining <= 0 || (request.Body != nil && request.GetBody == nil) {
+			return response
+		}
+		if wait > remaining {
+			wait = remaining
+		}
+
+		httpLog.Warnf("%s is overloaded (%d), retrying after %v", request.URL.Host, response.StatusCode, wait)
+		select {
+		case <-time.After(wait):
+		case <-request.Context().Done():
+			return response
+		}
+
+		retry := request.Clone(request.Context())
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return response
+			}
+			retry.Body = body
+		}
+
+		retryResponse, err := GetWithTimeout(request.Context(), client, retry, timeout)
+		if err != nil {
+			return response
+		}
+
+		response.Body.Close()
+		response = retryResponse
+	}
+
+	return response
+}
+
+// retryAfterHint reads a wait embedded by the Experiment Manager in a response, 'retry_after' in seconds,
+// or 'duration_in_seconds' of the 'wait' status; fallback is returned without a hint
+func retryAfterHint(response map[string]interface{}, fallback time.Duration) time.Duration {
+	for _, key := range []string{"retry_after", "duration_in_seconds"} {
+		switch seconds := response[key].(type) {
+		case float64:
+			return boundRetryAfter(time.Duration(seconds * float64(time.Second)))
+		case string:
+			if value, err := strconv.ParseFloat(seconds, 64); err == nil {
+				return boundRetryAfter(time.Duration(value * float64(time.Second)))
+			}
+		}
+	}
+
+	return fallback
+}
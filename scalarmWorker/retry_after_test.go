@@ -0,0 +1,110 @@
+package scalarmWorker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfterShouldAcceptSecondsAndHttpDates(t *testing.T) {
+	now := time.Date(2017, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]time.Duration{
+		"5":                             5 * time.Second,
+		"Sat, 01 Apr 2017 12:00:30 GMT": 30 * time.Second,
+		"Sat, 01 Apr 2017 11:00:00 GMT": 0,
+		"86400":                         maxRetryAfter,
+	}
+
+	for header, expected := range cases {
+		if wait, ok := ParseRetryAfter(header, now); !ok || wait != expected {
+			t.Errorf("Got: '%v' - Expected '%v' for %s", wait, expected, header)
+		}
+	}
+
+	if _, ok := ParseRetryAfter("soon", now); ok {
+		t.Errorf("Invalid header should be ignored")
+	}
+}
+
+func TestExecuteScalarmRequestShouldRetryAfterServiceUnavailable(t *testing.T) {
+	// === GIVEN ===
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	config := getSimConfig()
+	reqInfo := RequestInfo{"GET", nil, "", "experiments/random_experiment"}
+
+	// === WHEN ===
+	start := time.Now()
+	resp, err := ExecuteScalarmRequest(context.Background(), reqInfo, []string{"system.scalarm.com"}, config, getHttpClientMock(server.URL), 10*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", resp.StatusCode, 200)
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("The request should be repeated after Retry-After")
+	}
+}
+
+func TestHonorRetryAfterShouldNotWaitPastTheTimeout(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+
+	request, _ := http.NewRequest("GET", server.URL, nil)
+	response, _ := http.DefaultClient.Do(request)
+
+	// === WHEN ===
+	start := time.Now()
+	response = honorRetryAfter(http.DefaultClient, request, response, time.Second)
+	response.Body.Close()
+
+	// === THEN ===
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Got: '%v' - Expected a wait capped at the timeout", elapsed)
+	}
+	if response.StatusCode != 503 {
+		t.Errorf("Got: '%v' - Expected '%v'", response.StatusCode, 503)
+	}
+}
+
+func TestRetryAfterHintShouldPreferRetryAfter(t *testing.T) {
+	fallback := 5 * time.Second
+
+	cases := []struct {
+		response map[string]interface{}
+		expected time.Duration
+	}{
+		{map[string]interface{}{"status": "error"}, fallback},
+		{map[string]interface{}{"status": "error", "retry_after": 30.0}, 30 * time.Second},
+		{map[string]interface{}{"status": "wait", "duration_in_seconds": 10.0}, 10 * time.Second},
+		{map[string]interface{}{"status": "wait", "duration_in_seconds": 10.0, "retry_after": "60"}, time.Minute},
+	}
+
+	for _, c := range cases {
+		if wait := retryAfterHint(c.response, fallback); wait != c.expected {
+			t.Errorf("Got: '%v' - Expected '%v'", wait, c.expected)
+		}
+	}
+}
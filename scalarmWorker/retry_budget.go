@@ -0,0 +1,130 @@
+package scalarmWorker
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// pauses between rounds of attempts used when the config does not set them
+var defaultRetryPolicy = RetryPolicy{InitialDelay: time.Second, Multiplier: 2, MaxDelay: 30 * time.Second, Jitter: 0.2}
+
+// RetryPolicy - pauses between rounds of attempts grow exponentially from InitialDelay by Multiplier up to MaxDelay,
+// each of them is randomized by up to Jitter of its length so workers do not retry in lockstep
+type RetryPolicy struct {
+	InitialDelay time.Duration
+	Multiplier   float64
+	MaxDelay     time.Duration
+	Jitter       float64
+}
+
+// retryPolicy returns the policy configured with retry_initial_delay, retry_multiplier, retry_max_delay
+// and retry_jitter, unset values are taken from the default policy
+func (config *SimulationManagerConfig) retryPolicy() RetryPolicy {
+	policy := defaultRetryPolicy
+	if config.RetryInitialDelay > 0 {
+		policy.InitialDelay = time.Duration(config.RetryInitialDelay * float64(time.Second))
+	}
+	if config.RetryMultiplier > 0 {
+		policy.Multiplier = config.RetryMultiplier
+	}
+	if config.RetryMaxDelay > 0 {
+		policy.MaxDelay = time.Duration(config.RetryMaxDelay * float64(time.Second))
+	}
+	if config.RetryJitter != nil {
+		policy.Jitter = *config.RetryJitter
+	}
+	if policy.MaxDelay < policy.InitialDelay {
+		policy.MaxDelay = policy.InitialDelay
+	}
+
+	return policy
+}
+
+// Delay returns the pause before the given retry round, counted from 0, without jitter
+func (policy RetryPolicy) Delay(retry int) time.Duration {
+	delay := float64(policy.InitialDelay) * math.Pow(policy.Multiplier, float64(retry))
+	if delay > float64(policy.MaxDelay) {
+		return policy.MaxDelay
+	}
+
+	return time.Duration(delay)
+}
+
+// JitteredDelay returns the pause before the given retry round randomized by up to Jitter of its length
+func (policy RetryPolicy) JitteredDelay(retry int) time.Duration {
+	delay := float64(policy.Delay(retry))
+	delay += delay * policy.Jitter * (2*randomFloat() - 1)
+	if delay < 0 {
+		return 0
+	}
+
+	return time.Duration(delay)
+}
+
+// RetryBudget bounds all attempts of a single request to Scalarm, across instances of a service
+// and retries of each of them, with a total deadline and an optional limit of attempts
+type RetryBudget struct {
+	Deadline    time.Time
+	MaxAttempts int // 0 means attempts are limited only by the deadline
+	Attempts    int
+	Policy      RetryPolicy
+	retries     int
+}
+
+// NewRetryBudget starts a budget which ends after timeout, with the default retry policy
+func NewRetryBudget(timeout time.Duration, maxAttempts int) *RetryBudget {
+	return &RetryBudget{Deadline: time.Now().Add(timeout), MaxAttempts: maxAttempts, Policy: defaultRetryPolicy}
+}
+
+// retryBudget starts a budget of a request which ends after timeout, limited by max_attempts and pausing
+// according to the configured retry policy
+func (config *SimulationManagerConfig) retryBudget(timeout time.Duration) *RetryBudget {
+	budget := NewRetryBudget(timeout, config.MaxAttempts)
+	budget.Policy = config.retryPolicy()
+
+	return budget
+}
+
+// Next tells whether another attempt fits in the budget and counts it, the first attempt is always made
+func (budget *RetryBudget) Next() bool {
+	if budget.Attempts > 0 && (budget.Exhausted() || time.Now().After(budget.Deadline)) {
+		return false
+	}
+
+	budget.Attempts++
+	return true
+}
+
+// Pause sleeps before the next round of attempts, each pause is longer than the previous one
+// and none of them extends past the deadline; it ends early when ctx is cancelled
+func (budget *RetryBudget) Pause(ctx context.Context) {
+	delay := budget.Policy.JitteredDelay(budget.retries)
+	budget.retries++
+
+	if remaining := budget.Remaining(); delay > remaining {
+		delay = remaining
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// Exhausted tells whether all allowed attempts were made
+func (budget *RetryBudget) Exhausted() bool {
+	return budget.MaxAttempts > 0 && budget.Attempts >= budget.MaxAttempts
+}
+
+// Remaining returns time left until the deadline
+func (budget *RetryBudget) Remaining() time.Duration {
+	if remaining := time.Until(budget.Deadline); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
@@ -0,0 +1,82 @@
+package scalarmWorker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelaysShouldGrowExponentiallyUpToMaxDelay(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+	config.RetryInitialDelay = 0.5
+	config.RetryMultiplier = 3
+	config.RetryMaxDelay = 10
+	policy := config.retryPolicy()
+
+	// === WHEN ===
+	delays := []time.Duration{policy.Delay(0), policy.Delay(1), policy.Delay(2), policy.Delay(3)}
+
+	// === THEN ===
+	expected := []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond, 4500 * time.Millisecond, 10 * time.Second}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("Got: '%v' - Expected '%v'", delays[i], expected[i])
+		}
+	}
+}
+
+func TestRetryPolicyJitterShouldStayWithinBounds(t *testing.T) {
+	// === GIVEN ===
+	jitter := 0.5
+	config := getSimConfig()
+	config.RetryJitter = &jitter
+	policy := config.retryPolicy()
+
+	// === WHEN ===
+	for i := 0; i < 100; i++ {
+		delay := policy.JitteredDelay(2)
+
+		// === THEN ===
+		if delay < 2*time.Second || delay > 6*time.Second {
+			t.Errorf("Got: '%v' - Expected '%v'", delay, "between 2s and 6s")
+		}
+	}
+}
+
+func TestRetryBudgetPauseShouldNotExtendPastDeadline(t *testing.T) {
+	// === GIVEN ===
+	budget := NewRetryBudget(100*time.Millisecond, 0)
+	budget.Policy = RetryPolicy{InitialDelay: time.Minute, Multiplier: 2, MaxDelay: time.Hour}
+
+	// === WHEN ===
+	start := time.Now()
+	budget.Pause(context.Background())
+
+	// === THEN ===
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Got: '%v' - Expected '%v'", elapsed, "at most the remaining budget")
+	}
+}
+
+func TestRetryPolicyShouldBeValidated(t *testing.T) {
+	// === GIVEN ===
+	jitter := 1.5
+	config := getSimConfig()
+	config.RetryMultiplier = 0.5
+	config.RetryJitter = &jitter
+
+	// === WHEN ===
+	errs := config.Validate()
+
+	// === THEN ===
+	found := 0
+	for _, err := range errs {
+		if err.Error() == "retry_multiplier has to be at least 1" || err.Error() == "retry_jitter has to be between 0 and 1" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", errs, "retry_multiplier and retry_jitter errors")
+	}
+}
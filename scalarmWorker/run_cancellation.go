@@ -0,0 +1,236 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// time the executor of a cancelled simulation run gets to exit after SIGTERM
+var cancellationGrace = 10 * time.Second
+
+// the Experiment Manager does not support checking cancellation of simulation runs
+var errCancellationUnsupported = errors.New("Experiment manager does not support cancellation of simulation runs")
+
+// RunCancellation is signalled when the Experiment Manager cancels the current simulation run
+type RunCancellation struct {
+	Reason string
+
+	done  chan struct{}
+	once  sync.Once
+	mutex sync.Mutex
+}
+
+// NewRunCancellation creates a cancellation of a simulation run which was not signalled yet
+func NewRunCancellation() *RunCancellation {
+	return &RunCancellation{done: make(chan struct{})}
+}
+
+// Cancel signals the cancellation, only the first reason is kept
+func (cancellation *RunCancellation) Cancel(reason string) {
+	cancellation.once.Do(func() {
+		cancellation.mutex.Lock()
+		cancellation.Reason = reason
+		cancellation.mutex.Unlock()
+		close(cancellation.done)
+	})
+}
+
+// Done is closed once the simulation run is cancelled
+func (cancellation *RunCancellation) Done() <-chan struct{} {
+	return cancellation.done
+}
+
+// Cancelled tells whether the simulation run was cancelled and why
+func (cancellation *RunCancellation) Cancelled() (bool, string) {
+	select {
+	case <-cancellation.done:
+		cancellation.mutex.Lock()
+		defer cancellation.mutex.Unlock()
+		return true, cancellation.Reason
+	default:
+		return false, ""
+	}
+}
+
+// cancellations of simulation runs whose executors are running, signalled also by progress info responses
+var runCancellations struct {
+	cancellations map[runKey]*RunCancellation
+	mutex         sync.Mutex
+}
+
+// setRunCancellation makes the simulation run cancellable while its executor runs, nil when the executor exited
+func setRunCancellation(experimentID string, simulationIndex int, cancellation *RunCancellation) {
+	runCancellations.mutex.Lock()
+	defer runCancellations.mutex.Unlock()
+
+	if runCancellations.cancellations == nil {
+		runCancellations.cancellations = map[runKey]*RunCancellation{}
+	}
+	if cancellation == nil {
+		delete(runCancellations.cancellations, runKey{experimentID, simulationIndex})
+	} else {
+		runCancellations.cancellations[runKey{experimentID, simulationIndex}] = cancellation
+	}
+
+	// an executor started during a shutdown is stopped right away
+	if shuttingDown, reason := shutdownRequested(); shuttingDown && cancellation != nil {
+		cancellation.Cancel(reason)
+	}
+}
+
+// cancelRun cancels the simulation run if this worker executes it
+func cancelRun(experimentID string, simulationIndex int, reason string) {
+	runCancellations.mutex.Lock()
+	defer runCancellations.mutex.Unlock()
+
+	if cancellation, ok := runCancellations.cancellations[runKey{experimentID, simulationIndex}]; ok {
+		cancellation.Cancel(reason)
+	}
+}
+
+// isCancellationResponse recognizes a response of the Experiment Manager cancelling a simulation run
+func isCancellationResponse(emResponse map[string]interface{}) (bool, string) {
+	command, _ := emResponse["command"].(string)
+	status, _ := emResponse["status"].(string)
+	if command != "abort" && status != "cancelled" {
+		return false, ""
+	}
+
+	reason, _ := emResponse["reason"].(string)
+	if reason == "" {
+		reason = "Cancelled by the Experiment Manager"
+	}
+
+	return true, reason
+}
+
+// CheckCancellation asks the Experiment Manager whether the simulation run was cancelled
+func (em *ExperimentManager) CheckCancellation(simulationIndex int) (bool, string, error) {
+	emResponse := map[string]interface{}{}
+
+	path := "experiments/" + em.ExperimentId + "/simulations/" + strconv.Itoa(simulationIndex) + "/cancellation"
+	reqInfo := RequestInfo{"GET", nil, "", path}
+
+	resp, err := ExecuteScalarmRequest(em.context(), reqInfo, em.BaseUrls, em.Config, em.HttpClient, em.CommunicationTimeout)
+	if err != nil {
+		return false, "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return false, "", errCancellationUnsupported
+	} else if resp.StatusCode != 200 {
+		return false, "", errors.New("Experiment manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	if err := json.Unmarshal(body, &emResponse); err != nil {
+		return false, "", errors.New("Returned response body is not JSON.")
+	}
+
+	if cancelled, _ := emResponse["cancelled"].(bool); cancelled {
+		reason, _ := emResponse["reason"].(string)
+		if reason == "" {
+			reason = "Cancelled by the Experiment Manager"
+		}
+		return true, reason, nil
+	}
+
+	cancelled, reason := isCancellationResponse(emResponse)
+	return cancelled, reason, nil
+}
+
+// pollCancellation checks every interval whether the simulation run was cancelled until stop is closed,
+// Experiment Managers which do not support it are not asked again
+func pollCancellation(em *ExperimentManager, simulationIndex int, interval time.Duration,
+	cancellation *RunCancellation, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-cancellation.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cancelled, reason, err := em.CheckCancellation(simulationIndex)
+		if err == errCancellationUnsupported {
+			simLog.Infof("%v", err)
+			return
+		} else if err != nil {
+			simLog.Warnf("Could not check cancellation of the simulation run: %v", Redact(err.Error()))
+		} else if cancelled {
+			cancellation.Cancel(reason)
+			return
+		}
+	}
+}
+
+// superviseExecutor terminates the executor, which runs in its own process group, as soon as the simulation
+// run is cancelled by the Experiment Manager or a shutdown of SiM; CancelWorker kills it at once
+func superviseExecutor(cmd *exec.Cmd, cancellation *RunCancellation, exited <-chan struct{}) {
+	trackProcessGroup(cmd)
+	defer untrackProcessGroup(cmd)
+
+	select {
+	case <-exited:
+	case <-cancellation.Done():
+		_, reason := cancellation.Cancelled()
+		simLog.Infof("The simulation run was cancelled: %s, stopping the executor ...", reason)
+		terminateProcessGroup(cmd, cancellationGrace, exited)
+	}
+}
+
+// abortRun uploads the run log of a cancelled simulation run, reports the run as aborted and removes its directory
+func (sim SimulationManager) abortRun(report *RunReport, reason string, markAsComplete func(int, url.Values) error,
+	simulationDirPath string, finished chan struct{}, storageManagers []string, timeout time.Duration) {
+
+	report.Status = "aborted"
+	report.ReasonCode = ReasonAborted
+	report.Reason = reason
+
+	runLog := RunLogName(report.SimulationIndex)
+	if _, err := os.Stat(path.Join(simulationDirPath, runLog)); err == nil {
+		simLog.Infof("Uploading STDOUT of the aborted simulation run (%s) ...", runLog)
+
+		stdoutUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/stdout", report.ExperimentID, report.SimulationIndex)
+		sim.uploadLog(report, simulationDirPath, runLog, stdoutUploadUrl, storageManagers, timeout)
+	}
+	if info, err := os.Stat(path.Join(simulationDirPath, stderrLog)); err == nil && info.Size() > 0 {
+		stderrUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/stderr", report.ExperimentID, report.SimulationIndex)
+		sim.uploadLog(report, simulationDirPath, stderrLog, stderrUploadUrl, storageManagers, timeout)
+	}
+
+	data := url.Values{}
+	data.Set("status", "aborted")
+	data.Add("reason", reason)
+	if err := markAsComplete(report.SimulationIndex, data); err != nil {
+		simLog.Warnf("Could not report the aborted simulation run: %v", Redact(err.Error()))
+	}
+
+	sim.saveRunReport(report, storageManagers, timeout)
+
+	go func() {
+		<-finished
+		sim.finishSimulationDir(simulationDirPath, "aborted")
+		close(finished)
+	}()
+}
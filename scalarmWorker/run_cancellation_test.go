@@ -0,0 +1,99 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCheckCancellationShouldReportCancelledRuns(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/simulations/3/cancellation" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintln(w, `{"status": "ok", "cancelled": true, "reason": "Experiment stopped"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	cancelled, reason, err := em.CheckCancellation(3)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	if !cancelled || reason != "Experiment stopped" {
+		t.Errorf("Got: '%v, %v' - Expected '%v'", cancelled, reason, "cancelled: Experiment stopped")
+	}
+
+	if _, _, err := em.CheckCancellation(4); err != errCancellationUnsupported {
+		t.Errorf("Got: '%v' - Expected '%v'", err, errCancellationUnsupported)
+	}
+}
+
+func TestProgressInfoResponseShouldCancelCurrentRun(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status": "ok", "command": "abort"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	cancellation := NewRunCancellation()
+	setRunCancellation(em.ExperimentId, 3, cancellation)
+	defer setRunCancellation(em.ExperimentId, 3, nil)
+	otherRun := NewRunCancellation()
+	setRunCancellation(em.ExperimentId, 4, otherRun)
+	defer setRunCancellation(em.ExperimentId, 4, nil)
+
+	// === WHEN ===
+	err := em.PostProgressInfo(3, url.Values{"status": {"ok"}})
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+	if cancelled, reason := cancellation.Cancelled(); !cancelled || reason != "Cancelled by the Experiment Manager" {
+		t.Errorf("Got: '%v, %v' - Expected '%v'", cancelled, reason, "a cancelled run")
+	}
+	if cancelled, _ := otherRun.Cancelled(); cancelled {
+		t.Errorf("Got: '%v' - Expected '%v'", cancelled, false)
+	}
+}
+
+func TestSuperviseExecutorShouldStopProcessGroupOfCancelledRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30; wait")
+	startInProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	cancellation := NewRunCancellation()
+	exited := make(chan struct{})
+	go superviseExecutor(cmd, cancellation, exited)
+
+	// === WHEN ===
+	start := time.Now()
+	cancellation.Cancel("test")
+	cmd.Wait()
+	close(exited)
+
+	// === THEN ===
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("The executor should be stopped right after cancellation")
+	}
+}
@@ -0,0 +1,136 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// name of the log adapters used to share, kept as a symlink to the log of the simulation run
+const legacyRunLog = "_stdout.txt"
+
+// stderr of adapters of a simulation run, kept apart from the run log
+const stderrLog = "_stderr.txt"
+
+// number of last lines of stderr added to the reason of a failed simulation run
+const stderrReasonLines = 20
+
+// run logs bigger than this are truncated before upload unless configured otherwise
+const defaultStdoutUploadMaxSize = 64 * 1024 * 1024
+
+// RunLogName returns the name of the log of a simulation run, e.g. simulation_7.log
+func RunLogName(simulationIndex int) string {
+	return fmt.Sprintf("simulation_%d.log", simulationIndex)
+}
+
+// prepareRunLog creates the log of the simulation run in its directory, with _stdout.txt linked to it
+// for adapters which write to or read from the old name; the name adapters should write to is returned,
+// which is _stdout.txt itself where symlinks cannot be created
+func prepareRunLog(simulationDirPath string, simulationIndex int) string {
+	logPath := filepath.Join(simulationDirPath, RunLogName(simulationIndex))
+	legacyPath := filepath.Join(simulationDirPath, legacyRunLog)
+
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err == nil {
+		file.Close()
+		os.Remove(legacyPath)
+		err = os.Symlink(RunLogName(simulationIndex), legacyPath)
+	}
+	if err != nil {
+		simLog.Warnf("Could not create %s linked from %s, using %s: %v", RunLogName(simulationIndex),
+			legacyRunLog, legacyRunLog, err)
+		os.Remove(logPath)
+		return legacyRunLog
+	}
+
+	return RunLogName(simulationIndex)
+}
+
+// runLogName returns the name of the log of the simulation run in its directory
+func runLogName(simulationDirPath string, simulationIndex int) string {
+	if _, err := os.Stat(filepath.Join(simulationDirPath, RunLogName(simulationIndex))); err == nil {
+		return RunLogName(simulationIndex)
+	}
+
+	return legacyRunLog
+}
+
+// TruncateRunLog writes a copy of a log bigger than maxSize with its middle cut out to truncatedPath, the log
+// itself is kept whole; the first and the last maxSize/2 bytes are copied with a line telling how many bytes
+// were left out between them
+func TruncateRunLog(logPath string, truncatedPath string, maxSize int64) (bool, error) {
+	info, err := os.Stat(logPath)
+	if err != nil || info.Size() <= maxSize {
+		return false, err
+	}
+
+	src, err := os.Open(logPath)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	dest, err := os.Create(truncatedPath)
+	if err != nil {
+		return false, err
+	}
+
+	headSize := maxSize / 2
+	tailSize := maxSize - headSize
+	omitted := info.Size() - headSize - tailSize
+
+	_, err = copyWithPooledBuffer(dest, io.NewSectionReader(src, 0, headSize))
+	if err == nil {
+		_, err = fmt.Fprintf(dest, "\n... [%d bytes truncated] ...\n", omitted)
+	}
+	if err == nil {
+		_, err = copyWithPooledBuffer(dest, io.NewSectionReader(src, headSize+omitted, tailSize))
+	}
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(truncatedPath)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// uploadLog uploads a log of the simulation run; a log bigger than stdout_upload_max_size is uploaded
+// as a truncated copy, while the whole log stays in the simulation directory
+func (sim SimulationManager) uploadLog(report *RunReport, simulationDirPath string, name string, serviceMethod string,
+	storageManagers []string, timeout time.Duration) {
+
+	uploadDirPath := simulationDirPath
+	if sim.Config.StdoutUploadMaxSize > 0 {
+		truncatedDirPath := filepath.Join(simulationDirPath, ".truncated_logs")
+		truncated, err := false, os.MkdirAll(truncatedDirPath, 0777)
+		if err == nil {
+			truncated, err = TruncateRunLog(filepath.Join(simulationDirPath, name), filepath.Join(truncatedDirPath, name),
+				sim.Config.StdoutUploadMaxSize)
+		}
+		if err != nil {
+			simLog.Warnf("Could not truncate %s: %v", name, err)
+		} else if truncated {
+			simLog.Infof("Uploading %s truncated to its first and last %d bytes", name, sim.Config.StdoutUploadMaxSize/2)
+			uploadDirPath = truncatedDirPath
+		}
+		defer os.RemoveAll(truncatedDirPath)
+	}
+
+	sim.uploadArtifact(report, uploadDirPath, name, serviceMethod, storageManagers, timeout)
+}
+
+// withStderrTail appends the last lines of stderr of the simulation run to the reason of its failure
+func withStderrTail(reason string, simulationDirPath string) string {
+	lines, err := LastLines(filepath.Join(simulationDirPath, stderrLog), stderrReasonLines)
+	if err != nil || len(lines) == 0 {
+		return reason
+	}
+
+	return reason + "\nstderr:\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,94 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrepareRunLogShouldLinkLegacyNameToRunLog(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "run_log")
+	defer os.RemoveAll(dir)
+
+	// === WHEN ===
+	runLog := prepareRunLog(dir, 7)
+	ioutil.WriteFile(filepath.Join(dir, legacyRunLog), []byte("output\n"), 0666)
+
+	// === THEN ===
+	if runLog != "simulation_7.log" || runLogName(dir, 7) != runLog {
+		t.Errorf("Got: '%v' - Expected '%v'", runLog, "simulation_7.log")
+	}
+
+	if content, _ := ioutil.ReadFile(filepath.Join(dir, runLog)); string(content) != "output\n" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "output")
+	}
+
+	if runLogName(dir, 8) != legacyRunLog {
+		t.Errorf("Got: '%v' - Expected '%v'", runLogName(dir, 8), legacyRunLog)
+	}
+}
+
+func TestTruncateRunLogShouldKeepHeadAndTailOfBigLog(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "run_log")
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "simulation_1.log")
+	ioutil.WriteFile(logPath, []byte("head-0123456789-tail"), 0666)
+
+	truncatedPath := filepath.Join(dir, "truncated.log")
+	untouchedPath := filepath.Join(dir, "untouched.log")
+
+	// === WHEN ===
+	truncated, err := TruncateRunLog(logPath, truncatedPath, 10)
+	untouched, _ := TruncateRunLog(logPath, untouchedPath, 1024)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	expected := "head-\n... [10 bytes truncated] ...\n-tail"
+	if content, _ := ioutil.ReadFile(truncatedPath); !truncated || untouched || string(content) != expected {
+		t.Errorf("Got: '%s' (%v, %v) - Expected '%v'", content, truncated, untouched, expected)
+	}
+	if content, _ := ioutil.ReadFile(logPath); string(content) != "head-0123456789-tail" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "head-0123456789-tail")
+	}
+	if _, err := os.Stat(untouchedPath); !os.IsNotExist(err) {
+		t.Errorf("A log within the limit should not be copied")
+	}
+}
+
+func TestWithStderrTailShouldAppendLastLinesOfStderr(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "run_log")
+	defer os.RemoveAll(dir)
+
+	emptyDir, _ := ioutil.TempDir("", "run_log")
+	defer os.RemoveAll(emptyDir)
+
+	stderr := ""
+	for i := 0; i < stderrReasonLines+5; i++ {
+		stderr += "line\n"
+	}
+	stderr += "Segmentation fault\n"
+	ioutil.WriteFile(filepath.Join(dir, stderrLog), []byte(stderr), 0666)
+
+	// === WHEN ===
+	reason := withStderrTail("No output.json file found", dir)
+	unchanged := withStderrTail("No output.json file found", emptyDir)
+
+	// === THEN ===
+	lines := strings.Split(reason, "\n")
+	if len(lines) != stderrReasonLines+2 || lines[1] != "stderr:" || lines[len(lines)-1] != "Segmentation fault" {
+		t.Errorf("Got: '%v' - Expected '%v'", reason, "the reason with the last lines of stderr")
+	}
+
+	if unchanged != "No output.json file found" {
+		t.Errorf("Got: '%v' - Expected '%v'", unchanged, "No output.json file found")
+	}
+}
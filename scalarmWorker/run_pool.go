@@ -0,0 +1,157 @@
+package scalarmWorker
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// RunPool executes up to size simulation runs at the same time, each in its own goroutine; a panic of a run,
+// e.g. after Fatal, is raised again in the worker loop waiting for the pool once the other runs finished,
+// so the supervisor neither restarts the loop nor terminates SiM while they are executed
+type RunPool struct {
+	size    int
+	running int
+	done    int
+	failure interface{}
+	mutex   sync.Mutex
+	changed *sync.Cond
+}
+
+// NewRunPool creates a pool of the given size, at least one simulation run is executed at a time
+func NewRunPool(size int) *RunPool {
+	pool := &RunPool{}
+	pool.changed = sync.NewCond(&pool.mutex)
+	pool.Resize(size)
+
+	return pool
+}
+
+// Resize changes the number of simulation runs executed at the same time, at least one; when there are more
+// runs being executed than the new size, they are finished before next ones are started
+func (pool *RunPool) Resize(size int) {
+	if size < 1 {
+		size = 1
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.size = size
+	pool.changed.Broadcast()
+}
+
+// Size returns the number of simulation runs executed at the same time
+func (pool *RunPool) Size() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.size
+}
+
+// WaitForSlot blocks until fewer than size simulation runs are executed
+func (pool *RunPool) WaitForSlot() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for pool.running >= pool.size && pool.failure == nil {
+		pool.changed.Wait()
+	}
+	pool.raiseFailure()
+}
+
+// Go executes the simulation run in a new goroutine, runs which return true count as done
+func (pool *RunPool) Go(run func() bool) {
+	pool.mutex.Lock()
+	pool.running++
+	pool.mutex.Unlock()
+
+	go func() {
+		counted := false
+		defer func() {
+			pool.mutex.Lock()
+			defer pool.mutex.Unlock()
+
+			pool.running--
+			if counted {
+				pool.done++
+			}
+			pool.changed.Broadcast()
+		}()
+		defer pool.RecoverFailure()
+
+		counted = run()
+	}()
+}
+
+// RecoverFailure, deferred by goroutines of a simulation run, e.g. its progress monitoring, records their panic
+// as a failure of the run; it is raised in the worker loop like a panic of the run itself
+func (pool *RunPool) RecoverFailure() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, isFatal := r.(fatalError); !isFatal {
+		supervisorLog.Errorf("Panic in a simulation run: %v\n%s", r, debug.Stack())
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pool.failure == nil {
+		pool.failure = r
+	}
+	pool.changed.Broadcast()
+}
+
+// Wait blocks until all simulation runs of the pool finished
+func (pool *RunPool) Wait() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for pool.running > 0 && pool.failure == nil {
+		pool.changed.Wait()
+	}
+	pool.raiseFailure()
+}
+
+// Drain blocks until all simulation runs of the pool finished, their failures are not raised
+func (pool *RunPool) Drain() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for pool.running > 0 {
+		pool.changed.Wait()
+	}
+}
+
+// raiseFailure panics with the panic of a failed simulation run, if there was one, in the calling goroutine
+// after the other runs finished; the mutex of the pool has to be held
+func (pool *RunPool) raiseFailure() {
+	if pool.failure == nil {
+		return
+	}
+
+	for pool.running > 0 {
+		pool.changed.Wait()
+	}
+
+	failure := pool.failure
+	pool.failure = nil
+	panic(failure)
+}
+
+// Running returns the number of simulation runs being executed
+func (pool *RunPool) Running() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.running
+}
+
+// Done returns the number of finished simulation runs which count as done
+func (pool *RunPool) Done() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.done
+}
@@ -0,0 +1,103 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPoolShouldExecuteAtMostSizeRunsAtTheSameTime(t *testing.T) {
+	// === GIVEN ===
+	pool := NewRunPool(3)
+	var running, maxRunning int32
+
+	// === WHEN ===
+	for i := 0; i < 10; i++ {
+		pool.WaitForSlot()
+		counted := i%2 == 0
+		pool.Go(func() bool {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if now <= max || atomic.CompareAndSwapInt32(&maxRunning, max, now) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return counted
+		})
+	}
+	pool.Wait()
+
+	// === THEN ===
+	if maxRunning != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", maxRunning, 3)
+	}
+
+	if pool.Done() != 5 || pool.Running() != 0 {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", pool.Done(), pool.Running(), 5, 0)
+	}
+}
+
+func TestRunPoolShouldRaisePanicOfRunInWaitingGoroutine(t *testing.T) {
+	// === GIVEN ===
+	pool := NewRunPool(1)
+	fatal := fatalError{errors.New("connection reset by peer")}
+
+	// === WHEN ===
+	pool.Go(func() bool {
+		panic(fatal)
+	})
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		pool.Wait()
+	}()
+
+	// === THEN ===
+	if recovered != fatal || pool.Running() != 0 {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", recovered, pool.Running(), fatal, 0)
+	}
+
+	// the failure is raised once, the restarted loop gets a working pool
+	pool.Wait()
+}
+
+func TestRunPoolShouldRaiseFailureOfHelperGoroutineAfterOtherRunsFinished(t *testing.T) {
+	// === GIVEN ===
+	pool := NewRunPool(2)
+	fatal := fatalError{errors.New("'progress_monitor' failed")}
+	var finished int32
+
+	// === WHEN ===
+	pool.Go(func() bool {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return true
+	})
+	go func() {
+		defer pool.RecoverFailure()
+		panic(fatal)
+	}()
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		for {
+			pool.WaitForSlot()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// === THEN ===
+	if recovered != fatal {
+		t.Errorf("Got: '%v' - Expected '%v'", recovered, fatal)
+	}
+
+	if atomic.LoadInt32(&finished) != 1 || pool.Done() != 1 {
+		t.Errorf("Got: '%v, %v' - Expected the other run to finish first", finished, pool.Done())
+	}
+}
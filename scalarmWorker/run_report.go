@@ -0,0 +1,139 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"time"
+)
+
+// reason codes describing how a simulation run ended
+const (
+	ReasonOK                   = "ok"
+	ReasonMemoized             = "memoized"
+	ReasonInvalidInput         = "invalid_input"
+	ReasonInputStagingFailed   = "input_staging_failed"
+	ReasonInputTemplatesFailed = "input_templates_failed"
+	ReasonInputWriterFailed    = "input_writer_failed"
+	ReasonExecutorFailed       = "executor_failed"
+	ReasonOutputReaderFailed   = "output_reader_failed"
+	ReasonSummaryFailed        = "summary_failed"
+	ReasonPostProcessingFailed = "post_processing_failed"
+	ReasonNoOutput             = "no_output"
+	ReasonInvalidOutput        = "invalid_output"
+	ReasonSimulationError      = "simulation_error"
+	ReasonAborted              = "aborted"
+	ReasonTimeout              = "timeout"
+	ReasonMemoryLimit          = "memory_limit_exceeded"
+)
+
+// PhaseTiming - how long a single phase of a simulation run took
+type PhaseTiming struct {
+	Name      string  `json:"name"`
+	StartedAt int64   `json:"started_at"`
+	Duration  float64 `json:"duration"` // in seconds
+}
+
+// RunReport is a machine-readable record of a single simulation run
+type RunReport struct {
+	ExperimentID    string            `json:"experiment_id"`
+	SimulationIndex int               `json:"simulation_id"`
+	ParametersHash  string            `json:"parameters_hash"`
+	InputParameters json.RawMessage   `json:"input_parameters"`
+	StartedAt       int64             `json:"started_at"`
+	FinishedAt      int64             `json:"finished_at"`
+	Phases          []PhaseTiming     `json:"phases"`
+	ExitCodes       map[string]int    `json:"exit_codes"`
+	Artifacts       map[string]string `json:"artifacts"`
+	FailedUploads   []string          `json:"failed_uploads,omitempty"`
+	Status          string            `json:"status"`
+	ReasonCode      string            `json:"reason_code"`
+	Reason          string            `json:"reason"`
+	BenchmarkScore  float64           `json:"benchmark_score,omitempty"`
+	EnergyJoules    float64           `json:"energy_joules,omitempty"`
+	PeakRSS         uint64            `json:"peak_rss,omitempty"`
+	Provenance      *Provenance       `json:"provenance,omitempty"`
+	// seconds the clock of Experiment Manager is ahead of the local clock, timestamps above are in its time
+	ClockOffset float64 `json:"clock_offset,omitempty"`
+	// Storage Managers which store replicas of artifacts, when upload_replicas is greater than 1
+	Replicas map[string][]ArtifactReplica `json:"replicas,omitempty"`
+
+	// uploads of mirrors of artifacts, started once the report is written
+	mirrors []func(reportPath string)
+}
+
+// NewRunReport creates a report for a simulation run with the given input parameters (as JSON)
+func NewRunReport(experimentID string, simulationIndex int, inputParameters []byte) *RunReport {
+	hash := sha256.Sum256(inputParameters)
+
+	return &RunReport{
+		ExperimentID:    experimentID,
+		SimulationIndex: simulationIndex,
+		ParametersHash:  hex.EncodeToString(hash[:]),
+		InputParameters: json.RawMessage(inputParameters),
+		StartedAt:       serverNow().Unix(),
+		Phases:          []PhaseTiming{},
+		ExitCodes:       map[string]int{},
+		Artifacts:       map[string]string{},
+		Replicas:        map[string][]ArtifactReplica{},
+	}
+}
+
+// AddPhase records a phase which started at the given time and has just finished
+func (report *RunReport) AddPhase(name string, start time.Time) {
+	report.Phases = append(report.Phases, PhaseTiming{
+		Name:      name,
+		StartedAt: ServerTime(start).Unix(),
+		Duration:  time.Since(start).Seconds(),
+	})
+}
+
+// SetExitCode records an exit code of the given adapter based on the error returned by exec
+func (report *RunReport) SetExitCode(adapter string, cmd *exec.Cmd, err error) {
+	if cmd.ProcessState != nil {
+		report.ExitCodes[adapter] = cmd.ProcessState.ExitCode()
+	} else if err != nil {
+		report.ExitCodes[adapter] = -1
+	} else {
+		report.ExitCodes[adapter] = 0
+	}
+}
+
+// Fail marks the whole run as failed with the given reason code
+func (report *RunReport) Fail(reasonCode string, reason string) {
+	report.Status = "error"
+	report.ReasonCode = reasonCode
+	report.Reason = reason
+}
+
+// Write stores the report as report.json in a run-specific subdirectory of reportsDir
+// and returns a path to the created file
+func (report *RunReport) Write(reportsDir string) (string, error) {
+	report.FinishedAt = serverNow().Unix()
+	if offset, ok := ClockOffset(); ok {
+		report.ClockOffset = offset.Seconds()
+	}
+
+	runReportDir := path.Join(reportsDir, "experiment_"+report.ExperimentID,
+		"simulation_"+strconv.Itoa(report.SimulationIndex))
+	if err := os.MkdirAll(runReportDir, 0777); err != nil {
+		return "", err
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	reportPath := path.Join(runReportDir, "report.json")
+	if err = ioutil.WriteFile(reportPath, []byte(Redact(string(content))), 0666); err != nil {
+		return "", err
+	}
+
+	return reportPath, nil
+}
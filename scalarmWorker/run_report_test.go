@@ -0,0 +1,79 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestNewRunReportShouldHashInputParameters(t *testing.T) {
+	report1 := NewRunReport("1", 1, []byte(`{"parameter1":10}`))
+	report2 := NewRunReport("1", 2, []byte(`{"parameter1":10}`))
+	report3 := NewRunReport("1", 3, []byte(`{"parameter1":11}`))
+
+	if report1.ParametersHash != report2.ParametersHash {
+		t.Errorf("Got: '%v' - Expected '%v'", report2.ParametersHash, report1.ParametersHash)
+	}
+
+	if report1.ParametersHash == report3.ParametersHash {
+		t.Errorf("Hashes of different parameters should differ")
+	}
+}
+
+func TestRunReportShouldRecordExitCodes(t *testing.T) {
+	report := NewRunReport("1", 1, []byte(`{}`))
+
+	okCmd := exec.Command("sh", "-c", "exit 0")
+	report.SetExitCode("input_writer", okCmd, okCmd.Run())
+
+	failingCmd := exec.Command("sh", "-c", "exit 3")
+	report.SetExitCode("executor", failingCmd, failingCmd.Run())
+
+	if report.ExitCodes["input_writer"] != 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", report.ExitCodes["input_writer"], 0)
+	}
+
+	if report.ExitCodes["executor"] != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", report.ExitCodes["executor"], 3)
+	}
+}
+
+func TestRunReportShouldBeWrittenToReportsDir(t *testing.T) {
+	// === GIVEN ===
+	reportsDir := "./test_reports"
+	defer os.RemoveAll(reportsDir)
+
+	report := NewRunReport("1", 2, []byte(`{"parameter1":10}`))
+	report.AddPhase("executor", time.Now())
+	report.Fail(ReasonExecutorFailed, "exit status 1")
+
+	// === WHEN ===
+	reportPath, err := report.Write(reportsDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected nil", err)
+	}
+
+	expectedPath := "test_reports/experiment_1/simulation_2/report.json"
+	if reportPath != expectedPath {
+		t.Errorf("Got: '%v' - Expected '%v'", reportPath, expectedPath)
+	}
+
+	content, _ := ioutil.ReadFile(reportPath)
+	written := new(RunReport)
+	if err = json.Unmarshal(content, written); err != nil {
+		t.Fatalf("Report is not a valid JSON: %v", err)
+	}
+
+	if written.ReasonCode != ReasonExecutorFailed || written.Status != "error" {
+		t.Errorf("Got: '%v' - Expected '%v'", written.ReasonCode, ReasonExecutorFailed)
+	}
+
+	if len(written.Phases) != 1 || written.Phases[0].Name != "executor" {
+		t.Errorf("Got: '%v' - Expected a single 'executor' phase", written.Phases)
+	}
+}
@@ -0,0 +1,59 @@
+package scalarmWorker
+
+import "sync"
+
+// simulation runs fetched by this worker which were not marked as complete yet; when SiM stops before
+// completing them, because of a shutdown, preemption or crash, they are returned to the queue of the experiment
+// instead of staying in progress until the Experiment Manager times them out
+var unfinishedRuns struct {
+	runs  map[runKey]*unfinishedRun
+	mutex sync.Mutex
+}
+
+// runKey identifies a simulation run, indexes of runs are unique only within their experiment
+type runKey struct {
+	experimentID    string
+	simulationIndex int
+}
+
+type unfinishedRun struct {
+	em *ExperimentManager
+}
+
+// setUnfinishedRun makes the simulation run roll back on exit until finishRun is called
+func setUnfinishedRun(em *ExperimentManager, simulationIndex int) {
+	unfinishedRuns.mutex.Lock()
+	defer unfinishedRuns.mutex.Unlock()
+
+	if unfinishedRuns.runs == nil {
+		unfinishedRuns.runs = map[runKey]*unfinishedRun{}
+	}
+	unfinishedRuns.runs[runKey{em.ExperimentId, simulationIndex}] = &unfinishedRun{em: em}
+}
+
+// finishRun is called once the Experiment Manager knows the outcome of the unfinished simulation run
+func finishRun(experimentID string, simulationIndex int) {
+	unfinishedRuns.mutex.Lock()
+	defer unfinishedRuns.mutex.Unlock()
+
+	delete(unfinishedRuns.runs, runKey{experimentID, simulationIndex})
+}
+
+// rollbackUnfinishedRun returns unfinished simulation runs, if any, to the queue of the experiment
+func rollbackUnfinishedRun() {
+	unfinishedRuns.mutex.Lock()
+	runs := map[runKey]*ExperimentManager{}
+	for key, run := range unfinishedRuns.runs {
+		runs[key] = run.em
+	}
+	unfinishedRuns.mutex.Unlock()
+
+	for key, em := range runs {
+		finishRun(key.experimentID, key.simulationIndex)
+
+		simLog.Infof("Rolling back unfinished simulation run %v of experiment %s ...", key.simulationIndex, key.experimentID)
+		if err := em.RollbackSimulationRun(key.simulationIndex); err != nil {
+			simLog.Warnf("Could not roll back simulation run %v: %v", key.simulationIndex, Redact(err.Error()))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUnfinishedRunShouldBeRolledBackUnlessItWasCompleted(t *testing.T) {
+	// === GIVEN ===
+	var rollbacks int32
+	server := setupPrefetchServer(&rollbacks)
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	setUnfinishedRun(&em, 7)
+	rollbackUnfinishedRun()
+	rollbackUnfinishedRun()
+
+	setUnfinishedRun(&em, 7)
+	finishRun(em.ExperimentId, 7)
+	rollbackUnfinishedRun()
+
+	// === THEN ===
+	if rollbacks != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", rollbacks, 1)
+	}
+}
+
+func TestAllUnfinishedRunsShouldBeRolledBack(t *testing.T) {
+	// === GIVEN ===
+	var rollbacks int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/rollback") {
+			atomic.AddInt32(&rollbacks, 1)
+		}
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	setUnfinishedRun(&em, 7)
+	setUnfinishedRun(&em, 8)
+	setUnfinishedRun(&em, 9)
+	finishRun(em.ExperimentId, 8)
+	rollbackUnfinishedRun()
+
+	// === THEN ===
+	if rollbacks != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", rollbacks, 2)
+	}
+}
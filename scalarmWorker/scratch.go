@@ -0,0 +1,133 @@
+package scalarmWorker
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// scratchDir returns the node-local directory in which simulations run, the agent sets one for every
+// worker of a fleet with a scratch directory; an empty path means simulations run in the experiment directory
+func (sim SimulationManager) scratchDir() string {
+	scratchDir := sim.Config.ScratchDir
+	if scratchDir == "" {
+		scratchDir = os.Getenv("SCALARM_SCRATCH_DIR")
+	}
+
+	// simulations change the working directory, so a relative path is resolved against the root directory
+	if scratchDir != "" && !filepath.IsAbs(scratchDir) {
+		scratchDir = filepath.Join(sim.RootDirPath, scratchDir)
+	}
+
+	return scratchDir
+}
+
+// PrepareScratchCodeBase copies the code base into the scratch directory of the experiment once,
+// so adapters and their files are read from fast local storage
+func PrepareScratchCodeBase(codeBaseDir string, scratchCodeBaseDir string) error {
+	unlock, err := lockFile(scratchCodeBaseDir + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(scratchCodeBaseDir); err == nil {
+		return nil
+	}
+
+	// a partial copy is never used, it is renamed once complete
+	partialDir := scratchCodeBaseDir + ".part"
+	os.RemoveAll(partialDir)
+
+	if err = copyTree(codeBaseDir, partialDir, nil); err != nil {
+		os.RemoveAll(partialDir)
+		return err
+	}
+
+	return os.Rename(partialDir, scratchCodeBaseDir)
+}
+
+// CopyBack copies outputs of a simulation run matching any of the patterns, all files when there are none,
+// from the scratch directory to destDirPath; symlinks, e.g. to shared datasets, are copied as symlinks
+func CopyBack(scratchDirPath string, destDirPath string, patterns []string) error {
+	return copyTree(scratchDirPath, destDirPath, func(relativePath string) bool {
+		if len(patterns) == 0 {
+			return true
+		}
+
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, relativePath); matched {
+				return true
+			}
+			if matched, _ := filepath.Match(pattern, filepath.Base(relativePath)); matched {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// copyTree copies regular files and symlinks accepted by include, all when it is nil, keeping modes of files
+// and targets of symlinks
+func copyTree(srcDir string, destDir string, include func(relativePath string) bool) error {
+	return filepath.Walk(srcDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(srcDir, filePath)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDir, relativePath)
+
+		if info.IsDir() {
+			return os.MkdirAll(destPath, info.Mode().Perm()|0700)
+		}
+
+		if include != nil && !include(relativePath) {
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return copySymlink(filePath, destPath)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyRegularFile(filePath, destPath, info.Mode().Perm())
+	})
+}
+
+// copySymlink creates a symlink with the same target, replacing whatever is at destPath
+func copySymlink(srcPath string, destPath string) error {
+	target, err := os.Readlink(srcPath)
+	if err != nil {
+		return err
+	}
+
+	os.Remove(destPath)
+	return os.Symlink(target, destPath)
+}
+
+func copyRegularFile(srcPath string, destPath string, mode os.FileMode) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = copyWithPooledBuffer(dest, src); err != nil {
+		dest.Close()
+		return err
+	}
+
+	return dest.Close()
+}
@@ -0,0 +1,96 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyBackShouldCopyOnlyMatchingOutputs(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "scratch")
+	defer os.RemoveAll(dir)
+
+	scratchDirPath := filepath.Join(dir, "scratch", "simulation_1")
+	destDirPath := filepath.Join(dir, "shared", "simulation_1")
+
+	os.MkdirAll(filepath.Join(scratchDirPath, "out"), 0755)
+	ioutil.WriteFile(filepath.Join(scratchDirPath, "output.json"), []byte(`{"y":1}`), 0644)
+	ioutil.WriteFile(filepath.Join(scratchDirPath, "out", "field.h5"), []byte("data"), 0644)
+	ioutil.WriteFile(filepath.Join(scratchDirPath, "checkpoint.bin"), []byte("large"), 0644)
+	os.Symlink(dir, filepath.Join(scratchDirPath, "shared"))
+
+	// === WHEN ===
+	err := CopyBack(scratchDirPath, destDirPath, []string{"*.json", "out/*.h5"})
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	for _, name := range []string{"output.json", filepath.Join("out", "field.h5")} {
+		if _, err := os.Stat(filepath.Join(destDirPath, name)); err != nil {
+			t.Errorf("'%v' should be copied back: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"checkpoint.bin", "shared"} {
+		if _, err := os.Lstat(filepath.Join(destDirPath, name)); !os.IsNotExist(err) {
+			t.Errorf("'%v' should not be copied back", name)
+		}
+	}
+}
+
+func TestPrepareScratchCodeBaseShouldKeepModesOfAdapters(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "scratch")
+	defer os.RemoveAll(dir)
+
+	codeBaseDir := filepath.Join(dir, "code_base")
+	os.MkdirAll(codeBaseDir, 0755)
+	ioutil.WriteFile(filepath.Join(codeBaseDir, "executor"), []byte("#!/bin/sh\n"), 0755)
+
+	scratchCodeBaseDir := filepath.Join(dir, "scratch", "code_base")
+	os.MkdirAll(filepath.Dir(scratchCodeBaseDir), 0755)
+
+	// === WHEN ===
+	err := PrepareScratchCodeBase(codeBaseDir, scratchCodeBaseDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	info, err := os.Stat(filepath.Join(scratchCodeBaseDir, "executor"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Got: '%v' - Expected an executable copy of the executor", info)
+	}
+}
+
+func TestPrepareScratchCodeBaseShouldKeepSymlinks(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "scratch")
+	defer os.RemoveAll(dir)
+
+	codeBaseDir := filepath.Join(dir, "code_base")
+	os.MkdirAll(filepath.Join(codeBaseDir, "lib"), 0755)
+	ioutil.WriteFile(filepath.Join(codeBaseDir, "lib", "libmodel.so.1"), []byte("lib"), 0644)
+	os.Symlink("libmodel.so.1", filepath.Join(codeBaseDir, "lib", "libmodel.so"))
+
+	scratchCodeBaseDir := filepath.Join(dir, "scratch", "code_base")
+	os.MkdirAll(filepath.Dir(scratchCodeBaseDir), 0755)
+
+	// === WHEN ===
+	err := PrepareScratchCodeBase(codeBaseDir, scratchCodeBaseDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	target, err := os.Readlink(filepath.Join(scratchCodeBaseDir, "lib", "libmodel.so"))
+	if err != nil || target != "libmodel.so.1" {
+		t.Errorf("Got: '%v' - Expected '%v'", target, "libmodel.so.1")
+	}
+}
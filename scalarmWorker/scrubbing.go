@@ -0,0 +1,89 @@
+package scalarmWorker
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// number of checks whether a scrubbed directory disappeared, shared filesystems can remove files with a delay
+var scrubVerifyAttempts = 5
+
+var scrubVerifyInterval = 1 * time.Second
+
+// ScrubDir overwrites every regular file in dirPath with random data passes times and truncates it before
+// the directory is removed; removal is verified as files still open by other processes can keep
+// the directory alive on shared filesystems (e.g. NFS '.nfs*' files)
+func ScrubDir(dirPath string, passes int) error {
+	if passes < 1 {
+		passes = 1
+	}
+
+	err := filepath.Walk(dirPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// symbolic links are not followed, their targets are not part of the simulation directory
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		// read-only outputs have to be overwritten as well
+		if info.Mode().Perm()&0200 == 0 {
+			if err = os.Chmod(filePath, info.Mode().Perm()|0200); err != nil {
+				return err
+			}
+		}
+
+		return scrubFile(filePath, info.Size(), passes)
+	})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < scrubVerifyAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(scrubVerifyInterval)
+		}
+
+		os.RemoveAll(dirPath)
+
+		if _, err = os.Lstat(dirPath); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s still exists after scrubbing", dirPath)
+}
+
+// scrubFile overwrites the content of a file in place, so data is gone even if the file has other hard links
+func scrubFile(filePath string, size int64, passes int) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buffer)
+
+	for pass := 0; pass < passes; pass++ {
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		if _, err = io.CopyBuffer(file, io.LimitReader(rand.Reader, size), *buffer); err != nil {
+			return err
+		}
+
+		if err = file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	return file.Truncate(0)
+}
@@ -0,0 +1,41 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScrubDirShouldOverwriteAndRemoveFiles(t *testing.T) {
+	// === GIVEN ===
+	rootDir, _ := ioutil.TempDir("", "scrub_test_")
+	defer os.RemoveAll(rootDir)
+
+	simulationDir := filepath.Join(rootDir, "simulation_1")
+	os.MkdirAll(filepath.Join(simulationDir, "nested"), 0777)
+	ioutil.WriteFile(filepath.Join(simulationDir, "input.json"), []byte(`{"patient":"John Doe"}`), 0666)
+	ioutil.WriteFile(filepath.Join(simulationDir, "nested", "output.dat"), []byte("sensitive output"), 0666)
+
+	// a hard link keeps the content reachable after removal unless it was overwritten
+	hardLink := filepath.Join(rootDir, "hard_link")
+	if err := os.Link(filepath.Join(simulationDir, "nested", "output.dat"), hardLink); err != nil {
+		t.Skipf("hard links are not supported: %v", err)
+	}
+
+	// === WHEN ===
+	err := ScrubDir(simulationDir, 2)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if _, err := os.Stat(simulationDir); !os.IsNotExist(err) {
+		t.Errorf("Simulation directory should be removed, but got: %v", err)
+	}
+
+	if content, _ := ioutil.ReadFile(hardLink); len(content) != 0 {
+		t.Errorf("Got: '%s' - Expected scrubbed file to be empty", content)
+	}
+}
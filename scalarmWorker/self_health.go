@@ -0,0 +1,198 @@
+package scalarmWorker
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var healthLog = NewLogger("health")
+
+// how often resources of SiM are checked when no interval is configured
+const defaultSelfHealthInterval = 30
+
+// SelfHealthThresholds are limits of resources used by SiM itself; exceeding one of them indicates a leak,
+// thresholds equal to 0 are not checked
+type SelfHealthThresholds struct {
+	// resident set size in MB
+	MaxRSS        int64
+	MaxGoroutines int
+	MaxOpenFiles  int
+}
+
+// environment variable passing the number of simulation runs done by SiM to the instance replacing it
+// on a restart, so simulations_limit counts runs of both
+const simulationsDoneEnv = "SCALARM_SIMULATIONS_DONE"
+
+// simulation runs done by instances of SiM replaced by this one
+var simulationsDoneBefore int
+
+// set when SiM exceeded a threshold and should restart after the current simulation run
+var healthRestart struct {
+	reason string
+	mutex  sync.Mutex
+}
+
+// selfHealthThresholds returns thresholds configured with max_rss, max_goroutines and max_open_files
+func (config *SimulationManagerConfig) selfHealthThresholds() SelfHealthThresholds {
+	return SelfHealthThresholds{MaxRSS: config.MaxRSS, MaxGoroutines: config.MaxGoroutines, MaxOpenFiles: config.MaxOpenFiles}
+}
+
+// Enabled tells whether any threshold is set
+func (thresholds SelfHealthThresholds) Enabled() bool {
+	return thresholds.MaxRSS > 0 || thresholds.MaxGoroutines > 0 || thresholds.MaxOpenFiles > 0
+}
+
+// Check returns which threshold is exceeded, empty when resources of SiM are within all of them
+func (thresholds SelfHealthThresholds) Check() string {
+	if thresholds.MaxRSS > 0 {
+		if rss := residentSetSize() / (1024 * 1024); rss > thresholds.MaxRSS {
+			return fmt.Sprintf("resident set size %d MB exceeds %d MB", rss, thresholds.MaxRSS)
+		}
+	}
+
+	if thresholds.MaxGoroutines > 0 {
+		if goroutines := runtime.NumGoroutine(); goroutines > thresholds.MaxGoroutines {
+			return fmt.Sprintf("%d goroutines exceed %d", goroutines, thresholds.MaxGoroutines)
+		}
+	}
+
+	if thresholds.MaxOpenFiles > 0 {
+		if openFiles := openFileCount(); openFiles > thresholds.MaxOpenFiles {
+			return fmt.Sprintf("%d open files exceed %d", openFiles, thresholds.MaxOpenFiles)
+		}
+	}
+
+	return ""
+}
+
+// MonitorSelfHealth checks thresholds every interval and requests a restart of SiM once one of them is exceeded
+func MonitorSelfHealth(thresholds SelfHealthThresholds, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if reason := thresholds.Check(); reason != "" {
+			requestHealthRestart(reason)
+			return
+		}
+	}
+}
+
+func requestHealthRestart(reason string) {
+	healthRestart.mutex.Lock()
+	defer healthRestart.mutex.Unlock()
+
+	if healthRestart.reason == "" {
+		healthRestart.reason = reason
+		healthLog.Infof("%s, restarting after the current simulation run", reason)
+	}
+}
+
+// healthRestartReason returns why SiM should restart, empty when it is healthy
+func healthRestartReason() string {
+	healthRestart.mutex.Lock()
+	defer healthRestart.mutex.Unlock()
+
+	return healthRestart.reason
+}
+
+// takeSimulationsDone reads the number of simulation runs done before a restart and removes it from
+// the environment, so adapters and later restarts do not see a stale value
+func takeSimulationsDone() {
+	if done, err := strconv.Atoi(os.Getenv(simulationsDoneEnv)); err == nil && done > 0 {
+		simulationsDoneBefore = done
+	}
+	os.Unsetenv(simulationsDoneEnv)
+}
+
+// remainingSimulationsLimit returns how many simulation runs are left of limit after a restart,
+// a limit which is not set stays as it is
+func remainingSimulationsLimit(limit int) int {
+	if limit <= 0 {
+		return limit
+	}
+
+	return limit - simulationsDoneBefore
+}
+
+// restartForHealth returns a prefetched simulation run, which was not started, writes diagnostics
+// into the root directory and restarts SiM, passing the number of simulation runs done
+func (sim SimulationManager) restartForHealth(prefetch *SimulationRunPrefetch, experimentID string, done int) {
+	if prefetch != nil {
+		prefetch.Rollback()
+	}
+
+	reason := healthRestartReason()
+	healthLog.Infof("%s", RuntimeStats())
+	if diagnosticsPath, err := writeHealthDiagnostics(sim.RootDirPath, reason); err != nil {
+		healthLog.Warnf("Could not write diagnostics: %v", err)
+	} else {
+		healthLog.Infof("Diagnostics written to %s", diagnosticsPath)
+	}
+
+	sim.emit("worker_restarting", experimentID, 0, map[string]interface{}{"reason": reason})
+	os.Setenv(simulationsDoneEnv, strconv.Itoa(simulationsDoneBefore+done))
+	Restart()
+}
+
+// writeHealthDiagnostics writes the reason of a restart, runtime stats and stacks of all goroutines
+func writeHealthDiagnostics(dir string, reason string) (string, error) {
+	diagnosticsPath := filepath.Join(dir, fmt.Sprintf("health_%s.txt", time.Now().UTC().Format("20060102T150405Z")))
+
+	file, err := os.Create(diagnosticsPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(file, "reason: %s\n%s\nresident set size: %d KB, open files: %d\n\n", reason, RuntimeStats(),
+		residentSetSize()/1024, openFileCount())
+	if err = pprof.Lookup("goroutine").WriteTo(file, 1); err != nil {
+		return "", err
+	}
+
+	return diagnosticsPath, file.Close()
+}
+
+// residentSetSize returns the RSS of SiM in bytes from /proc, or memory obtained by the Go runtime where
+// /proc is not available
+func residentSetSize() int64 {
+	if status, err := os.Open("/proc/self/status"); err == nil {
+		defer status.Close()
+
+		scanner := bufio.NewScanner(status)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "VmRSS:" {
+				if kilobytes, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+					return kilobytes * 1024
+				}
+			}
+		}
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	return int64(memStats.Sys)
+}
+
+// openFileCount returns the number of open file descriptors of SiM, 0 where it cannot be determined
+func openFileCount() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := ioutil.ReadDir(dir); err == nil {
+			return len(entries)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,73 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSelfHealthThresholdsShouldReportExceededThreshold(t *testing.T) {
+	// === GIVEN ===
+	cases := []struct {
+		thresholds SelfHealthThresholds
+		expected   string
+	}{
+		{SelfHealthThresholds{MaxGoroutines: 1}, "goroutines exceed 1"},
+		{SelfHealthThresholds{MaxRSS: 1}, "MB exceeds 1 MB"},
+		{SelfHealthThresholds{MaxGoroutines: 100000, MaxRSS: 1024 * 1024}, ""},
+	}
+
+	for _, c := range cases {
+		// === WHEN ===
+		reason := c.thresholds.Check()
+
+		// === THEN ===
+		if (c.expected == "") != (reason == "") || !strings.Contains(reason, c.expected) {
+			t.Errorf("Got: '%v' - Expected '%v'", reason, c.expected)
+		}
+	}
+
+	if (SelfHealthThresholds{}).Enabled() {
+		t.Errorf("Got: '%v' - Expected '%v'", true, false)
+	}
+}
+
+func TestWriteHealthDiagnosticsShouldDumpGoroutines(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "self_health")
+	defer os.RemoveAll(dir)
+
+	// === WHEN ===
+	diagnosticsPath, err := writeHealthDiagnostics(dir, "12 goroutines exceed 10")
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	content, _ := ioutil.ReadFile(diagnosticsPath)
+	if !strings.HasPrefix(string(content), "reason: 12 goroutines exceed 10\n") || !strings.Contains(string(content), "goroutine profile") {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "the reason and stacks of goroutines")
+	}
+}
+
+func TestRemainingSimulationsLimitShouldCountRunsDoneBeforeRestart(t *testing.T) {
+	// === GIVEN ===
+	defer func(done int) { simulationsDoneBefore = done }(simulationsDoneBefore)
+	os.Setenv(simulationsDoneEnv, "3")
+
+	// === WHEN ===
+	takeSimulationsDone()
+
+	// === THEN ===
+	if os.Getenv(simulationsDoneEnv) != "" {
+		t.Errorf("%s should be removed from the environment", simulationsDoneEnv)
+	}
+	if remaining := remainingSimulationsLimit(10); remaining != 7 {
+		t.Errorf("Got: '%v' - Expected '%v'", remaining, 7)
+	}
+	if remaining := remainingSimulationsLimit(-1); remaining != -1 {
+		t.Errorf("Got: '%v' - Expected '%v'", remaining, -1)
+	}
+}
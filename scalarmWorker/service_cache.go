@@ -0,0 +1,120 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// default name of the file with cached responses of Information Service, in the root directory of SiM
+const defaultServiceCacheFile = "service_cache.json"
+
+// longest interval between attempts to refresh a cached response while Information Service is unavailable
+const maxServiceCacheRefreshInterval = 5 * time.Minute
+
+// ServiceCache keeps addresses of Scalarm services returned by Information Service on disk for TTL, so workers
+// restarted during a brief outage of Information Service, or other workers sharing the file, can start working
+type ServiceCache struct {
+	Path string
+	TTL  time.Duration
+
+	mutex    sync.Mutex
+	closed   chan struct{}
+	stopOnce sync.Once
+}
+
+type serviceCacheEntry struct {
+	Addresses []string  `json:"addresses"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// NewServiceCache creates a cache of Information Service responses stored in path
+func NewServiceCache(path string, ttl time.Duration) *ServiceCache {
+	return &ServiceCache{Path: path, TTL: ttl, closed: make(chan struct{})}
+}
+
+// Close stops background refreshes of addresses cached by this cache
+func (cache *ServiceCache) Close() {
+	cache.stopOnce.Do(func() {
+		close(cache.closed)
+	})
+}
+
+// Load returns addresses cached under the key if they are younger than TTL
+func (cache *ServiceCache) Load(key string) ([]string, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.read()[key]
+	if !ok || len(entry.Addresses) == 0 || time.Since(entry.FetchedAt) > cache.TTL {
+		return nil, false
+	}
+
+	return entry.Addresses, true
+}
+
+// Store caches addresses under the key, entries of other keys are kept; workers sharing the file write it
+// one at a time and never read a partially written one
+func (cache *ServiceCache) Store(key string, addresses []string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	unlock, err := lockFile(cache.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries := cache.read()
+	entries[key] = serviceCacheEntry{Addresses: addresses, FetchedAt: time.Now()}
+
+	content, err := marshalJSON(entries)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(cache.Path), filepath.Base(cache.Path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(content)
+	tmpFile.Close()
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), cache.Path)
+}
+
+func (cache *ServiceCache) read() map[string]serviceCacheEntry {
+	entries := map[string]serviceCacheEntry{}
+
+	if content, err := ioutil.ReadFile(cache.Path); err == nil {
+		json.Unmarshal(content, &entries)
+	}
+
+	return entries
+}
+
+// serviceCache returns the cache of Information Service responses configured with service_cache_ttl, if any
+func (sim SimulationManager) serviceCache() *ServiceCache {
+	// addresses of the offline queue change with every start
+	if sim.Config.ServiceCacheTTL <= 0 || sim.Config.OfflineDir != "" {
+		return nil
+	}
+
+	path := sim.Config.ServiceCacheFile
+	if path == "" {
+		path = filepath.Join(sim.RootDirPath, defaultServiceCacheFile)
+	}
+
+	return NewServiceCache(path, time.Duration(sim.Config.ServiceCacheTTL)*time.Second)
+}
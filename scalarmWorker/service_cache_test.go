@@ -0,0 +1,135 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInformationServiceShouldUseCachedAddressesDuringOutage(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "service_cache")
+	defer os.RemoveAll(dir)
+
+	var outage int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&outage) == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		fmt.Fprintln(w, `["siteA.com", "siteB.com"]`)
+	}))
+	defer server.Close()
+
+	// the background refresh asks the test server only and stops with the cache
+	config := getSimConfig()
+	config.Development = true
+	config.InformationServiceUrl = strings.TrimPrefix(server.URL, "http://")
+	cachePath := filepath.Join(dir, defaultServiceCacheFile)
+
+	is := NewInformationService(config, &http.Client{}, time.Second)
+	is.Cache = NewServiceCache(cachePath, time.Hour)
+	defer is.Cache.Close()
+
+	if _, err := is.GetExperimentManagers(); err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+	atomic.StoreInt32(&outage, 1)
+
+	// === WHEN ===
+	restarted := NewInformationService(config, &http.Client{}, time.Second)
+	restarted.Cache = NewServiceCache(cachePath, time.Hour)
+	defer restarted.Cache.Close()
+	experimentManagers, err := restarted.GetExperimentManagers()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if expected := []string{"siteA.com", "siteB.com"}; !reflect.DeepEqual(expected, experimentManagers) {
+		t.Errorf("Got: '%v' - Expected '%v'", experimentManagers, expected)
+	}
+
+	if _, err := restarted.GetStorageManagers(); err == nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "an error, storage managers were not cached")
+	}
+}
+
+func TestServiceCacheShouldIgnoreExpiredEntries(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "service_cache")
+	defer os.RemoveAll(dir)
+
+	cache := NewServiceCache(filepath.Join(dir, defaultServiceCacheFile), 50*time.Millisecond)
+	cache.Store("is/experiment_managers", []string{"siteA.com"})
+
+	// === WHEN ===
+	_, fresh := cache.Load("is/experiment_managers")
+	time.Sleep(100 * time.Millisecond)
+	_, expired := cache.Load("is/experiment_managers")
+
+	// === THEN ===
+	if !fresh || expired {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", fresh, expired, true, false)
+	}
+}
+
+func TestServiceCacheShouldKeepEntriesOfWorkersWritingAtTheSameTime(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "service_cache")
+	defer os.RemoveAll(dir)
+
+	cachePath := filepath.Join(dir, defaultServiceCacheFile)
+	var wg sync.WaitGroup
+
+	// === WHEN ===
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			cache := NewServiceCache(cachePath, time.Hour)
+			if err := cache.Store(fmt.Sprintf("is%d/experiment_managers", worker), []string{"siteA.com"}); err != nil {
+				t.Errorf("Returned error should be nil, but it is '%v'", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// === THEN ===
+	cache := NewServiceCache(cachePath, time.Hour)
+	for i := 0; i < 8; i++ {
+		if _, ok := cache.Load(fmt.Sprintf("is%d/experiment_managers", i)); !ok {
+			t.Errorf("Got: '%v' - Expected '%v'", ok, "addresses cached by every worker")
+		}
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", len(files), "the cache and its lock file only")
+	}
+}
+
+func TestStartServiceRefreshShouldAllowOneRefreshAtATime(t *testing.T) {
+	// === WHEN ===
+	first := startServiceRefresh("test_is/experiment_managers")
+	second := startServiceRefresh("test_is/experiment_managers")
+	other := startServiceRefresh("test_is/storage_managers")
+	finishServiceRefresh("test_is/experiment_managers")
+	finishServiceRefresh("test_is/storage_managers")
+	afterFinish := startServiceRefresh("test_is/experiment_managers")
+	finishServiceRefresh("test_is/experiment_managers")
+
+	// === THEN ===
+	if !first || second || !other || !afterFinish {
+		t.Errorf("Got: '%v, %v, %v, %v' - Expected '%v, %v, %v, %v'", first, second, other, afterFinish, true, false, true, true)
+	}
+}
@@ -0,0 +1,101 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// file of a code base which lists datasets shared by all simulation runs of an experiment
+const sharedDataManifest = "shared_data.json"
+
+// SharedDataset is a large file used by every simulation run, e.g. reference data
+type SharedDataset struct {
+	// URL or id of a file stored in Storage Manager
+	Source string `json:"source"`
+	// file name in the shared directory, the last element of source by default
+	Name string `json:"name"`
+	// optional hex encoded SHA-256 checksum of the file
+	Sha256 string `json:"sha256"`
+}
+
+// LoadSharedDataManifest reads the list of shared datasets from a code base file
+func LoadSharedDataManifest(filePath string) ([]SharedDataset, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets := []SharedDataset{}
+	if err = json.Unmarshal(content, &datasets); err != nil {
+		return nil, errors.New("Incorrect JSON in " + filePath + ": " + err.Error())
+	}
+
+	for _, dataset := range datasets {
+		if dataset.Source == "" {
+			return nil, errors.New("Every entry of " + sharedDataManifest + " requires source")
+		}
+	}
+
+	return datasets, nil
+}
+
+// PrepareSharedData downloads datasets which are not in sharedDir yet, so each of them is downloaded
+// once per experiment instead of once per simulation run
+func (stager *InputStager) PrepareSharedData(datasets []SharedDataset, sharedDir string) error {
+	if err := os.MkdirAll(sharedDir, 0777); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(sharedDir + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, dataset := range datasets {
+		name := dataset.Name
+		if name == "" {
+			name = dataset.Source
+		}
+
+		datasetPath := filepath.Join(sharedDir, stagedFileName(name))
+		if _, err := os.Stat(datasetPath); err == nil {
+			continue
+		}
+
+		simLog.Infof("Downloading shared dataset %s ...", Redact(dataset.Source))
+		if err := stager.download(dataset.Source, datasetPath, dataset.Sha256); err != nil {
+			return fmt.Errorf("shared dataset '%s': %v", dataset.Source, err)
+		}
+	}
+
+	return nil
+}
+
+// LinkSharedData makes the shared directory of the experiment available as 'shared' in the simulation directory,
+// a link left there before is kept when it points to the shared directory and replaced otherwise
+func LinkSharedData(sharedDir string, simulationDirPath string) error {
+	absolutePath, err := filepath.Abs(sharedDir)
+	if err != nil {
+		return err
+	}
+
+	linkPath := filepath.Join(simulationDirPath, "shared")
+	if info, err := os.Lstat(linkPath); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return errors.New(linkPath + " already exists and is not a link to the shared data")
+		}
+		if target, err := os.Readlink(linkPath); err == nil && target == absolutePath {
+			return nil
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+
+	return os.Symlink(absolutePath, linkPath)
+}
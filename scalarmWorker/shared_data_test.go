@@ -0,0 +1,105 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrepareSharedDataShouldDownloadEveryDatasetOnce(t *testing.T) {
+	// === GIVEN ===
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/experiments/568e5bece138232e76000002/input_files/5a1c" {
+			w.WriteHeader(404)
+			return
+		}
+		downloads++
+		fmt.Fprint(w, "reference data")
+	}))
+	defer server.Close()
+
+	experimentDir, _ := ioutil.TempDir("", "shared_data")
+	defer os.RemoveAll(experimentDir)
+	sharedDir := filepath.Join(experimentDir, "shared")
+	simulationDir := filepath.Join(experimentDir, "simulation_1")
+	os.MkdirAll(simulationDir, 0777)
+
+	stager := InputStager{
+		CacheDir:        filepath.Join(experimentDir, "input_cache"),
+		ExperimentID:    "568e5bece138232e76000002",
+		StorageManagers: []string{"system.scalarm.com"},
+		Config:          getSimConfig(),
+		HttpClient:      getHttpClientMock(server.URL),
+		Timeout:         5 * time.Second}
+
+	datasets := []SharedDataset{{Source: "5a1c", Name: "genome.fa"}}
+
+	// === WHEN ===
+	for i := 0; i < 2; i++ {
+		if err := stager.PrepareSharedData(datasets, sharedDir); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+			return
+		}
+	}
+	err := LinkSharedData(sharedDir, simulationDir)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if downloads != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", downloads, 1)
+	}
+
+	if content, _ := ioutil.ReadFile(filepath.Join(simulationDir, "shared", "genome.fa")); string(content) != "reference data" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "reference data")
+	}
+}
+
+func TestLoadSharedDataManifestShouldRequireSource(t *testing.T) {
+	// === GIVEN ===
+	manifest, _ := ioutil.TempFile("", "shared_data")
+	defer os.Remove(manifest.Name())
+	manifest.WriteString(`[{"name": "genome.fa"}]`)
+	manifest.Close()
+
+	// === WHEN ===
+	_, err := LoadSharedDataManifest(manifest.Name())
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+}
+
+func TestLinkSharedDataShouldReplaceExistingLink(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "shared_data")
+	defer os.RemoveAll(dir)
+
+	sharedDir := filepath.Join(dir, "shared")
+	simulationDir := filepath.Join(dir, "simulation")
+	os.MkdirAll(sharedDir, 0777)
+	os.MkdirAll(simulationDir, 0777)
+	os.Symlink(filepath.Join(dir, "old_shared"), filepath.Join(simulationDir, "shared"))
+
+	// === WHEN ===
+	first := LinkSharedData(sharedDir, simulationDir)
+	second := LinkSharedData(sharedDir, simulationDir)
+
+	// === THEN ===
+	if first != nil || second != nil {
+		t.Errorf("Returned errors should be nil, but they are '%v', '%v'", first, second)
+	}
+
+	if target, _ := os.Readlink(filepath.Join(simulationDir, "shared")); target != sharedDir {
+		t.Errorf("Got: '%v' - Expected '%v'", target, sharedDir)
+	}
+}
@@ -0,0 +1,109 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// set once SIGINT or SIGTERM asked SiM to shut down
+var shutdown = struct {
+	requested chan struct{}
+	reason    string
+	once      sync.Once
+}{requested: make(chan struct{})}
+
+// signals received while SiM pauses after a failure are passed only to the pause, Ctrl+C ending the pause
+// or typed in its shell does not shut SiM down
+var failurePause = struct {
+	// set once handleShutdownSignals receives signals, the pause has to get them from it then
+	handled bool
+	pauses  map[chan os.Signal]struct{}
+	mutex   sync.Mutex
+}{pauses: map[chan os.Signal]struct{}{}}
+
+// handleShutdownSignals shuts SiM down gracefully on the first SIGINT or SIGTERM; the second one makes it exit
+// at once, rolling back unfinished simulation runs
+func handleShutdownSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	handleFailurePauseSignals(true)
+
+	sig := nextShutdownSignal(signals)
+	simLog.Warnf("Received %v, shutting down gracefully, send it again to exit immediately", sig)
+	RequestShutdown(fmt.Sprintf("SiM received %v", sig))
+
+	// an idle worker has nothing to finish
+	unfinishedRuns.mutex.Lock()
+	idle := len(unfinishedRuns.runs) == 0
+	unfinishedRuns.mutex.Unlock()
+	if idle {
+		Exit(0)
+	}
+
+	sig = nextShutdownSignal(signals)
+	simLog.Warnf("Received %v, exiting", sig)
+	Exit(1)
+}
+
+// handleFailurePauseSignals tells whether signals are received by handleShutdownSignals, which passes them to a pause
+func handleFailurePauseSignals(handled bool) {
+	failurePause.mutex.Lock()
+	defer failurePause.mutex.Unlock()
+
+	failurePause.handled = handled
+}
+
+// nextShutdownSignal returns the next signal which is not passed to a pause after a failure,
+// nil when the channel is closed
+func nextShutdownSignal(signals <-chan os.Signal) os.Signal {
+	for sig := range signals {
+		failurePause.mutex.Lock()
+		paused := len(failurePause.pauses) > 0
+		for pause := range failurePause.pauses {
+			select {
+			case pause <- sig:
+			default:
+			}
+		}
+		failurePause.mutex.Unlock()
+
+		if !paused {
+			return sig
+		}
+		simLog.Infof("Received %v while paused after a failure, it does not shut SiM down", sig)
+	}
+
+	return nil
+}
+
+// RequestShutdown makes SiM stop fetching simulation runs and stop executors of running ones; these runs
+// are reported as aborted with the reason, together with their output, so the Experiment Manager can reschedule them
+func RequestShutdown(reason string) {
+	shutdown.once.Do(func() {
+		shutdown.reason = reason
+		close(shutdown.requested)
+	})
+
+	atomic.StoreInt32(&draining, 1)
+
+	runCancellations.mutex.Lock()
+	defer runCancellations.mutex.Unlock()
+
+	for _, cancellation := range runCancellations.cancellations {
+		cancellation.Cancel(reason)
+	}
+}
+
+// shutdownRequested tells whether SiM is shutting down and why
+func shutdownRequested() (bool, string) {
+	select {
+	case <-shutdown.requested:
+		return true, shutdown.reason
+	default:
+		return false, ""
+	}
+}
@@ -0,0 +1,98 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func resetShutdown() {
+	shutdown.requested = make(chan struct{})
+	shutdown.reason = ""
+	shutdown.once = sync.Once{}
+	atomic.StoreInt32(&draining, 0)
+}
+
+func TestShutdownShouldCancelRunningAndNewlyStartedRuns(t *testing.T) {
+	// === GIVEN ===
+	defer resetShutdown()
+
+	running := NewRunCancellation()
+	setRunCancellation("1", 7, running)
+	defer setRunCancellation("1", 7, nil)
+
+	// === WHEN ===
+	RequestShutdown("SiM received terminated")
+
+	started := NewRunCancellation()
+	setRunCancellation("1", 8, started)
+	defer setRunCancellation("1", 8, nil)
+
+	// === THEN ===
+	for _, cancellation := range []*RunCancellation{running, started} {
+		if cancelled, reason := cancellation.Cancelled(); !cancelled || reason != "SiM received terminated" {
+			t.Errorf("Got: '%v, %v' - Expected '%v'", cancelled, reason, "cancelled by the shutdown")
+		}
+	}
+	if !Draining() {
+		t.Errorf("Got: '%v' - Expected '%v'", Draining(), true)
+	}
+}
+
+func TestSignalToWorkerPausedOnFailureShouldOnlyEndThePause(t *testing.T) {
+	// === GIVEN ===
+	defer resetShutdown()
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	os.Stdin, _ = ioutil.TempFile("", "stdin")
+	defer os.Remove(os.Stdin.Name())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM)
+	handleFailurePauseSignals(true)
+	defer handleFailurePauseSignals(false)
+
+	shutdownSignal := make(chan os.Signal, 1)
+	go func() { shutdownSignal <- nextShutdownSignal(signals) }()
+
+	cmd := exec.Command("./executor")
+	cmd.Dir = os.TempDir()
+	pauseFinished := make(chan struct{})
+	go func() {
+		PauseOnFailure("executor", cmd)
+		close(pauseFinished)
+	}()
+
+	for paused := false; !paused; time.Sleep(10 * time.Millisecond) {
+		failurePause.mutex.Lock()
+		paused = len(failurePause.pauses) > 0
+		failurePause.mutex.Unlock()
+	}
+
+	// === WHEN ===
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+
+	// === THEN ===
+	select {
+	case <-pauseFinished:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Got: '%v' - Expected '%v'", "pause still waiting", "pause finished")
+	}
+
+	signal.Stop(signals)
+	close(signals)
+	if sig := <-shutdownSignal; sig != nil {
+		t.Errorf("Got: '%v' - Expected '%v'", sig, nil)
+	}
+
+	if requested, _ := shutdownRequested(); requested {
+		t.Errorf("Got: '%v' - Expected '%v'", requested, false)
+	}
+}
@@ -2,22 +2,20 @@ package scalarmWorker
 
 import (
 	"archive/zip"
-	"bytes"
 	"container/list"
 	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"math/rand"
-	"mime/multipart"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,6 +23,21 @@ type SimulationManager struct {
 	Config      *SimulationManagerConfig
 	RootDirPath string
 	HttpClient  *http.Client
+	// absolute path of the config file, read again by the reload-config command of the admin API
+	ConfigPath string
+
+	adapterAccount *AdapterAccount
+	events         *EventStream
+	annotations    *GrafanaAnnotations
+	metrics        *PushgatewayMetrics
+	notifier       *Notifier
+	history        *History
+	inventory      *HostInventory
+	benchmark      *BenchmarkResult
+	// absolute path of the shared data directory of the current experiment, if it has one
+	sharedDataDir string
+	// container image declared by the current experiment, a SIF file when pulled by apptainer or singularity
+	containerImage string
 }
 
 func listIncludeString(l *list.List, a string) bool {
@@ -36,125 +49,231 @@ func listIncludeString(l *list.List, a string) bool {
 	return false
 }
 
-// Calling Get multiple time until valid response or exceed 'communicationTimeout' period
-func getWithTimeout(client *http.Client, request *http.Request, communicationTimeout time.Duration) ([]byte, error) {
-	var resp *http.Response
-	var err error
-	communicationFailed := true
-	communicationStart := time.Now()
-	var body []byte
+// ExecuteScalarmRequest tries instances of a Scalarm service in random order and returns the body
+// of the first response; a RequestError lists all failed attempts
+func (sim SimulationManager) ExecuteScalarmRequest(reqInfo RequestInfo, serviceUrls []string, client *http.Client, timeout time.Duration) ([]byte, error) {
+	response, err := ExecuteScalarmRequest(WorkerContext(), reqInfo, serviceUrls, sim.Config, client, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
 
-	for communicationStart.Add(communicationTimeout).After(time.Now()) {
-		resp, err = client.Do(request)
+	return ioutil.ReadAll(response.Body)
+}
 
-		if err != nil {
-			time.Sleep(1 * time.Second)
-			fmt.Printf("[SiM] %v\n", err)
-		} else {
-			communicationFailed = false
-			break
+// GetRandomExperimentID Makes request to experiments/random_experiment
+// Returns String: random experiment id available for current user
+func (sim SimulationManager) GetRandomExperimentID(experimentManagers []string, client *http.Client) string {
+	communicationTimeout := 30 * time.Second
+	simLog.Infof("Getting random experiment id...")
+	getExpReqInfo := RequestInfo{"GET", nil, "", "experiments/random_experiment"}
+	body, err := sim.ExecuteScalarmRequest(getExpReqInfo, experimentManagers, client, communicationTimeout)
+	if err != nil {
+		simLog.Warnf("%v", Redact(err.Error()))
+		return ""
+	}
+	simLog.Debugf("Random experiment response body: %s", Redact(string(body)))
+	return fmt.Sprintf("%s", body)
+}
+
+func (sim SimulationManager) Run() {
+	if sim.Config.RandomSeed != nil {
+		SeedRandom(WorkerSeed(*sim.Config.RandomSeed, os.Getenv("SCALARM_WORKER_ID")))
+	}
+	simLog.Infof("Random seed: %d", RandomSeed())
+
+	// error.json of a previous execution does not describe this one
+	clearFatalErrorFile()
+
+	go handleShutdownSignals()
+
+	// a restarted worker goes on counting simulation runs of the instance it replaced
+	takeSimulationsDone()
+	if limit := sim.Config.SimulationsLimit; limit > 0 {
+		if sim.Config.SimulationsLimit = remainingSimulationsLimit(limit); sim.Config.SimulationsLimit <= 0 {
+			simLog.Infof("Exiting due to simulation runs limit (%v), reached before the restart", limit)
+			Exit(simulationsLimitExitCode())
 		}
+		simLog.Infof("Simulations limit set to %v, %v runs left", limit, sim.Config.SimulationsLimit)
 	}
 
-	if communicationFailed {
-		return nil, err
+	if sim.Config.OfflineDir != "" {
+		sim.useOfflineQueue()
 	}
 
-	defer resp.Body.Close()
+	if sim.Config.Timeout <= 0 {
+		sim.Config.Timeout = 60
+	}
+	communicationTimeout := time.Duration(sim.Config.Timeout) * time.Second
 
-	if body, err = ioutil.ReadAll(resp.Body); err != nil {
-		return nil, err
+	if sim.Config.CooldownInterval <= 0 {
+		sim.Config.CooldownInterval = 5
 	}
 
-	return body, nil
-}
+	if sim.Config.ReportsDir == "" {
+		sim.Config.ReportsDir = path.Join(sim.RootDirPath, "reports")
+	}
+
+	sim.history = NewHistory(HistoryPath(sim.Config, sim.RootDirPath))
+	if sim.history != nil {
+		OnExit(sim.history.Close)
+	}
 
-func (sim SimulationManager) ExecuteScalarmRequest(reqInfo RequestInfo, serviceUrls []string, client *http.Client, timeout time.Duration) []byte {
-	protocol := "https"
-	if sim.Config.Development {
-		protocol = "http"
+	if sim.Config.DrainFile == "" {
+		sim.Config.DrainFile = path.Join(sim.RootDirPath, "scalarm.drain")
 	}
 
-	// 1. shuffle service url
-	perm := rand.Perm(len(serviceUrls))
+	if hostInfo := currentHostInfo(); hostInfo != nil {
+		simLog.Infof("CPU: %s, %d cores, %.0f MHz", hostInfo.ModelName, hostInfo.Cores, hostInfo.Mhz)
+	}
 
-	for _, v := range perm {
-		// 2. get next service url and prepare a request
-		serviceURL := serviceUrls[v]
-		fmt.Printf("[SiM] %s://%s/%s\n", protocol, serviceURL, reqInfo.ServiceMethod)
-		req, err := http.NewRequest(reqInfo.HttpMethod, fmt.Sprintf("%s://%s/%s", protocol, serviceURL, reqInfo.ServiceMethod), reqInfo.Body)
-		if err != nil {
-			Fatal(err)
+	if sim.Config.Benchmark {
+		if sim.Config.BenchmarkDuration <= 0 {
+			sim.Config.BenchmarkDuration = 3
+		}
+
+		benchmarkDir := sim.scratchDir()
+		if benchmarkDir == "" {
+			benchmarkDir = sim.RootDirPath
 		}
-		req.SetBasicAuth(sim.Config.ExperimentManagerUser, sim.Config.ExperimentManagerPass)
-		if reqInfo.Body != nil {
-			req.Header.Set("Content-Type", reqInfo.ContentType)
+
+		simLog.Infof("Running the node benchmark ...")
+		if benchmark, err := RunBenchmark(benchmarkDir, time.Duration(sim.Config.BenchmarkDuration)*time.Second); err != nil {
+			simLog.Warnf("Benchmark failed: %v", err)
+		} else {
+			simLog.Infof("Benchmark %v", benchmark)
+			sim.benchmark = benchmark
 		}
-		// 3. execute request with timeout
-		response, err := getWithTimeout(client, req, timeout)
-		// 4. if response body is nil go to 2.
-		if err == nil {
-			return response
+	}
+
+	if sim.Config.HostInventory {
+		sim.inventory = CollectHostInventory()
+		sim.inventory.Benchmark = sim.benchmark
+		logHostInventory(sim.inventory)
+
+		if err := os.MkdirAll(sim.Config.ReportsDir, 0777); err == nil {
+			content, _ := marshalJSON(sim.inventory)
+			ioutil.WriteFile(path.Join(sim.Config.ReportsDir, "host_inventory.json"), content, 0666)
 		}
 	}
 
-	Fatal(fmt.Errorf("Could not execute request against Scalarm service"))
-	return nil
-}
+	if sim.retentionPolicy() != nil {
+		go sim.runJanitor()
+	}
 
-// GetRandomExperimentID Makes request to experiments/random_experiment
-// Returns String: random experiment id available for current user
-func (sim SimulationManager) GetRandomExperimentID(experimentManagers []string, client *http.Client) string {
-	communicationTimeout := 30 * time.Second
-	fmt.Printf("[SiM] Getting random experiment id...\n")
-	getExpReqInfo := RequestInfo{"GET", nil, "", "experiments/random_experiment"}
-	body := sim.ExecuteScalarmRequest(getExpReqInfo, experimentManagers, client, communicationTimeout)
-	fmt.Printf("[SiM] Random experiment response body: %s\n", body)
-	return fmt.Sprintf("%s", body)
-}
+	if sim.Config.PauseFile == "" {
+		sim.Config.PauseFile = path.Join(sim.RootDirPath, "scalarm.pause")
+	}
 
-func (sim SimulationManager) Run() {
-	// TODO: use flags with options to override all sim.Config values
-	simulationsLimitPtr := flag.Int("simulations_limit", -1, "max number of simulation run to execute")
-	flag.Parse()
+	// workers of an agent are controlled through the admin API of the agent
+	if sim.Config.AdminAddress != "" && os.Getenv("SCALARM_WORKER_ID") == "" {
+		sim.startAdmin()
+	}
+
+	if sim.Config.DiagnosticsAddress != "" {
+		if _, err := StartDiagnostics(sim.Config.DiagnosticsAddress); err != nil {
+			diagnosticsLog.Warnf("Could not start diagnostics: %v", err)
+		}
+	}
 
-	simulationsLimit := *simulationsLimitPtr
+	if sim.Config.Dashboard {
+		if err := StartDashboard(); err != nil {
+			simLog.Warnf("Could not start the dashboard: %v", err)
+		}
+	}
 
-	// use sim.Config json if simulations limit not provided in command line
-	if simulationsLimit == -1 {
-		simulationsLimit = sim.Config.SimulationsLimit
+	if sim.Config.RuntimeStatsInterval > 0 {
+		go LogRuntimeStats(time.Duration(sim.Config.RuntimeStatsInterval) * time.Second)
 	}
 
-	if simulationsLimit > 0 {
-		fmt.Printf("[SiM] Simulations limit set to %v\n", simulationsLimit)
+	if thresholds := sim.Config.selfHealthThresholds(); thresholds.Enabled() {
+		if sim.Config.SelfHealthInterval <= 0 {
+			sim.Config.SelfHealthInterval = defaultSelfHealthInterval
+		}
+		go MonitorSelfHealth(thresholds, time.Duration(sim.Config.SelfHealthInterval)*time.Second)
 	}
 
-	if sim.Config.Timeout <= 0 {
-		sim.Config.Timeout = 60
+	if sim.Config.CredentialsRefreshCommand != "" || sim.Config.CredentialsRefreshUrl != "" {
+		refresher := NewCredentialsRefresher(sim.Config, sim.HttpClient)
+
+		// without a known expiry the first refresh tells how long credentials are valid
+		if refresher.ExpiresAt.IsZero() {
+			if err := refresher.Refresh(); err != nil {
+				credentialsLog.Warnf("Refresh failed: %v", Redact(err.Error()))
+			}
+		}
+
+		go refresher.Run()
 	}
-	communicationTimeout := time.Duration(sim.Config.Timeout) * time.Second
 
-	if sim.Config.CooldownInterval <= 0 {
-		sim.Config.CooldownInterval = 5
+	if sim.Config.AdapterUser != "" {
+		sim.adapterAccount = sim.prepareAdapterAccount()
+	}
+
+	if sim.Config.EventStream != "" {
+		events, err := OpenEventStream(sim.Config.EventStream)
+		if err != nil {
+			eventsLog.Warnf("Could not open the event stream: %v", err)
+		} else {
+			sim.events = events
+		}
+	}
+	sim.annotations = NewGrafanaAnnotations(sim.Config)
+
+	// batch jobs are too short-lived to be scraped, their metrics are pushed periodically and on exit
+	if sim.metrics = NewPushgatewayMetrics(sim.Config); sim.metrics != nil {
+		OnExit(sim.metrics.Finish)
+		if sim.Config.PushgatewayInterval > 0 {
+			go sim.metrics.PushEvery(time.Duration(sim.Config.PushgatewayInterval) * time.Second)
+		}
+	}
+
+	if sim.events != nil || sim.annotations != nil {
+		OnExit(func() {
+			sim.emit("worker_stopped", "", 0, nil)
+			sim.events.Close()
+			sim.annotations.Close()
+		})
+	}
+	sim.emit("worker_started", "", 0, map[string]interface{}{"root_dir": sim.RootDirPath, "pid": os.Getpid()})
+
+	if notifier := NewNotifier(sim.Config); notifier != nil {
+		sim.notifier = notifier
+		OnFatal(func(err error) {
+			notifier.Notify("Worker died", fmt.Sprintf("The worker in %s exited after a fatal error: %v", sim.RootDirPath, err))
+		})
 	}
 
 	if len(sim.Config.StartAt) > 0 {
 		startTime, err := time.Parse(time.RFC3339, sim.Config.StartAt)
 		if err != nil {
-			fmt.Printf("[SiM] %v\n", err)
+			simLog.Warnf("%v", err)
 		} else {
-			fmt.Println("[SiM] We have start_at provided")
+			simLog.Infof("We have start_at provided")
 			time.Sleep(startTime.Sub(time.Now()))
-			fmt.Println("[SiM] We are ready to work")
+			simLog.Infof("We are ready to work")
 		}
 	}
 
+	supervisor := NewSupervisor(sim.Config.MaxRestarts, time.Duration(sim.Config.RestartWindow)*time.Second)
+	supervisor.Run(func() {
+		sim.work(communicationTimeout)
+	})
+}
+
+// work executes simulation runs of experiments, it is restarted by the supervisor after recoverable failures
+func (sim SimulationManager) work(communicationTimeout time.Duration) {
+	simulationsLimit := sim.Config.SimulationsLimit
+	tailOutput := sim.Config.Tail
+
+	// a restarted loop may have failed inside a simulation directory
+	if err := os.Chdir(sim.RootDirPath); err != nil {
+		Fatal(err)
+	}
+
 	//2. getting experiment and storage manager addresses
-	is := InformationService{
-		HttpClient:           sim.HttpClient,
-		BaseUrl:              sim.Config.InformationServiceUrl,
-		CommunicationTimeout: communicationTimeout,
-		Config:               sim.Config}
+	is := NewInformationService(sim.Config, sim.HttpClient, communicationTimeout)
+	is.Cache = sim.serviceCache()
 
 	var experimentManagers []string
 	experimentManagers, err := is.GetExperimentManagers()
@@ -169,6 +288,25 @@ func (sim SimulationManager) Run() {
 		Fatal(err)
 	}
 
+	// redirects are followed only between Scalarm services
+	TrustServiceHosts(experimentManagers...)
+	TrustServiceHosts(storageManagers...)
+
+	// a run which cannot be completed, because SiM stops or this loop fails, goes back to the queue
+	OnExit(rollbackUnfinishedRun)
+	OnExit(closeCurrentBatch)
+
+	// simulation runs are executed by a pool of concurrency goroutines, one by one by default; when the loop
+	// fails, runs being executed are finished before the supervisor runs exit hooks and restarts it or exits
+	pool := NewRunPool(sim.Config.Concurrency)
+	setWorkerPool(pool)
+	defer func() {
+		if r := recover(); r != nil {
+			pool.Drain()
+			panic(r)
+		}
+	}()
+
 	var experimentID string
 	executedExperiments := list.New()
 	singleExperiment := false
@@ -178,15 +316,15 @@ func (sim SimulationManager) Run() {
 		if sim.Config.ExperimentId == "" {
 			experimentID = ""
 			for experimentID == "" {
-				experimentID = sim.GetRandomExperimentID(experimentManagers, sim.HttpClient)
+				experimentID = sim.SelectExperimentID(experimentManagers, sim.HttpClient, executedExperiments)
 
 				if experimentID == "" {
-					fmt.Printf("[SiM] Random experiment id empty, waiting 30 seconds to try again\n")
+					simLog.Infof("No experiment to execute, waiting 30 seconds to try again")
 					time.Sleep(30 * time.Second)
 
 					// check if this experiment was executed by this SiM
 				} else if listIncludeString(executedExperiments, experimentID) {
-					fmt.Printf("[SiM] That experiment was already executed, waiting 10 seconds to get other id\n")
+					simLog.Infof("That experiment was already executed, waiting 10 seconds to get other id")
 					experimentID = ""
 					time.Sleep(10 * time.Second)
 
@@ -210,378 +348,683 @@ func (sim SimulationManager) Run() {
 			Config:               sim.Config,
 			ExperimentId:         experimentID}
 
+		// files referenced by input parameters are cached for all experiments executed by this worker
+		inputStager := InputStager{
+			CacheDir:        path.Join(sim.RootDirPath, "input_cache"),
+			ExperimentID:    experimentID,
+			StorageManagers: storageManagers,
+			Config:          sim.Config,
+			HttpClient:      sim.HttpClient,
+			Timeout:         communicationTimeout}
+
 		if err = os.MkdirAll(experimentDir, 0777); err != nil {
 			Fatal(err)
 		}
 
+		sim.emit("experiment_started", experimentID, 0, nil)
+
+		if sim.inventory != nil {
+			if err = em.ReportHostInventory(sim.inventory); err != nil {
+				simLog.Warnf("Could not register the host inventory - %v", Redact(err.Error()))
+			}
+		}
+
 		// 3. get code base for the experiment if necessary
+		setCurrentPhase("code_base")
 		codeBaseDir := path.Join(experimentDir, "code_base")
 
-		if _, err := os.Stat(codeBaseDir); os.IsNotExist(err) {
-			if err = os.MkdirAll(codeBaseDir, 0777); err != nil {
+		// the container image of the experiment is pulled while the code base is prepared
+		waitForContainerImage := sim.prefetchContainerImage(&em)
+
+		if err = sim.prepareExperimentCodeBase(&em, experimentID, experimentDir, codeBaseDir); err != nil {
+			Fatal(err)
+		}
+
+		// simulations in a node-local scratch directory run adapters from a local copy of the code base
+		scratchExperimentDir := ""
+		adaptersDir := codeBaseDir
+		if scratchDir := sim.scratchDir(); scratchDir != "" {
+			scratchExperimentDir = path.Join(scratchDir, fmt.Sprintf("experiment_%s", experimentID))
+			adaptersDir = path.Join(scratchExperimentDir, "code_base")
+
+			if err = os.MkdirAll(scratchExperimentDir, 0777); err != nil {
+				Fatal(err)
+			}
+			if err = PrepareScratchCodeBase(codeBaseDir, adaptersDir); err != nil {
 				Fatal(err)
 			}
+			simLog.Infof("Simulations run in the scratch directory %s", scratchExperimentDir)
+		}
 
-			for i := 0; i < 10; i++ {
-				fmt.Println("[SiM] Getting code base ...")
+		sim.containerImage = waitForContainerImage()
 
-				err = em.DownloadExperimentCodeBase(codeBaseDir)
-				if err != nil {
-					fmt.Printf("[SiM] There was a problem while getting code base: %v\n", err)
-				} else {
+		// results of every simulation run are recorded with the worker, code base and image which computed them
+		provenance := NewProvenance(codeBaseDir, sim.containerImage, sim.Config.ContainerRuntime)
 
-					if err = Extract(codeBaseDir+"/code_base.zip", codeBaseDir); err != nil {
-						fmt.Println("[SiM] An error occurred while unzipping 'code_base.zip'.")
-						fmt.Println("[Error] occured while unzipping 'code_base.zip'.")
-						fmt.Printf("[Error] %s\n", err.Error())
-					}
+		// adapters are looked up once, not before every simulation run
+		capabilities := ScanCodeBase(codeBaseDir)
+		capabilities.Print()
 
-					if err = Extract(codeBaseDir+"/simulation_binaries.zip", codeBaseDir); err != nil {
-						fmt.Println("[SiM] An error occurred while unzipping 'simulation_binaries.zip'.")
-						fmt.Println("[Error] occured while unzipping 'simulation_binaries.zip'.")
-						fmt.Printf("[Error] %s\n", err.Error())
-					}
-				}
+		// large datasets used by all simulation runs are downloaded once into experiment_<id>/shared
+		sim.sharedDataDir = ""
+		if capabilities.SharedData {
+			sharedDataDir, err := filepath.Abs(path.Join(experimentDir, "shared"))
+			if err != nil {
+				Fatal(err)
+			}
 
-				if err == nil {
-					break
-				} else {
-					time.Sleep(time.Duration(sim.Config.CooldownInterval) * time.Second)
-				}
+			datasets, err := LoadSharedDataManifest(path.Join(codeBaseDir, sharedDataManifest))
+			if err == nil {
+				err = inputStager.PrepareSharedData(datasets, sharedDataDir)
 			}
+			if err != nil {
+				Fatal(err)
+			}
+			sim.sharedDataDir = sharedDataDir
+		}
 
-			if err = exec.Command("sh", "-c", fmt.Sprintf("chmod a+x \"%s\"/*", codeBaseDir)).Run(); err != nil {
-				fmt.Println("[SiM] An error occurred during executing 'chmod' command. Please check if you have required permissions.")
-				fmt.Printf("[Fatal error] occured during '%v' execution \n", fmt.Sprintf("chmod a+x \"%s\"/*", codeBaseDir))
-				fmt.Printf("[Fatal error] %s\n", err.Error())
-				os.Exit(2)
+		// input parameters of simulation runs are checked against the parameter space of the experiment
+		var parameterSpace *ParameterSpace
+		if sim.Config.ValidateInputParameters {
+			if parameterSpace, err = em.GetParameterSpace(); err != nil {
+				simLog.Warnf("Could not get the parameter space, input parameters are not validated: %v", Redact(err.Error()))
+			} else if parameterSpace == nil {
+				simLog.Infof("The Experiment Manager does not provide the parameter space, input parameters are not validated")
 			}
 		}
 
+		var resultSummaries []ResultSummary
+		if capabilities.ResultSummary {
+			if resultSummaries, err = LoadResultSummaries(path.Join(codeBaseDir, resultSummaryFile)); err != nil {
+				Fatal(err)
+			}
+		}
+
+		// workers started by an agent with a coordinator get and complete simulation runs through it
+		runsManager := em
+		if socketPath := os.Getenv(coordinatorSocketEnv); socketPath != "" {
+			simLog.Infof("Getting simulation runs from the node coordinator at %s", socketPath)
+			runsManager = CoordinatedExperimentManager(em, socketPath, os.Getenv(workerSlotEnv), os.Getenv("SCALARM_WORKER_ID"))
+		}
+
+		// runs fetched in a batch are acknowledged together, unexecuted ones are rolled back on exit;
+		// a coordinator fetches batches for all workers of the node by itself
+		var batch *SimulationRunBatch
+		if sim.Config.SimulationBatchSize > 1 && os.Getenv(coordinatorSocketEnv) == "" {
+			batch = NewSimulationRunBatch(&runsManager, sim.Config.SimulationBatchSize)
+		}
+		setCurrentBatch(batch)
+
+		exp := &experimentRuns{
+			experimentID:         experimentID,
+			em:                   em,
+			runsManager:          &runsManager,
+			experimentManagers:   experimentManagers,
+			storageManagers:      storageManagers,
+			timeout:              communicationTimeout,
+			inputStager:          inputStager,
+			resultCache:          &ResultCache{Dir: path.Join(sim.RootDirPath, "result_cache")},
+			provenance:           provenance,
+			capabilities:         capabilities,
+			parameterSpace:       parameterSpace,
+			resultSummaries:      resultSummaries,
+			experimentDir:        experimentDir,
+			scratchExperimentDir: scratchExperimentDir,
+			adaptersDir:          adaptersDir,
+			pool:                 pool,
+			simulationsLimit:     simulationsLimit,
+			tailOutput:           tailOutput,
+		}
+
 		// 4. main loop for getting simulation runs of an experiment
-		simulationsDone := 0
 		for {
+			pool.WaitForSlot()
+
+			if simulationsLimit > 0 && pool.Done()+pool.Running() >= simulationsLimit {
+				pool.Wait()
+				if pool.Done() >= simulationsLimit {
+					simLog.Infof("Exiting due to simulation runs limit (%v)", simulationsLimit)
+					Exit(simulationsLimitExitCode())
+				}
+			}
+
+			// a paused worker waits and a reloaded config is applied between simulation runs
+			sim.waitWhilePaused()
+			if reloadPending() {
+				// runs being executed read the config
+				pool.Wait()
+				if sim.applyReloadedConfig() {
+					simulationsLimit, tailOutput = sim.Config.SimulationsLimit, sim.Config.Tail
+					exp.simulationsLimit, exp.tailOutput = simulationsLimit, tailOutput
+				}
+			}
+
+			// a drained worker exits between simulation runs
+			if sim.drainRequested() {
+				pool.Wait()
+				sim.drain(exp.takePrefetch(), experimentID)
+			}
+
+			// a worker which exceeded thresholds of its own resources restarts instead of being killed mid-run
+			if healthRestartReason() != "" {
+				pool.Wait()
+				sim.restartForHealth(exp.takePrefetch(), experimentID, pool.Done())
+			}
+
 			nextSimulationFailed := true
-			communicationStart := time.Now()
+			budget := sim.Config.retryBudget(communicationTimeout)
 
 			var simulationRun map[string]interface{}
 			wait := false
+			drained := false
+			// the experiment ended only when the last response said that all simulation runs were sent
+			allSent := false
 
 			// 4.a getting input values for next simulation run
-			for communicationStart.Add(communicationTimeout * time.Duration(len(experimentManagers))).After(time.Now()) {
-				fmt.Println("[SiM] Getting next simulation run ...")
-				simulationRun, err = em.GetNextSimulationRunConfig()
+			setCurrentPhase("next_simulation")
+			for budget.Next() {
+				if prefetch := exp.takePrefetch(); prefetch != nil {
+					simLog.Infof("Using prefetched simulation run ...")
+					simulationRun, err = prefetch.Take()
+				} else if batch != nil {
+					simLog.Infof("Getting next simulation run ...")
+					limit := 0
+					if simulationsLimit > 0 {
+						limit = simulationsLimit - pool.Done() - pool.Running()
+					}
+					simulationRun, err = batch.Next(limit)
+				} else {
+					simLog.Infof("Getting next simulation run ...")
+					simulationRun, err = runsManager.GetNextSimulationRunConfig()
+				}
 
 				if err != nil {
 					Fatal(err)
 				}
 
+				// runs of a chunk are executed one after another and acknowledged together
+				if _, ok := simulationRun["chunk"]; ok && batch == nil {
+					batch = NewSimulationRunBatch(&runsManager, 1)
+					setCurrentBatch(batch)
+					simulationRun = batch.Adopt(simulationRun)
+				}
+
 				status := simulationRun["status"].(string)
+				allSent = status == "all_sent"
+
+				// a run handed out together with the drain command is still executed
+				if command, _ := simulationRun["command"].(string); command == "drain" || status == "drain" {
+					RequestDrain("the Experiment Manager")
+				}
 
-				if status == "all_sent" {
-					fmt.Println("[SiM] There is no more simulations to run in this experiment.")
+				if status == "drain" {
+					drained = true
+					break
+				} else if status == "all_sent" {
+					simLog.Infof("There is no more simulations to run in this experiment.")
 				} else if status == "error" {
-					fmt.Println("[SiM] An error occurred while getting next simulation.")
+					simLog.Warnf("An error occurred while getting next simulation.")
 				} else if status == "wait" {
-					fmt.Printf("[SiM] There is no more simulations to run in this experiment "+
-						"at the moment, time to wait: %vs\n", simulationRun["duration_in_seconds"])
+					simLog.Infof("There is no more simulations to run in this experiment"+
+						"at the moment, time to wait: %v\n", retryAfterHint(simulationRun, 0))
 					wait = true
 					break
 				} else if status != "ok" {
-					fmt.Println("[SiM] We cannot continue due to unsupported status.")
+					simLog.Errorf("We cannot continue due to unsupported status.")
 				} else {
 					nextSimulationFailed = false
 					break
 				}
 
-				fmt.Println("[SiM] There was a problem while getting next simulation to run.")
-				time.Sleep(time.Duration(sim.Config.CooldownInterval) * time.Second)
+				simLog.Warnf("There was a problem while getting next simulation to run.")
+				time.Sleep(retryAfterHint(simulationRun, time.Duration(sim.Config.CooldownInterval)*time.Second))
+			}
+			if drained {
+				continue
 			}
+
 			if wait {
-				time.Sleep(time.Duration(simulationRun["duration_in_seconds"].(float64)) * time.Second)
+				time.Sleep(retryAfterHint(simulationRun, time.Duration(sim.Config.CooldownInterval)*time.Second))
 				continue
 			}
 
 			if nextSimulationFailed {
-				fmt.Println("[SiM] Couldn't get simulation to run")
+				simLog.Warnf("Couldn't get simulation to run")
+				pool.Wait()
+				closeCurrentBatch()
+				if allSent {
+					sim.emit("experiment_finished", experimentID, 0, nil)
+					sim.notifier.Notify("Experiment finished", fmt.Sprintf("There are no more simulation runs of experiment %s, "+
+						"%d of them were executed by the worker in %s", experimentID, pool.Done(), sim.RootDirPath))
+				}
 				if singleExperiment {
-					fmt.Println("[SiM] that was single experiment run -> finishing work.")
+					simLog.Infof("that was single experiment run -> finishing work.")
 					return
 				} else {
-					fmt.Println("[SiM] will try another experiment")
+					simLog.Infof("will try another experiment")
 					break
 				}
 			}
 
-			simulationIndex := int(simulationRun["simulation_id"].(float64))
+			// runs of a chunk are acknowledged together, the batch is taken when the run is started
+			runBatch := batch
+			pool.Go(func() bool {
+				if !sim.executeSimulationRun(exp, runBatch, simulationRun) {
+					return false
+				}
+				if simulationsLimit := exp.simulationsLimit; simulationsLimit > 0 {
+					simLog.Infof("Simulations done: %v/%v", pool.Done()+1, simulationsLimit)
+				}
+				return true
+			})
+		}
+	}
+}
 
-			fmt.Printf("[SiM] Simulation index: %v\n", simulationIndex)
-			fmt.Printf("[SiM] Simulation execution constraints: %v\n", simulationRun["execution_constraints"])
+// simulationsLimitExitCode returns the status of a worker which executed simulations_limit runs: 1, which wrappers
+// of SiM expect, or 0 for a worker of an agent, so the agent does not restart it
+func simulationsLimitExitCode() int {
+	if os.Getenv("SCALARM_WORKER_ID") != "" {
+		return 0
+	}
 
-			simulationDirPath := path.Join(experimentDir, fmt.Sprintf("simulation_%v", simulationIndex))
+	return 1
+}
 
-			err = os.MkdirAll(simulationDirPath, 0777)
-			if err != nil {
-				Fatal(err)
-			}
+// PrepareCodeBase downloads the code base of an experiment, extracts it into codeBaseDir and makes adapters executable,
+// an error is returned when the code base could not be prepared or verified
+func (sim SimulationManager) PrepareCodeBase(em *ExperimentManager, codeBaseDir string) error {
+	var err error
 
-			inputParameters, _ := json.Marshal(simulationRun["input_parameters"].(map[string]interface{}))
+	if err := os.MkdirAll(codeBaseDir, 0777); err != nil {
+		return err
+	}
 
-			err = ioutil.WriteFile(path.Join(simulationDirPath, "input.json"), inputParameters, 0777)
-			if err != nil {
-				Fatal(err)
-			}
+	// a truncated or corrupted archive is downloaded again instead of being extracted
+	checksumFailed := false
 
-			simulationDir, err := os.Open(simulationDirPath)
-			if err != nil {
-				Fatal(err)
-			}
+	for i := 0; i < 10; i++ {
+		simLog.Infof("Getting code base ...")
 
-			wd, err := os.Getwd()
-			fmt.Printf("[SiM] Working dir: %v\n", wd)
-			if err = simulationDir.Chdir(); err != nil {
-				Fatal(err)
+		var checksums map[string]string
+		err = em.DownloadExperimentCodeBase(codeBaseDir)
+		if err == nil && sim.Config.codeBaseChecksumsEnabled() {
+			if checksums, err = sim.codeBaseChecksums(em); err == nil {
+				err = VerifyChecksum(path.Join(codeBaseDir, CodeBaseArchive), CodeBaseArchive, checksums[CodeBaseArchive])
 			}
+			checksumFailed = err != nil
+		}
 
-			// 4b. run an adapter script (input writer) for input information: input.json -> some specific code
-			if _, err := os.Stat(path.Join(codeBaseDir, "input_writer")); err == nil {
-				fmt.Println("[SiM] Before input writer ...")
-				inputWriterCmd := exec.Command("sh", "-c", path.Join(codeBaseDir, "input_writer input.json >>_stdout.txt 2>&1"))
-				inputWriterCmd.Dir = simulationDirPath
-				if err = inputWriterCmd.Run(); err != nil {
-					fmt.Println("[SiM] An error occurred during 'input_writer' execution.")
-					fmt.Println("[SiM] Please check if 'input_writer' executes correctly on the selected infrastructure.")
-					fmt.Printf("[Fatal error] occured during '%v' execution \n", strings.Join(inputWriterCmd.Args, " "))
-					fmt.Printf("[Fatal error] %s\n", err.Error())
-					PrintStdoutLog()
-					os.Exit(1)
+		if err != nil {
+			simLog.Warnf("There was a problem while getting code base: %v", err)
+		} else {
+
+			// an archive which cannot be verified is never extracted nor kept for later runs
+			if sim.Config.CodeBaseSignature != "" {
+				simLog.Infof("Verifying %s signature of the code base ...", sim.Config.CodeBaseSignature)
+				if err = sim.verifyCodeBase(em, codeBaseDir); err != nil {
+					os.RemoveAll(codeBaseDir)
+					simLog.Errorf("Refusing to execute an unsigned or tampered code base.")
+					return err
 				}
-				fmt.Println("[SiM] After input writer ...")
 			}
 
-			// 4c.1. progress monitoring scheduling if available
-			messages := make(chan struct{}, 1)
-			finished := make(chan struct{}, 1)
-			go sim.IntermediateMonitoring(messages, finished, codeBaseDir, experimentManagers, simulationIndex, simulationDirPath, sim.HttpClient, experimentID)
-
-			// 4c. run an executor of this simulation
-			fmt.Println("[SiM] Before executor ...")
-			executorCmd := exec.Command("sh", "-c", path.Join(codeBaseDir, "executor >>_stdout.txt 2>&1"))
-			executorCmd.Dir = simulationDirPath
-			if err = executorCmd.Start(); err != nil {
-				fmt.Println("[SiM] An error occurred during 'executor' execution.")
-				fmt.Println("[SiM] Please check if 'executor' executes correctly on the selected infrastructure.")
-				fmt.Printf("[Fatal error] occured during '%v' execution \n", strings.Join(executorCmd.Args, " "))
-				fmt.Printf("[Fatal error] %s\n", err.Error())
-				PrintStdoutLog()
-				os.Exit(1)
+			if err = Extract(codeBaseDir+"/code_base.zip", codeBaseDir); err != nil {
+				simLog.Errorf("An error occurred while extracting 'code_base.zip': %s", err.Error())
 			}
 
-			pid := executorCmd.Process.Pid
-			RunProcessMonitoring(pid, &sim, &em, simulationIndex)
-
-			if err = executorCmd.Wait(); err != nil {
-				fmt.Println("[SiM] An error occurred during 'executor' execution.")
-				fmt.Println("[SiM] Please check if 'executor' executes correctly on the selected infrastructure.")
-				fmt.Printf("[Fatal error] occured during '%v' execution \n", strings.Join(executorCmd.Args, " "))
-				fmt.Printf("[Fatal error] %s\n", err.Error())
-				PrintStdoutLog()
-				os.Exit(1)
+			binariesPath := path.Join(codeBaseDir, SimulationBinariesArchive)
+			if err = VerifyChecksum(binariesPath, SimulationBinariesArchive, checksums[SimulationBinariesArchive]); err != nil {
+				checksumFailed = true
+				simLog.Warnf("There was a problem while verifying simulation binaries: %v", err)
+			} else if err = Extract(binariesPath, codeBaseDir); err != nil {
+				simLog.Errorf("An error occurred while extracting 'simulation_binaries.zip': %s", err.Error())
 			}
+		}
 
-			fmt.Println("[SiM] After executor ...")
-
-			messages <- struct{}{}
-			close(messages)
-
-			// 4d. run an adapter script (output reader) to transform specific output format to scalarm model (output.json)
-			if _, err := os.Stat(path.Join(codeBaseDir, "output_reader")); err == nil {
-				fmt.Println("[SiM] Before output reader ...")
-				outputReaderCmd := exec.Command("sh", "-c", path.Join(codeBaseDir, "output_reader >>_stdout.txt 2>&1"))
-				outputReaderCmd.Dir = simulationDirPath
-				if err = outputReaderCmd.Run(); err != nil {
-					fmt.Println("[SiM] An error occurred during 'output_reader' execution.")
-					fmt.Println("[SiM] Please check if 'output_reader' executes correctly on the selected infrastructure.")
-					fmt.Printf("[Fatal error] occured during '%v' execution \n", strings.Join(outputReaderCmd.Args, " "))
-					fmt.Printf("[Fatal error] %s\n", err.Error())
-					PrintStdoutLog()
-					os.Exit(1)
-				}
-				fmt.Println("[SiM] After output reader ...")
-			}
+		if err == nil {
+			break
+		} else {
+			time.Sleep(time.Duration(sim.Config.CooldownInterval) * time.Second)
+		}
+	}
 
-			// 4e. upload output json to experiment manager and set the run simulation as done
-			simulationRunResults := new(SimulationRunResults)
+	if checksumFailed {
+		os.RemoveAll(codeBaseDir)
+		simLog.Errorf("Refusing to execute a code base which does not match its checksum.")
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("Could not get the code base: %v", err)
+	}
 
-			if _, err := os.Stat("output.json"); os.IsNotExist(err) {
-				simulationRunResults.Status = "error"
-				simulationRunResults.Reason = fmt.Sprintf("No output.json file found: %s", err.Error())
-			} else {
-				file, err := os.Open("output.json")
+	if err = makeExecutable(codeBaseDir); err != nil {
+		simLog.Errorf("An error occurred while making adapters executable. Please check if you have required permissions.")
+		simLog.Errorf("Fatal error occured while making files in '%s' executable: %s", codeBaseDir, err.Error())
+		runFatalHooks(err, 2)
+		Exit(2)
+	}
 
-				if err != nil {
-					simulationRunResults.Status = "error"
-					simulationRunResults.Reason = fmt.Sprintf("Could not open output.json: %s", err.Error())
-				} else {
-					err = json.NewDecoder(file).Decode(&simulationRunResults)
+	return nil
+}
 
-					if err != nil {
-						simulationRunResults.Status = "error"
-						simulationRunResults.Reason = fmt.Sprintf("Error during output.json parsing: %s", err.Error())
-					}
-				}
+// uploadArtifact uploads a file of the simulation run from its directory and records the response, or the failure,
+// in the report
+func (sim SimulationManager) uploadArtifact(report *RunReport, simulationDirPath string, name string, serviceMethod string,
+	storageManagers []string, timeout time.Duration) {
 
-				file.Close()
-			}
+	filePath := path.Join(simulationDirPath, name)
+	// the artifact is not uploaded when a transform, e.g. anonymization, cannot be applied
+	err := sim.transformArtifact(report, filePath)
+	var body []byte
+	if err == nil && name == "output.tar.gz" && sim.Config.uploadReplicas() > 1 {
+		body, err = sim.uploadReplicated(report, filePath, serviceMethod, storageManagers, timeout)
+	} else if err == nil {
+		body, err = sim.uploadFile(filePath, serviceMethod, storageManagers, timeout)
+	}
+	recordUpload(report.SimulationIndex, name, err)
+	if err != nil {
+		simLog.Warnf("Uploading '%s' failed: %v", name, Redact(err.Error()))
+		report.FailedUploads = append(report.FailedUploads, name)
+		sim.emit("artifact_upload_failed", report.ExperimentID, report.SimulationIndex,
+			map[string]interface{}{"name": name, "error": err.Error()})
+		return
+	}
 
-			resultJson, _ := json.Marshal(simulationRunResults.Results)
+	report.Artifacts[name] = string(body)
+	sim.emit("artifact_uploaded", report.ExperimentID, report.SimulationIndex,
+		map[string]interface{}{"name": name, "response": string(body)})
 
-			if !simulationRunResults.isValid() || !IsJSON(string(resultJson)) {
-				fmt.Printf("[output.json] Invalid results.json: %s\n", resultJson)
-				simulationRunResults.Status = "error"
-				simulationRunResults.Results = nil
-				simulationRunResults.Reason = fmt.Sprintf("Invalid results.json: %s", resultJson)
-				resultJson = nil
-			}
+	simLog.Debugf("Response body: %s", Redact(string(body)))
+}
 
-			// 4f. upload structural results of a simulation run
-			data := url.Values{}
-			data.Set("status", simulationRunResults.Status)
-			data.Add("reason", simulationRunResults.Reason)
-			data.Add("result", string(resultJson))
+// uploadFile sends the file to a Storage Manager, a response code other than 2xx means the upload failed
+func (sim SimulationManager) uploadFile(filePath string, serviceMethod string, storageManagers []string, timeout time.Duration) ([]byte, error) {
+	// archives bigger than upload_chunk_size are uploaded in chunks, text artifacts are compressed instead
+	if info, err := os.Stat(filePath); err == nil && sim.Config.UploadChunkSize > 0 &&
+		info.Size() > sim.Config.UploadChunkSize && !isTextArtifact(filePath) {
+		return sim.uploadChunked(filePath, info.Size(), serviceMethod, storageManagers, timeout)
+	}
 
-			fmt.Printf("[SiM] Results: %v\n", data)
+	return sim.uploadWhole(filePath, serviceMethod, storageManagers, timeout)
+}
 
-			_, err = em.MarkSimulationRunAsComplete(simulationIndex, data)
-			if err != nil {
-				fmt.Println("[SiM] Error during marking simulation run as complete.")
-				Fatal(err)
-			}
+// uploadWhole sends the whole file to a Storage Manager in a single request
+func (sim SimulationManager) uploadWhole(filePath string, serviceMethod string, storageManagers []string, timeout time.Duration) ([]byte, error) {
+	upload := NewFileUpload(filePath)
 
-			// 4g. upload binary output if provided
-			if _, err := os.Stat("output.tar.gz"); err == nil {
-				fmt.Printf("[SiM] Uploading 'output.tar.gz' ...\n")
-				file, err := os.Open("output.tar.gz")
+	// verbose logs are compressed, archives like output.tar.gz are sent as they are
+	if info, err := os.Stat(filePath); err == nil && sim.Config.UploadCompressionThreshold > 0 &&
+		info.Size() > sim.Config.UploadCompressionThreshold && isTextArtifact(filePath) {
+		simLog.Infof("Compressing '%s' (%d bytes) ...", filepath.Base(filePath), info.Size())
+		upload.Compress = true
+	}
 
-				if err != nil {
-					Fatal(err)
-				}
+	uploadInfo := RequestInfo{"PUT", upload, upload.ContentType(), serviceMethod}
 
-				defer file.Close()
+	resp, err := ExecuteScalarmRequest(WorkerContext(), uploadInfo, storageManagers, sim.Config, sim.HttpClient, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-				requestBody := &bytes.Buffer{}
-				writer := multipart.NewWriter(requestBody)
-				part, err := writer.CreateFormFile("file", filepath.Base("output.tar.gz"))
-				if err != nil {
-					Fatal(err)
-				}
-				_, err = io.Copy(part, file)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 
-				err = writer.Close()
-				if err != nil {
-					Fatal(err)
-				}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, errors.New("Storage manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
 
-				binariesUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v", experimentID, simulationIndex)
-				binariesUploadUrlInfo := RequestInfo{"PUT", requestBody, writer.FormDataContentType(), binariesUploadUrl}
-				body := sim.ExecuteScalarmRequest(binariesUploadUrlInfo, storageManagers, sim.HttpClient, communicationTimeout)
+	return body, nil
+}
 
-				fmt.Printf("[SiM] Response body: %s\n", body)
-			}
+// memoizedResult looks for results of the same input parameters in the local cache and,
+// if configured, asks the Experiment Manager for them
+func (sim SimulationManager) memoizedResult(em *ExperimentManager, cache *ResultCache, experimentID string,
+	inputParameters []byte) (json.RawMessage, bool) {
 
-			// 4h. upload stdout if provided
-			if _, err := os.Stat("_stdout.txt"); err == nil {
-				fmt.Println("[SiM] Uploading STDOUT of the simulation run ...")
+	if result, found := cache.Load(experimentID, inputParameters); found {
+		return result, true
+	}
 
-				file, err := os.Open("_stdout.txt")
-				if err != nil {
-					Fatal(err)
-				}
+	if !sim.Config.MemoizeAskManager {
+		return nil, false
+	}
 
-				requestBody := &bytes.Buffer{}
-				writer := multipart.NewWriter(requestBody)
-				part, err := writer.CreateFormFile("file", filepath.Base("_stdout.txt"))
-				if err != nil {
-					Fatal(err)
-				}
-				_, err = io.Copy(part, file)
-				file.Close()
+	result, found, err := em.GetComputedResult(inputParameters)
+	if err != nil {
+		simLog.Warnf("Could not ask for results of identical parameter points: %v", Redact(err.Error()))
+		return nil, false
+	}
 
-				err = writer.Close()
-				if err != nil {
-					Fatal(err)
-				}
+	return result, found
+}
 
-				stdoutUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/stdout", experimentID, simulationIndex)
-				stdoutUploadUrlInfo := RequestInfo{"PUT", requestBody, writer.FormDataContentType(), stdoutUploadUrl}
-				body := sim.ExecuteScalarmRequest(stdoutUploadUrlInfo, storageManagers, sim.HttpClient, communicationTimeout)
+// energySampleInterval returns how often energy counters are read, every second by default
+func (sim SimulationManager) energySampleInterval() time.Duration {
+	if sim.Config.EnergySampleInterval <= 0 {
+		return time.Second
+	}
 
-				fmt.Printf("[SiM] Response body: %s\n", body)
-			}
+	return time.Duration(sim.Config.EnergySampleInterval) * time.Second
+}
 
-			// 5. clean up - removing simulation dir
-			go func() {
-				select {
-				case _ = <-finished:
-					os.RemoveAll(simulationDirPath)
-					close(finished)
-				}
-			}()
+// saveRunReport writes a run report to the reports directory and uploads it if configured to do so
+func (sim SimulationManager) saveRunReport(report *RunReport, storageManagers []string, timeout time.Duration) {
+	if sim.benchmark != nil {
+		report.BenchmarkScore = sim.benchmark.Score
+	}
+	sim.emit("simulation_run_finished", report.ExperimentID, report.SimulationIndex, report)
 
-			rootDir, err := os.Open(sim.RootDirPath)
-			if err != nil {
-				Fatal(err)
-			}
+	reportPath, err := report.Write(sim.Config.ReportsDir)
+	startMirrors(report, reportPath)
+	sim.history.RecordFinished(report)
+	if err != nil {
+		simLog.Warnf("Could not write run report - %v", err)
+		return
+	}
+	simLog.Infof("Run report saved in %s", reportPath)
 
-			// 6. going to the root dir and moving
-			if err = rootDir.Chdir(); err != nil {
-				Fatal(err)
-			}
+	if sim.Config.UploadReport {
+		simLog.Infof("Uploading run report ...")
+		reportUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/report", report.ExperimentID, report.SimulationIndex)
+		body, err := sim.uploadFile(reportPath, reportUploadUrl, storageManagers, timeout)
+		if err != nil {
+			simLog.Warnf("Could not upload the run report, it is kept in %s: %v", reportPath, Redact(err.Error()))
+			return
+		}
+		simLog.Debugf("Response body: %s", Redact(string(body)))
+	}
+}
+
+// verifyCodeBase downloads the signature of the downloaded code base and verifies it with trusted keys from the config
+func (sim SimulationManager) verifyCodeBase(em *ExperimentManager, codeBaseDir string) error {
+	if err := em.DownloadCodeBaseSignature(codeBaseDir); err != nil {
+		return err
+	}
 
-			simulationsDone++
+	return VerifyCodeBaseSignature(sim.Config.CodeBaseSignature, sim.Config.CodeBaseTrustedKeys,
+		path.Join(codeBaseDir, "code_base.zip"), path.Join(codeBaseDir, "code_base.zip.sig"))
+}
 
-			if simulationsLimit > 0 {
-				fmt.Printf("[SiM] Simulations done: %v/%v\n", simulationsDone, simulationsLimit)
-			}
+// removeSimulationDir removes a directory of a finished simulation run, scrubbing its files first if configured
+func (sim SimulationManager) removeSimulationDir(simulationDirPath string) {
+	if !sim.Config.ScrubSimulationDirs {
+		os.RemoveAll(simulationDirPath)
+		return
+	}
 
-			if simulationsLimit > 0 && simulationsDone >= simulationsLimit {
-				fmt.Printf("[SiM] Exiting due to simulation runs limit (%v)\n", simulationsLimit)
-				os.Exit(1)
-			}
+	if err := ScrubDir(simulationDirPath, sim.Config.ScrubPasses); err != nil {
+		simLog.Warnf("Scrubbing of %s failed: %v", simulationDirPath, err)
+	}
+}
+
+// adapterCall returns a command line which starts the adapter of a code base, to be followed by its arguments
+func adapterCall(codeBaseDir string, adapter string) string {
+	if adapterPath := adapterFile(codeBaseDir, adapter); adapterPath != "" {
+		return adapterInvocation(adapterPath)
+	}
+
+	return path.Join(codeBaseDir, adapter)
+}
+
+// adapterCommand prepares a shell command of an adapter executed in dir, as the adapter account if one is configured
+func (sim SimulationManager) adapterCommand(command string, dir string) *exec.Cmd {
+	// adapters are killed when the worker loop is cancelled
+	cmd := shellCommand(WorkerContext(), command)
+	cmd.Dir = dir
+
+	if sim.adapterAccount != nil {
+		sim.adapterAccount.Apply(cmd)
+	}
+
+	if sim.sharedDataDir != "" {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
 		}
+		cmd.Env = append(cmd.Env, "SCALARM_SHARED_DIR="+sim.sharedDataDir)
 	}
+
+	if sim.containerImage != "" {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, "SCALARM_CONTAINER_IMAGE="+sim.containerImage)
+	}
+
+	return cmd
 }
 
-func Extract(zip_path, dest string) error {
+// prepareAdapterAccount looks up the account configured in adapter_user, adapters are executed as the worker
+// when it does not have privileges to switch users
+func (sim SimulationManager) prepareAdapterAccount() *AdapterAccount {
+	if os.Geteuid() != 0 {
+		simLog.Warnf("adapter_user '%s' is ignored, SiM is not running as root", sim.Config.AdapterUser)
+		return nil
+	}
+
+	account, err := LookupAdapterAccount(sim.Config.AdapterUser, sim.Config.AdapterHome)
+	if err != nil {
+		Fatal(err)
+	}
+
+	if err = account.Prepare(); err != nil {
+		Fatal(err)
+	}
+
+	simLog.Infof("Adapters are executed as '%s' (uid %d) with HOME %s", account.Name, account.Uid, account.Home)
+	return account
+}
+
+// adapterFailure prints details about a failed adapter, saves the run report and terminates the worker
+// once its other simulation runs finished
+func (sim SimulationManager) adapterFailure(adapter string, cmd *exec.Cmd, err error, report *RunReport,
+	storageManagers []string, timeout time.Duration) {
+
+	simLog.Errorf("An error occurred during '%s' execution.", adapter)
+	simLog.Errorf("Please check if '%s' executes correctly on the selected infrastructure.", adapter)
+	simLog.Errorf("Fatal error occured during '%v' execution: %s", strings.Join(cmd.Args, " "), Redact(err.Error()))
+	PrintStdoutLog(path.Join(cmd.Dir, runLogName(cmd.Dir, report.SimulationIndex)), sim.Config.StdoutLogLines)
+	if lines, _ := LastLines(path.Join(cmd.Dir, stderrLog), stderrReasonLines); len(lines) > 0 {
+		simLog.Errorf("Last lines of %s:\n%s", stderrLog, Redact(strings.Join(lines, "\n")))
+	}
+
+	report.SetExitCode(adapter, cmd, err)
+	report.Fail(adapter+"_failed", withStderrTail(err.Error(), cmd.Dir))
+	// the directory of the failed run is left for inspection, subject to the retention policy if there is one
+	if sim.retentionPolicy() != nil && cmd.Dir != "" {
+		markSimulationDir(cmd.Dir, "error")
+	}
+	sim.emit("adapter_failed", report.ExperimentID, report.SimulationIndex,
+		map[string]interface{}{"adapter": adapter, "error": err.Error()})
+	sim.saveRunReport(report, storageManagers, timeout)
+
+	if sim.Config.PauseOnFailure {
+		PauseOnFailure(adapter, cmd)
+	}
+
+	Fatal(&AdapterFailure{adapter, report.ExperimentID, report.SimulationIndex, err})
+}
+
+// max number of archive entries extracted at the same time
+var extractWorkers = runtime.NumCPU()
+
+// extractZip unpacks the zip archive into dest; directories are created first, then files are extracted
+// in parallel by a bounded pool of workers
+func extractZip(zip_path, dest string) error {
 	r, err := zip.OpenReader(zip_path)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 
+	files := make(chan *zip.File)
+	errs := make(chan error, len(r.File))
+
+	// all entries are checked before anything is written
 	for _, f := range r.File {
-		err = cloneZipItem(f, dest)
-		if err != nil {
+		if _, err = SanitizeArchivePath(dest, f.Name); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
+	for _, f := range r.File {
+		entryPath, _ := SanitizeArchivePath(dest, f.Name)
+		dirPath := filepath.Dir(entryPath)
+		if f.FileInfo().IsDir() {
+			dirPath = entryPath
+		}
 
-func PrintStdoutLog() {
-	linesNum := "100" // TODO: make int strconv.Itoa(linesNum)
-	stdoutPath := "_stdout.txt"
-	out, _ := exec.Command("tail", "-n", linesNum, stdoutPath).CombinedOutput()
-	fmt.Printf("----------\nLast %v lines of %v:\n----------\n", linesNum, stdoutPath)
-	fmt.Println(string(out))
+		if err = os.MkdirAll(dirPath, os.ModeDir|os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	workers := extractWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range files {
+				if err := cloneZipItem(f, dest); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			files <- f
+		}
+	}
+	close(files)
+
+	wg.Wait()
+	close(errs)
+
+	for err = range errs {
+		return err
+	}
+
+	return nil
 }
 
 func cloneZipItem(f *zip.File, dest string) error {
 	//create full directory path
-	path := filepath.Join(dest, f.Name)
+	path, err := SanitizeArchivePath(dest, f.Name)
+	if err != nil {
+		return err
+	}
 
-	err := os.MkdirAll(filepath.Dir(path), os.ModeDir|os.ModePerm)
+	err = os.MkdirAll(filepath.Dir(path), os.ModeDir|os.ModePerm)
 	if err != nil {
 		return err
 	}
@@ -599,7 +1042,7 @@ func cloneZipItem(f *zip.File, dest string) error {
 			return err
 		}
 
-		_, err = io.Copy(fileCopy, rc)
+		_, err = copyWithPooledBuffer(fileCopy, rc)
 		fileCopy.Close()
 		if err != nil {
 			return err
@@ -3,23 +3,131 @@ package scalarmWorker
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	ExecutorNetworkHost = "host"
+	ExecutorNetworkNone = "none"
 )
 
 // Config file description - this should be provided by Experiment Manager in 'config.json'
 type SimulationManagerConfig struct {
-	ExperimentId           string `json:"experiment_id"`
-	InformationServiceUrl  string `json:"information_service_url"`
-	ExperimentManagerUser  string `json:"experiment_manager_user"`
-	ExperimentManagerPass  string `json:"experiment_manager_pass"`
-	Development            bool   `json:"development"`
-	StartAt                string `json:"start_at"`
-	Timeout                int    `json:"timeout"`
-	ScalarmCertificatePath string `json:"scalarm_certificate_path"`
-	SimulationsLimit       int    `json:"simulations_limit"`
-	InsecureSSL            bool   `json:"insecure_ssl"`
-	MonitoringInterval     int    `json:"monitoring_interval"`
-	CooldownInterval       int    `json:"cooldown_interval"`
+	ExperimentId               string                       `json:"experiment_id"`
+	InformationServiceUrl      string                       `json:"information_service_url"`
+	InformationServiceUrls     []string                     `json:"information_service_urls"`
+	InformationServiceSRV      string                       `json:"information_service_srv"`
+	OfflineDir                 string                       `json:"offline_dir"`
+	ExperimentManagerUser      string                       `json:"experiment_manager_user"`
+	ExperimentManagerPass      string                       `json:"experiment_manager_pass"`
+	CredentialHelper           string                       `json:"credential_helper"`
+	CredentialsExpireAt        string                       `json:"credentials_expire_at"`
+	CredentialsRefreshCommand  string                       `json:"credentials_refresh_command"`
+	CredentialsRefreshUrl      string                       `json:"credentials_refresh_url"`
+	CredentialsRefreshBefore   int                          `json:"credentials_refresh_before"`
+	Development                bool                         `json:"development"`
+	StartAt                    string                       `json:"start_at"`
+	Timeout                    int                          `json:"timeout"`
+	MaxAttempts                int                          `json:"max_attempts"`
+	RetryInitialDelay          float64                      `json:"retry_initial_delay"`
+	RetryMultiplier            float64                      `json:"retry_multiplier"`
+	RetryMaxDelay              float64                      `json:"retry_max_delay"`
+	RetryJitter                *float64                     `json:"retry_jitter"`
+	ScalarmCertificatePath     string                       `json:"scalarm_certificate_path"`
+	SimulationsLimit           int                          `json:"simulations_limit"`
+	Concurrency                int                          `json:"concurrency"`
+	InsecureSSL                bool                         `json:"insecure_ssl"`
+	CertificateFingerprints    []string                     `json:"certificate_fingerprints"`
+	StrictTLS                  bool                         `json:"strict_tls"`
+	DNSCacheTTL                int                          `json:"dns_cache_ttl"`
+	ServiceCacheTTL            int                          `json:"service_cache_ttl"`
+	ServiceCacheFile           string                       `json:"service_cache_file"`
+	RedirectTrustedHosts       []string                     `json:"redirect_trusted_hosts"`
+	MonitoringInterval         int                          `json:"monitoring_interval"`
+	CooldownInterval           int                          `json:"cooldown_interval"`
+	ReportsDir                 string                       `json:"reports_dir"`
+	UploadReport               bool                         `json:"upload_report"`
+	PauseOnFailure             bool                         `json:"pause_on_failure"`
+	AdapterUser                string                       `json:"adapter_user"`
+	AdapterHome                string                       `json:"adapter_home"`
+	ExecutorNetwork            string                       `json:"executor_network"`
+	KeepSimulationDirs         int                          `json:"keep_simulation_dirs"`
+	KeepFailedSimulationDirs   bool                         `json:"keep_failed_simulation_dirs"`
+	SimulationDirsMaxSize      int                          `json:"simulation_dirs_max_size"`
+	CleanupPolicy              string                       `json:"cleanup_policy"`
+	EmergencyCleanupSize       int                          `json:"emergency_cleanup_size"`
+	ScrubSimulationDirs        bool                         `json:"scrub_simulation_dirs"`
+	ScrubPasses                int                          `json:"scrub_passes"`
+	Tail                       bool                         `json:"tail"`
+	Dashboard                  bool                         `json:"dashboard"`
+	DownloadConnections        int                          `json:"download_connections"`
+	CodeBaseSignature          string                       `json:"code_base_signature"`
+	CodeBaseTrustedKeys        []string                     `json:"code_base_trusted_keys"`
+	CodeBaseSHA256             string                       `json:"code_base_sha256"`
+	SimulationBinariesSHA256   string                       `json:"simulation_binaries_sha256"`
+	UploadCompressionThreshold int64                        `json:"upload_compression_threshold"`
+	StdoutUploadMaxSize        int64                        `json:"stdout_upload_max_size"`
+	StdoutLogLines             int                          `json:"stdout_log_lines"`
+	Benchmark                  bool                         `json:"benchmark"`
+	BenchmarkDuration          int                          `json:"benchmark_duration"`
+	EnergySource               string                       `json:"energy_source"`
+	EnergySampleInterval       int                          `json:"energy_sample_interval"`
+	CancellationPollInterval   int                          `json:"cancellation_poll_interval"`
+	ContainerRuntime           string                       `json:"container_runtime"`
+	HostInventory              bool                         `json:"host_inventory"`
+	MemoizeResults             bool                         `json:"memoize_results"`
+	MemoizeAskManager          bool                         `json:"memoize_ask_manager"`
+	ValidateInputParameters    bool                         `json:"validate_input_parameters"`
+	PostProcessCommand         string                       `json:"post_process_command"`
+	UploadTransforms           []UploadTransform            `json:"upload_transforms"`
+	UploadReplicas             int                          `json:"upload_replicas"`
+	UploadReplication          string                       `json:"upload_replication"`
+	UploadChunkSize            int64                        `json:"upload_chunk_size"`
+	OutputFiles                []string                     `json:"output_files"`
+	ExperimentSelection        string                       `json:"experiment_selection"`
+	LogLevel                   string                       `json:"log_level"`
+	PrefetchNextSimulation     bool                         `json:"prefetch_next_simulation"`
+	SimulationBatchSize        int                          `json:"simulation_batch_size"`
+	ProgressBatchSize          int                          `json:"progress_batch_size"`
+	ProgressBatchInterval      int                          `json:"progress_batch_interval"`
+	CodeBaseStore              string                       `json:"code_base_store"`
+	ScratchDir                 string                       `json:"scratch_dir"`
+	ScratchCopyBack            []string                     `json:"scratch_copy_back"`
+	HistoryFile                string                       `json:"history_file"`
+	PauseFile                  string                       `json:"pause_file"`
+	DrainFile                  string                       `json:"drain_file"`
+	MaxRestarts                int                          `json:"max_restarts"`
+	RestartWindow              int                          `json:"restart_window"`
+	NotifySlackWebhook         string                       `json:"notify_slack_webhook"`
+	NotifySMTPServer           string                       `json:"notify_smtp_server"`
+	NotifySMTPUser             string                       `json:"notify_smtp_user"`
+	NotifySMTPPass             string                       `json:"notify_smtp_pass"`
+	NotifyEmailFrom            string                       `json:"notify_email_from"`
+	NotifyEmailTo              []string                     `json:"notify_email_to"`
+	GrafanaUrl                 string                       `json:"grafana_url"`
+	GrafanaApiKey              string                       `json:"grafana_api_key"`
+	GrafanaDashboardUID        string                       `json:"grafana_dashboard_uid"`
+	PushgatewayUrl             string                       `json:"pushgateway_url"`
+	PushgatewayJob             string                       `json:"pushgateway_job"`
+	PushgatewayInterval        int                          `json:"pushgateway_interval"`
+	EventStream                string                       `json:"event_stream"`
+	AuditLogPath               string                       `json:"audit_log_path"`
+	AuditLogUploadUrl          string                       `json:"audit_log_upload_url"`
+	RandomSeed                 *int64                       `json:"random_seed"`
+	HttpHeaders                map[string]string            `json:"http_headers"`
+	HttpHeadersByClass         map[string]map[string]string `json:"http_headers_by_class"`
+	AdminAddress               string                       `json:"admin_address"`
+	AdminToken                 string                       `json:"admin_token"`
+	DiagnosticsAddress         string                       `json:"diagnostics_address"`
+	RuntimeStatsInterval       int                          `json:"runtime_stats_interval"`
+	MaxRSS                     int64                        `json:"max_rss"`
+	MaxGoroutines              int                          `json:"max_goroutines"`
+	MaxOpenFiles               int                          `json:"max_open_files"`
+	SelfHealthInterval         int                          `json:"self_health_interval"`
 }
 
 func CreateSimulationManagerConfig(filePath string) (*SimulationManagerConfig, error) {
@@ -36,6 +144,10 @@ func CreateSimulationManagerConfig(filePath string) (*SimulationManagerConfig, e
 		return nil, errors.New("Incorrect JSON in the file.")
 	}
 
+	// the password must not show up in any output of SiM
+	RegisterCredentials(config.ExperimentManagerUser, config.ExperimentManagerPass)
+	registerCustomHeaderSecrets(config)
+
 	if config.SimulationsLimit <= 0 {
 		config.SimulationsLimit = -1
 	}
@@ -44,5 +156,178 @@ func CreateSimulationManagerConfig(filePath string) (*SimulationManagerConfig, e
 		config.Timeout = 60
 	}
 
+	if config.DownloadConnections <= 0 {
+		config.DownloadConnections = 4
+	}
+
+	if config.ProgressBatchInterval <= 0 {
+		config.ProgressBatchInterval = 60
+	}
+
+	if config.CredentialsRefreshBefore <= 0 {
+		config.CredentialsRefreshBefore = 600
+	}
+
+	if config.MaxRestarts == 0 {
+		config.MaxRestarts = 5
+	}
+
+	if config.RestartWindow <= 0 {
+		config.RestartWindow = 600
+	}
+
+	if config.StdoutUploadMaxSize == 0 {
+		config.StdoutUploadMaxSize = defaultStdoutUploadMaxSize
+	}
+
+	if config.StdoutLogLines <= 0 {
+		config.StdoutLogLines = defaultStdoutLogLines
+	}
+
 	return config, nil
 }
+
+// String prints the config with the password redacted
+func (config SimulationManagerConfig) String() string {
+	type plainConfig SimulationManagerConfig
+	plain := plainConfig(config)
+	if plain.ExperimentManagerPass != "" {
+		plain.ExperimentManagerPass = redactedValue
+	}
+
+	return fmt.Sprintf("%+v", plain)
+}
+
+// Validate checks if the config contains everything required to connect to Scalarm
+func (config *SimulationManagerConfig) Validate() []error {
+	errs := []error{}
+
+	// in the offline mode Scalarm services are not contacted
+	if config.OfflineDir != "" {
+		if _, err := os.Stat(filepath.Join(config.OfflineDir, "code_base.zip")); err != nil {
+			errs = append(errs, errors.New("offline_dir has to contain code_base.zip"))
+		}
+	} else {
+		if config.InformationServiceUrl == "" && len(config.InformationServiceUrls) == 0 && config.InformationServiceSRV == "" {
+			errs = append(errs, errors.New("information_service_url, information_service_urls or information_service_srv is required"))
+		}
+
+		if (config.ExperimentManagerUser == "" || config.ExperimentManagerPass == "") && config.CredentialHelper == "" {
+			errs = append(errs, errors.New("experiment_manager_user and experiment_manager_pass or credential_helper are required"))
+		}
+	}
+
+	if config.StartAt != "" {
+		if _, err := time.Parse(time.RFC3339, config.StartAt); err != nil {
+			errs = append(errs, errors.New("start_at is not a valid RFC3339 time: "+err.Error()))
+		}
+	}
+
+	if config.CredentialsExpireAt != "" {
+		if _, err := time.Parse(time.RFC3339, config.CredentialsExpireAt); err != nil {
+			errs = append(errs, errors.New("credentials_expire_at is not a valid RFC3339 time: "+err.Error()))
+		}
+	}
+
+	if config.ScalarmCertificatePath != "" {
+		if _, err := os.Stat(config.ScalarmCertificatePath); err != nil {
+			errs = append(errs, errors.New("scalarm_certificate_path does not point to a readable file"))
+		}
+	}
+
+	if config.RetryInitialDelay < 0 || config.RetryMaxDelay < 0 {
+		errs = append(errs, errors.New("retry_initial_delay and retry_max_delay cannot be negative"))
+	}
+
+	if config.RetryMultiplier != 0 && config.RetryMultiplier < 1 {
+		errs = append(errs, errors.New("retry_multiplier has to be at least 1"))
+	}
+
+	if config.RetryJitter != nil && (*config.RetryJitter < 0 || *config.RetryJitter > 1) {
+		errs = append(errs, errors.New("retry_jitter has to be between 0 and 1"))
+	}
+
+	if config.StrictTLS && config.InsecureSSL {
+		errs = append(errs, errors.New("insecure_ssl cannot be used together with strict_tls"))
+	}
+
+	for _, fingerprint := range config.CertificateFingerprints {
+		if err := checkFingerprint(fingerprint); err != nil {
+			errs = append(errs, errors.New("certificate_fingerprints: "+err.Error()))
+		}
+	}
+
+	if config.CodeBaseSHA256 != "" && config.CodeBaseSHA256 != ChecksumRequest && !isSHA256Digest(config.CodeBaseSHA256) {
+		errs = append(errs, errors.New("code_base_sha256 has to be a hex encoded SHA-256 digest or 'request'"))
+	}
+	if config.SimulationBinariesSHA256 != "" && !isSHA256Digest(config.SimulationBinariesSHA256) {
+		errs = append(errs, errors.New("simulation_binaries_sha256 has to be a hex encoded SHA-256 digest"))
+	}
+
+	if config.CodeBaseSignature != "" {
+		if config.CodeBaseSignature != SignatureGPG && config.CodeBaseSignature != SignatureEd25519 {
+			errs = append(errs, errors.New("code_base_signature has to be 'gpg' or 'ed25519'"))
+		}
+		if len(config.CodeBaseTrustedKeys) == 0 {
+			errs = append(errs, errors.New("code_base_trusted_keys are required when code_base_signature is set"))
+		}
+	}
+
+	if config.ExecutorNetwork != "" && config.ExecutorNetwork != ExecutorNetworkHost && config.ExecutorNetwork != ExecutorNetworkNone {
+		errs = append(errs, errors.New("executor_network has to be 'host' or 'none'"))
+	}
+
+	errs = append(errs, validateUploadTransforms(config.UploadTransforms)...)
+
+	if config.UploadReplication != "" && config.UploadReplication != UploadReplicationSync &&
+		config.UploadReplication != UploadReplicationAsync {
+		errs = append(errs, errors.New("upload_replication has to be 'sync' or 'async'"))
+	}
+
+	if config.UploadChunkSize < 0 {
+		errs = append(errs, errors.New("upload_chunk_size has to be a positive number of bytes or 0"))
+	}
+
+	if config.ExperimentSelection != "" && config.ExperimentSelection != ExperimentSelectionRandom &&
+		config.ExperimentSelection != ExperimentSelectionLeastLoaded {
+		errs = append(errs, errors.New("experiment_selection has to be 'random' or 'least_loaded'"))
+	}
+
+	if config.LogLevel != "" && !IsLogLevel(config.LogLevel) {
+		errs = append(errs, errors.New("log_level has to be 'debug', 'info', 'warn' or 'error'"))
+	}
+
+	if config.CleanupPolicy != "" && config.CleanupPolicy != CleanupAlways && config.CleanupPolicy != CleanupOnSuccess &&
+		config.CleanupPolicy != CleanupNever {
+		errs = append(errs, errors.New("cleanup_policy has to be 'always', 'on-success' or 'never'"))
+	}
+
+	if config.EnergySource != "" && config.EnergySource != EnergySourceRAPL && config.EnergySource != EnergySourceIPMI &&
+		config.EnergySource != EnergySourceAuto {
+		errs = append(errs, errors.New("energy_source has to be 'rapl', 'ipmi' or 'auto'"))
+	}
+
+	if config.Concurrency < 0 {
+		errs = append(errs, errors.New("concurrency cannot be negative"))
+	}
+
+	if config.MonitoringInterval < 0 || config.CooldownInterval < 0 {
+		errs = append(errs, errors.New("monitoring_interval and cooldown_interval cannot be negative"))
+	}
+
+	if config.DiagnosticsAddress != "" {
+		if err := checkLoopbackAddress(config.DiagnosticsAddress); err != nil {
+			errs = append(errs, errors.New("diagnostics_address is not valid: "+err.Error()))
+		}
+	}
+
+	errs = append(errs, checkCustomHeaders(config)...)
+
+	if config.AdminAddress != "" && !strings.HasPrefix(config.AdminAddress, "unix://") {
+		if err := checkLoopbackAddress(config.AdminAddress); err != nil {
+			errs = append(errs, errors.New("admin_address is not valid: "+err.Error()))
+		}
+	}
+
+	return errs
+}
@@ -30,6 +30,18 @@ func TestHandlingCorrectSimulationManagerConfigWithoutTimeout(t *testing.T) {
 	}
 }
 
+func TestHandlingCorrectSimulationManagerConfigWithoutUploadCompression(t *testing.T) {
+	config, err := CreateSimulationManagerConfig("test_assets/correct_input.json")
+
+	if err != nil {
+		t.Errorf("Got: '%v' - Expected nil", err)
+	}
+
+	if config.UploadCompressionThreshold != 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", config.UploadCompressionThreshold, 0)
+	}
+}
+
 func TestHandlingNoFileToCreateSimulationManagerConfig(t *testing.T) {
 	_, err := CreateSimulationManagerConfig("test_assets/does_not_exist.json")
 	if err == nil {
@@ -55,3 +67,25 @@ func TestHandlingIncorrectSimulationManagerConfig(t *testing.T) {
 		t.Errorf("Got: '%v' - Expected '%v'", err.Error(), expected_msg)
 	}
 }
+
+func TestValidateShouldAcceptCorrectSimulationManagerConfig(t *testing.T) {
+	config, _ := CreateSimulationManagerConfig("test_assets/correct_input.json")
+
+	if errs := config.Validate(); len(errs) != 0 {
+		t.Errorf("Got: '%v' - Expected no errors", errs)
+	}
+}
+
+func TestValidateShouldReportMissingAndInvalidValues(t *testing.T) {
+	config := &SimulationManagerConfig{
+		ExperimentManagerUser: "user",
+		StartAt:               "tomorrow",
+	}
+
+	errs := config.Validate()
+
+	expected_count := 3
+	if len(errs) != expected_count {
+		t.Errorf("Got: '%v' - Expected '%v' errors", errs, expected_count)
+	}
+}
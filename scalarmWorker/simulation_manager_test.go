@@ -1,13 +1,18 @@
 package scalarmWorker
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSimRunShouldRunSimulationsFromExperiment(t *testing.T) {
@@ -118,3 +123,88 @@ func TestSimRunShouldRunSimulationsFromExperiment(t *testing.T) {
 		t.Errorf("Performance information has not been sent")
 	}
 }
+
+func TestExtractShouldUnpackAllEntries(t *testing.T) {
+	// === GIVEN ===
+	zipPath := "./test_extract.zip"
+	dest := "./test_extract"
+	defer os.Remove(zipPath)
+	defer os.RemoveAll(dest)
+
+	zipFile, _ := os.Create(zipPath)
+	zipWriter := zip.NewWriter(zipFile)
+	zipWriter.Create("empty_dir/")
+	for i := 0; i < 50; i++ {
+		w, _ := zipWriter.Create(fmt.Sprintf("dir_%d/nested/file_%d.txt", i%5, i))
+		fmt.Fprintf(w, "content %d", i)
+	}
+	zipWriter.Close()
+	zipFile.Close()
+
+	// === WHEN ===
+	err := Extract(zipPath, dest)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+
+	if info, err := os.Stat(filepath.Join(dest, "empty_dir")); err != nil || !info.IsDir() {
+		t.Errorf("Directory entry was not created: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		content, _ := ioutil.ReadFile(filepath.Join(dest, fmt.Sprintf("dir_%d/nested/file_%d.txt", i%5, i)))
+		if string(content) != fmt.Sprintf("content %d", i) {
+			t.Errorf("Got: '%s' - Expected '%v'", content, fmt.Sprintf("content %d", i))
+		}
+	}
+}
+
+func TestExecuteScalarmRequestShouldReportAllFailedAttempts(t *testing.T) {
+	// === GIVEN ===
+	config := getSimConfig()
+	config.Development = true
+	config.MaxAttempts = 4
+	sim := SimulationManager{Config: config}
+	reqInfo := RequestInfo{"GET", nil, "", "experiments/random_experiment"}
+
+	// === WHEN ===
+	body, err := sim.ExecuteScalarmRequest(reqInfo, []string{"127.0.0.1:1", "127.0.0.1:2"}, &http.Client{}, time.Minute)
+
+	// === THEN ===
+	if body != nil {
+		t.Errorf("Got: '%s' - Expected nil", body)
+	}
+
+	requestErr, ok := err.(*RequestError)
+	if !ok {
+		t.Fatalf("Got: '%v' - Expected '%v'", err, "a RequestError")
+	}
+	if len(requestErr.Attempts) != 4 {
+		t.Errorf("Got: '%v' - Expected '%v'", len(requestErr.Attempts), 4)
+	}
+	if !strings.Contains(err.Error(), "(2 attempts)") {
+		t.Errorf("Got: '%v' - Expected '%v'", err.Error(), "2 attempts of every instance")
+	}
+	if !isRecoverable(err) {
+		t.Errorf("Connection errors should be recoverable: %v", err)
+	}
+}
+
+func TestSimulationsLimitExitCodeShouldBeZeroOnlyForWorkersOfAnAgent(t *testing.T) {
+	// === GIVEN ===
+	defer os.Setenv("SCALARM_WORKER_ID", os.Getenv("SCALARM_WORKER_ID"))
+	os.Unsetenv("SCALARM_WORKER_ID")
+
+	// === WHEN ===
+	standalone := simulationsLimitExitCode()
+	os.Setenv("SCALARM_WORKER_ID", "3")
+	agentWorker := simulationsLimitExitCode()
+
+	// === THEN ===
+	if standalone != 1 || agentWorker != 0 {
+		t.Errorf("Got: '%v, %v' - Expected '%v, %v'", standalone, agentWorker, 1, 0)
+	}
+}
@@ -0,0 +1,557 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// experimentRuns is what simulation runs of an experiment share, they may be executed concurrently
+type experimentRuns struct {
+	experimentID string
+	em           ExperimentManager
+	// gets and completes simulation runs, through the node coordinator if there is one
+	runsManager        *ExperimentManager
+	experimentManagers []string
+	storageManagers    []string
+	timeout            time.Duration
+	inputStager        InputStager
+	resultCache        *ResultCache
+	provenance         *Provenance
+	capabilities       *CodeBaseCapabilities
+	parameterSpace     *ParameterSpace
+	resultSummaries    []ResultSummary
+	experimentDir      string
+	// node-local directory simulations run in, if any, with a copy of the code base in adaptersDir
+	scratchExperimentDir string
+	adaptersDir          string
+
+	pool             *RunPool
+	simulationsLimit int
+	tailOutput       bool
+	// simulation run requested while results of the previous one were sent, only when runs are executed one by one
+	prefetch      *SimulationRunPrefetch
+	prefetchMutex sync.Mutex
+}
+
+// setPrefetch keeps the simulation run prefetched by a run being executed for the worker loop
+func (exp *experimentRuns) setPrefetch(prefetch *SimulationRunPrefetch) {
+	exp.prefetchMutex.Lock()
+	defer exp.prefetchMutex.Unlock()
+
+	exp.prefetch = prefetch
+}
+
+// takePrefetch returns the prefetched simulation run, if there is one, and forgets it
+func (exp *experimentRuns) takePrefetch() *SimulationRunPrefetch {
+	exp.prefetchMutex.Lock()
+	defer exp.prefetchMutex.Unlock()
+
+	prefetch := exp.prefetch
+	exp.prefetch = nil
+	return prefetch
+}
+
+// markAsComplete sends results of a simulation run started at runStartedAt, runs fetched in a batch are
+// acknowledged together with the rest of it
+func (sim SimulationManager) markAsComplete(exp *experimentRuns, batch *SimulationRunBatch, simulationIndex int,
+	data url.Values, runStartedAt time.Time) error {
+
+	if data.Get("provenance") == "" {
+		runProvenance, _ := marshalJSON(exp.provenance.ForRun(runStartedAt, time.Now()))
+		data.Set("provenance", string(runProvenance))
+	}
+	if sim.benchmark != nil {
+		data.Set("benchmark_score", strconv.FormatFloat(sim.benchmark.Score, 'f', 1, 64))
+	}
+	setCPUModel(data)
+	var err error
+	if batch != nil {
+		err = batch.Complete(simulationIndex, data)
+	} else {
+		_, err = exp.runsManager.MarkSimulationRunAsComplete(simulationIndex, data)
+	}
+	if err == nil {
+		finishRun(exp.experimentID, simulationIndex)
+	}
+	return err
+}
+
+// executeSimulationRun executes a simulation run in its own simulation_<index> directory and sends its results;
+// it returns whether the run counts towards simulations_limit, runs completed without being executed do not
+func (sim SimulationManager) executeSimulationRun(exp *experimentRuns, batch *SimulationRunBatch,
+	simulationRun map[string]interface{}) bool {
+
+	experimentID, em, inputStager, provenance := exp.experimentID, exp.em, exp.inputStager, exp.provenance
+	experimentDir, scratchExperimentDir, adaptersDir := exp.experimentDir, exp.scratchExperimentDir, exp.adaptersDir
+	capabilities, parameterSpace, resultSummaries := exp.capabilities, exp.parameterSpace, exp.resultSummaries
+	experimentManagers, storageManagers, communicationTimeout := exp.experimentManagers, exp.storageManagers, exp.timeout
+	resultCache, tailOutput := exp.resultCache, exp.tailOutput
+	// the next run is requested while results of this one are being sent, unless runs are executed concurrently;
+	// it is decided now, the pool may be resized while the run is executed
+	prefetchNext := sim.Config.PrefetchNextSimulation && exp.pool.Size() == 1 && batch == nil
+	var err error
+
+	simulationIndex := int(simulationRun["simulation_id"].(float64))
+
+	simLog.Infof("Simulation index: %v", simulationIndex)
+	simLog.Debugf("Simulation execution constraints: %v", simulationRun["execution_constraints"])
+	sim.emit("simulation_run_started", experimentID, simulationIndex,
+		map[string]interface{}{"input_parameters": simulationRun["input_parameters"]})
+	sim.history.RecordFetched(experimentID, simulationIndex)
+	addCurrentRun(experimentID, simulationIndex)
+	defer removeCurrentRun(experimentID, simulationIndex)
+	setUnfinishedRun(exp.runsManager, simulationIndex)
+	runStartedAt := time.Now()
+	setPhase := func(phase string) {
+		setRunPhase(experimentID, simulationIndex, phase)
+	}
+	setPhase("input_staging")
+
+	markAsComplete := func(simulationIndex int, data url.Values) error {
+		return sim.markAsComplete(exp, batch, simulationIndex, data, runStartedAt)
+	}
+
+	// input parameters before staging identify a parameter point, staged paths differ between runs
+	originalParameters, _ := marshalJSON(simulationRun["input_parameters"])
+
+	if sim.Config.MemoizeResults {
+		if result, found := sim.memoizedResult(&em, resultCache, experimentID, originalParameters); found {
+			simLog.Infof("Reusing results of an identical parameter point: %s", Redact(string(result)))
+
+			report := NewRunReport(experimentID, simulationIndex, originalParameters)
+			report.Status = "ok"
+			report.ReasonCode = ReasonMemoized
+
+			data := url.Values{}
+			data.Set("status", "ok")
+			data.Add("reason", "")
+			data.Add("result", string(result))
+			if err = markAsComplete(simulationIndex, data); err != nil {
+				simLog.Errorf("Error during marking simulation run as complete.")
+				Fatal(err)
+			}
+
+			sim.saveRunReport(report, storageManagers, communicationTimeout)
+			return false
+		}
+	}
+
+	simulationDirPath := path.Join(experimentDir, fmt.Sprintf("simulation_%v", simulationIndex))
+	copyBackDirPath := ""
+	if scratchExperimentDir != "" {
+		copyBackDirPath = simulationDirPath
+		simulationDirPath = path.Join(scratchExperimentDir, fmt.Sprintf("simulation_%v", simulationIndex))
+	}
+
+	err = os.MkdirAll(simulationDirPath, 0777)
+	if err != nil {
+		Fatal(err)
+	}
+
+	if sim.adapterAccount != nil {
+		if err = sim.adapterAccount.Own(simulationDirPath); err != nil {
+			Fatal(err)
+		}
+	}
+
+	if sim.sharedDataDir != "" {
+		if err = LinkSharedData(sim.sharedDataDir, simulationDirPath); err != nil {
+			Fatal(err)
+		}
+	}
+
+	inputParametersMap := simulationRun["input_parameters"].(map[string]interface{})
+	// bad input parameters fail the run with a precise reason instead of crashing the simulation
+	failureCode, failure := ReasonInvalidInput, "Invalid input parameters"
+	err = parameterSpace.Validate(inputParametersMap)
+	if err == nil {
+		failureCode, failure = ReasonInputStagingFailed, "Could not stage input files"
+		err = inputStager.StageInputFiles(inputParametersMap, simulationDirPath)
+	}
+	if err == nil {
+		err = inputStager.StageInputBlobs(simulationRun["input_files"], simulationDirPath)
+	}
+	// simple experiments generate input files from templates instead of an input_writer
+	if err == nil && capabilities.InputTemplates {
+		simLog.Infof("Rendering input templates ...")
+		failureCode, failure = ReasonInputTemplatesFailed, "Could not render input templates"
+		err = RenderInputTemplates(path.Join(adaptersDir, inputTemplatesDir), simulationDirPath, inputParametersMap)
+	}
+	if err != nil {
+		reason := failure + ": " + err.Error()
+		simLog.Infof("%s", Redact(reason))
+
+		report := NewRunReport(experimentID, simulationIndex, nil)
+		report.Fail(failureCode, reason)
+
+		data := url.Values{}
+		data.Set("status", "error")
+		data.Add("reason", reason)
+		if err = markAsComplete(simulationIndex, data); err != nil {
+			simLog.Errorf("Error during marking simulation run as complete.")
+			Fatal(err)
+		}
+
+		sim.saveRunReport(report, storageManagers, communicationTimeout)
+		sim.finishSimulationDir(simulationDirPath, "error")
+		return false
+	}
+
+	// output of all adapters of the run goes to simulation_<index>.log, linked from _stdout.txt
+	runLog := prepareRunLog(simulationDirPath, simulationIndex)
+	recordRunLog(simulationIndex, path.Join(simulationDirPath, runLog))
+
+	inputParameters, _ := marshalJSON(inputParametersMap)
+
+	err = ioutil.WriteFile(path.Join(simulationDirPath, "input.json"), inputParameters, 0777)
+	if err != nil {
+		Fatal(err)
+	}
+
+	report := NewRunReport(experimentID, simulationIndex, inputParameters)
+
+	// 4b. run an adapter script (input writer) for input information: input.json -> some specific code
+	if capabilities.InputWriter {
+		simLog.Debugf("Before input writer ...")
+		setPhase("input_writer")
+		phaseStart := time.Now()
+		inputWriterCmd := sim.adapterCommand(adapterCall(adaptersDir, "input_writer")+" input.json >>"+runLog+" 2>>"+stderrLog, simulationDirPath)
+		if err = inputWriterCmd.Run(); err != nil {
+			sim.adapterFailure("input_writer", inputWriterCmd, err, report, storageManagers, communicationTimeout)
+		}
+		report.AddPhase("input_writer", phaseStart)
+		report.SetExitCode("input_writer", inputWriterCmd, nil)
+		simLog.Debugf("After input writer ...")
+	}
+
+	// 4c.1. progress monitoring scheduling if available
+	messages := make(chan struct{}, 1)
+	finished := make(chan struct{}, 1)
+	go sim.IntermediateMonitoring(WorkerContext(), exp.pool, messages, finished, capabilities, experimentManagers, simulationIndex, simulationDirPath, sim.HttpClient, experimentID)
+
+	// 4c. run an executor of this simulation
+	simLog.Debugf("Before executor ...")
+	setPhase("executor")
+	phaseStart := time.Now()
+	executorCmd := sim.adapterCommand(adapterCall(adaptersDir, "executor")+" >>"+runLog+" 2>>"+stderrLog, simulationDirPath)
+	if sim.Config.ExecutorNetwork == ExecutorNetworkNone {
+		if err = isolateNetwork(executorCmd); err != nil {
+			Fatal(err)
+		}
+	}
+	var energyMeter *EnergyMeter
+	if sim.Config.EnergySource != "" {
+		var meterErr error
+		if energyMeter, meterErr = StartEnergyMeter(sim.Config.EnergySource, sim.energySampleInterval()); meterErr != nil {
+			simLog.Warnf("Energy consumption cannot be measured: %v", meterErr)
+		}
+	}
+	// the executor is stopped when the Experiment Manager cancels the run
+	cancellation := NewRunCancellation()
+	setRunCancellation(experimentID, simulationIndex, cancellation)
+	startInProcessGroup(executorCmd)
+	if err = executorCmd.Start(); err != nil {
+		sim.adapterFailure("executor", executorCmd, err, report, storageManagers, communicationTimeout)
+	}
+	executorExited := make(chan struct{})
+	go superviseExecutor(executorCmd, cancellation, executorExited)
+	walltime := StartWalltime(executorCmd, TimeLimit(simulationRun), executorExited)
+	memoryWatch := StartMemoryWatch(executorCmd, MemoryLimit(simulationRun), executorExited)
+	if sim.Config.CancellationPollInterval > 0 {
+		go pollCancellation(&em, simulationIndex, time.Duration(sim.Config.CancellationPollInterval)*time.Second,
+			cancellation, executorExited)
+	}
+
+	var tailStop, tailDone chan struct{}
+	if tailOutput {
+		tailStop = make(chan struct{})
+		tailDone = make(chan struct{})
+		go TailFile(path.Join(simulationDirPath, runLog), RedactingWriter{LogOutput{}}, tailStop, tailDone)
+	}
+
+	pid := executorCmd.Process.Pid
+	RunProcessMonitoring(pid, &sim, &em, simulationIndex)
+
+	err = executorCmd.Wait()
+	walltime.Stop()
+	close(executorExited)
+	memoryWatch.Wait()
+	report.PeakRSS = memoryWatch.Peak()
+	setRunCancellation(experimentID, simulationIndex, nil)
+	if energyMeter != nil {
+		report.EnergyJoules = energyMeter.Stop()
+		simLog.Infof("Energy consumed during the executor: %.1f J (%s)", report.EnergyJoules, energyMeter.Source)
+	}
+	if tailOutput {
+		close(tailStop)
+		<-tailDone
+	}
+	if cancelled, reason := cancellation.Cancelled(); cancelled {
+		report.AddPhase("executor", phaseStart)
+		messages <- struct{}{}
+		close(messages)
+		simLog.Warnf("Simulation run %v aborted", simulationIndex)
+		sim.abortRun(report, reason, markAsComplete, simulationDirPath, finished, storageManagers, communicationTimeout)
+		// a cancelled run is not returned to the queue even if reporting it failed
+		finishRun(exp.experimentID, simulationIndex)
+		return false
+	}
+	// an executor stopped after its time or memory limit produces an error result instead of failing the worker
+	limitExceeded := walltime.Exceeded() || memoryWatch.Exceeded()
+	if err != nil && !limitExceeded {
+		sim.adapterFailure("executor", executorCmd, err, report, storageManagers, communicationTimeout)
+	}
+	report.AddPhase("executor", phaseStart)
+	report.SetExitCode("executor", executorCmd, nil)
+
+	simLog.Debugf("After executor ...")
+
+	messages <- struct{}{}
+	close(messages)
+
+	// 4d. run an adapter script (output reader) to transform specific output format to scalarm model (output.json)
+	if capabilities.OutputReader && !limitExceeded {
+		simLog.Debugf("Before output reader ...")
+		setPhase("output_reader")
+		phaseStart := time.Now()
+		outputReaderCmd := sim.adapterCommand(adapterCall(adaptersDir, "output_reader")+" >>"+runLog+" 2>>"+stderrLog, simulationDirPath)
+		if err = outputReaderCmd.Run(); err != nil {
+			sim.adapterFailure("output_reader", outputReaderCmd, err, report, storageManagers, communicationTimeout)
+		}
+		report.AddPhase("output_reader", phaseStart)
+		report.SetExitCode("output_reader", outputReaderCmd, nil)
+		simLog.Debugf("After output reader ...")
+	}
+
+	if prefetchNext && (exp.simulationsLimit <= 0 || exp.pool.Done()+1 < exp.simulationsLimit) {
+		exp.setPrefetch(PrefetchSimulationRun(exp.runsManager))
+	}
+
+	// 4e. upload output json to experiment manager and set the run simulation as done
+	simulationRunResults := new(SimulationRunResults)
+
+	reasonCode := ReasonOK
+
+	outputPath := path.Join(simulationDirPath, "output.json")
+	if walltime.Exceeded() {
+		simulationRunResults.Status = "error"
+		simulationRunResults.Reason = fmt.Sprintf("The executor exceeded its time limit of %v", walltime.Limit)
+		reasonCode = ReasonTimeout
+	} else if memoryWatch.Exceeded() {
+		simulationRunResults.Status = "error"
+		simulationRunResults.Reason = fmt.Sprintf("memory limit exceeded: %d MB", memoryWatch.Limit/(1024*1024))
+		reasonCode = ReasonMemoryLimit
+	} else if _, err := os.Stat(outputPath); os.IsNotExist(err) {
+		simulationRunResults.Status = "error"
+		simulationRunResults.Reason = fmt.Sprintf("No output.json file found: %s", err.Error())
+		reasonCode = ReasonNoOutput
+	} else {
+		file, err := os.Open(outputPath)
+
+		if err != nil {
+			simulationRunResults.Status = "error"
+			simulationRunResults.Reason = fmt.Sprintf("Could not open output.json: %s", err.Error())
+			reasonCode = ReasonNoOutput
+		} else {
+			err = json.NewDecoder(file).Decode(&simulationRunResults)
+
+			if err != nil {
+				simulationRunResults.Status = "error"
+				simulationRunResults.Reason = fmt.Sprintf("Error during output.json parsing: %s", err.Error())
+				reasonCode = ReasonInvalidOutput
+			} else if simulationRunResults.Status == "error" {
+				reasonCode = ReasonSimulationError
+			}
+		}
+
+		file.Close()
+	}
+
+	// values from HDF5 and NetCDF output files are added to results of the output_reader
+	if len(resultSummaries) > 0 && (reasonCode == ReasonOK || reasonCode == ReasonNoOutput) {
+		simLog.Infof("Summarizing output files ...")
+		results, isObject := simulationRunResults.Results.(map[string]interface{})
+		if reasonCode == ReasonNoOutput || simulationRunResults.Results == nil {
+			results, isObject = map[string]interface{}{}, true
+		}
+
+		summary, err := SummarizeResults(resultSummaries, simulationDirPath)
+		if err == nil && !isObject {
+			err = errors.New("results in output.json are not a JSON object")
+		}
+
+		if err != nil {
+			simLog.Warnf("Summarizing output files failed: %v", err)
+			simulationRunResults.Status = "error"
+			simulationRunResults.Results = nil
+			simulationRunResults.Reason = fmt.Sprintf("Could not summarize output files: %s", err.Error())
+			reasonCode = ReasonSummaryFailed
+		} else {
+			for key, value := range summary {
+				results[key] = value
+			}
+			simulationRunResults.Status = "ok"
+			simulationRunResults.Results = results
+			simulationRunResults.Reason = ""
+			reasonCode = ReasonOK
+		}
+	}
+
+	// results are transformed by the post-processing command shared by all experiments
+	if sim.Config.PostProcessCommand != "" && simulationRunResults.Status == "ok" {
+		simLog.Debugf("Before post-processing ...")
+		phaseStart := time.Now()
+		postProcessCmd := sim.adapterCommand(sim.Config.PostProcessCommand, simulationDirPath)
+		if results, err := PostProcessResults(postProcessCmd, simulationRunResults.Results); err != nil {
+			simLog.Warnf("Post-processing failed: %v", Redact(err.Error()))
+			simulationRunResults.Status = "error"
+			simulationRunResults.Results = nil
+			simulationRunResults.Reason = fmt.Sprintf("Post-processing failed: %s", err.Error())
+			reasonCode = ReasonPostProcessingFailed
+		} else {
+			simulationRunResults.Results = results
+		}
+		report.AddPhase("post_processing", phaseStart)
+		simLog.Debugf("After post-processing ...")
+	}
+
+	resultJson, _ := marshalJSON(simulationRunResults.Results)
+
+	if !simulationRunResults.isValid() || !IsJSON(string(resultJson)) {
+		simLog.Warnf("Invalid results.json in output.json: %s", resultJson)
+		simulationRunResults.Status = "error"
+		simulationRunResults.Results = nil
+		simulationRunResults.Reason = fmt.Sprintf("Invalid results.json: %s", resultJson)
+		resultJson = nil
+		if reasonCode == ReasonOK {
+			reasonCode = ReasonInvalidOutput
+		}
+	}
+
+	// the end of stderr usually tells why the simulation failed
+	if simulationRunResults.Status == "error" {
+		simulationRunResults.Reason = withStderrTail(simulationRunResults.Reason, simulationDirPath)
+	}
+
+	report.Status = simulationRunResults.Status
+	report.ReasonCode = reasonCode
+	report.Reason = simulationRunResults.Reason
+
+	// outputs are copied to the shared storage, where the cleanup and retention policies apply to them
+	if copyBackDirPath != "" {
+		simLog.Infof("Copying outputs back to %s ...", copyBackDirPath)
+		if err = CopyBack(simulationDirPath, copyBackDirPath, sim.Config.ScratchCopyBack); err != nil {
+			Fatal(err)
+		}
+	}
+
+	// 4f. upload structural results of a simulation run
+	data := url.Values{}
+	data.Set("status", simulationRunResults.Status)
+	data.Add("reason", simulationRunResults.Reason)
+	data.Add("result", string(resultJson))
+	if energyMeter != nil {
+		data.Add("energy_joules", strconv.FormatFloat(report.EnergyJoules, 'f', 1, 64))
+	}
+	if report.PeakRSS > 0 {
+		data.Add("peak_rss", strconv.FormatUint(report.PeakRSS, 10))
+	}
+
+	// provenance is sent with results and stored in the uploaded archive
+	report.Provenance = provenance.ForRun(runStartedAt, time.Now())
+	runProvenance, _ := marshalJSON(report.Provenance)
+	data.Set("provenance", string(runProvenance))
+	outputArchivePath := path.Join(simulationDirPath, "output.tar.gz")
+	if _, err := os.Stat(outputArchivePath); err == nil {
+		if err = addFileToTarGz(outputArchivePath, provenanceFile, runProvenance); err == errEntryExists {
+			simLog.Infof("output.tar.gz already contains %s, it is not replaced", provenanceFile)
+		} else if err != nil {
+			simLog.Warnf("Could not add %s to output.tar.gz: %v", provenanceFile, err)
+		}
+	}
+
+	simLog.Infof("Results: %v", data)
+	sim.emit("simulation_run_results", experimentID, simulationIndex, simulationRunResults)
+
+	if err = markAsComplete(simulationIndex, data); err != nil {
+		simLog.Errorf("Error during marking simulation run as complete.")
+		if prefetch := exp.takePrefetch(); prefetch != nil {
+			prefetch.Rollback()
+		}
+		Fatal(err)
+	}
+
+	if sim.Config.MemoizeResults && simulationRunResults.Status == "ok" {
+		if err = resultCache.Store(experimentID, originalParameters, resultJson); err != nil {
+			simLog.Warnf("Could not cache results: %v", Redact(err.Error()))
+		}
+	}
+
+	// 4g. upload binary output if provided
+	setPhase("upload")
+	phaseStart = time.Now()
+	if _, err := os.Stat(outputArchivePath); err == nil {
+		simLog.Infof("Uploading 'output.tar.gz' ...")
+
+		binariesUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v", experimentID, simulationIndex)
+		sim.uploadArtifact(report, simulationDirPath, "output.tar.gz", binariesUploadUrl, storageManagers, communicationTimeout)
+	}
+
+	// 4h. upload stdout if provided
+	if _, err := os.Stat(path.Join(simulationDirPath, runLog)); err == nil {
+		simLog.Infof("Uploading STDOUT of the simulation run (%s) ...", runLog)
+
+		stdoutUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/stdout", experimentID, simulationIndex)
+		sim.uploadLog(report, simulationDirPath, runLog, stdoutUploadUrl, storageManagers, communicationTimeout)
+	}
+
+	// 4i. upload stderr if anything was written to it
+	if info, err := os.Stat(path.Join(simulationDirPath, stderrLog)); err == nil && info.Size() > 0 {
+		simLog.Infof("Uploading STDERR of the simulation run (%s) ...", stderrLog)
+
+		stderrUploadUrl := fmt.Sprintf("experiments/%s/simulations/%v/stderr", experimentID, simulationIndex)
+		sim.uploadLog(report, simulationDirPath, stderrLog, stderrUploadUrl, storageManagers, communicationTimeout)
+	}
+
+	// 4j. upload extra output files declared by output_files or the output_files.txt manifests
+	outputFilePatterns := append(append([]string{}, sim.Config.OutputFiles...),
+		ReadOutputFilesManifest(path.Join(adaptersDir, outputFilesManifest))...)
+	outputFilePatterns = append(outputFilePatterns, ReadOutputFilesManifest(path.Join(simulationDirPath, outputFilesManifest))...)
+	for _, name := range OutputFiles(simulationDirPath, outputFilePatterns, []string{"output.tar.gz", runLog, stderrLog, outputFilesManifest}) {
+		simLog.Infof("Uploading '%s' ...", name)
+
+		sim.uploadArtifact(report, simulationDirPath, name, outputFileUploadUrl(experimentID, simulationIndex, name),
+			storageManagers, communicationTimeout)
+	}
+	report.AddPhase("upload", phaseStart)
+
+	// 4k. store a machine-readable report of the run
+	sim.saveRunReport(report, storageManagers, communicationTimeout)
+
+	// 5. clean up - removing simulation dir unless the cleanup or retention policy keeps it
+	status := simulationRunResults.Status
+	failedUploads := report.FailedUploads
+	go func() {
+		select {
+		case _ = <-finished:
+			if copyBackDirPath != "" {
+				// the copy on shared storage is kept or removed by the policies, the scratch one is not needed
+				sim.cleanUpSimulationDir(copyBackDirPath, status, failedUploads)
+				sim.removeSimulationDir(simulationDirPath)
+			} else {
+				sim.cleanUpSimulationDir(simulationDirPath, status, failedUploads)
+			}
+			close(finished)
+		}
+	}()
+
+	return true
+}
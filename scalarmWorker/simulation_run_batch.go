@@ -0,0 +1,262 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sync"
+)
+
+var errBatchNotSupported = errors.New("Experiment manager does not support batches of simulation runs")
+
+// SimulationRunCompletion is a result of a single simulation run acknowledged in a batch
+type SimulationRunCompletion struct {
+	SimulationId int             `json:"simulation_id"`
+	Status       string          `json:"status"`
+	Reason       string          `json:"reason,omitempty"`
+	Result       json.RawMessage `json:"result,omitempty"`
+	Provenance   json.RawMessage `json:"provenance,omitempty"`
+}
+
+// SimulationRunBatch fetches up to Size simulation runs with a single request, hands them out one by one
+// and acknowledges their results together once the whole batch is executed; when the Experiment Manager
+// does not support batches, runs are fetched and acknowledged one at a time
+type SimulationRunBatch struct {
+	em          *ExperimentManager
+	size        int
+	unsupported bool
+	pending     []map[string]interface{}
+	completed   []SimulationRunCompletion
+	mutex       sync.Mutex
+}
+
+// the batch of the experiment being executed, it is closed by closeCurrentBatch registered once as an exit hook
+var currentBatch struct {
+	batch *SimulationRunBatch
+	mutex sync.Mutex
+}
+
+// setCurrentBatch makes the batch close on exit instead of the previous one
+func setCurrentBatch(batch *SimulationRunBatch) {
+	currentBatch.mutex.Lock()
+	defer currentBatch.mutex.Unlock()
+
+	currentBatch.batch = batch
+}
+
+// closeCurrentBatch acknowledges completed and rolls back unexecuted simulation runs of the current batch, if any
+func closeCurrentBatch() {
+	currentBatch.mutex.Lock()
+	batch := currentBatch.batch
+	currentBatch.batch = nil
+	currentBatch.mutex.Unlock()
+
+	if batch != nil {
+		batch.Close()
+	}
+}
+
+// NewSimulationRunBatch prepares batches of up to size simulation runs of the experiment
+func NewSimulationRunBatch(em *ExperimentManager, size int) *SimulationRunBatch {
+	return &SimulationRunBatch{em: em, size: size}
+}
+
+// Next returns the next simulation run in the format of GetNextSimulationRunConfig; a new batch
+// is requested, after acknowledging the previous one, when all fetched runs were handed out;
+// limit caps the number of requested runs when greater than 0
+func (batch *SimulationRunBatch) Next(limit int) (map[string]interface{}, error) {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	if len(batch.pending) == 0 {
+		if err := batch.flush(); err != nil {
+			return nil, err
+		}
+
+		count := batch.size
+		if limit > 0 && limit < count {
+			count = limit
+		}
+
+		if batch.unsupported || count <= 1 {
+			return batch.nextSingle()
+		}
+
+		response, err := batch.em.GetNextSimulationRunConfigs(count)
+		if err == errBatchNotSupported {
+			simLog.Infof("Experiment manager does not support batches, getting simulation runs one by one")
+			batch.unsupported = true
+			return batch.nextSingle()
+		} else if err != nil {
+			return nil, err
+		}
+
+		if status, _ := response["status"].(string); status != "ok" {
+			return response, nil
+		}
+
+		simulations, _ := response["simulations"].([]interface{})
+		for _, simulation := range simulations {
+			if simulationRun, ok := simulation.(map[string]interface{}); ok {
+				simulationRun["status"] = "ok"
+				// hints for the whole batch apply to each of its runs
+				if concurrency, ok := response["concurrency"]; ok {
+					simulationRun["concurrency"] = concurrency
+				}
+				batch.pending = append(batch.pending, simulationRun)
+			}
+		}
+
+		if len(batch.pending) == 0 {
+			return map[string]interface{}{"status": "error"}, nil
+		}
+
+		simLog.Infof("Received a batch of %d simulation runs", len(batch.pending))
+	}
+
+	simulationRun := batch.pending[0]
+	batch.pending = batch.pending[1:]
+
+	return simulationRun, nil
+}
+
+// nextSingle fetches a simulation run with next_simulation, which may return a chunk of them
+func (batch *SimulationRunBatch) nextSingle() (map[string]interface{}, error) {
+	response, err := batch.em.GetNextSimulationRunConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return batch.adopt(response), nil
+}
+
+// Adopt queues simulation runs of a chunk, an array of parameter sets returned by next_simulation for experiments
+// with very short runs, and returns the first of them; runs of a chunk are executed back-to-back and acknowledged
+// together like a batch, responses without a chunk are returned as they are
+func (batch *SimulationRunBatch) Adopt(response map[string]interface{}) map[string]interface{} {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	return batch.adopt(response)
+}
+
+func (batch *SimulationRunBatch) adopt(response map[string]interface{}) map[string]interface{} {
+	chunk, ok := response["chunk"].([]interface{})
+	if !ok {
+		return response
+	}
+
+	for _, item := range chunk {
+		if simulationRun, ok := item.(map[string]interface{}); ok {
+			// fields of the response, e.g. status or execution constraints, apply to each run of the chunk
+			for key, value := range response {
+				if _, ok := simulationRun[key]; !ok && key != "chunk" {
+					simulationRun[key] = value
+				}
+			}
+			batch.pending = append(batch.pending, simulationRun)
+		}
+	}
+
+	if len(batch.pending) == 0 {
+		return map[string]interface{}{"status": "error"}
+	}
+
+	simLog.Infof("Received a chunk of %d simulation runs", len(batch.pending))
+
+	simulationRun := batch.pending[0]
+	batch.pending = batch.pending[1:]
+
+	return simulationRun
+}
+
+// Complete stores the result of a simulation run in the format of MarkSimulationRunAsComplete,
+// results are sent when the last run of the batch is completed
+func (batch *SimulationRunBatch) Complete(simulationIndex int, data url.Values) error {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	completion := SimulationRunCompletion{
+		SimulationId: simulationIndex,
+		Status:       data.Get("status"),
+		Reason:       data.Get("reason"),
+	}
+	if result := data.Get("result"); result != "" {
+		completion.Result = json.RawMessage(result)
+	}
+	if provenance := data.Get("provenance"); provenance != "" {
+		completion.Provenance = json.RawMessage(provenance)
+	}
+	batch.completed = append(batch.completed, completion)
+
+	if len(batch.pending) > 0 {
+		return nil
+	}
+
+	return batch.flush()
+}
+
+// Close acknowledges completed simulation runs and returns runs which were not executed
+// to the Experiment Manager, so they can be computed by other workers
+func (batch *SimulationRunBatch) Close() {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	if err := batch.flush(); err != nil {
+		simLog.Warnf("Could not mark simulation runs as complete: %v", err)
+	}
+
+	for _, simulationRun := range batch.pending {
+		id, ok := simulationRun["simulation_id"].(float64)
+		if !ok {
+			simLog.Warnf("Could not roll back a simulation run without simulation_id: %v", simulationRun)
+			continue
+		}
+		simulationIndex := int(id)
+		simLog.Infof("Rolling back simulation run %v ...", simulationIndex)
+
+		if err := batch.em.RollbackSimulationRun(simulationIndex); err != nil {
+			simLog.Warnf("Could not roll back simulation run %v: %v", simulationIndex, err)
+		}
+	}
+	batch.pending = nil
+}
+
+// flush sends results of completed simulation runs, they are kept for the next flush until they are sent
+func (batch *SimulationRunBatch) flush() error {
+	if len(batch.completed) == 0 {
+		return nil
+	}
+
+	if !batch.unsupported {
+		err := batch.em.MarkSimulationRunsAsComplete(batch.completed)
+		if err == nil {
+			batch.completed = nil
+		}
+		if err != errBatchNotSupported {
+			return err
+		}
+
+		simLog.Infof("Experiment manager does not support batches, marking simulation runs as complete one by one")
+		batch.unsupported = true
+	}
+
+	for len(batch.completed) > 0 {
+		completion := batch.completed[0]
+
+		data := url.Values{}
+		data.Set("status", completion.Status)
+		data.Add("reason", completion.Reason)
+		data.Add("result", string(completion.Result))
+		if len(completion.Provenance) > 0 {
+			data.Add("provenance", string(completion.Provenance))
+		}
+
+		if _, err := batch.em.MarkSimulationRunAsComplete(completion.SimulationId, data); err != nil {
+			return err
+		}
+		batch.completed = batch.completed[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSimulationRunBatchShouldFetchAndAcknowledgeRunsTogether(t *testing.T) {
+	// === GIVEN ===
+	fetches := 0
+	acknowledged := [][]SimulationRunCompletion{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulations":
+			fetches++
+			if r.URL.Query().Get("count") != "2" {
+				w.WriteHeader(400)
+				return
+			}
+			fmt.Fprintln(w, `{"status":"ok","simulations":[{"simulation_id":1,"input_parameters":{"x":1}},`+
+				`{"simulation_id":2,"input_parameters":{"x":2}}]}`)
+		case "/experiments/568e5bece138232e76000002/simulations/mark_as_complete":
+			completions := []SimulationRunCompletion{}
+			json.Unmarshal([]byte(r.FormValue("results")), &completions)
+			acknowledged = append(acknowledged, completions)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batch := NewSimulationRunBatch(&em, 5)
+
+	// === WHEN ===
+	for i := 1; i <= 2; i++ {
+		simulationRun, err := batch.Next(2)
+		if err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+			return
+		}
+
+		// === THEN ===
+		if simulationRun["status"] != "ok" || simulationRun["simulation_id"] != float64(i) {
+			t.Errorf("Got: '%v' - Expected simulation run %v", simulationRun, i)
+		}
+
+		data := url.Values{}
+		data.Set("status", "ok")
+		data.Add("reason", "")
+		data.Add("result", fmt.Sprintf(`{"y":%d}`, i))
+		if err = batch.Complete(i, data); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", fetches, 1)
+	}
+
+	if len(acknowledged) != 1 || len(acknowledged[0]) != 2 {
+		t.Errorf("Got: '%v' - Expected a single request with 2 results", acknowledged)
+		return
+	}
+
+	if acknowledged[0][1].SimulationId != 2 || string(acknowledged[0][1].Result) != `{"y":2}` {
+		t.Errorf("Got: '%+v' - Expected result of simulation run 2", acknowledged[0][1])
+	}
+}
+
+func TestSimulationRunBatchShouldRollBackRunsWhichWereNotExecuted(t *testing.T) {
+	// === GIVEN ===
+	rolledBack := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulations":
+			fmt.Fprintln(w, `{"status":"ok","simulations":[{"simulation_id":1},{"simulation_id":2},{"simulation_id":3}]}`)
+		case "/experiments/568e5bece138232e76000002/simulations/2/rollback",
+			"/experiments/568e5bece138232e76000002/simulations/3/rollback":
+			rolledBack = append(rolledBack, r.URL.Path)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batch := NewSimulationRunBatch(&em, 3)
+
+	// === WHEN ===
+	batch.Next(0)
+	batch.Close()
+
+	// === THEN ===
+	if len(rolledBack) != 2 {
+		t.Errorf("Got: '%v' - Expected simulation runs 2 and 3 to be rolled back", rolledBack)
+	}
+}
+
+func TestSimulationRunBatchShouldFallBackToSingleRunsWhenNotSupported(t *testing.T) {
+	// === GIVEN ===
+	singleFetches := 0
+	singleMarks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulation":
+			singleFetches++
+			fmt.Fprintln(w, `{"status":"ok","simulation_id":7}`)
+		case "/experiments/568e5bece138232e76000002/simulations/7/mark_as_complete":
+			singleMarks++
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batch := NewSimulationRunBatch(&em, 10)
+
+	// === WHEN ===
+	simulationRun, err := batch.Next(0)
+	if err == nil {
+		err = batch.Complete(7, url.Values{"status": {"ok"}})
+	}
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if simulationRun["simulation_id"] != float64(7) || singleFetches != 1 || singleMarks != 1 {
+		t.Errorf("Got: '%v', %v fetches, %v marks - Expected a single run fetched and marked one by one",
+			simulationRun, singleFetches, singleMarks)
+	}
+}
+
+func TestSimulationRunBatchShouldExecuteChunksFromSingleFetch(t *testing.T) {
+	// === GIVEN ===
+	fetches := 0
+	acknowledged := [][]SimulationRunCompletion{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulation":
+			fetches++
+			fmt.Fprintln(w, `{"status":"ok","execution_constraints":{"time_constraint_in_sec":1},"chunk":[`+
+				`{"simulation_id":1,"input_parameters":{"x":1}},{"simulation_id":2,"input_parameters":{"x":2}},`+
+				`{"simulation_id":3,"input_parameters":{"x":3}}]}`)
+		case "/experiments/568e5bece138232e76000002/simulations/mark_as_complete":
+			completions := []SimulationRunCompletion{}
+			json.Unmarshal([]byte(r.FormValue("results")), &completions)
+			acknowledged = append(acknowledged, completions)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batch := NewSimulationRunBatch(&em, 1)
+
+	// === WHEN ===
+	for i := 1; i <= 3; i++ {
+		simulationRun, err := batch.Next(0)
+		if err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+			return
+		}
+
+		// === THEN ===
+		if simulationRun["status"] != "ok" || simulationRun["simulation_id"] != float64(i) ||
+			simulationRun["execution_constraints"] == nil {
+			t.Errorf("Got: '%v' - Expected simulation run %v with execution constraints", simulationRun, i)
+		}
+
+		data := url.Values{}
+		data.Set("status", "ok")
+		data.Add("result", fmt.Sprintf(`{"y":%d}`, i))
+		if err = batch.Complete(i, data); err != nil {
+			t.Errorf("Returned error should be nil, but it is '%v'", err)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", fetches, 1)
+	}
+
+	if len(acknowledged) != 1 || len(acknowledged[0]) != 3 {
+		t.Errorf("Got: '%v' - Expected a single request with 3 results", acknowledged)
+	}
+}
+
+func TestSimulationRunBatchShouldKeepResultsWhichWereNotSent(t *testing.T) {
+	// === GIVEN ===
+	failing := true
+	acknowledged := []SimulationRunCompletion{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulations":
+			fmt.Fprintln(w, `{"status":"ok","simulations":[{"simulation_id":1},{"simulation_id":2}]}`)
+		case "/experiments/568e5bece138232e76000002/simulations/mark_as_complete":
+			if failing {
+				w.WriteHeader(409)
+				return
+			}
+			json.Unmarshal([]byte(r.FormValue("results")), &acknowledged)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+	batch := NewSimulationRunBatch(&em, 2)
+
+	// === WHEN ===
+	batch.Next(0)
+	batch.Next(0)
+	batch.Complete(1, url.Values{"status": {"ok"}})
+	err := batch.Complete(2, url.Values{"status": {"ok"}})
+	failing = false
+	batch.Close()
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Returned error should not be nil")
+	}
+
+	if len(acknowledged) != 2 {
+		t.Errorf("Got: '%v' - Expected both results sent again", acknowledged)
+	}
+}
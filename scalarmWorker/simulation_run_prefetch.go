@@ -0,0 +1,80 @@
+package scalarmWorker
+
+import "sync"
+
+// SimulationRunPrefetch is a request for the next simulation run sent in the background while
+// the current run is finishing; a prefetched run which is not taken is rolled back
+type SimulationRunPrefetch struct {
+	em            *ExperimentManager
+	simulationRun map[string]interface{}
+	err           error
+	fetched       chan struct{}
+	released      chan struct{}
+	releaseOnce   sync.Once
+}
+
+// PrefetchSimulationRun starts getting the next simulation run of the experiment; until the run is taken,
+// a shutdown of SiM rolls it back so it is not stuck on the Experiment Manager side
+func PrefetchSimulationRun(em *ExperimentManager) *SimulationRunPrefetch {
+	prefetch := &SimulationRunPrefetch{
+		em:       em,
+		fetched:  make(chan struct{}),
+		released: make(chan struct{}),
+	}
+
+	go func() {
+		prefetch.simulationRun, prefetch.err = em.GetNextSimulationRunConfig()
+		close(prefetch.fetched)
+	}()
+
+	go prefetch.rollbackOnShutdown()
+
+	return prefetch
+}
+
+// Take waits for the prefetched simulation run and hands it over to the caller
+func (prefetch *SimulationRunPrefetch) Take() (map[string]interface{}, error) {
+	<-prefetch.fetched
+	prefetch.release()
+
+	return prefetch.simulationRun, prefetch.err
+}
+
+// Rollback waits for the prefetch to finish and returns the fetched simulation run to the Experiment Manager,
+// it does nothing when the run was already taken or nothing was fetched
+func (prefetch *SimulationRunPrefetch) Rollback() {
+	<-prefetch.fetched
+	if !prefetch.release() || prefetch.err != nil {
+		return
+	}
+
+	if status, _ := prefetch.simulationRun["status"].(string); status != "ok" {
+		return
+	}
+
+	simulationIndex := int(prefetch.simulationRun["simulation_id"].(float64))
+	simLog.Infof("Rolling back prefetched simulation run %v ...", simulationIndex)
+
+	if err := prefetch.em.RollbackSimulationRun(simulationIndex); err != nil {
+		simLog.Warnf("Could not roll back simulation run %v: %v", simulationIndex, err)
+	}
+}
+
+// release marks the prefetched run as handled, only the first call returns true
+func (prefetch *SimulationRunPrefetch) release() bool {
+	released := false
+	prefetch.releaseOnce.Do(func() {
+		close(prefetch.released)
+		released = true
+	})
+
+	return released
+}
+
+func (prefetch *SimulationRunPrefetch) rollbackOnShutdown() {
+	select {
+	case <-shutdown.requested:
+		prefetch.Rollback()
+	case <-prefetch.released:
+	}
+}
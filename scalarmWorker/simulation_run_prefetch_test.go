@@ -0,0 +1,70 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func setupPrefetchServer(rollbacks *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/experiments/568e5bece138232e76000002/next_simulation":
+			fmt.Fprintln(w, `{"status":"ok","simulation_id":7,"input_parameters":{"parameter1":0.0}}`)
+		case "/experiments/568e5bece138232e76000002/simulations/7/rollback":
+			atomic.AddInt32(rollbacks, 1)
+			fmt.Fprintln(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestPrefetchSimulationRunShouldReturnFetchedRun(t *testing.T) {
+	// === GIVEN ===
+	var rollbacks int32
+	server := setupPrefetchServer(&rollbacks)
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	prefetch := PrefetchSimulationRun(&em)
+	simulationRun, err := prefetch.Take()
+	prefetch.Rollback()
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, nil)
+		return
+	}
+
+	if simulationRun["simulation_id"].(float64) != 7 {
+		t.Errorf("Got: '%v' - Expected '%v'", simulationRun["simulation_id"], 7)
+	}
+
+	if rollbacks != 0 {
+		t.Errorf("Taken run should not be rolled back, rollbacks: %v", rollbacks)
+	}
+}
+
+func TestPrefetchSimulationRunShouldRollbackRunWhichWasNotTaken(t *testing.T) {
+	// === GIVEN ===
+	var rollbacks int32
+	server := setupPrefetchServer(&rollbacks)
+	defer server.Close()
+
+	em := setupExperimentManager(getSimConfig(), getHttpClientMock(server.URL))
+
+	// === WHEN ===
+	prefetch := PrefetchSimulationRun(&em)
+	prefetch.Rollback()
+	prefetch.Rollback()
+
+	// === THEN ===
+	if rollbacks != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", rollbacks, 1)
+	}
+}
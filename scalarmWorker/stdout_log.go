@@ -0,0 +1,81 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+const defaultStdoutLogLines = 100
+
+// blocks of this size are read from the end of a file until enough lines are collected
+var lastLinesBlockSize int64 = 64 * 1024
+
+// LastLines returns at most n last lines of the file; the file is read backwards from its end
+// so only the needed part of a huge log is loaded into memory
+func LastLines(filePath string, n int) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size()
+	data := []byte{}
+	newlines := 0
+
+	// one more newline than requested lines is needed to know that the first line is complete,
+	// a newline at the very end of the file does not start a new line
+	for offset > 0 && newlines <= n {
+		blockSize := lastLinesBlockSize
+		if blockSize > offset {
+			blockSize = offset
+		}
+		offset -= blockSize
+
+		block := make([]byte, blockSize)
+		if _, err = file.ReadAt(block, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		newlines += bytes.Count(block, []byte("\n"))
+		data = append(block, data...)
+	}
+
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) == 0 || n <= 0 {
+		return []string{}, nil
+	}
+
+	lines := []string{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		lines = append(lines, string(line))
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, nil
+}
+
+// PrintStdoutLog prints the last linesNum lines of the standard output of a simulation run saved in stdoutPath
+func PrintStdoutLog(stdoutPath string, linesNum int) {
+	fmt.Fprintf(LogOutput{}, "----------\nLast %v lines of %v:\n----------\n", linesNum, stdoutPath)
+
+	lines, err := LastLines(stdoutPath, linesNum)
+	if err != nil {
+		simLog.Warnf("Could not read %v: %v", stdoutPath, err)
+		return
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(LogOutput{}, Redact(line))
+	}
+}
@@ -0,0 +1,106 @@
+package scalarmWorker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLastLinesShouldReturnRequestedNumberOfLines(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_last_lines.txt"
+	defer os.Remove(filePath)
+
+	content := ""
+	for i := 1; i <= 1000; i++ {
+		content += fmt.Sprintf("line %d\n", i)
+	}
+	ioutil.WriteFile(filePath, []byte(content), 0666)
+
+	previousBlockSize := lastLinesBlockSize
+	lastLinesBlockSize = 16
+	defer func() { lastLinesBlockSize = previousBlockSize }()
+
+	// === WHEN ===
+	lines, err := LastLines(filePath, 3)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, nil)
+	}
+
+	expected := []string{"line 998", "line 999", "line 1000"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", lines, expected)
+	}
+}
+
+func TestLastLinesShouldReturnWholeShortFile(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_last_lines_short.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("first\nsecond"), 0666)
+
+	// === WHEN ===
+	lines, _ := LastLines(filePath, 100)
+
+	// === THEN ===
+	expected := []string{"first", "second"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Got: '%v' - Expected '%v'", lines, expected)
+	}
+}
+
+func TestLastLinesShouldHandleEmptyFile(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_last_lines_empty.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte{}, 0666)
+
+	// === WHEN ===
+	lines, err := LastLines(filePath, 10)
+
+	// === THEN ===
+	if err != nil || len(lines) != 0 {
+		t.Errorf("Got: '%v', '%v' - Expected no lines", lines, err)
+	}
+}
+
+func TestLastLinesShouldKeepLongLines(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_last_lines_long.txt"
+	defer os.Remove(filePath)
+	longLine := strings.Repeat("x", 200*1024)
+	ioutil.WriteFile(filePath, []byte("short\n"+longLine+"\n"), 0666)
+
+	// === WHEN ===
+	lines, _ := LastLines(filePath, 1)
+
+	// === THEN ===
+	if len(lines) != 1 || lines[0] != longLine {
+		t.Errorf("Got: %d lines - Expected the long line", len(lines))
+	}
+}
+
+func TestPrintStdoutLogShouldPrintLogOfTheGivenSimulationRun(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "stdout_log")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, RunLogName(3)), []byte("step 1\nstep 2\n"), 0666)
+	ioutil.WriteFile(filepath.Join(dir, RunLogName(4)), []byte("other run\n"), 0666)
+
+	// === WHEN ===
+	output := captureLog(t, func() {
+		PrintStdoutLog(filepath.Join(dir, RunLogName(3)), 1)
+	})
+
+	// === THEN ===
+	if !strings.HasSuffix(output, "----------\nstep 2\n") {
+		t.Errorf("Got: '%v' - Expected '%v'", output, "step 2")
+	}
+}
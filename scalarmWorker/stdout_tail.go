@@ -0,0 +1,56 @@
+package scalarmWorker
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+var tailPollInterval = 500 * time.Millisecond
+
+// TailFile copies everything appended to the file at filePath to out until stop is closed,
+// content which was already in the file when tailing started is skipped; done is closed when
+// the remaining content was copied after stop; a truncated or rotated file is followed from its beginning
+func TailFile(filePath string, out io.Writer, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	var offset int64
+	lastInfo, err := os.Stat(filePath)
+	if err == nil {
+		offset = lastInfo.Size()
+	}
+
+	for {
+		if info, err := os.Stat(filePath); err == nil {
+			if lastInfo != nil && (!os.SameFile(lastInfo, info) || info.Size() < offset) {
+				offset = 0
+			}
+			lastInfo = info
+		}
+
+		offset = copyFileFrom(filePath, offset, out)
+
+		select {
+		case <-stop:
+			copyFileFrom(filePath, offset, out)
+			return
+		case <-time.After(tailPollInterval):
+		}
+	}
+}
+
+// copyFileFrom copies the file content starting at offset and returns the offset of its end
+func copyFileFrom(filePath string, offset int64, out io.Writer) int64 {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return offset
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+
+	copied, _ := copyWithPooledBuffer(out, file)
+	return offset + copied
+}
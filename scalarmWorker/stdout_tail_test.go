@@ -0,0 +1,120 @@
+package scalarmWorker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTailFileShouldCopyOnlyAppendedContent(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_tail_stdout.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("before\n"), 0666)
+
+	out := &bytes.Buffer{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	// === WHEN ===
+	go TailFile(filePath, out, stop, done)
+	time.Sleep(100 * time.Millisecond)
+
+	file, _ := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0666)
+	file.WriteString("after\n")
+	file.Close()
+
+	close(stop)
+	<-done
+
+	// === THEN ===
+	if out.String() != "after\n" {
+		t.Errorf("Got: '%v' - Expected '%v'", out.String(), "after\n")
+	}
+}
+
+func TestTailFileShouldWaitForFileToBeCreated(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_tail_missing.txt"
+	os.Remove(filePath)
+	defer os.Remove(filePath)
+
+	out := &bytes.Buffer{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	// === WHEN ===
+	go TailFile(filePath, out, stop, done)
+	time.Sleep(100 * time.Millisecond)
+	ioutil.WriteFile(filePath, []byte("created\n"), 0666)
+
+	close(stop)
+	<-done
+
+	// === THEN ===
+	if out.String() != "created\n" {
+		t.Errorf("Got: '%v' - Expected '%v'", out.String(), "created\n")
+	}
+}
+
+func TestTailFileShouldFollowTruncatedFile(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_tail_truncated.txt"
+	defer os.Remove(filePath)
+	ioutil.WriteFile(filePath, []byte("a long line written before\n"), 0666)
+
+	out := &bytes.Buffer{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	previousInterval := tailPollInterval
+	tailPollInterval = 10 * time.Millisecond
+	defer func() { tailPollInterval = previousInterval }()
+
+	// === WHEN ===
+	go TailFile(filePath, out, stop, done)
+	time.Sleep(50 * time.Millisecond)
+	ioutil.WriteFile(filePath, []byte("new\n"), 0666)
+	time.Sleep(50 * time.Millisecond)
+
+	close(stop)
+	<-done
+
+	// === THEN ===
+	if out.String() != "new\n" {
+		t.Errorf("Got: '%v' - Expected '%v'", out.String(), "new\n")
+	}
+}
+
+func TestTailFileShouldFollowRotatedFile(t *testing.T) {
+	// === GIVEN ===
+	filePath := "./test_tail_rotated.txt"
+	defer os.Remove(filePath)
+	defer os.Remove(filePath + ".1")
+	ioutil.WriteFile(filePath, []byte("old\n"), 0666)
+
+	out := &bytes.Buffer{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	previousInterval := tailPollInterval
+	tailPollInterval = 10 * time.Millisecond
+	defer func() { tailPollInterval = previousInterval }()
+
+	// === WHEN ===
+	go TailFile(filePath, out, stop, done)
+	time.Sleep(50 * time.Millisecond)
+	os.Rename(filePath, filePath+".1")
+	ioutil.WriteFile(filePath, []byte("rotated content\n"), 0666)
+	time.Sleep(50 * time.Millisecond)
+
+	close(stop)
+	<-done
+
+	// === THEN ===
+	if out.String() != "rotated content\n" {
+		t.Errorf("Got: '%v' - Expected '%v'", out.String(), "rotated content\n")
+	}
+}
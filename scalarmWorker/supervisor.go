@@ -0,0 +1,148 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var supervisorLog = NewLogger("supervisor")
+
+// errors of file system operations which usually disappear when retried, e.g. on overloaded network file systems
+var transientErrnos = []syscall.Errno{syscall.EIO, syscall.EAGAIN, syscall.EBUSY, syscall.EINTR, syscall.ESTALE, syscall.ETIMEDOUT}
+
+// set while the worker loop is executed by a supervisor, Fatal then lets it restart the loop or terminate SiM
+// once simulation runs being executed finished
+var supervised int32
+
+// fatalError carries an error passed to Fatal from the worker loop, or from one of its simulation runs, to its supervisor
+type fatalError struct {
+	err error
+}
+
+// isRecoverable tells whether the worker loop may be restarted after the error
+func isRecoverable(err error) bool {
+	// adapters fail again on the same simulation run
+	var adapterFailure *AdapterFailure
+	if errors.As(err, &adapterFailure) {
+		return false
+	}
+
+	// errno values implement net.Error too, only errors of network operations are accepted here
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if _, isErrno := netErr.(syscall.Errno); !isErrno {
+			return true
+		}
+	}
+
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Supervisor restarts the worker loop after panics and recoverable errors passed to Fatal; a delay before
+// every restart doubles up to MaxBackoff and more than MaxRestarts restarts within Window terminate SiM,
+// as do other errors passed to Fatal
+type Supervisor struct {
+	MaxRestarts int
+	Window      time.Duration
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+
+	restarts []time.Time
+}
+
+// NewSupervisor creates a supervisor allowing maxRestarts restarts within window, a negative maxRestarts
+// disables restarts
+func NewSupervisor(maxRestarts int, window time.Duration) *Supervisor {
+	return &Supervisor{
+		MaxRestarts: maxRestarts,
+		Window:      window,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Minute,
+	}
+}
+
+// Run executes work until it returns or fails with an error which cannot be recovered from
+func (supervisor *Supervisor) Run(work func()) {
+	atomic.StoreInt32(&supervised, 1)
+	defer atomic.StoreInt32(&supervised, 0)
+
+	for {
+		// hooks registered by the failed loop, e.g. rollback of fetched simulation runs, are run before restart
+		hooksMark := exitHooksMark()
+
+		recoverable, err := supervisor.runOnce(work)
+		if err == nil {
+			return
+		}
+
+		// the loop fails only after its simulation runs finished, so exit hooks do not race with them
+		if !recoverable || supervisor.MaxRestarts < 0 {
+			runFatalHooks(err, 1)
+			Exit(1)
+			return
+		}
+
+		// requests and adapters left by the failed loop are aborted, the next one gets a new context
+		runExitHooksFrom(hooksMark)
+		CancelWorker()
+		resetWorkerContext()
+
+		now := time.Now()
+		recent := []time.Time{}
+		for _, restart := range supervisor.restarts {
+			if now.Sub(restart) < supervisor.Window {
+				recent = append(recent, restart)
+			}
+		}
+		supervisor.restarts = recent
+
+		if len(supervisor.restarts) >= supervisor.MaxRestarts {
+			supervisorLog.Errorf("%d restarts within %v, giving up", len(supervisor.restarts), supervisor.Window)
+			runFatalHooks(fmt.Errorf("%d restarts within %v, last error: %w", len(supervisor.restarts), supervisor.Window, err), 1)
+			Exit(1)
+			return
+		}
+
+		backoff := supervisor.MinBackoff
+		for i := 0; i < len(supervisor.restarts) && backoff < supervisor.MaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > supervisor.MaxBackoff {
+			backoff = supervisor.MaxBackoff
+		}
+
+		supervisorLog.Warnf("Worker loop failed: %v, restarting in %v", Redact(err.Error()), backoff)
+		time.Sleep(backoff)
+
+		supervisor.restarts = append(supervisor.restarts, time.Now())
+	}
+}
+
+// runOnce executes work and returns whether the loop may be restarted after the error which stopped it,
+// the error is nil when work returned
+func (supervisor *Supervisor) runOnce(work func()) (recoverable bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if fatal, ok := r.(fatalError); ok {
+				recoverable, err = isRecoverable(fatal.err), fatal.err
+			} else {
+				supervisorLog.Errorf("Panic: %v\n%s", r, debug.Stack())
+				recoverable, err = true, fmt.Errorf("panic: %v", r)
+			}
+		}
+	}()
+
+	work()
+	return false, nil
+}
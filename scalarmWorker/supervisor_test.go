@@ -0,0 +1,67 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSupervisorShouldRestartWorkAfterPanicsAndRecoverableErrors(t *testing.T) {
+	// === GIVEN ===
+	supervisor := NewSupervisor(5, time.Minute)
+	supervisor.MinBackoff = time.Millisecond
+
+	calls := 0
+	hooksRun := 0
+	work := func() {
+		calls++
+		OnExit(func() { hooksRun++ })
+
+		switch calls {
+		case 1:
+			panic("unexpected state")
+		case 2:
+			Fatal(&os.PathError{Op: "open", Path: "input.json", Err: syscall.ESTALE})
+		}
+	}
+
+	// === WHEN ===
+	supervisor.Run(work)
+
+	// === THEN ===
+	if calls != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", calls, 3)
+	}
+
+	if hooksRun != 2 {
+		t.Errorf("Got: '%v' - Expected exit hooks of 2 failed runs", hooksRun)
+	}
+
+	// the hook of the successful run stays registered
+	runExitHooksFrom(0)
+	if hooksRun != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", hooksRun, 3)
+	}
+}
+
+func TestIsRecoverableShouldAcceptOnlyTransientErrors(t *testing.T) {
+	// === GIVEN ===
+	cases := map[error]bool{
+		&os.PathError{Op: "write", Path: "output.txt", Err: syscall.EIO}:    true,
+		&os.PathError{Op: "open", Path: "config.json", Err: syscall.ENOENT}: false,
+		errors.New("Incorrect JSON in the file."):                           false,
+		&AdapterFailure{"executor", "e1", 1, syscall.EIO}:                   false,
+	}
+
+	for err, expected := range cases {
+		// === WHEN ===
+		recoverable := isRecoverable(err)
+
+		// === THEN ===
+		if recoverable != expected {
+			t.Errorf("Got: '%v' - Expected '%v' for '%v'", recoverable, expected, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scalarmWorker
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// cipher suites allowed in strict mode: ECDHE key exchange with AES-GCM, as approved by FIPS 140
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+var strictCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate in the 'AB:CD:...' form printed by openssl
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+
+	return strings.Join(pairs, ":")
+}
+
+// normalizeFingerprint allows fingerprints to be configured with or without colons and in any case
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.Replace(strings.TrimSpace(fingerprint), ":", "", -1))
+}
+
+// checkFingerprint verifies that a configured fingerprint is a hex encoded SHA-256 sum
+func checkFingerprint(fingerprint string) error {
+	normalized := normalizeFingerprint(fingerprint)
+	if decoded, err := hex.DecodeString(normalized); err != nil || len(decoded) != sha256.Size {
+		return errors.New("'" + fingerprint + "' is not a SHA-256 fingerprint")
+	}
+
+	return nil
+}
+
+// NewTLSConfig prepares TLS settings for communication with Scalarm:
+//   - certificate_fingerprints set: only servers presenting a leaf certificate with one of these fingerprints
+//     and matching the host name are accepted, the CA chain is not checked,
+//   - insecure_ssl set: certificates are not verified but fingerprints of accepted certificates are logged,
+//   - otherwise certificates are verified against system CAs and the configured Scalarm certificate.
+//
+// In strict mode only TLS 1.2+ with approved cipher suites and curves is allowed, insecure_ssl is refused
+// and pinned fingerprints are checked in addition to the regular verification of certificates.
+func NewTLSConfig(config *SimulationManagerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if config.StrictTLS {
+		if config.InsecureSSL {
+			return nil, errors.New("insecure_ssl cannot be used together with strict_tls")
+		}
+
+		simLog.Infof("Strict TLS mode: TLS 1.2+ with approved cipher suites only")
+		tlsConfig.MinVersion = tls.VersionTLS12
+		tlsConfig.CipherSuites = strictCipherSuites
+		tlsConfig.CurvePreferences = strictCurves
+	}
+
+	if config.ScalarmCertificatePath != "" {
+		CAPool := x509.NewCertPool()
+		serverCert, err := ioutil.ReadFile(config.ScalarmCertificatePath)
+		if err != nil {
+			return nil, errors.New("Could not load Scalarm certificate")
+		}
+		CAPool.AppendCertsFromPEM(serverCert)
+
+		tlsConfig.RootCAs = CAPool
+	}
+
+	if len(config.CertificateFingerprints) > 0 {
+		pinned := map[string]bool{}
+		for _, fingerprint := range config.CertificateFingerprints {
+			if err := checkFingerprint(fingerprint); err != nil {
+				return nil, err
+			}
+			pinned[normalizeFingerprint(fingerprint)] = true
+		}
+
+		simLog.Infof("TLS certificates are verified against %d pinned fingerprint(s)", len(pinned))
+		tlsConfig.InsecureSkipVerify = !config.StrictTLS
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPinnedCertificate(state, pinned)
+		}
+	} else if config.InsecureSSL {
+		simLog.Warnf("##############################################################################")
+		simLog.Warnf("insecure_ssl is enabled, certificates of Scalarm services are NOT verified.")
+		simLog.Warnf("Configure certificate_fingerprints to accept only known certificates instead.")
+		simLog.Warnf("##############################################################################")
+
+		logged := &sync.Map{}
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			logInsecureCertificate(state, logged)
+			return nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+func verifyPinnedCertificate(state tls.ConnectionState, pinned map[string]bool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server did not present any certificate")
+	}
+
+	leaf := state.PeerCertificates[0]
+	fingerprint := CertificateFingerprint(leaf)
+	if !pinned[normalizeFingerprint(fingerprint)] {
+		return fmt.Errorf("certificate of %s with fingerprint %s is not pinned", state.ServerName, fingerprint)
+	}
+
+	// there is no host name to check when a service is addressed by its IP
+	if state.ServerName == "" {
+		return nil
+	}
+
+	return leaf.VerifyHostname(state.ServerName)
+}
+
+// logInsecureCertificate prints the fingerprint of every certificate accepted without verification, once per host and certificate
+func logInsecureCertificate(state tls.ConnectionState, logged *sync.Map) {
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+	if _, seen := logged.LoadOrStore(state.ServerName+" "+fingerprint, true); seen {
+		return
+	}
+
+	simLog.Warnf("Accepted unverified certificate of %s, subject: %s, SHA-256 fingerprint: %s",
+		state.ServerName, state.PeerCertificates[0].Subject, fingerprint)
+}
@@ -0,0 +1,123 @@
+package scalarmWorker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getTLSClient(t *testing.T, config *SimulationManagerConfig) *http.Client {
+	tlsConfig, err := NewTLSConfig(config)
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func TestNewTLSConfigShouldAcceptPinnedCertificate(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	fingerprint := strings.ToLower(CertificateFingerprint(server.Certificate()))
+	client := getTLSClient(t, &SimulationManagerConfig{CertificateFingerprints: []string{fingerprint}})
+
+	// === WHEN ===
+	resp, err := client.Get(server.URL)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func TestNewTLSConfigShouldRejectCertificateWhichIsNotPinned(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	otherFingerprint := strings.Repeat("AB:", 31) + "AB"
+	client := getTLSClient(t, &SimulationManagerConfig{InsecureSSL: true, CertificateFingerprints: []string{otherFingerprint}})
+
+	// === WHEN ===
+	_, err := client.Get(server.URL)
+
+	// === THEN ===
+	if err == nil || !strings.Contains(err.Error(), "is not pinned") {
+		t.Errorf("Got: '%v' - Expected the certificate to be rejected", err)
+	}
+}
+
+func TestNewTLSConfigShouldAcceptAnyCertificateWithInsecureSSL(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := getTLSClient(t, &SimulationManagerConfig{InsecureSSL: true})
+
+	// === WHEN ===
+	resp, err := client.Get(server.URL)
+
+	// === THEN ===
+	if err != nil {
+		t.Errorf("Returned error should be nil, but it is '%v'", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func TestNewTLSConfigShouldRejectInvalidFingerprints(t *testing.T) {
+	if _, err := NewTLSConfig(&SimulationManagerConfig{CertificateFingerprints: []string{"AB:CD"}}); err == nil {
+		t.Errorf("Invalid fingerprint should be rejected")
+	}
+}
+
+func TestNewTLSConfigShouldRefuseInsecureSSLInStrictMode(t *testing.T) {
+	if _, err := NewTLSConfig(&SimulationManagerConfig{StrictTLS: true, InsecureSSL: true}); err == nil {
+		t.Errorf("insecure_ssl should be refused in strict mode")
+	}
+}
+
+func TestNewTLSConfigShouldRejectOldTLSVersionsInStrictMode(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS11}
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig, _ := NewTLSConfig(&SimulationManagerConfig{StrictTLS: true})
+	tlsConfig.RootCAs = x509.NewCertPool()
+	tlsConfig.RootCAs.AddCert(server.Certificate())
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	// === WHEN ===
+	_, err := client.Get(server.URL)
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Connection with TLS 1.1 should be rejected in strict mode")
+	}
+}
+
+func TestNewTLSConfigShouldVerifyPinnedCertificateChainInStrictMode(t *testing.T) {
+	// === GIVEN ===
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	fingerprint := CertificateFingerprint(server.Certificate())
+	client := getTLSClient(t, &SimulationManagerConfig{StrictTLS: true, CertificateFingerprints: []string{fingerprint}})
+
+	// === WHEN ===
+	_, err := client.Get(server.URL)
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Pinned certificate signed by an unknown CA should be rejected in strict mode")
+	}
+}
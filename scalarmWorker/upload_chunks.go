@@ -0,0 +1,153 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errChunkedUploadUnsupported is returned when a Storage Manager does not accept chunks
+var errChunkedUploadUnsupported = errors.New("Storage manager does not support chunked uploads")
+
+// FileChunk is a request body with a part of a file, streamed straight from disk; ExecuteScalarmRequest opens
+// a new stream for every attempt of sending the request and describes the part with a Content-Range header
+type FileChunk struct {
+	FilePath string
+	Offset   int64
+	Size     int64
+	// size of the whole file
+	Total  int64
+	stream io.ReadCloser
+}
+
+// ContentRange returns the value of the Content-Range header describing the chunk
+func (chunk *FileChunk) ContentRange() string {
+	return fmt.Sprintf("bytes %d-%d/%d", chunk.Offset, chunk.Offset+chunk.Size-1, chunk.Total)
+}
+
+// Open starts a new stream of the chunk
+func (chunk *FileChunk) Open() (io.ReadCloser, error) {
+	file, err := os.Open(chunk.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(file, chunk.Offset, chunk.Size), file}, nil
+}
+
+// Read allows FileChunk to be used as a plain io.Reader, the stream is opened on the first read
+func (chunk *FileChunk) Read(p []byte) (int, error) {
+	if chunk.stream == nil {
+		stream, err := chunk.Open()
+		if err != nil {
+			return 0, err
+		}
+		chunk.stream = stream
+	}
+
+	return chunk.stream.Read(p)
+}
+
+// uploadChunked uploads the file in chunks of upload_chunk_size bytes to one of the Storage Managers, so a dropped
+// connection repeats a single chunk instead of the whole file; a Storage Manager which does not support chunked
+// uploads gets the whole file in a single request, and after a failed response the next one is tried from the
+// first chunk
+func (sim SimulationManager) uploadChunked(filePath string, size int64, serviceMethod string, storageManagers []string,
+	timeout time.Duration) ([]byte, error) {
+
+	err := errors.New("There are no Storage Managers")
+	for _, i := range randomPerm(len(storageManagers)) {
+		storageManager := storageManagers[i]
+
+		var body []byte
+		body, err = sim.uploadChunksTo(filePath, size, serviceMethod, storageManager, timeout)
+		if err == errChunkedUploadUnsupported {
+			simLog.Infof("%s does not support chunked uploads, uploading '%s' at once", storageManager, filepath.Base(filePath))
+			body, err = sim.uploadWhole(filePath, serviceMethod, []string{storageManager}, timeout)
+		}
+		if err == nil {
+			return body, nil
+		}
+		simLog.Warnf("Uploading '%s' to %s failed: %v", filepath.Base(filePath), storageManager, Redact(err.Error()))
+	}
+
+	return nil, err
+}
+
+// uploadChunksTo sends subsequent chunks of the file with PUT <service method>/chunks/<index> and finalizes
+// the upload with POST <service method>/chunks/finalize, which returns the response of a regular upload
+func (sim SimulationManager) uploadChunksTo(filePath string, size int64, serviceMethod string, storageManager string,
+	timeout time.Duration) ([]byte, error) {
+
+	chunkSize := sim.Config.UploadChunkSize
+	chunks := int((size + chunkSize - 1) / chunkSize)
+
+	for index := 0; index < chunks; index++ {
+		chunk := &FileChunk{FilePath: filePath, Offset: int64(index) * chunkSize, Size: chunkSize, Total: size}
+		if chunk.Offset+chunk.Size > size {
+			chunk.Size = size - chunk.Offset
+		}
+
+		chunkPath := serviceMethod + "/chunks/" + strconv.Itoa(index)
+		_, status, err := sim.sendUploadRequest(RequestInfo{"PUT", chunk, "application/octet-stream", chunkPath},
+			storageManager, timeout)
+		if err != nil && index == 0 && (status == http.StatusNotFound || status == http.StatusMethodNotAllowed ||
+			status == http.StatusNotImplemented) {
+			return nil, errChunkedUploadUnsupported
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %v", index+1, chunks, err)
+		}
+		simLog.Debugf("Uploaded chunk %d of %d of '%s'", index+1, chunks, filepath.Base(filePath))
+	}
+
+	checksum, err := fileSHA256(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	finalizeData := url.Values{}
+	finalizeData.Set("file_name", filepath.Base(filePath))
+	finalizeData.Set("chunks", strconv.Itoa(chunks))
+	finalizeData.Set("size", strconv.FormatInt(size, 10))
+	finalizeData.Set("sha256", checksum)
+
+	body, _, err := sim.sendUploadRequest(RequestInfo{"POST", strings.NewReader(finalizeData.Encode()),
+		"application/x-www-form-urlencoded", serviceMethod + "/chunks/finalize"}, storageManager, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("finalizing the upload: %v", err)
+	}
+
+	return body, nil
+}
+
+// sendUploadRequest sends a request to the Storage Manager, failed connections are retried by ExecuteScalarmRequest
+// within its retry budget while any response other than 2xx fails the request; the status code of the response
+// is returned along with an error
+func (sim SimulationManager) sendUploadRequest(reqInfo RequestInfo, storageManager string,
+	timeout time.Duration) ([]byte, int, error) {
+
+	resp, err := ExecuteScalarmRequest(WorkerContext(), reqInfo, []string{storageManager}, sim.Config, sim.HttpClient, timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		err = errors.New("Storage manager response code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return body, resp.StatusCode, err
+}
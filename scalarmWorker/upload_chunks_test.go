@@ -0,0 +1,124 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadFileShouldSendArchiveInChunksAndFinalize(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_chunks")
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "output.tar.gz")
+	ioutil.WriteFile(archive, []byte("0123456789"), 0666)
+
+	var mutex sync.Mutex
+	chunks := map[string]string{}
+	ranges := []string{}
+	failures := 1
+	finalized := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/chunks/finalize") {
+			r.ParseForm()
+			finalized = r.Form.Get("chunks") + "," + r.Form.Get("size") + "," + r.Form.Get("sha256")
+			w.Write([]byte("stored"))
+			return
+		}
+
+		content, _ := ioutil.ReadAll(r.Body)
+		// the second chunk is rejected once, as if the Storage Manager was restarted, so the upload is repeated
+		if strings.HasSuffix(r.URL.Path, "/chunks/1") && failures > 0 {
+			failures--
+			w.WriteHeader(503)
+			return
+		}
+		chunks[r.URL.Path] = string(content)
+		ranges = append(ranges, r.Header.Get("Content-Range"))
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig(), HttpClient: getHttpClientMock(server.URL), RootDirPath: dir}
+	sim.Config.UploadChunkSize = 4
+	checksum, _ := fileSHA256(archive)
+
+	// === WHEN ===
+	body, err := sim.uploadFile(archive, "experiments/e1/simulations/1", []string{"sm1.com", "sm2.com"}, 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if string(body) != "stored" || finalized != "3,10,"+checksum {
+		t.Errorf("Got: '%s', '%v' - Expected '%v', '%v'", body, finalized, "stored", "3,10,"+checksum)
+	}
+
+	expectedChunks := map[string]string{
+		"/experiments/e1/simulations/1/chunks/0": "0123",
+		"/experiments/e1/simulations/1/chunks/1": "4567",
+		"/experiments/e1/simulations/1/chunks/2": "89",
+	}
+	for path, content := range expectedChunks {
+		if chunks[path] != content {
+			t.Errorf("Got: '%v' - Expected '%v'", chunks[path], content)
+		}
+	}
+
+	expectedRanges := "bytes 0-3/10,bytes 0-3/10,bytes 4-7/10,bytes 8-9/10"
+	if strings.Join(ranges, ",") != expectedRanges {
+		t.Errorf("Got: '%v' - Expected '%v'", strings.Join(ranges, ","), expectedRanges)
+	}
+}
+
+func TestUploadFileShouldSendWholeArchiveWhenChunksAreNotSupported(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_chunks")
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "output.tar.gz")
+	ioutil.WriteFile(archive, []byte("0123456789"), 0666)
+
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/chunks/") {
+			w.WriteHeader(404)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		content, _ := ioutil.ReadAll(file)
+		received = string(content)
+		w.Write([]byte("stored"))
+	}))
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig(), HttpClient: getHttpClientMock(server.URL), RootDirPath: dir}
+	sim.Config.UploadChunkSize = 4
+
+	// === WHEN ===
+	body, err := sim.uploadFile(archive, "experiments/e1/simulations/1", []string{"sm1.com"}, 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	if string(body) != "stored" || received != "0123456789" {
+		t.Errorf("Got: '%s', '%v' - Expected '%v', '%v'", body, received, "stored", "0123456789")
+	}
+}
@@ -0,0 +1,217 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// how output archives are replicated when upload_replicas is greater than 1
+const (
+	// all replicas are uploaded before the simulation run is completed
+	UploadReplicationSync = "sync"
+	// the simulation run is completed after the first upload, the other replicas are uploaded in the background
+	UploadReplicationAsync = "async"
+)
+
+// directory in the root directory of SiM with copies of output archives waiting to be mirrored
+const mirrorsDir = "mirrors"
+
+// mirrors of output archives which are still being uploaded, SiM waits for them before it exits
+var pendingMirrors sync.WaitGroup
+
+// registers the exit hook waiting for mirrors with the first mirror
+var mirrorsExitHook sync.Once
+
+// ArtifactReplica is a copy of an artifact stored by a single Storage Manager
+type ArtifactReplica struct {
+	StorageManager string `json:"storage_manager"`
+	Response       string `json:"response"`
+}
+
+// uploadReplicas returns how many Storage Managers should store every output archive
+func (config *SimulationManagerConfig) uploadReplicas() int {
+	if config.UploadReplicas < 1 {
+		return 1
+	}
+
+	return config.UploadReplicas
+}
+
+// uploadReplicated uploads the output archive to upload_replicas of the Storage Managers in random order; with
+// async replication only the first replica is uploaded before it returns and the others are mirrored in the background
+func (sim SimulationManager) uploadReplicated(report *RunReport, filePath string, serviceMethod string,
+	storageManagers []string, timeout time.Duration) ([]byte, error) {
+
+	candidates := []string{}
+	for _, i := range randomPerm(len(storageManagers)) {
+		candidates = append(candidates, storageManagers[i])
+	}
+	replicas := sim.Config.uploadReplicas()
+	if sim.Config.UploadReplication == UploadReplicationAsync {
+		replicas = 1
+	}
+
+	locations, rest, err := sim.uploadToReplicas(filePath, serviceMethod, candidates, replicas, timeout)
+	if len(locations) == 0 {
+		return nil, err
+	}
+	report.Replicas[filepath.Base(filePath)] = locations
+
+	if missing := sim.Config.uploadReplicas() - len(locations); missing > 0 {
+		if sim.Config.UploadReplication == UploadReplicationAsync {
+			sim.mirrorArtifact(report, filePath, serviceMethod, rest, missing, timeout)
+		} else {
+			simLog.Infof("'%s' is stored by %d of %d Storage Managers: %v", filePath, len(locations),
+				sim.Config.uploadReplicas(), Redact(err.Error()))
+		}
+	}
+
+	return []byte(locations[0].Response), nil
+}
+
+// uploadToReplicas uploads the file to subsequent Storage Managers until the given number of them store it,
+// Storage Managers which were not tried are returned along with the last error
+func (sim SimulationManager) uploadToReplicas(filePath string, serviceMethod string, storageManagers []string,
+	replicas int, timeout time.Duration) ([]ArtifactReplica, []string, error) {
+
+	locations := []ArtifactReplica{}
+	err := errors.New("There are no Storage Managers")
+
+	for len(storageManagers) > 0 && len(locations) < replicas {
+		storageManager := storageManagers[0]
+		storageManagers = storageManagers[1:]
+
+		body, uploadErr := sim.uploadFile(filePath, serviceMethod, []string{storageManager}, timeout)
+		if uploadErr != nil {
+			simLog.Warnf("Uploading '%s' to %s failed: %v", filePath, storageManager, Redact(uploadErr.Error()))
+			err = uploadErr
+			continue
+		}
+		locations = append(locations, ArtifactReplica{StorageManager: storageManager, Response: string(body)})
+	}
+
+	return locations, storageManagers, err
+}
+
+// mirrorArtifact copies the artifact, since the simulation directory may be removed before it is mirrored, and
+// prepares uploads of the copy to further Storage Managers, which are started in the background once the run
+// report is written; mirrored locations are added to the run report and reported with an artifact_replicated event
+func (sim SimulationManager) mirrorArtifact(report *RunReport, filePath string, serviceMethod string,
+	storageManagers []string, replicas int, timeout time.Duration) {
+
+	experimentID, simulationIndex, name := report.ExperimentID, report.SimulationIndex, filepath.Base(filePath)
+	mirrorPath := filepath.Join(sim.RootDirPath, mirrorsDir,
+		fmt.Sprintf("%s_%s_%s", experimentID, strconv.Itoa(simulationIndex), name))
+	if err := copyFile(filePath, mirrorPath); err != nil {
+		simLog.Warnf("Could not mirror '%s': %v", filePath, err)
+		return
+	}
+
+	report.mirrors = append(report.mirrors, func(reportPath string) {
+		mirrorsExitHook.Do(func() {
+			OnExit(func() { WaitForMirrors(timeout) })
+		})
+
+		pendingMirrors.Add(1)
+		go func() {
+			defer pendingMirrors.Done()
+			defer os.Remove(mirrorPath)
+
+			locations, _, err := sim.uploadToReplicas(mirrorPath, serviceMethod, storageManagers, replicas, timeout)
+			if len(locations) < replicas {
+				simLog.Infof("'%s' is mirrored to %d of %d Storage Managers: %v", filePath, len(locations),
+					replicas, Redact(err.Error()))
+			}
+			if len(locations) == 0 {
+				return
+			}
+
+			sim.emit("artifact_replicated", experimentID, simulationIndex,
+				map[string]interface{}{"name": name, "replicas": locations})
+			if reportPath != "" {
+				if err := addReplicasToReport(reportPath, name, locations); err != nil {
+					simLog.Warnf("Could not add mirrors of '%s' to %s: %v", name, reportPath, err)
+				}
+			}
+		}()
+	})
+}
+
+// startMirrors starts uploads of mirrors of artifacts of the run, their locations are added to the run report
+// written to reportPath, if any
+func startMirrors(report *RunReport, reportPath string) {
+	for _, mirror := range report.mirrors {
+		mirror(reportPath)
+	}
+	report.mirrors = nil
+}
+
+// addReplicasToReport adds locations of mirrors of the artifact to the run report written to reportPath
+func addReplicasToReport(reportPath string, name string, locations []ArtifactReplica) error {
+	content, err := ioutil.ReadFile(reportPath)
+	if err != nil {
+		return err
+	}
+
+	report := RunReport{}
+	if err = json.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	if report.Replicas == nil {
+		report.Replicas = map[string][]ArtifactReplica{}
+	}
+	report.Replicas[name] = append(report.Replicas[name], locations...)
+
+	if content, err = json.MarshalIndent(report, "", "  "); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(reportPath, []byte(Redact(string(content))), 0666)
+}
+
+// WaitForMirrors waits until output archives are mirrored, at most for the given time
+func WaitForMirrors(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		pendingMirrors.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		simLog.Warnf("Some output archives were not mirrored in %v", timeout)
+	}
+}
+
+func copyFile(sourcePath string, targetPath string) error {
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0777); err != nil {
+		return err
+	}
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+
+	return target.Close()
+}
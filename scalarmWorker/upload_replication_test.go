@@ -0,0 +1,122 @@
+package scalarmWorker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func storageManagersMock(failing string) (*httptest.Server, func() []string) {
+	var mutex sync.Mutex
+	uploads := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if r.Host == failing {
+			w.WriteHeader(500)
+			return
+		}
+
+		mutex.Lock()
+		uploads = append(uploads, r.Host)
+		mutex.Unlock()
+		w.Write([]byte("stored by " + r.Host))
+	}))
+
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]string{}, uploads...)
+	}
+}
+
+func TestUploadReplicatedShouldStoreOutputArchiveOnRequestedNumberOfStorageManagers(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_replication")
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "output.tar.gz")
+	ioutil.WriteFile(archive, []byte("results"), 0666)
+
+	server, uploads := storageManagersMock("sm2.com")
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig(), HttpClient: getHttpClientMock(server.URL), RootDirPath: dir}
+	sim.Config.UploadReplicas = 2
+	report := NewRunReport("e1", 1, nil)
+
+	// === WHEN ===
+	body, err := sim.uploadReplicated(report, archive, "experiments/e1/simulations/1",
+		[]string{"sm1.com", "sm2.com", "sm3.com"}, 5*time.Second)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Returned error should be nil, but it is '%v'", err)
+	}
+
+	replicas := report.Replicas["output.tar.gz"]
+	if len(replicas) != 2 || replicas[0].StorageManager == replicas[1].StorageManager {
+		t.Fatalf("Got: '%v' - Expected '%v'", replicas, "replicas on sm1.com and sm3.com")
+	}
+	for _, replica := range replicas {
+		if replica.StorageManager == "sm2.com" || replica.Response != "stored by "+replica.StorageManager {
+			t.Errorf("Got: '%v' - Expected '%v'", replica, "a replica on sm1.com or sm3.com")
+		}
+	}
+
+	if string(body) != replicas[0].Response || len(uploads()) != 2 {
+		t.Errorf("Got: '%s', %v - Expected '%v', %v", body, uploads(), replicas[0].Response, 2)
+	}
+}
+
+func TestUploadReplicatedShouldMirrorAsynchronouslyAfterFirstUpload(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_replication")
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "output.tar.gz")
+	ioutil.WriteFile(archive, []byte("results"), 0666)
+
+	server, uploads := storageManagersMock("")
+	defer server.Close()
+
+	sim := SimulationManager{Config: getSimConfig(), HttpClient: getHttpClientMock(server.URL), RootDirPath: dir}
+	sim.Config.UploadReplicas = 3
+	sim.Config.UploadReplication = UploadReplicationAsync
+	report := NewRunReport("e1", 1, nil)
+
+	// === WHEN ===
+	_, err := sim.uploadReplicated(report, archive, "experiments/e1/simulations/1",
+		[]string{"sm1.com", "sm2.com", "sm3.com"}, 5*time.Second)
+	os.Remove(archive)
+	reportPath, _ := report.Write(filepath.Join(dir, "reports"))
+	startMirrors(report, reportPath)
+	WaitForMirrors(10 * time.Second)
+
+	// === THEN ===
+	if err != nil || len(report.Replicas["output.tar.gz"]) != 1 {
+		t.Errorf("Got: '%v', %v - Expected '%v', %v", err, report.Replicas["output.tar.gz"], nil, 1)
+	}
+
+	written := RunReport{}
+	content, _ := ioutil.ReadFile(reportPath)
+	json.Unmarshal(content, &written)
+	if len(written.Replicas["output.tar.gz"]) != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", written.Replicas["output.tar.gz"], "3 replicas in the run report")
+	}
+
+	if len(uploads()) != 3 {
+		t.Errorf("Got: '%v' - Expected '%v'", uploads(), "uploads to 3 Storage Managers")
+	}
+
+	if mirrors, _ := ioutil.ReadDir(filepath.Join(dir, mirrorsDir)); len(mirrors) != 0 {
+		t.Errorf("Got: '%v' - Expected '%v'", len(mirrors), 0)
+	}
+}
@@ -0,0 +1,167 @@
+package scalarmWorker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// policies applied when a transform of an artifact fails
+const (
+	// the artifact is not uploaded and the simulation directory is kept like after a failed upload
+	TransformFailureAbort = "abort"
+	// the chain continues with the input of the failed transform
+	TransformFailureSkip = "skip"
+)
+
+// time a transform may run when no timeout is configured
+const defaultTransformTimeout = 300
+
+// UploadTransform is an external command applied to artifacts of simulation runs before they are uploaded,
+// e.g. to anonymize, downsample or convert them; it reads $SCALARM_ARTIFACT_IN and writes $SCALARM_ARTIFACT_OUT
+type UploadTransform struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// glob patterns of artifact names the transform is applied to, all artifacts when empty
+	Artifacts []string `json:"artifacts"`
+	// seconds after which the transform is stopped and fails
+	Timeout   int    `json:"timeout"`
+	OnFailure string `json:"on_failure"`
+}
+
+// appliesTo tells whether the transform is applied to the artifact
+func (transform UploadTransform) appliesTo(artifact string) bool {
+	if len(transform.Artifacts) == 0 {
+		return true
+	}
+
+	for _, pattern := range transform.Artifacts {
+		if matched, _ := filepath.Match(pattern, filepath.Base(artifact)); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (transform UploadTransform) timeout() time.Duration {
+	if transform.Timeout <= 0 {
+		return defaultTransformTimeout * time.Second
+	}
+
+	return time.Duration(transform.Timeout) * time.Second
+}
+
+// validateUploadTransforms checks commands and failure policies of upload transforms
+func validateUploadTransforms(transforms []UploadTransform) []error {
+	errs := []error{}
+
+	for i, transform := range transforms {
+		if transform.Command == "" {
+			errs = append(errs, fmt.Errorf("upload_transforms[%d]: command is required", i))
+		}
+		if transform.OnFailure != "" && transform.OnFailure != TransformFailureAbort && transform.OnFailure != TransformFailureSkip {
+			errs = append(errs, fmt.Errorf("upload_transforms[%d]: on_failure has to be 'abort' or 'skip'", i))
+		}
+		if transform.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("upload_transforms[%d]: timeout cannot be negative", i))
+		}
+		for _, pattern := range transform.Artifacts {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("upload_transforms[%d]: invalid artifact pattern '%s'", i, pattern))
+			}
+		}
+	}
+
+	return errs
+}
+
+// transformArtifact passes the artifact through the chain of upload transforms in their order; the result
+// replaces the artifact, which is then uploaded under its name
+func (sim SimulationManager) transformArtifact(report *RunReport, artifact string) error {
+	input := artifact
+	transformed := []string{}
+	defer func() {
+		for _, file := range transformed {
+			os.Remove(file)
+		}
+	}()
+
+	for i, transform := range sim.Config.UploadTransforms {
+		if !transform.appliesTo(artifact) {
+			continue
+		}
+
+		name := transform.Name
+		if name == "" {
+			name = fmt.Sprintf("upload_transforms[%d]", i)
+		}
+
+		output := fmt.Sprintf("%s.transform_%d", artifact, i)
+		transformed = append(transformed, output)
+
+		simLog.Infof("Transforming '%s' with %s ...", artifact, name)
+		err := sim.runTransform(transform, artifact, input, output)
+		if err == nil {
+			input = output
+			continue
+		}
+
+		simLog.Warnf("Transform %s of '%s' failed: %v", name, artifact, Redact(err.Error()))
+		sim.emit("artifact_transform_failed", report.ExperimentID, report.SimulationIndex,
+			map[string]interface{}{"name": artifact, "transform": name, "error": err.Error()})
+
+		if transform.OnFailure != TransformFailureSkip {
+			return fmt.Errorf("Transform %s failed: %v", name, err)
+		}
+	}
+
+	if input == artifact {
+		return nil
+	}
+
+	return os.Rename(input, artifact)
+}
+
+// runTransform executes the transform in the directory of the artifact and checks that it wrote its output in time
+func (sim SimulationManager) runTransform(transform UploadTransform, artifact string, input string, output string) error {
+	cmd := sim.adapterCommand(transform.Command, filepath.Dir(artifact))
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "SCALARM_ARTIFACT_NAME="+filepath.Base(artifact), "SCALARM_ARTIFACT_IN="+input,
+		"SCALARM_ARTIFACT_OUT="+output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	startInProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+	waitResult := make(chan error, 1)
+	go func() {
+		waitResult <- cmd.Wait()
+		close(exited)
+	}()
+
+	select {
+	case err := <-waitResult:
+		if err != nil {
+			return err
+		}
+	case <-time.After(transform.timeout()):
+		terminateProcessGroup(cmd, cancellationGrace, exited)
+		<-exited
+		return fmt.Errorf("Timed out after %v", transform.timeout())
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		return errors.New("The transform did not write $SCALARM_ARTIFACT_OUT")
+	}
+
+	return nil
+}
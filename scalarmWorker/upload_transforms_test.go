@@ -0,0 +1,86 @@
+package scalarmWorker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransformArtifactShouldApplyTransformsInOrder(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_transforms")
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "simulation_1.log")
+	ioutil.WriteFile(artifact, []byte("secret output\n"), 0666)
+
+	sim := SimulationManager{Config: getSimConfig()}
+	sim.Config.UploadTransforms = []UploadTransform{
+		{Name: "anonymize", Command: `sed s/secret/xxx/ "$SCALARM_ARTIFACT_IN" > "$SCALARM_ARTIFACT_OUT"`},
+		{Name: "broken", Command: "exit 3", OnFailure: TransformFailureSkip},
+		{Name: "archives only", Command: "exit 3", Artifacts: []string{"*.tar.gz"}},
+		{Name: "uppercase", Command: `tr a-z A-Z < "$SCALARM_ARTIFACT_IN" > "$SCALARM_ARTIFACT_OUT"`},
+	}
+	report := NewRunReport("e1", 1, nil)
+
+	// === WHEN ===
+	err := sim.transformArtifact(report, artifact)
+
+	// === THEN ===
+	if err != nil {
+		t.Fatalf("Got: '%v' - Expected '%v'", err, nil)
+	}
+
+	if content, _ := ioutil.ReadFile(artifact); string(content) != "XXX OUTPUT\n" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "XXX OUTPUT")
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Got: '%v' - Expected '%v'", len(files), 1)
+	}
+}
+
+func TestTransformArtifactShouldAbortUploadWhenTransformTimesOut(t *testing.T) {
+	// === GIVEN ===
+	dir, _ := ioutil.TempDir("", "upload_transforms")
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "output.tar.gz")
+	ioutil.WriteFile(artifact, []byte("raw"), 0666)
+
+	previousGrace := cancellationGrace
+	cancellationGrace = 100 * time.Millisecond
+	defer func() { cancellationGrace = previousGrace }()
+
+	sim := SimulationManager{Config: getSimConfig()}
+	sim.Config.UploadTransforms = []UploadTransform{{Name: "slow", Command: "sleep 30", Timeout: 1}}
+	report := NewRunReport("e1", 1, nil)
+
+	// === WHEN ===
+	started := time.Now()
+	err := sim.transformArtifact(report, artifact)
+
+	// === THEN ===
+	if err == nil || time.Since(started) > 10*time.Second {
+		t.Errorf("Got: '%v' after %v - Expected '%v'", err, time.Since(started), "a timeout")
+	}
+
+	if content, _ := ioutil.ReadFile(artifact); string(content) != "raw" {
+		t.Errorf("Got: '%s' - Expected '%v'", content, "raw")
+	}
+}
+
+func TestValidateUploadTransformsShouldRejectUnknownPolicies(t *testing.T) {
+	// === WHEN ===
+	errs := validateUploadTransforms([]UploadTransform{
+		{Command: "true", OnFailure: TransformFailureSkip},
+		{Command: "", OnFailure: "retry"},
+	})
+
+	// === THEN ===
+	if len(errs) != 2 {
+		t.Errorf("Got: '%v' - Expected '%v'", errs, "missing command and invalid on_failure")
+	}
+}
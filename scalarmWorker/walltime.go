@@ -0,0 +1,68 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// TimeLimit returns the walltime of the executor of a simulation run, given in seconds by the 'time_limit'
+// execution constraint or by 'time_constraint_in_sec' sent by Experiment Managers, 0 means there is no limit
+func TimeLimit(simulationRun map[string]interface{}) time.Duration {
+	constraints, _ := simulationRun["execution_constraints"].(map[string]interface{})
+
+	limit, ok := constraints["time_limit"]
+	if !ok {
+		limit = constraints["time_constraint_in_sec"]
+	}
+
+	var seconds float64
+	switch limit := limit.(type) {
+	case float64:
+		seconds = limit
+	case string:
+		seconds, _ = strconv.ParseFloat(limit, 64)
+	}
+	if seconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Walltime stops the executor, which runs in its own process group, once its time limit is exceeded
+type Walltime struct {
+	Limit time.Duration
+
+	timer    *time.Timer
+	exceeded int32
+}
+
+// StartWalltime starts counting the time limit of a started executor; without a limit it never fires
+func StartWalltime(cmd *exec.Cmd, limit time.Duration, exited <-chan struct{}) *Walltime {
+	walltime := &Walltime{Limit: limit}
+	if limit <= 0 {
+		return walltime
+	}
+
+	walltime.timer = time.AfterFunc(limit, func() {
+		atomic.StoreInt32(&walltime.exceeded, 1)
+		simLog.Warnf("The executor exceeded its time limit of %v, stopping it ...", limit)
+		terminateProcessGroup(cmd, cancellationGrace, exited)
+	})
+
+	return walltime
+}
+
+// Stop stops counting once the executor exited
+func (walltime *Walltime) Stop() {
+	if walltime.timer != nil {
+		walltime.timer.Stop()
+	}
+}
+
+// Exceeded tells whether the executor was stopped because of its time limit
+func (walltime *Walltime) Exceeded() bool {
+	return atomic.LoadInt32(&walltime.exceeded) == 1
+}
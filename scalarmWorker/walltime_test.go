@@ -0,0 +1,77 @@
+package scalarmWorker
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTimeLimitShouldBeParsedFromExecutionConstraints(t *testing.T) {
+	cases := []struct {
+		simulationRun map[string]interface{}
+		expected      time.Duration
+	}{
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"time_limit": 90.0}}, 90 * time.Second},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"time_limit": "1.5"}}, 1500 * time.Millisecond},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"time_constraint_in_sec": 3300.0}}, 3300 * time.Second},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"time_limit": 60.0, "time_constraint_in_sec": 3300.0}}, time.Minute},
+		{map[string]interface{}{"execution_constraints": map[string]interface{}{"time_limit": -1.0}}, 0},
+		{map[string]interface{}{}, 0},
+	}
+
+	for _, c := range cases {
+		if limit := TimeLimit(c.simulationRun); limit != c.expected {
+			t.Errorf("Got: '%v' - Expected '%v' for %v", limit, c.expected, c.simulationRun)
+		}
+	}
+}
+
+func TestWalltimeShouldStopProcessGroupOfExecutorPastItsLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30; wait")
+	startInProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+
+	// === WHEN ===
+	start := time.Now()
+	walltime := StartWalltime(cmd, 100*time.Millisecond, exited)
+	cmd.Wait()
+	walltime.Stop()
+	close(exited)
+
+	// === THEN ===
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("The executor should be stopped right after its time limit")
+	}
+	if !walltime.Exceeded() {
+		t.Errorf("Got: '%v' - Expected '%v'", walltime.Exceeded(), true)
+	}
+}
+
+func TestWalltimeShouldNotFireWithoutLimit(t *testing.T) {
+	// === GIVEN ===
+	cmd := exec.Command("sh", "-c", "true")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+
+	// === WHEN ===
+	walltime := StartWalltime(cmd, 0, exited)
+	cmd.Wait()
+	walltime.Stop()
+	close(exited)
+
+	// === THEN ===
+	if walltime.Exceeded() {
+		t.Errorf("Got: '%v' - Expected '%v'", walltime.Exceeded(), false)
+	}
+}
@@ -0,0 +1,65 @@
+package scalarmWorker
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+)
+
+// context of the worker loop: cancelling it aborts requests to Scalarm in flight and kills adapters
+// started by the loop, so nothing is left running after a fatal error, the second signal or an exit
+var workerContext struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	processes map[*exec.Cmd]struct{}
+	mutex     sync.Mutex
+}
+
+func init() {
+	resetWorkerContext()
+}
+
+// WorkerContext returns the context of the current worker loop
+func WorkerContext() context.Context {
+	workerContext.mutex.Lock()
+	defer workerContext.mutex.Unlock()
+
+	return workerContext.ctx
+}
+
+// CancelWorker aborts requests and adapters of the current worker loop; process groups of running executors
+// are killed before it returns
+func CancelWorker() {
+	workerContext.mutex.Lock()
+	defer workerContext.mutex.Unlock()
+
+	workerContext.cancel()
+	for cmd := range workerContext.processes {
+		killProcessGroup(cmd)
+	}
+	workerContext.processes = map[*exec.Cmd]struct{}{}
+}
+
+// resetWorkerContext starts a new context for a restarted worker loop
+func resetWorkerContext() {
+	workerContext.mutex.Lock()
+	defer workerContext.mutex.Unlock()
+
+	workerContext.ctx, workerContext.cancel = context.WithCancel(context.Background())
+	workerContext.processes = map[*exec.Cmd]struct{}{}
+}
+
+// trackProcessGroup makes CancelWorker kill the process group of a started command until untrackProcessGroup
+func trackProcessGroup(cmd *exec.Cmd) {
+	workerContext.mutex.Lock()
+	defer workerContext.mutex.Unlock()
+
+	workerContext.processes[cmd] = struct{}{}
+}
+
+func untrackProcessGroup(cmd *exec.Cmd) {
+	workerContext.mutex.Lock()
+	defer workerContext.mutex.Unlock()
+
+	delete(workerContext.processes, cmd)
+}
@@ -0,0 +1,58 @@
+package scalarmWorker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExecuteScalarmRequestShouldAbortRequestInFlightWhenContextIsCancelled(t *testing.T) {
+	// === GIVEN ===
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	reqInfo := RequestInfo{"GET", nil, "", "experiment_managers"}
+
+	// === WHEN ===
+	start := time.Now()
+	_, err := ExecuteScalarmRequest(ctx, reqInfo, []string{"system.scalarm.com"}, getSimConfig(), getHttpClientMock(server.URL), time.Minute)
+
+	// === THEN ===
+	if err == nil {
+		t.Errorf("Got: '%v' - Expected '%v'", err, "an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Got: '%v' - Expected '%v'", elapsed, "the request aborted right after cancel")
+	}
+}
+
+func TestCancelWorkerShouldCancelWorkerContext(t *testing.T) {
+	defer resetWorkerContext()
+
+	// === GIVEN ===
+	ctx := WorkerContext()
+
+	// === WHEN ===
+	CancelWorker()
+
+	// === THEN ===
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Got: '%v' - Expected '%v'", ctx.Err(), context.Canceled)
+	}
+
+	resetWorkerContext()
+	if WorkerContext().Err() != nil {
+		t.Errorf("Got: '%v' - Expected '%v'", WorkerContext().Err(), nil)
+	}
+}